@@ -0,0 +1,131 @@
+package hjson
+
+import (
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// BatchJob describes a single conversion done by ConvertBatch.
+type BatchJob struct {
+	// Src is the path of the JSON or Hjson input file.
+	Src string
+	// Dst is the path of the Hjson output file. It is created or truncated.
+	Dst string
+}
+
+// FileError records an error that occurred while converting a file.
+type FileError struct {
+	Path string
+	Err  error
+}
+
+func (e *FileError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+// BatchError is returned by ConvertBatch when one or more jobs failed. It
+// holds one FileError per failed job, in the order of the jobs.
+type BatchError []*FileError
+
+func (e BatchError) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// ConvertBatch converts many independent JSON or Hjson files to Hjson
+// concurrently, using at most workers goroutines (runtime.NumCPU() if
+// workers is not positive).
+//
+// The members of objects keep the order of the input.
+//
+// A failing job does not stop the others. If any job failed the returned
+// error is a BatchError listing all failures.
+//
+// For example, to convert all JSON files of a directory:
+//
+//	files, _ := filepath.Glob("config/*.json")
+//	var jobs []hjson.BatchJob
+//	for _, f := range files {
+//		jobs = append(jobs, hjson.BatchJob{Src: f, Dst: strings.TrimSuffix(f, ".json") + ".hjson"})
+//	}
+//	err := hjson.ConvertBatch(jobs, 0, hjson.DefaultOptions())
+func ConvertBatch(jobs []BatchJob, workers int, options EncoderOptions) error {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(jobs) {
+		workers = len(jobs)
+	}
+
+	errs := make([]*FileError, len(jobs))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				errs[i] = convertFile(jobs[i], options)
+			}
+		}()
+	}
+	for i := range jobs {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	var batchErr BatchError
+	for _, err := range errs {
+		if err != nil {
+			batchErr = append(batchErr, err)
+		}
+	}
+	if len(batchErr) > 0 {
+		return batchErr
+	}
+	return nil
+}
+
+func convertFile(job BatchJob, options EncoderOptions) *FileError {
+	data, err := readFile(job.Src)
+	if err != nil {
+		return &FileError{job.Src, err}
+	}
+	// objects keep the order of their members
+	decOpt := DefaultDecoderOptions()
+	decOpt.UseOrderedMap = true
+	var value interface{}
+	if err = UnmarshalWithOptions(data, &value, decOpt); err != nil {
+		return &FileError{job.Src, err}
+	}
+	out, err := MarshalWithOptions(value, options)
+	if err != nil {
+		return &FileError{job.Src, err}
+	}
+	out = append(out, options.eol()...)
+	if err = ioutil.WriteFile(job.Dst, out, 0666); err != nil {
+		return &FileError{job.Dst, err}
+	}
+	return nil
+}
+
+// readFile reads a file, decompressing it if needed.
+func readFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r, err := DecompressReader(f)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(r)
+}
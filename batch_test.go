@@ -0,0 +1,75 @@
+package hjson
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConvertBatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hjson-batch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inputs := map[string]string{
+		"a.json":   `{"z": 1, "a": {"y": 2, "b": 3}}`,
+		"b.json":   `["x", "y"]`,
+		"bad.json": `{"a": `,
+	}
+	var jobs []BatchJob
+	for _, name := range []string{"a.json", "missing.json", "b.json", "bad.json"} {
+		src := filepath.Join(dir, name)
+		if content, ok := inputs[name]; ok {
+			if err := ioutil.WriteFile(src, []byte(content), 0666); err != nil {
+				t.Fatal(err)
+			}
+		}
+		jobs = append(jobs, BatchJob{Src: src, Dst: src + ".hjson"})
+	}
+
+	err = ConvertBatch(jobs, 2, DefaultOptions())
+	batchErr, ok := err.(BatchError)
+	if !ok {
+		t.Fatalf("expected a BatchError, got %v", err)
+	}
+	if len(batchErr) != 2 || batchErr[0].Path != jobs[1].Src || batchErr[1].Path != jobs[3].Src {
+		t.Errorf("unexpected errors: %v", batchErr)
+	}
+
+	out, err := ioutil.ReadFile(jobs[2].Dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "[\n  x\n  y\n]\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+
+	// keys keep the order of the input
+	if out, _ = ioutil.ReadFile(jobs[0].Dst); string(out) != "{\n  z: 1\n  a:\n  {\n    y: 2\n    b: 3\n  }\n}\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+
+	if err = ConvertBatch(jobs[:1], 0, DefaultOptions()); err != nil {
+		t.Error(err)
+	}
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte(inputs["b.json"]))
+	zw.Close()
+	job := BatchJob{Src: filepath.Join(dir, "c.json.gz"), Dst: filepath.Join(dir, "c.hjson")}
+	if err = ioutil.WriteFile(job.Src, gz.Bytes(), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err = ConvertBatch([]BatchJob{job}, 1, DefaultOptions()); err != nil {
+		t.Fatal(err)
+	}
+	if out, _ = ioutil.ReadFile(job.Dst); string(out) != "[\n  x\n  y\n]\n" {
+		t.Errorf("unexpected output %q for compressed input", out)
+	}
+}
@@ -0,0 +1,62 @@
+package hjson
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"reflect"
+	"strconv"
+)
+
+// isByteSlice reports whether values of type t are byte slices encoded as a
+// string, like encoding/json does: slices of a byte type that does not
+// implement a marshaling interface.
+func isByteSlice(t reflect.Type) bool {
+	if t.Kind() != reflect.Slice || t.Elem().Kind() != reflect.Uint8 {
+		return false
+	}
+	p := reflect.PtrTo(t.Elem())
+	return !p.Implements(hjsonMarshaler) && !p.Implements(marshaler) && !p.Implements(textMarshaler)
+}
+
+// bytesFormat returns the format of byte slices, see
+// EncoderOptions.BytesFormat.
+func (e *hjsonEncoder) bytesFormat() string {
+	if e.BytesFormat == "" {
+		if e.FormatVersion == FormatVersion1 {
+			return "array"
+		}
+		return "base64"
+	}
+	return e.BytesFormat
+}
+
+// writeBytes writes the byte slice value as a base64 or hex string. It
+// reports false for the array format, which is written like other slices.
+func (e *hjsonEncoder) writeBytes(value reflect.Value, format, separator string, isRootObject bool) bool {
+	switch format {
+	case "base64":
+		e.quote(base64.StdEncoding.EncodeToString(value.Bytes()), separator, isRootObject)
+	case "hex":
+		e.quote(hex.EncodeToString(value.Bytes()), separator, isRootObject)
+	default:
+		return false
+	}
+	return true
+}
+
+// setBytes stores a base64 or hex string in the byte slice dest, depending
+// on DecoderOptions.BytesFormat.
+func (p *hjsonParser) setBytes(dest reflect.Value, s string) error {
+	var b []byte
+	var err error
+	if p.BytesFormat == "hex" {
+		b, err = hex.DecodeString(s)
+	} else {
+		b, err = base64.StdEncoding.DecodeString(s)
+	}
+	if err != nil {
+		return p.typeError("string "+strconv.Quote(s), dest.Type())
+	}
+	dest.Set(reflect.ValueOf(b).Convert(dest.Type()))
+	return nil
+}
@@ -0,0 +1,92 @@
+package hjson
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testBytesStruct struct {
+	Data  []byte
+	Hex   []byte `json:"hex,format=hex"`
+	Array []byte `json:"array,format=array"`
+	Empty []byte
+}
+
+func TestBytes(t *testing.T) {
+	input := testBytesStruct{
+		Data:  []byte("hello"),
+		Hex:   []byte{0x12, 0x34},
+		Array: []byte{1, 2},
+		Empty: []byte{},
+	}
+	buf, err := Marshal(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\n  Data: aGVsbG8=\n  hex: \"1234\"\n  array:\n  [\n    1\n    2\n  ]\n  Empty: \"\"\n}"
+	if string(buf) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf)
+	}
+
+	var output testBytesStruct
+	if err = Unmarshal(buf, &output); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(input, output) {
+		t.Errorf("expected\n%#v\ngot\n%#v", input, output)
+	}
+
+	// quoteless values that look like numbers, and arrays
+	if err = Unmarshal([]byte("Data: 1234\nhex: 1234\nEmpty: [3, 4]"), &output); err != nil {
+		t.Fatal(err)
+	}
+	if string(output.Data) != "\xd7\x6d\xf8" || string(output.Hex) != "\x12\x34" || string(output.Empty) != "\x03\x04" {
+		t.Errorf("unexpected %#v", output)
+	}
+
+	for _, data := range []string{"Data: '!!'", "hex: xyz", "Data: 12"} {
+		if err = Unmarshal([]byte(data), &output); err == nil {
+			t.Errorf("expected an error for %q", data)
+		}
+	}
+}
+
+func TestBytesFormat(t *testing.T) {
+	value := map[string]interface{}{"b": []byte{0xff, 0}}
+	for format, expected := range map[string]string{
+		"":       "{\n  b: /wA=\n}",
+		"base64": "{\n  b: /wA=\n}",
+		"hex":    "{\n  b: ff00\n}",
+		"array":  "{\n  b:\n  [\n    255\n    0\n  ]\n}",
+	} {
+		opt := DefaultOptions()
+		opt.BytesFormat = format
+		buf, err := MarshalWithOptions(value, opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(buf) != expected {
+			t.Errorf("%q: expected\n%s\ngot\n%s", format, expected, buf)
+		}
+	}
+
+	opt := DefaultOptions()
+	opt.FormatVersion = FormatVersion1
+	if buf, _ := MarshalWithOptions([]byte{1}, opt); string(buf) != "[\n  1\n]" {
+		t.Errorf("FormatVersion1: expected an array, got %s", buf)
+	}
+	opt = DefaultOptions()
+	opt.BytesFormat = "base32"
+	if _, err := MarshalWithOptions([]byte{1}, opt); err == nil {
+		t.Error("expected an error for an unknown BytesFormat")
+	}
+
+	decOpt, err := NewDecoderOptions(WithDecodeBytesFormat("hex"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b []byte
+	if err = UnmarshalWithOptions([]byte("ff00"), &b, decOpt); err != nil || string(b) != "\xff\x00" {
+		t.Errorf("unexpected %q, %v", b, err)
+	}
+}
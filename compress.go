@@ -0,0 +1,55 @@
+package hjson
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"sync"
+)
+
+var decompressors struct {
+	sync.RWMutex
+	list []decompressor
+}
+
+type decompressor struct {
+	magic     string
+	newReader func(io.Reader) (io.Reader, error)
+}
+
+// RegisterDecompressor makes DecompressReader recognize input that starts
+// with magic and decompress it with newReader. gzip is registered by
+// default; other formats like zstd can be added with a third party package:
+//
+//	hjson.RegisterDecompressor("\x28\xb5\x2f\xfd", func(r io.Reader) (io.Reader, error) {
+//		return zstd.NewReader(r)
+//	})
+func RegisterDecompressor(magic string, newReader func(io.Reader) (io.Reader, error)) {
+	decompressors.Lock()
+	defer decompressors.Unlock()
+	decompressors.list = append(decompressors.list, decompressor{magic, newReader})
+}
+
+func init() {
+	RegisterDecompressor("\x1f\x8b", func(r io.Reader) (io.Reader, error) {
+		return gzip.NewReader(r)
+	})
+}
+
+// DecompressReader returns a reader that reads r, decompressed if r starts
+// with the magic bytes of a registered compression format. Hjson and JSON
+// never start with these bytes.
+//
+// ConvertBatch and GetAndDecode use DecompressReader for their input.
+func DecompressReader(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	decompressors.RLock()
+	defer decompressors.RUnlock()
+	for _, d := range decompressors.list {
+		magic, _ := br.Peek(len(d.magic))
+		if string(magic) == d.magic {
+			return d.newReader(br)
+		}
+	}
+	return br, nil
+}
@@ -0,0 +1,35 @@
+package hjson
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestDecompressReader(t *testing.T) {
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte("a: 1"))
+	zw.Close()
+
+	for _, input := range [][]byte{gz.Bytes(), []byte("a: 1")} {
+		r, err := DecompressReader(bytes.NewReader(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(r)
+		if err != nil || string(data) != "a: 1" {
+			t.Errorf("expected a: 1, got %q, %v", data, err)
+		}
+	}
+
+	r, err := DecompressReader(strings.NewReader(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := ioutil.ReadAll(r); len(data) != 0 {
+		t.Errorf("expected no data, got %q", data)
+	}
+}
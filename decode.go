@@ -2,43 +2,435 @@ package hjson
 
 import (
 	"bytes"
+	"encoding"
+	"encoding/json"
 	"fmt"
+	"io/fs"
+	"math"
 	"reflect"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// DecoderOptions defines options for decoding Hjson.
+type DecoderOptions struct {
+	// Abort decoding when the decoded value is estimated to use more than
+	// this many bytes of memory (0 for no limit). Strings, objects and
+	// array elements are counted before they are allocated.
+	MaxAlloc int
+	// Called for each loss of information that does not fail the decoding,
+	// see Warning
+	OnWarning func(w Warning)
+	// Names of the registered extensions to enable, see Extension
+	Extensions []string
+	// Abort decoding when arrays and objects are nested deeper than this
+	// (0 for no limit)
+	MaxDepth int
+	// What to do when a key appears more than once in an object
+	DuplicateKeys DuplicateKeyPolicy
+	// Fail when an object decoded into a struct has a key without a
+	// matching field, instead of ignoring the key. The error suggests the
+	// field the key is most likely a typo of.
+	DisallowUnknownFields bool
+	// Store numbers decoded into interface values as a json.Number holding
+	// the literal instead of a float64, which keeps large integers exact
+	UseNumber bool
+	// Store objects decoded into interface values as an *OrderedMap instead
+	// of a map[string]interface{}, which keeps the order of the members
+	UseOrderedMap bool
+	// Abort decoding inputs longer than this many bytes (0 for no limit)
+	MaxInputBytes int
+	// Abort decoding when a string or key is longer than this many bytes
+	// (0 for no limit)
+	MaxStringLen int
+	// Format of the time.Time values to decode, like
+	// EncoderOptions.TimeFormat; "" for RFC 3339
+	TimeFormat string
+	// Encoding of the strings decoded into byte slices, "base64" or "hex";
+	// "" for base64. Arrays of numbers are always accepted.
+	BytesFormat string
+	// Accept the JSON5 syntax that Hjson does not have: hexadecimal numbers,
+	// Infinity, NaN, numbers with a leading + or a leading or trailing
+	// decimal point, and the escapes \v, \0, \xHH, line continuations and
+	// escaped characters that stand for themselves. Single quoted strings,
+	// unquoted keys, comments and trailing commas are Hjson already. Note
+	// that a value like Infinity is a number then instead of a quoteless
+	// string.
+	AcceptJSON5 bool
+	// Called with each decoded value before it is stored in a Go value of
+	// type to, with the kind of the decoded value in from (reflect.Bool,
+	// reflect.Float64, reflect.String, reflect.Slice or reflect.Map), like
+	// the DecodeHookFuncKind of mapstructure. The returned value is stored
+	// instead, so a hook can turn "10MB" into a number of bytes or a name
+	// into an enum constant, and returning value unchanged keeps the usual
+	// decoding. An error fails the decoding at the position of the value.
+	// Null values and values decoded by an Unmarshaler are not passed.
+	// Arrays and objects decoded into slices, structs or maps are not
+	// passed either, but their elements and members are; those decoded
+	// into an interface{} are passed whole.
+	DecodeHook func(from reflect.Kind, to reflect.Type, value interface{}) (interface{}, error)
+	// Convert between compatible representations when a value does not
+	// match the type of its Go value, for configs written by hand: strings
+	// like "8080" or " 2.5" into numbers, strings like "true", "1" or "f"
+	// into bools, numbers into bools (0 is false) and bools into numbers (1
+	// and 0). An empty string is the zero value then. Numbers and bools are
+	// decoded into strings as written also without this option.
+	WeaklyTyped bool
+	// Replace references to environment variables in string values, but
+	// not in keys: ${NAME} is the value of the variable, ${NAME:-default}
+	// is default when the variable is unset or empty and ${NAME:?message}
+	// fails the decoding then. $$ stands for a single $. A quoteless value
+	// that reads like a number, true, false or null after the replacement
+	// is that value, so port: ${PORT:-8080} can be decoded into an int.
+	ExpandEnv bool
+	// Look up the environment variables for ExpandEnv; nil for
+	// os.LookupEnv
+	LookupEnv func(name string) (string, bool)
+	// Resolve include directives with the files of this file system
+	// (nil for none). A quoteless value "!include NAME" is replaced by the
+	// decoded file NAME, and a member "!include: NAME" of an object, or
+	// "!include: [NAME, ...]", merges the members of the file into the
+	// object, recursively like DuplicateKeyMerge; the members after it are
+	// merged into those of the file. Names are relative to the directory of
+	// the including file and the input of Unmarshal is at the root. The
+	// files are decoded with the same options; an include cycle is an
+	// error.
+	IncludeFS fs.FS
+	// Report up to this many syntax errors instead of only the first one
+	// (0 for only the first one). After a syntax error the rest of its line
+	// is skipped and parsing goes on inside the current object or array, so
+	// an editor can show all the mistakes of a file at once. Unmarshal
+	// fails with a SyntaxErrors if there are several; those after the first
+	// may be caused by it.
+	MaxErrors int
+	// Record the position of each value in this map when it is not nil, by
+	// its path like "servers[1].timeout", in the form taken by Node.Lookup
+	// ("" for the root value), so that errors found after decoding, like a
+	// port out of range, can point at the line of the value. Values of
+	// included files are not recorded; Decoder does not record any. The map
+	// is written while decoding, so concurrent calls need their own maps,
+	// and SetDefaultDecoderOptions rejects it.
+	Positions map[string]Position
+	// The messages of the syntax errors by their Code, replacing the
+	// English ones of DefaultMessages, to localize or rephrase the errors
+	// shown to users (nil for the English messages). Codes missing from it
+	// keep their English messages.
+	Messages MessageCatalog
+}
+
+// A Position is a place in the input, see DecoderOptions.Positions and
+// Span.
+type Position struct {
+	Offset int // byte offset, starting at 0
+	Line   int // line, starting at 1
+	Column int // column in bytes, starting at 1
+}
+
+// DuplicateKeyPolicy tells the decoder how to handle keys that appear more
+// than once in an object.
+type DuplicateKeyPolicy int
+
+const (
+	// DuplicateKeyLast keeps the last value, like encoding/json
+	DuplicateKeyLast DuplicateKeyPolicy = iota
+	// DuplicateKeyFirst keeps the first value and ignores the others
+	DuplicateKeyFirst
+	// DuplicateKeyError fails the decoding, reporting the lines of both
+	// keys
+	DuplicateKeyError
+	// DuplicateKeyMerge merges the members of an object into those of the
+	// earlier object with the same key, recursively, so that a section can
+	// be continued further down; any other value replaces the earlier one
+	// like with DuplicateKeyLast
+	DuplicateKeyMerge
+)
+
+// DefaultDecoderOptions returns the default decoding options, as set by
+// SetDefaultDecoderOptions. They are used by Unmarshal.
+func DefaultDecoderOptions() DecoderOptions {
+	if opt, ok := defaultDecoderOptions.Load().(DecoderOptions); ok {
+		opt.Extensions = append([]string(nil), opt.Extensions...)
+		return opt
+	}
+	return builtinDecoderOptions()
+}
+
+// builtinDecoderOptions returns the default decoding options of this
+// package.
+func builtinDecoderOptions() DecoderOptions {
+	opt := DecoderOptions{}
+	opt.MaxAlloc = 0
+	opt.OnWarning = nil
+	opt.Extensions = nil
+	opt.MaxDepth = 0
+	opt.DuplicateKeys = DuplicateKeyLast
+	opt.DisallowUnknownFields = false
+	opt.UseNumber = false
+	opt.UseOrderedMap = false
+	opt.MaxInputBytes = 0
+	opt.MaxStringLen = 0
+	opt.TimeFormat = ""
+	opt.BytesFormat = ""
+	opt.AcceptJSON5 = false
+	opt.DecodeHook = nil
+	opt.WeaklyTyped = false
+	opt.ExpandEnv = false
+	opt.LookupEnv = nil
+	opt.IncludeFS = nil
+	opt.MaxErrors = 0
+	opt.Positions = nil
+	opt.Messages = nil
+	return opt
+}
+
+// validate reports options that have no meaning.
+func (options DecoderOptions) validate() error {
+	for _, limit := range []struct {
+		name  string
+		value int
+	}{
+		{"MaxAlloc", options.MaxAlloc},
+		{"MaxDepth", options.MaxDepth},
+		{"MaxInputBytes", options.MaxInputBytes},
+		{"MaxStringLen", options.MaxStringLen},
+		{"MaxErrors", options.MaxErrors},
+	} {
+		if limit.value < 0 {
+			return fmt.Errorf("Invalid DecoderOptions: %s must not be negative, not %d", limit.name, limit.value)
+		}
+	}
+	if options.DuplicateKeys < DuplicateKeyLast || options.DuplicateKeys > DuplicateKeyMerge {
+		return fmt.Errorf("Invalid DecoderOptions: unknown DuplicateKeys policy %d", options.DuplicateKeys)
+	}
+	switch options.BytesFormat {
+	case "", "base64", "hex":
+	default:
+		return fmt.Errorf("Invalid DecoderOptions: unknown BytesFormat %q", options.BytesFormat)
+	}
+	if options.LookupEnv != nil && !options.ExpandEnv {
+		return fmt.Errorf("Invalid DecoderOptions: LookupEnv has no effect without ExpandEnv")
+	}
+	for code := range options.Messages {
+		if _, ok := defaultMessages[code]; !ok {
+			return fmt.Errorf("Invalid DecoderOptions: unknown message code %q", code)
+		}
+	}
+	return nil
+}
+
 type hjsonParser struct {
-	data []byte
-	at   int  // The index of the current character
-	ch   byte // The current character
+	DecoderOptions
+	data      []byte
+	at        int  // The index of the current character
+	ch        byte // The current character
+	allocated int  // Estimated number of bytes allocated for the result
+	depth     int  // Nesting depth of the current array or object
+	maxDepth  int  // Deepest nesting of arrays and objects so far
+	mergeNext bool // The next value is merged into its destination, see DuplicateKeyMerge
+
+	extensions []*Extension // The enabled extensions
+	includes   []string     // The files of IncludeFS being parsed, the last one by this parser
+	path       []pathElem   // The path of the value being parsed
+
+	// for Positions: the line of posStart, the offset where it starts and
+	// the offset up to which lines were counted
+	posLine, posStart, posAt int
+
+	unknownField error // The error of DisallowUnknownFields, if any
+
+	scratch *bytes.Buffer                  // Space to unescape strings, reused for each string
+	fields  map[reflect.Type]*structFields // The struct fields looked up so far, nil to look up each time
 }
 
 func (p *hjsonParser) resetAt() {
 	p.at = 0
 	p.ch = ' '
+	p.allocated = 0
+	p.depth = 0
+	p.maxDepth = 0
+	p.path = p.path[:0]
+	p.posLine, p.posStart, p.posAt = 1, 0, 0
+	p.unknownField = nil
+}
+
+// limitError is returned when the input exceeds MaxAlloc, MaxDepth,
+// MaxInputBytes or MaxStringLen.
+type limitError struct {
+	error
+}
+
+func (e limitError) Unwrap() error {
+	return e.error
+}
+
+// enter records that an array or object is being parsed and enforces
+// MaxDepth, leave records that it has been parsed.
+func (p *hjsonParser) enter() error {
+	p.depth++
+	if p.depth > p.maxDepth {
+		p.maxDepth = p.depth
+	}
+	if p.MaxDepth > 0 && p.depth > p.MaxDepth {
+		return limitError{p.errCode("max-depth", p.MaxDepth)}
+	}
+	return nil
+}
+
+func (p *hjsonParser) leave() {
+	p.depth--
+}
+
+// Approximate sizes used to estimate the memory use of decoded values.
+const (
+	allocValue  = 16 // an interface{} holding a value
+	allocSlice  = 24 // a slice header
+	allocMap    = 48 // a map header
+	allocMember = 32 // a map entry without the key's bytes and the value
+)
+
+// alloc records that n more bytes are used by the decoded value and
+// enforces MaxAlloc.
+func (p *hjsonParser) alloc(n int) error {
+	p.allocated += n
+	if p.MaxAlloc > 0 && p.allocated > p.MaxAlloc {
+		return limitError{p.errCode("max-alloc", p.MaxAlloc)}
+	}
+	return nil
+}
+
+// allocString returns b as a string, recording its bytes with alloc before
+// the string is allocated.
+func (p *hjsonParser) allocString(b []byte) (string, error) {
+	if err := p.alloc(len(b)); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// scratchBuffer returns the empty scratch buffer of p. Strings built in it
+// must be copied before the next call.
+func (p *hjsonParser) scratchBuffer() *bytes.Buffer {
+	if p.scratch == nil {
+		p.scratch = new(bytes.Buffer)
+	}
+	p.scratch.Reset()
+	return p.scratch
+}
+
+// structFields returns the fields of the struct type t, remembered in
+// p.fields if it is not nil.
+func (p *hjsonParser) structFields(t reflect.Type) *structFields {
+	if p.fields == nil {
+		return cachedStructFields(t)
+	}
+	f, ok := p.fields[t]
+	if !ok {
+		f = cachedStructFields(t)
+		p.fields[t] = f
+	}
+	return f
+}
+
+// checkString enforces MaxStringLen for a string or key of n bytes.
+func (p *hjsonParser) checkString(n int) error {
+	if p.MaxStringLen > 0 && n > p.MaxStringLen {
+		return limitError{p.errCode("max-string-len", p.MaxStringLen)}
+	}
+	return nil
 }
 
 func isPunctuatorChar(c byte) bool {
 	return c == '{' || c == '}' || c == '[' || c == ']' || c == ',' || c == ':'
 }
 
-func (p *hjsonParser) errAt(message string) error {
-	var i int
-	col := 0
-	line := 1
-	for i = p.at - 1; i > 0 && p.data[i] != '\n'; i-- {
-		col++
+// A SyntaxError describes an error in the Hjson input and where it was
+// found. Errors about valid input, such as an UnmarshalTypeError for a value
+// that does not fit into its destination or an exceeded limit, wrap a
+// SyntaxError for their position, which errors.As retrieves.
+type SyntaxError struct {
+	Msg    string // description of the error
+	Offset int    // byte offset of the error in the input, starting at 0
+	Line   int    // line of the error, starting at 1
+	Column int    // column in bytes of the error, starting at 1
+	// The input from the start of the line to a few bytes after the error
+	Snippet string
+	// The key of Msg in a MessageCatalog, "" for the errors of decode hooks,
+	// Unmarshalers and other code outside the parser
+	Code string
+	// The values in Msg, the operands of its format in a MessageCatalog
+	Args []interface{}
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at line %d,%d >>> %s", e.Msg, e.Line, e.Column, e.Snippet)
+}
+
+// SyntaxErrors holds the syntax errors found with DecoderOptions.MaxErrors,
+// in the order of the input.
+type SyntaxErrors []*SyntaxError
+
+func (e SyntaxErrors) Error() string {
+	lines := make([]string, len(e))
+	for i, se := range e {
+		lines[i] = se.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Unwrap returns the errors for errors.As and errors.Is.
+func (e SyntaxErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, se := range e {
+		errs[i] = se
+	}
+	return errs
+}
+
+// moreErrors returns the syntax error err of data together with the ones
+// after it, up to options.MaxErrors, see DecoderOptions.MaxErrors. Other
+// errors are returned as they are.
+func moreErrors(data []byte, options DecoderOptions, err error) error {
+	first, ok := err.(*SyntaxError)
+	if !ok || options.MaxErrors <= 1 {
+		return err
 	}
-	for ; i > 0; i-- {
-		if p.data[i] == '\n' {
-			line++
+	errs := SyntaxErrors{first}
+	for _, se := range syntaxErrors(data, options, options.MaxErrors) {
+		if se.Offset > errs[len(errs)-1].Offset && len(errs) < options.MaxErrors {
+			errs = append(errs, se)
 		}
 	}
-	samEnd := p.at - col + 20
+	if len(errs) == 1 {
+		return first
+	}
+	return errs
+}
+
+func (p *hjsonParser) errAt(message string) error {
+	offset := p.at - 1
+	if offset < 0 {
+		offset = 0
+	} else if offset > len(p.data) {
+		offset = len(p.data)
+	}
+	lineStart := bytes.LastIndexByte(p.data[:offset], '\n') + 1
+	samEnd := lineStart + 20
 	if samEnd > len(p.data) {
 		samEnd = len(p.data)
 	}
-	return fmt.Errorf("%s at line %d,%d >>> %s", message, line, col, string(p.data[p.at-col:samEnd]))
+	return &SyntaxError{
+		Msg:     message,
+		Offset:  offset,
+		Line:    p.lineAt(offset),
+		Column:  offset - lineStart + 1,
+		Snippet: string(p.data[lineStart:samEnd]),
+	}
+}
+
+// lineAt returns the line of the input at offset, starting at 1.
+func (p *hjsonParser) lineAt(offset int) int {
+	return bytes.Count(p.data[:offset], []byte{'\n'}) + 1
 }
 
 func (p *hjsonParser) next() bool {
@@ -75,23 +467,43 @@ var escapee = map[byte]byte{
 func (p *hjsonParser) readString(allowML bool) (string, error) {
 
 	// Parse a string value.
-	res := new(bytes.Buffer)
+	// As long as there are no escapes the string is a sub-slice of the
+	// input, res is only needed to unescape.
+	var res *bytes.Buffer
+	start := p.at
+	charged := 0 // bytes of res recorded with alloc
 
 	// callers make sure that (ch === '"' || ch === "'")
 	// When parsing for string values, we must look for " and \ characters.
 	exitCh := p.ch
+	quote := -1 // index of the next exitCh, see plainEnd
 	for p.next() {
 		if p.ch == exitCh {
+			end := p.at - 1
 			p.next()
-			if allowML && exitCh == '\'' && p.ch == '\'' && res.Len() == 0 {
+			if allowML && exitCh == '\'' && p.ch == '\'' && res == nil && end == start {
 				// ''' indicates a multiline string
 				p.next()
 				return p.readMLString()
+			} else if res == nil {
+				return p.allocString(p.data[start:end])
 			} else {
+				// the escaped characters are recorded here
+				if err := p.alloc(res.Len() - charged); err != nil {
+					return "", err
+				}
 				return res.String(), nil
 			}
 		}
 		if p.ch == '\\' {
+			if res == nil {
+				if err := p.alloc(p.at - 1 - start); err != nil {
+					return "", err
+				}
+				charged = p.at - 1 - start
+				res = p.scratchBuffer()
+				res.Write(p.data[start : p.at-1])
+			}
 			p.next()
 			if p.ch == 'u' {
 				uffff := 0
@@ -105,29 +517,118 @@ func (p *hjsonParser) readString(allowML bool) (string, error) {
 					} else if p.ch >= 'A' && p.ch <= 'F' {
 						hex = int(p.ch - 'A' + 0xa)
 					} else {
-						return "", p.errAt("Bad \\u char " + string(p.ch))
+						return "", p.errCode("bad-unicode-escape", string(p.ch))
 					}
 					uffff = uffff*16 + hex
 				}
 				res.WriteRune(rune(uffff))
 			} else if ech, ok := escapee[p.ch]; ok {
 				res.WriteByte(ech)
+			} else if p.AcceptJSON5 {
+				if err := p.readJSON5Escape(res); err != nil {
+					return "", err
+				}
 			} else {
-				return "", p.errAt("Bad escape \\" + string(p.ch))
+				return "", p.errCode("bad-escape", string(p.ch))
 			}
 		} else if p.ch == '\n' || p.ch == '\r' {
-			return "", p.errAt("Bad string containing newline")
+			return "", p.errCode("string-newline")
 		} else {
-			res.WriteByte(p.ch)
+			// skip the whole run of plain characters at once
+			end := p.plainEnd(exitCh, &quote)
+			if res != nil {
+				if err := p.alloc(end - (p.at - 1)); err != nil {
+					return "", err
+				}
+				charged += end - (p.at - 1)
+				res.Write(p.data[p.at-1 : end])
+			}
+			p.at = end
+		}
+	}
+	return "", p.errCode("bad-string")
+}
+
+// readJSON5Escape reads the escape sequence at the current character that
+// JSON5 has in addition to Hjson, see DecoderOptions.AcceptJSON5.
+func (p *hjsonParser) readJSON5Escape(res *bytes.Buffer) error {
+	switch {
+	case p.ch == 'v':
+		res.WriteByte('\v')
+	case p.ch == '0' && !(p.peek(0) >= '0' && p.peek(0) <= '9'):
+		res.WriteByte(0)
+	case p.ch == 'x':
+		end := p.at + 2
+		if end > len(p.data) {
+			end = len(p.data)
+		}
+		hex := string(p.data[p.at:end])
+		n, err := strconv.ParseUint(hex, 16, 8)
+		if err != nil || len(hex) < 2 {
+			return p.errCode("bad-hex-escape", hex)
+		}
+		res.WriteRune(rune(n))
+		p.next()
+		p.next()
+	case p.ch == '\r' || p.ch == '\n':
+		// a line continuation
+		if p.ch == '\r' && p.peek(0) == '\n' {
+			p.next()
 		}
+	case p.ch == 0 || p.ch >= '0' && p.ch <= '9':
+		return p.errCode("bad-escape", string(p.ch))
+	default:
+		// the character stands for itself
+		res.WriteByte(p.ch)
 	}
-	return "", p.errAt("Bad string")
+	return nil
+}
+
+// plainEnd returns the index of the first character at or after the current
+// one that needs attention inside a quoted string: the closing quote, a
+// backslash or a newline. quote caches the position of the next exitCh so
+// that the scan stays linear for strings with many escapes.
+//
+// bytes.IndexByte is implemented in assembly on most architectures (with a
+// portable fallback), which makes this a lot faster than looking at each
+// character in turn.
+func (p *hjsonParser) plainEnd(exitCh byte, quote *int) int {
+	start := p.at - 1
+	if *quote < start {
+		*quote = len(p.data)
+		if i := bytes.IndexByte(p.data[start:], exitCh); i >= 0 {
+			*quote = start + i
+		}
+	}
+	end := *quote
+	for _, c := range []byte{'\\', '\n', '\r'} {
+		if i := bytes.IndexByte(p.data[start:end], c); i >= 0 {
+			end = start + i
+		}
+	}
+	return end
+}
+
+// skipToByte advances to the next occurrence of c or of a 0 byte (which is
+// treated like the end of the input), or to the end of the input.
+func (p *hjsonParser) skipToByte(c byte) {
+	if p.ch == c || p.ch == 0 {
+		return
+	}
+	end := len(p.data)
+	for _, b := range []byte{c, 0} {
+		if i := bytes.IndexByte(p.data[p.at:end], b); i >= 0 {
+			end = p.at + i
+		}
+	}
+	p.at = end
+	p.next()
 }
 
 func (p *hjsonParser) readMLString() (value string, err error) {
 
 	// Parse a multiline string value.
-	res := new(bytes.Buffer)
+	res := p.scratchBuffer()
 	triple := 0
 
 	// we are at ''' +1 - get indent
@@ -161,16 +662,16 @@ func (p *hjsonParser) readMLString() (value string, err error) {
 	lastLf := false
 	for {
 		if p.ch == 0 {
-			return "", p.errAt("Bad multiline string")
+			return "", p.errCode("bad-multiline-string")
 		} else if p.ch == '\'' {
 			triple++
 			p.next()
 			if triple == 3 {
 				sres := res.Bytes()
 				if lastLf {
-					return string(sres[0 : len(sres)-1]), nil // remove last EOL
+					return p.allocString(sres[0 : len(sres)-1]) // remove last EOL
 				}
-				return string(sres), nil
+				return p.allocString(sres)
 			}
 			continue
 		} else {
@@ -204,30 +705,31 @@ func (p *hjsonParser) readKeyname() (string, error) {
 		return p.readString(false)
 	}
 
-	name := new(bytes.Buffer)
+	// a valid name is a sub-slice of the input of length nameLen
 	start := p.at
+	nameLen := 0
 	space := -1
 	for {
 		if p.ch == ':' {
-			if name.Len() == 0 {
-				return "", p.errAt("Found ':' but no key name (for an empty key name use quotes)")
-			} else if space >= 0 && space != name.Len() {
+			if nameLen == 0 {
+				return "", p.errCode("empty-key")
+			} else if space >= 0 && space != nameLen {
 				p.at = start + space
-				return "", p.errAt("Found whitespace in your key name (use quotes to include)")
+				return "", p.errCode("key-whitespace")
 			}
-			return name.String(), nil
+			return p.allocString(p.data[start-1 : start-1+nameLen])
 		} else if p.ch <= ' ' {
 			if p.ch == 0 {
-				return "", p.errAt("Found EOF while looking for a key name (check your syntax)")
+				return "", p.errCode("key-eof")
 			}
 			if space < 0 {
-				space = name.Len()
+				space = nameLen
 			}
 		} else {
 			if isPunctuatorChar(p.ch) {
-				return "", p.errAt("Found '" + string(p.ch) + "' where a key name was expected (check your syntax or use quotes if the key name includes {}[],: or whitespace)")
+				return "", p.errCode("key-char", string(p.ch))
 			}
-			name.WriteByte(p.ch)
+			nameLen++
 		}
 		p.next()
 	}
@@ -239,201 +741,562 @@ func (p *hjsonParser) white() {
 		for p.ch > 0 && p.ch <= ' ' {
 			p.next()
 		}
-		// Hjson allows comments
-		if p.ch == '#' || p.ch == '/' && p.peek(0) == '/' {
-			for p.ch > 0 && p.ch != '\n' {
-				p.next()
-			}
-		} else if p.ch == '/' && p.peek(0) == '*' {
+		if !p.skipComment() {
+			break
+		}
+	}
+}
+
+// skipComment skips the comment starting at the current character and
+// reports whether there was one.
+func (p *hjsonParser) skipComment() bool {
+	// Hjson allows comments
+	if p.ch == '#' || p.ch == '/' && p.peek(0) == '/' {
+		p.skipToByte('\n')
+	} else if p.ch == '/' && p.peek(0) == '*' {
+		p.next()
+		p.next()
+		for p.ch > 0 && !(p.ch == '*' && p.peek(0) == '/') {
+			p.next()
+			p.skipToByte('*')
+		}
+		if p.ch > 0 {
 			p.next()
 			p.next()
-			for p.ch > 0 && !(p.ch == '*' && p.peek(0) == '/') {
-				p.next()
-			}
-			if p.ch > 0 {
-				p.next()
-				p.next()
-			}
-		} else {
-			break
 		}
+	} else {
+		return false
 	}
+	return true
 }
 
-func (p *hjsonParser) readTfnns() (interface{}, error) {
+func (p *hjsonParser) readTfnns() (interface{}, string, error) {
 
 	// Hjson strings can be quoteless
-	// returns string, true, false, or null.
+	// returns string, true, false, or null, and the literal text.
 
+	if p.ch == 0 {
+		return nil, "", p.errCode("value-eof")
+	}
 	if isPunctuatorChar(p.ch) {
-		return nil, p.errAt("Found a punctuator character '" + string(p.ch) + "' when expecting a quoteless string (check your syntax)")
+		return nil, "", p.errCode("value-punctuator", string(p.ch))
 	}
 	chf := p.ch
-	value := new(bytes.Buffer)
-	value.WriteByte(p.ch)
+	json5Number := p.AcceptJSON5 && (chf == '+' || chf == '.' || chf == 'I' || chf == 'N')
+	if !(chf == 'f' || chf == 'n' || chf == 't' || chf == '-' || chf >= '0' && chf <= '9' || json5Number) {
+		// this can only be a string, which always ends at the end of the line
+		start := p.at - 1
+		end := len(p.data)
+		for _, c := range []byte{'\n', '\r', 0} {
+			if i := bytes.IndexByte(p.data[start:end], c); i >= 0 {
+				end = start + i
+			}
+		}
+		p.at = end
+		p.next()
+		// remove any whitespace at the end (ignored in quoteless strings)
+		str, err := p.allocString(bytes.TrimSpace(p.data[start:end]))
+		if err != nil {
+			return nil, "", err
+		}
+		return p.quoteless(str)
+	}
+	start := p.at - 1
 
 	for {
-		p.next()
+		// value is always a sub-slice of the input
+		end := len(p.data)
+		if p.next() {
+			end = p.at - 1
+		}
+		value := p.data[start:end]
 		isEol := p.ch == '\r' || p.ch == '\n' || p.ch == 0
 		if isEol ||
 			p.ch == ',' || p.ch == '}' || p.ch == ']' ||
 			p.ch == '#' ||
 			p.ch == '/' && (p.peek(0) == '/' || p.peek(0) == '*') {
+			// remove any whitespace at the end (ignored in quoteless strings)
+			trimmed := bytes.TrimSpace(value)
 			switch chf {
 			case 'f':
-				if strings.TrimSpace(value.String()) == "false" {
-					return false, nil
+				if string(trimmed) == "false" {
+					return false, "false", nil
 				}
 			case 'n':
-				if strings.TrimSpace(value.String()) == "null" {
-					return nil, nil
+				if string(trimmed) == "null" {
+					return nil, "null", nil
 				}
 			case 't':
-				if strings.TrimSpace(value.String()) == "true" {
-					return true, nil
+				if string(trimmed) == "true" {
+					return true, "true", nil
 				}
 			default:
 				if chf == '-' || chf >= '0' && chf <= '9' {
-					if n, err := tryParseNumber(value.Bytes(), false); err == nil {
-						return n, nil
+					if n, err := tryParseNumber(value, false); err == nil {
+						literal, err := p.allocString(trimmed)
+						return n, literal, err
+					}
+				}
+				if p.AcceptJSON5 {
+					if n, literal, err := tryParseJSON5Number(trimmed); err == nil {
+						return n, literal, nil
 					}
 				}
 			}
 			if isEol {
-				// remove any whitespace at the end (ignored in quoteless strings)
-				return strings.TrimSpace(value.String()), nil
+				str, err := p.allocString(trimmed)
+				if err != nil {
+					return nil, "", err
+				}
+				return p.quoteless(str)
 			}
 		}
-		value.WriteByte(p.ch)
 	}
 }
 
-func (p *hjsonParser) readArray() (value interface{}, err error) {
+func (p *hjsonParser) readArray(dest reflect.Value) (value interface{}, err error) {
 
 	// Parse an array value.
 	// assuming ch == '['
 
-	array := make([]interface{}, 0, 1)
+	// array is used when decoding without a destination, otherwise the
+	// elements are decoded straight into dest.
+	var array []interface{}
+	if dest.IsValid() {
+		dest = indirect(dest)
+		switch dest.Kind() {
+		case reflect.Interface:
+			return nil, p.readGeneric(dest, p.readArray)
+		case reflect.Slice, reflect.Array:
+		default:
+			return nil, p.readMismatch(dest, "array", p.readArray)
+		}
+	}
+	if err = p.alloc(allocSlice); err != nil {
+		return nil, err
+	}
+	if !dest.IsValid() {
+		array = make([]interface{}, 0, 1)
+	} else if dest.Kind() == reflect.Slice {
+		dest.Set(reflect.MakeSlice(dest.Type(), 0, 0))
+	}
+	err = p.enter()
+	defer p.leave()
+	if err != nil {
+		return nil, err
+	}
 
 	p.next()
 	p.white()
 
-	if p.ch == ']' {
-		p.next()
-		return array, nil // empty array
-	}
-
-	for p.ch > 0 {
-		var val interface{}
-		if val, err = p.readValue(); err != nil {
-			return nil, err
+	for i := 0; p.ch > 0; i++ {
+		if p.ch == ']' {
+			p.next()
+			if array == nil {
+				// zero any array elements that were not in the input
+				if dest.Kind() == reflect.Array {
+					for ; i < dest.Len(); i++ {
+						dest.Index(i).Set(reflect.Zero(dest.Type().Elem()))
+					}
+				}
+				return nil, nil
+			}
+			return array, nil
 		}
-		array = append(array, val)
+		p.path = append(p.path, pathElem{"", i})
+		if array != nil {
+			var val interface{}
+			if val, err = p.readValue(reflect.Value{}); err != nil {
+				return nil, err
+			}
+			array = append(array, val)
+		} else {
+			var elem reflect.Value
+			if dest.Kind() == reflect.Slice {
+				// the element is recorded before the slice grows
+				if err = p.alloc(int(dest.Type().Elem().Size())); err != nil {
+					return nil, err
+				}
+				dest.Set(reflect.Append(dest, reflect.Zero(dest.Type().Elem())))
+				elem = dest.Index(i)
+			} else if i < dest.Len() {
+				elem = dest.Index(i)
+			}
+			// elements that do not fit into an array are parsed and dropped
+			if _, err = p.readValue(elem); err != nil {
+				return nil, err
+			}
+		}
+		p.path = p.path[:len(p.path)-1]
 		p.white()
 		// in Hjson the comma is optional and trailing commas are allowed
 		if p.ch == ',' {
 			p.next()
 			p.white()
 		}
-		if p.ch == ']' {
-			p.next()
-			return array, nil
-		}
-		p.white()
 	}
 
-	return nil, p.errAt("End of input while parsing an array (did you forget a closing ']'?)")
+	return nil, p.errCode("array-eof")
 }
 
-func (p *hjsonParser) readObject(withoutBraces bool) (value interface{}, err error) {
+func (p *hjsonParser) readObject(withoutBraces bool, dest reflect.Value) (value interface{}, err error) {
 	// Parse an object value.
 
-	object := make(map[string]interface{})
+	// object or ordered is used when decoding without a destination,
+	// otherwise the members are decoded straight into dest.
+	var object map[string]interface{}
+	var ordered *OrderedMap
+	var fields *structFields
+	merge := p.mergeNext
+	p.mergeNext = false
+	if dest.IsValid() {
+		dest = indirect(dest)
+		if dest.Type() == orderedMapType {
+			return nil, p.readOrderedMap(withoutBraces, dest, merge)
+		}
+		read := func(dest reflect.Value) (interface{}, error) {
+			return p.readObject(withoutBraces, dest)
+		}
+		switch dest.Kind() {
+		case reflect.Interface:
+			return nil, p.readGeneric(dest, read)
+		case reflect.Map:
+			if !isMapKeyType(dest.Type().Key()) {
+				return nil, p.readMismatch(dest, "object", read)
+			}
+		case reflect.Struct:
+			fields = p.structFields(dest.Type())
+		default:
+			return nil, p.readMismatch(dest, "object", read)
+		}
+	}
+	if err = p.alloc(allocMap); err != nil {
+		return nil, err
+	}
+	switch {
+	case dest.IsValid():
+		// like Unmarshal always did, the decoded object replaces any
+		// existing map rather than being merged into it, unless it
+		// continues an earlier object with DuplicateKeyMerge
+		if dest.Kind() == reflect.Map && (!merge || dest.IsNil()) {
+			dest.Set(reflect.MakeMap(dest.Type()))
+		}
+	case p.UseOrderedMap:
+		ordered = NewOrderedMap()
+	default:
+		object = make(map[string]interface{})
+	}
+	err = p.enter()
+	defer p.leave()
+	if err != nil {
+		return nil, err
+	}
 
 	if !withoutBraces {
 		// assuming ch == '{'
 		p.next()
 	}
+	var seen map[string]int // the offset of each key
+	if p.OnWarning != nil || p.DuplicateKeys != DuplicateKeyLast {
+		seen = make(map[string]int)
+	}
 
 	p.white()
-	if p.ch == '}' && !withoutBraces {
-		p.next()
-		return object, nil // empty object
-	}
 	for p.ch > 0 {
+		if p.ch == '}' && !withoutBraces {
+			p.next()
+			if ordered != nil {
+				return ordered, nil
+			} else if object == nil {
+				return nil, nil
+			}
+			return object, nil
+		}
 		var key string
 		if key, err = p.readKeyname(); err != nil {
 			return nil, err
 		}
+		if err = p.checkString(len(key)); err != nil {
+			return nil, err
+		}
 		p.white()
 		if p.ch != ':' {
-			return nil, p.errAt("Expected ':' instead of '" + string(p.ch) + "'")
+			return nil, p.errCode("expected-colon", string(p.ch))
 		}
 		p.next()
-		// duplicate keys overwrite the previous value
-		var val interface{}
-		if val, err = p.readValue(); err != nil {
+		if err = p.alloc(allocMember); err != nil {
 			return nil, err
 		}
-		object[key] = val
+		if key == includeKey && p.IncludeFS != nil {
+			if err = p.readInclude(dest, object, ordered); err != nil {
+				return nil, err
+			}
+			merge = true
+			p.white()
+			if p.ch == ',' {
+				p.next()
+				p.white()
+			}
+			continue
+		}
+		// by default duplicate keys overwrite the previous value
+		first, duplicate := seen[key]
+		if duplicate {
+			if p.DuplicateKeys == DuplicateKeyError {
+				return nil, p.errCode("duplicate-key", key, p.lineAt(first))
+			}
+			if p.OnWarning != nil {
+				p.warn(WarnDuplicateKey, key)
+			}
+		} else if seen != nil {
+			seen[key] = p.at - 1
+		}
+		// the members of an object that continues an earlier one are merged
+		// into the earlier values
+		mergeMember := merge || duplicate && p.DuplicateKeys == DuplicateKeyMerge
+		p.path = append(p.path, pathElem{key, -1})
+		if duplicate && p.DuplicateKeys == DuplicateKeyFirst {
+			// the position of the value that is kept is recorded
+			positions := p.Positions
+			p.Positions = nil
+			_, err = p.readValue(reflect.Value{})
+			p.Positions = positions
+			if err != nil {
+				return nil, err
+			}
+		} else if !dest.IsValid() {
+			var val interface{}
+			if val, err = p.readValue(reflect.Value{}); err != nil {
+				return nil, err
+			}
+			if ordered != nil {
+				if old, ok := ordered.Get(key); ok && mergeMember {
+					val = mergeValues(old, val)
+				}
+				ordered.Set(key, val)
+			} else {
+				if old, ok := object[key]; ok && mergeMember {
+					val = mergeValues(old, val)
+				}
+				object[key] = val
+			}
+		} else if fields != nil {
+			// members without a matching field are parsed and dropped
+			var fv reflect.Value
+			quoted, format := false, ""
+			if f := fields.lookup(key); f != nil {
+				fv = fieldByIndex(dest, f.index, true)
+				quoted, format = f.quoted, f.format
+			} else if p.DisallowUnknownFields {
+				if name := fields.suggest(key); name != "" {
+					p.unknownField = p.errCode("unknown-field-suggest", key, dest.Type().String(), name)
+				} else {
+					p.unknownField = p.errCode("unknown-field", key, dest.Type().String())
+				}
+				return nil, p.unknownField
+			} else if p.OnWarning != nil {
+				p.warn(WarnUnknownField, key)
+			}
+			if quoted && fv.IsValid() {
+				err = p.readQuoted(fv)
+			} else if format != "" && fv.IsValid() {
+				err = p.readFormatted(fv, format)
+			} else {
+				p.mergeNext = mergeMember
+				_, err = p.readValue(fv)
+				p.mergeNext = false
+			}
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			elem := reflect.New(dest.Type().Elem()).Elem()
+			if mergeMember {
+				if kv, err := p.mapKey(dest.Type().Key(), key); err == nil && dest.MapIndex(kv).IsValid() {
+					elem.Set(dest.MapIndex(kv))
+					p.mergeNext = true
+				}
+			}
+			_, err = p.readValue(elem)
+			p.mergeNext = false
+			if err != nil {
+				return nil, err
+			}
+			var kv reflect.Value
+			if kv, err = p.mapKey(dest.Type().Key(), key); err != nil {
+				return nil, err
+			}
+			dest.SetMapIndex(kv, elem)
+		}
+		p.path = p.path[:len(p.path)-1]
 		p.white()
 		// in Hjson the comma is optional and trailing commas are allowed
 		if p.ch == ',' {
 			p.next()
 			p.white()
 		}
-		if p.ch == '}' && !withoutBraces {
-			p.next()
-			return object, nil
-		}
-		p.white()
 	}
 
 	if withoutBraces {
+		if ordered != nil {
+			return ordered, nil
+		} else if object == nil {
+			return nil, nil
+		}
 		return object, nil
 	}
-	return nil, p.errAt("End of input while parsing an object (did you forget a closing '}'?)")
+	return nil, p.errCode("object-eof")
 }
 
-func (p *hjsonParser) readValue() (interface{}, error) {
+// readValue parses a value. If dest is valid the value is stored in dest and
+// nil is returned, otherwise the value is returned as a bool, float64 (or
+// json.Number with UseNumber), string, []interface{}, map[string]interface{}
+// (or *OrderedMap with UseOrderedMap) or nil.
+func (p *hjsonParser) readValue(dest reflect.Value) (value interface{}, err error) {
 
 	// Parse a Hjson value. It could be an object, an array, a string, a number or a word.
 
 	p.white()
+	if p.Positions != nil {
+		p.Positions[p.pathString()] = p.position(p.at - 1)
+	}
+	merge := p.mergeNext
+	p.mergeNext = false
+	if dest.IsValid() {
+		if dest.Kind() == reflect.Interface && merge && !dest.IsNil() {
+			if value, err = p.readValue(reflect.Value{}); err != nil {
+				return nil, err
+			}
+			return nil, p.setValue(dest, mergeValues(dest.Interface(), value), "")
+		}
+		if dest.Kind() == reflect.Interface {
+			return nil, p.readGeneric(dest, p.readValue)
+		}
+		if dest.Kind() == reflect.Ptr && p.ch == 'n' {
+			// null sets pointers to nil, anything else is stored in the
+			// value pointed to
+			at, allocated := p.at, p.allocated
+			if val, _, err := p.readTfnns(); err == nil && val == nil {
+				dest.Set(reflect.Zero(dest.Type()))
+				return nil, p.alloc(allocValue)
+			}
+			p.at, p.ch, p.allocated = at, 'n', allocated
+		}
+		if isOptional(indirect(dest).Type()) {
+			return nil, p.readOptional(indirect(dest))
+		}
+		if isUnmarshaler(indirect(dest)) {
+			if err = p.readUnmarshaler(indirect(dest)); err != nil {
+				return nil, err
+			}
+			return nil, p.alloc(allocValue)
+		}
+		if indirect(dest).Type() == rawMessageType {
+			return nil, p.readRawMessage(indirect(dest), p.readValue)
+		}
+		if (p.ch == '{' || p.ch == '[') && (isSQLNull(indirect(dest).Type()) || isTextUnmarshaler(indirect(dest))) {
+			return nil, p.readMismatch(dest, describeStart(p.ch), p.readValue)
+		}
+	}
+
+	// the bytes of strings and of objects and arrays are recorded as they
+	// are read, before they are allocated
+	if err = p.alloc(allocValue); err != nil {
+		return nil, err
+	}
 	switch p.ch {
 	case '{':
-		return p.readObject(false)
+		p.mergeNext = merge
+		value, err = p.readObject(false, dest)
 	case '[':
-		return p.readArray()
+		value, err = p.readArray(dest)
 	case '"', '\'':
-		return p.readString(true)
+		var str string
+		if str, err = p.readString(true); err == nil {
+			err = p.checkString(len(str))
+		}
+		if err == nil && p.ExpandEnv {
+			str, err = p.expandEnv(str)
+		}
+		if err == nil {
+			value = str
+			if dest.IsValid() {
+				value, err = nil, p.setValue(indirect(dest), str, "")
+			}
+		}
 	default:
-		return p.readTfnns()
+		var literal string
+		if value, literal, err = p.readTfnns(); err == nil {
+			if name, ok := p.includeName(literal); ok {
+				return p.include(dest, name, merge)
+			}
+			if _, ok := value.(string); ok {
+				err = p.checkString(len(literal))
+			}
+		}
+		if err == nil {
+			if n, ok := value.(float64); ok {
+				if p.UseNumber && !dest.IsValid() {
+					value = json.Number(literal)
+				} else if p.OnWarning != nil && !isIntegerType(dest) {
+					// integers are decoded from the literal, see setValue
+					p.checkPrecision(n, literal)
+				}
+			}
+			if dest.IsValid() {
+				value, err = nil, p.setValue(dest, value, literal)
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
 	}
+	return value, nil
 }
 
-func (p *hjsonParser) rootValue() (interface{}, error) {
+func (p *hjsonParser) rootValue(dest reflect.Value) (interface{}, error) {
 	// Braces for the root object are optional
 
+	if dest.IsValid() && dest.Kind() != reflect.Interface && indirect(dest).Type() == rawMessageType {
+		return nil, p.readRawMessage(indirect(dest), p.rootValue)
+	}
+
 	p.white()
 	switch p.ch {
-	case '{':
-		return p.checkTrailing(p.readObject(false))
-	case '[':
-		return p.checkTrailing(p.readArray())
+	case '{', '[':
+		return p.checkTrailing(p.readValue(dest))
 	}
 
 	// assume we have a root object without braces
-	res, err := p.checkTrailing(p.readObject(true))
-	if err == nil {
-		return res, nil
+	if p.Positions != nil {
+		p.Positions[""] = p.position(p.at - 1)
+	}
+	res, err := p.checkTrailing(p.readObject(true, dest))
+	if _, ok := err.(*UnmarshalTypeError); err == nil || ok {
+		// the input is a valid object
+		return res, err
+	}
+	switch err.(type) {
+	case limitError, includeError:
+		return nil, err
+	}
+	if err == p.unknownField {
+		// the input is an object, with a member that is not a field
+		return nil, err
 	}
 
 	// test if we are dealing with a single JSON value instead (true/false/null/num/"")
 	p.resetAt()
-	if res2, err2 := p.checkTrailing(p.readValue()); err2 == nil {
+	res2, err2 := p.checkTrailing(p.readValue(dest))
+	if err2 == nil {
 		return res2, nil
 	}
+	if _, ok := err2.(*UnmarshalTypeError); ok {
+		// only report the type error if the input is a valid single value
+		if _, err3 := p.checkTrailing(nil, nil); err3 == nil {
+			return nil, err2
+		}
+	}
 	return res, err
 }
 
@@ -443,38 +1306,536 @@ func (p *hjsonParser) checkTrailing(v interface{}, err error) (interface{}, erro
 	}
 	p.white()
 	if p.ch > 0 {
-		return nil, p.errAt("Syntax error, found trailing characters")
+		return nil, p.errCode("trailing-characters")
 	}
 	return v, nil
 }
 
+// An UnmarshalTypeError describes a value that is valid Hjson but does not
+// fit into the Go value it is decoded into. Its SyntaxError holds the
+// message and the position of the value.
+type UnmarshalTypeError struct {
+	*SyntaxError
+	Value string       // description of the value, like "string" or "number 1.5"
+	Type  reflect.Type // type of the Go value
+	// Path of the value in the input, like "servers[1].timeout", in the
+	// form taken by Node.Lookup; "" for the root value
+	Path string
+}
+
+func (e *UnmarshalTypeError) Error() string {
+	if e.Path == "" {
+		return e.SyntaxError.Error()
+	}
+	return e.Path + ": " + e.SyntaxError.Error()
+}
+
+func (e *UnmarshalTypeError) Unwrap() error {
+	return e.SyntaxError
+}
+
+func (p *hjsonParser) typeError(what string, t reflect.Type) error {
+	return &UnmarshalTypeError{
+		SyntaxError: p.errCode("unmarshal-type", what, t.String()).(*SyntaxError),
+		Value:       what,
+		Type:        t,
+		Path:        p.pathString(),
+	}
+}
+
+// pathString returns the path of the value being parsed, like
+// "servers[1].timeout".
+func (p *hjsonParser) pathString() string {
+	return formatPath(p.path)
+}
+
+// position returns the Position of offset, which is not before the
+// offsets passed earlier since resetAt.
+func (p *hjsonParser) position(offset int) Position {
+	for ; p.posAt < offset; p.posAt++ {
+		if p.data[p.posAt] == '\n' {
+			p.posLine++
+			p.posStart = p.posAt + 1
+		}
+	}
+	return Position{Offset: offset, Line: p.posLine, Column: offset - p.posStart + 1}
+}
+
+// readQuoted decodes a value into dest, a field with the string tag option.
+// A string holding a number or bool is decoded as that value, other values
+// are decoded as usual.
+func (p *hjsonParser) readQuoted(dest reflect.Value) error {
+	p.white()
+	if p.ch != '"' && p.ch != '\'' {
+		_, err := p.readValue(dest)
+		return err
+	}
+	if err := p.alloc(allocValue); err != nil {
+		return err
+	}
+	str, err := p.readString(false)
+	if err == nil {
+		err = p.checkString(len(str))
+	}
+	if err != nil {
+		return err
+	}
+	var value interface{}
+	switch str {
+	case "true":
+		value = true
+	case "false":
+		value = false
+	case "null":
+	default:
+		n, err := tryParseNumber([]byte(str), false)
+		if err != nil {
+			return p.typeError("string "+strconv.Quote(str), dest.Type())
+		}
+		value = n
+	}
+	return p.setValue(dest, value, str)
+}
+
+// readGeneric decodes a value without a destination and stores it in the
+// interface dest.
+func (p *hjsonParser) readGeneric(dest reflect.Value, read func(reflect.Value) (interface{}, error)) error {
+	value, err := read(reflect.Value{})
+	if err != nil {
+		return err
+	}
+	return p.setValue(dest, value, "")
+}
+
+// readMismatch parses a value that cannot be stored in dest so that syntax
+// errors take precedence, and then reports a type error.
+func (p *hjsonParser) readMismatch(dest reflect.Value, what string, read func(reflect.Value) (interface{}, error)) error {
+	if _, err := read(reflect.Value{}); err != nil {
+		return err
+	}
+	return p.typeError(what, dest.Type())
+}
+
+// hookError is returned when DecodeHook fails. It wraps the error of the
+// hook, and its message tells where the value is.
+type hookError struct {
+	error
+	cause error
+}
+
+func (e hookError) Unwrap() error {
+	return e.cause
+}
+
+// decodeHook passes value, which is about to be stored in dest, to
+// DecodeHook and returns the value to store instead. The literal is kept
+// only if the value is unchanged.
+func (p *hjsonParser) decodeHook(dest reflect.Value, value interface{}, literal string) (interface{}, string, error) {
+	to := dest.Type()
+	for to.Kind() == reflect.Ptr {
+		to = to.Elem()
+	}
+	result, err := p.DecodeHook(reflect.ValueOf(value).Kind(), to, value)
+	if err != nil {
+		return nil, "", hookError{p.errAt(err.Error()), err}
+	}
+	if !sameValue(result, value) {
+		literal = ""
+	}
+	return result, literal, nil
+}
+
+// setValue stores a decoded bool, float64, string, []interface{},
+// map[string]interface{} or nil in dest. literal is the source text of
+// quoteless values; it is stored when such a value is decoded into a string.
+func (p *hjsonParser) setValue(dest reflect.Value, value interface{}, literal string) error {
+	if p.DecodeHook != nil && value != nil {
+		var err error
+		if value, literal, err = p.decodeHook(dest, value, literal); err != nil {
+			return err
+		}
+	}
+	if isSQLNull(dest.Type()) {
+		return p.scanSQLNull(dest, value, literal)
+	}
+	if value == nil {
+		// like encoding/json, null only affects values that can be nil
+		switch dest.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice:
+			dest.Set(reflect.Zero(dest.Type()))
+		}
+		return nil
+	}
+
+	dest = indirect(dest)
+	if isSQLNull(dest.Type()) {
+		return p.scanSQLNull(dest, value, literal)
+	}
+	switch value.(type) {
+	case string, bool, float64, []interface{}, map[string]interface{}, *OrderedMap:
+	default:
+		// a value parsed by an extension
+		if rv := reflect.ValueOf(value); rv.Type().AssignableTo(dest.Type()) {
+			dest.Set(rv)
+			return nil
+		}
+	}
+	if dest.Type() == durationType {
+		if s, ok := value.(string); ok {
+			return p.setDuration(dest, s)
+		}
+	} else if dest.Type() == timeType && p.TimeFormat != "" {
+		return p.setTime(dest, value, p.TimeFormat)
+	}
+	if isTextUnmarshaler(dest) {
+		text, ok := value.(string)
+		if literal != "" {
+			// quoteless values are passed as written
+			text, ok = literal, true
+		}
+		if !ok {
+			return p.typeError(describe(value), dest.Type())
+		}
+		if err := dest.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text)); err != nil {
+			return p.errAt(err.Error())
+		}
+		return nil
+	}
+	if isByteSlice(dest.Type()) {
+		if literal != "" {
+			// quoteless base64 or hex text can look like a number
+			return p.setBytes(dest, literal)
+		} else if s, ok := value.(string); ok {
+			return p.setBytes(dest, s)
+		}
+	}
+	if dest.Kind() == reflect.Interface {
+		rv := reflect.ValueOf(value)
+		if !rv.Type().AssignableTo(dest.Type()) {
+			return p.typeError(describe(value), dest.Type())
+		}
+		dest.Set(rv)
+		return nil
+	}
+
+	if p.WeaklyTyped {
+		value, literal = weaken(dest.Kind(), value, literal)
+	}
+	switch v := value.(type) {
+	case string:
+		if dest.Kind() == reflect.String {
+			dest.SetString(v)
+			return nil
+		}
+	case bool:
+		switch dest.Kind() {
+		case reflect.Bool:
+			dest.SetBool(v)
+			return nil
+		case reflect.String:
+			dest.SetString(literal)
+			return nil
+		}
+	case float64:
+		switch dest.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			// integer literals are parsed exactly, float64 cannot hold
+			// all 64-bit integers
+			if n, err := strconv.ParseInt(literal, 10, 64); err == nil {
+				if !dest.OverflowInt(n) {
+					dest.SetInt(n)
+					return nil
+				}
+			} else if v == math.Trunc(v) && v >= -(1<<63) && v < 1<<63 && !dest.OverflowInt(int64(v)) {
+				dest.SetInt(int64(v))
+				return nil
+			}
+			return p.typeError("number "+literal, dest.Type())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if n, err := strconv.ParseUint(literal, 10, 64); err == nil {
+				if !dest.OverflowUint(n) {
+					dest.SetUint(n)
+					return nil
+				}
+			} else if v == math.Trunc(v) && v >= 0 && v < 1<<64 && !dest.OverflowUint(uint64(v)) {
+				dest.SetUint(uint64(v))
+				return nil
+			}
+			return p.typeError("number "+literal, dest.Type())
+		case reflect.Float32, reflect.Float64:
+			if !dest.OverflowFloat(v) {
+				dest.SetFloat(v)
+				return nil
+			}
+			return p.typeError("number "+literal, dest.Type())
+		case reflect.String:
+			dest.SetString(literal)
+			return nil
+		}
+	default:
+		if dest.Kind() == reflect.String && literal != "" {
+			dest.SetString(literal)
+			return nil
+		}
+	}
+	return p.typeError(describe(value), dest.Type())
+}
+
+// weaken converts value, with its literal, to the representation matching
+// values of kind, see WeaklyTyped. Values that cannot be converted are
+// returned unchanged.
+func weaken(kind reflect.Kind, value interface{}, literal string) (interface{}, string) {
+	switch kind {
+	case reflect.Bool:
+		switch v := value.(type) {
+		case string:
+			if strings.TrimSpace(v) == "" {
+				return false, ""
+			}
+			if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+				return b, ""
+			}
+		case float64:
+			return v != 0, ""
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		switch v := value.(type) {
+		case string:
+			text := strings.TrimSpace(v)
+			if text == "" {
+				return 0.0, "0"
+			}
+			if n, err := tryParseNumber([]byte(text), false); err == nil {
+				return n, text
+			}
+		case bool:
+			if v {
+				return 1.0, "1"
+			}
+			return 0.0, "0"
+		}
+	case reflect.String:
+		if literal == "" {
+			// a value without its source text, like one from DecodeHook
+			switch v := value.(type) {
+			case float64:
+				return v, strconv.FormatFloat(v, 'g', -1, 64)
+			case bool:
+				return v, strconv.FormatBool(v)
+			}
+		}
+	}
+	return value, literal
+}
+
+// isIntegerType reports whether values decoded into dest are integers.
+func isIntegerType(dest reflect.Value) bool {
+	if !dest.IsValid() {
+		return false
+	}
+	t := dest.Type()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// describe names the kind of a decoded value for error messages.
+func describe(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case float64, json.Number:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}, *OrderedMap:
+		return "object"
+	}
+	return "null"
+}
+
+// describeStart names the kind of the value starting with c.
+func describeStart(c byte) string {
+	if c == '{' {
+		return "object"
+	}
+	return "array"
+}
+
+var textUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// isTextUnmarshaler reports whether the addressable v is filled with
+// UnmarshalText.
+func isTextUnmarshaler(v reflect.Value) bool {
+	return v.Kind() != reflect.Interface && v.CanAddr() && v.Addr().Type().Implements(textUnmarshaler)
+}
+
+// isMapKeyType reports whether objects can be decoded into maps with keys
+// of type t: strings, integers and types implementing
+// encoding.TextUnmarshaler.
+func isMapKeyType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return reflect.PtrTo(t).Implements(textUnmarshaler)
+}
+
+// mapKey converts an object key to a map key of type t.
+func (p *hjsonParser) mapKey(t reflect.Type, key string) (reflect.Value, error) {
+	if reflect.PtrTo(t).Implements(textUnmarshaler) && t.Kind() != reflect.String {
+		kv := reflect.New(t)
+		if err := kv.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(key)); err != nil {
+			return kv, p.errAt(err.Error())
+		}
+		return kv.Elem(), nil
+	}
+	kv := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.String:
+		kv.SetString(key)
+		return kv, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(key, 10, 64)
+		if err == nil && !kv.OverflowInt(n) {
+			kv.SetInt(n)
+			return kv, nil
+		}
+	default:
+		n, err := strconv.ParseUint(key, 10, 64)
+		if err == nil && !kv.OverflowUint(n) {
+			kv.SetUint(n)
+			return kv, nil
+		}
+	}
+	return kv, p.typeError("key "+strconv.Quote(key), t)
+}
+
+// indirect follows pointers, allocating new values for nil pointers.
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
 // Unmarshal parses the Hjson-encoded data and stores the result
+// in the value pointed to by v, using default options.
+//
+// See UnmarshalWithOptions.
+//
+func Unmarshal(data []byte, v interface{}) error {
+	return UnmarshalWithOptions(data, v, DefaultDecoderOptions())
+}
+
+// UnmarshalWithOptions parses the Hjson-encoded data and stores the result
 // in the value pointed to by v.
 //
-// Unmarshal uses the inverse of the encodings that
+// UnmarshalWithOptions uses the inverse of the encodings that
 // Marshal uses, allocating maps, slices, and pointers as necessary.
+// Values are decoded straight into the destination, without building an
+// intermediate map[string]interface{} tree.
 //
-func Unmarshal(data []byte, v interface{}) (err error) {
-	var value interface{}
-	parser := &hjsonParser{data, 0, ' '}
-	parser.resetAt()
-	value, err = parser.rootValue()
-	if err != nil {
+// To unmarshal into an interface value, UnmarshalWithOptions stores one of
+// these in the interface value:
+//
+//	bool, for booleans
+//	float64, for numbers, or json.Number if options.UseNumber is set
+//	string, for strings
+//	[]interface{}, for arrays
+//	map[string]interface{}, for objects, or *OrderedMap if
+//	options.UseOrderedMap is set
+//	nil for null
+//
+// Object members are matched to struct fields by the name in the field's
+// hjson or json tag or by the field name, preferring an exact match but also
+// accepting a case-insensitive one. Members without a matching field are
+// ignored, unless options.DisallowUnknownFields is set. Number and bool
+// fields with the string tag option also accept their value as a string.
+//
+// A time.Time is decoded from an RFC 3339 string, or in the format of
+// options.TimeFormat or of the field's format tag option, for example
+// `json:"start,format=unixms"`. A time.Duration is decoded from a number
+// of nanoseconds or from a string like "1h30m".
+//
+// A byte slice is decoded from a base64 string, or a hex string if
+// options.BytesFormat or the field's format tag option is "hex", or from an
+// array of numbers.
+//
+// A quoteless value that looks like a number or a boolean is stored as
+// written when it is decoded into a string.
+//
+// A value decoded into a type implementing Unmarshaler is passed to its
+// UnmarshalHJSON method as written, including the root value. A value
+// decoded into a json.RawMessage is stored as compact JSON, for decoding it
+// later.
+//
+// Strings decoded into a value implementing encoding.TextUnmarshaler are
+// passed to its UnmarshalText method. Objects can be decoded into maps
+// whose keys are strings, integers or implement encoding.TextUnmarshaler.
+//
+// The Null types of database/sql are filled through their Scan method,
+// null makes them invalid. sql.NullTime expects an RFC 3339 string. The
+// same goes for Optional.
+//
+func UnmarshalWithOptions(data []byte, v interface{}, options DecoderOptions) error {
+	hook := loadMetricsHook()
+	if hook == nil {
+		_, err := unmarshal(data, v, options)
 		return err
 	}
+	start := time.Now()
+	depth, err := unmarshal(data, v, options)
+	hook(Metrics{"decode", time.Since(start), len(data), depth, err})
+	return err
+}
 
+// unmarshal implements UnmarshalWithOptions and also returns the nesting
+// depth of the input.
+func unmarshal(data []byte, v interface{}, options DecoderOptions) (depth int, err error) {
 	rv := reflect.ValueOf(v)
 	if rv.Kind() != reflect.Ptr || rv.IsNil() {
-		return fmt.Errorf("non-pointer %v", reflect.TypeOf(v))
+		return 0, fmt.Errorf("non-pointer %v", reflect.TypeOf(v))
 	}
-	for rv.Kind() == reflect.Ptr {
-		rv = rv.Elem()
+	if options.MaxInputBytes > 0 && len(data) > options.MaxInputBytes {
+		return 0, limitError{fmt.Errorf("Input of %d bytes exceeds the limit of %d bytes", len(data), options.MaxInputBytes)}
 	}
+	exts, err := lookupExtensions(options.Extensions)
+	if err != nil {
+		return 0, err
+	}
+	parser := &hjsonParser{DecoderOptions: options, data: data, extensions: exts}
 	defer func() {
 		if e := recover(); e != nil {
 			err = fmt.Errorf("%v", e)
 		}
+		depth = parser.maxDepth
 	}()
-	rv.Set(reflect.ValueOf(value))
-	return err
+	parser.resetAt()
+	if isUnmarshaler(rv.Elem()) {
+		// the whole input is a single value or a braceless object
+		if _, err = parser.rootValue(reflect.Value{}); err == nil {
+			err = rv.Interface().(Unmarshaler).UnmarshalHJSON(data)
+		}
+		err = moreErrors(data, options, err)
+		return
+	}
+	_, err = parser.rootValue(rv.Elem())
+	err = moreErrors(data, options, err)
+	return
 }
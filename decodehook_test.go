@@ -0,0 +1,122 @@
+package hjson
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type level int
+
+const (
+	levelDebug level = iota
+	levelInfo
+)
+
+var levelType = reflect.TypeOf(level(0))
+var ipType = reflect.TypeOf(net.IP{})
+
+type fileSize int64
+
+var fileSizeType = reflect.TypeOf(fileSize(0))
+
+var errUnknownLevel = errors.New("unknown level")
+
+// testHook converts strings like mapstructure's StringToTimeDurationHookFunc
+// and friends do.
+func testHook(from reflect.Kind, to reflect.Type, value interface{}) (interface{}, error) {
+	s, ok := value.(string)
+	if from != reflect.String || !ok {
+		return value, nil
+	}
+	switch to {
+	case reflect.TypeOf(time.Duration(0)):
+		return time.ParseDuration(s)
+	case levelType:
+		switch s {
+		case "debug":
+			return levelDebug, nil
+		case "info":
+			return levelInfo, nil
+		}
+		return nil, errUnknownLevel
+	case ipType:
+		return net.ParseIP(s), nil
+	case fileSizeType:
+		for i, unit := range []string{"KB", "MB", "GB"} {
+			if strings.HasSuffix(s, unit) {
+				n, err := strconv.ParseInt(strings.TrimSuffix(s, unit), 10, 64)
+				return fileSize(n << (10 * uint(i+1))), err
+			}
+		}
+	}
+	return value, nil
+}
+
+func TestDecodeHook(t *testing.T) {
+	decOpt, err := NewDecoderOptions(WithDecodeHook(testHook))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s struct {
+		Timeout time.Duration
+		Level   level
+		Addr    net.IP
+		Max     *fileSize
+		Sizes   map[string]fileSize
+		Name    string
+		Port    int
+		Any     interface{}
+	}
+	input := `{
+  Timeout: 1m30s
+  Level: info
+  Addr: 10.0.0.1
+  Max: 10MB
+  Sizes: { small: "1KB", big: "2GB" }
+  Name: 10MB
+  Port: 8080
+  Any: 5m
+}`
+	if err = UnmarshalWithOptions([]byte(input), &s, decOpt); err != nil {
+		t.Fatal(err)
+	}
+	if s.Timeout != 90*time.Second || s.Level != levelInfo || !s.Addr.Equal(net.IPv4(10, 0, 0, 1)) ||
+		s.Max == nil || *s.Max != 10<<20 || s.Sizes["small"] != 1<<10 || s.Sizes["big"] != 2<<30 ||
+		s.Name != "10MB" || s.Port != 8080 || s.Any != "5m" {
+		t.Errorf("unexpected %+v", s)
+	}
+
+	var kinds []string
+	decOpt.DecodeHook = func(from reflect.Kind, to reflect.Type, value interface{}) (interface{}, error) {
+		kinds = append(kinds, fmt.Sprintf("%s>%s", from, to))
+		return value, nil
+	}
+	var v []interface{}
+	if err = UnmarshalWithOptions([]byte("[1, true, \"x\", null, {a: [2]}]"), &v, decOpt); err != nil {
+		t.Fatal(err)
+	}
+	// values decoded into an interface{} are passed whole
+	expected := []string{"float64>interface {}", "bool>interface {}", "string>interface {}", "map>interface {}"}
+	if !reflect.DeepEqual(kinds, expected) {
+		t.Errorf("expected %v, got %v", expected, kinds)
+	}
+
+	decOpt.DecodeHook = testHook
+	err = UnmarshalWithOptions([]byte("{ Level: \"trace\" }"), &s, decOpt)
+	if err == nil || !strings.Contains(err.Error(), "unknown level at line 1") {
+		t.Errorf("expected the hook error with its position, got %v", err)
+	}
+	if hookErr, ok := err.(hookError); !ok || hookErr.Unwrap() != errUnknownLevel {
+		t.Errorf("expected the hook error to be wrapped, got %#v", err)
+	}
+
+	if _, err = NewDecoderOptions(WithDecodeHook(nil)); err == nil {
+		t.Error("expected an error for a nil DecodeHook")
+	}
+}
@@ -0,0 +1,47 @@
+package hjson
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// The options set by SetDefaultEncoderOptions and SetDefaultDecoderOptions.
+var defaultEncoderOptions, defaultDecoderOptions atomic.Value
+
+// SetDefaultEncoderOptions replaces the options returned by DefaultOptions,
+// and so the options used by Marshal, the presets and NewEncoderOptions. It
+// lets an application configure its house style once at startup; functions
+// that take options still use the options they are given. It is safe to
+// call concurrently with encoding.
+//
+// It fails if the options are invalid. Pass the options returned by
+// DefaultOptions before the first call to restore them.
+func SetDefaultEncoderOptions(options EncoderOptions) error {
+	if err := options.validate(); err != nil {
+		return err
+	}
+	if _, err := lookupExtensions(options.Extensions); err != nil {
+		return err
+	}
+	options.Extensions = append([]string(nil), options.Extensions...)
+	defaultEncoderOptions.Store(options)
+	return nil
+}
+
+// SetDefaultDecoderOptions replaces the options returned by
+// DefaultDecoderOptions, and so the options used by Unmarshal. It is safe to
+// call concurrently with decoding.
+//
+// It fails if the options enable an unknown extension, or set Positions,
+// which would be written by all decoding at the same time.
+func SetDefaultDecoderOptions(options DecoderOptions) error {
+	if _, err := lookupExtensions(options.Extensions); err != nil {
+		return err
+	}
+	if options.Positions != nil {
+		return errors.New("Invalid DecoderOptions: Positions cannot be a default, as it would be shared by all decoding")
+	}
+	options.Extensions = append([]string(nil), options.Extensions...)
+	defaultDecoderOptions.Store(options)
+	return nil
+}
@@ -0,0 +1,81 @@
+package hjson
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSetDefaultOptions(t *testing.T) {
+	encOpt, decOpt := DefaultOptions(), DefaultDecoderOptions()
+	defer SetDefaultEncoderOptions(encOpt)
+	defer SetDefaultDecoderOptions(decOpt)
+
+	house := DefaultOptions()
+	house.IndentBy = "\t"
+	house.QuoteAlways = true
+	if err := SetDefaultEncoderOptions(house); err != nil {
+		t.Fatal(err)
+	}
+	buf, _ := Marshal(map[string]string{"a": "x"})
+	if string(buf) != "{\n\ta: \"x\"\n}" {
+		t.Errorf("unexpected output %q", buf)
+	}
+	buf, _ = MarshalWithOptions(map[string]string{"a": "x"}, encOpt)
+	if string(buf) != "{\n  a: x\n}" {
+		t.Errorf("unexpected output with explicit options %q", buf)
+	}
+	if opt, _ := NewEncoderOptions(); opt.IndentBy != "\t" {
+		t.Error("expected NewEncoderOptions to start from the defaults")
+	}
+
+	house.Eol = "\r"
+	if err := SetDefaultEncoderOptions(house); err == nil {
+		t.Error("expected an error for invalid options")
+	}
+
+	dec := DefaultDecoderOptions()
+	dec.MaxAlloc = 10
+	if err := SetDefaultDecoderOptions(dec); err != nil {
+		t.Fatal(err)
+	}
+	var v interface{}
+	if err := Unmarshal([]byte("[1, 2, 3, 4, 5]"), &v); err == nil {
+		t.Error("expected Unmarshal to use MaxAlloc from the defaults")
+	}
+	dec.Extensions = []string{"missing"}
+	if err := SetDefaultDecoderOptions(dec); err == nil {
+		t.Error("expected an error for an unknown extension")
+	}
+}
+
+func TestDefaultDecoderOptionsConcurrent(t *testing.T) {
+	decOpt := DefaultDecoderOptions()
+	decOpt.Positions = map[string]Position{}
+	if err := SetDefaultDecoderOptions(decOpt); err == nil {
+		t.Fatal("expected an error for a default Positions map")
+	}
+
+	// run with -race: the defaults are shared, the maps are not
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data := []byte("a: 1\nb: [2, 3]")
+			var v interface{}
+			if err := Unmarshal(data, &v); err != nil {
+				t.Error(err)
+			}
+			if _, err := Get(data, "b[1]"); err != nil {
+				t.Error(err)
+			}
+			Validate(data)
+			opt := DefaultDecoderOptions()
+			opt.Positions = map[string]Position{}
+			if err := UnmarshalWithOptions(data, &v, opt); err != nil || len(opt.Positions) != 5 {
+				t.Errorf("unexpected %v, %v", opt.Positions, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,268 @@
+package hjson
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// ChangeKind tells how a value differs between two documents, see Change.
+type ChangeKind int
+
+const (
+	// ChangeAdded is a member or element only found in the second document
+	ChangeAdded ChangeKind = iota
+	// ChangeRemoved is a member or element only found in the first document
+	ChangeRemoved
+	// ChangeModified is a value that differs, or is of another kind
+	ChangeModified
+	// ChangeComments is a value with different comments, see
+	// DiffOptions.Comments
+	ChangeComments
+)
+
+// A Change is a difference between two documents found by Diff.
+type Change struct {
+	Kind ChangeKind
+	// The path of the value, like "server.ports[0]" (see Node.Lookup), or
+	// "" for the root
+	Path string
+	// The value in the first document, nil for ChangeAdded
+	Old *Node
+	// The value in the second document, nil for ChangeRemoved
+	New *Node
+}
+
+// String returns c on a line of its own, for example
+//
+//	~ server.port: 80 -> 8080
+//	+ server.hosts[2]: "c.example.com"
+//	- server.debug: true
+//	# server.port: comments changed
+//
+// Values are written as compact JSON, and the root as ".".
+func (c Change) String() string {
+	path := c.Path
+	if path == "" {
+		path = "."
+	}
+	switch c.Kind {
+	case ChangeAdded:
+		return "+ " + path + ": " + compactNode(c.New)
+	case ChangeRemoved:
+		return "- " + path + ": " + compactNode(c.Old)
+	case ChangeModified:
+		return "~ " + path + ": " + compactNode(c.Old) + " -> " + compactNode(c.New)
+	}
+	return "# " + path + ": comments changed"
+}
+
+// compactNode returns the value of n as compact JSON, keeping the order of
+// object members.
+func compactNode(n *Node) string {
+	var buf bytes.Buffer
+	if err := writeCompactJSON(&buf, orderedValue(n)); err != nil {
+		return "?"
+	}
+	return buf.String()
+}
+
+// orderedValue returns the value of n like Interface, but with objects as
+// *OrderedMap.
+func orderedValue(n *Node) interface{} {
+	switch n.Kind {
+	case ObjectNode:
+		object := NewOrderedMap()
+		for _, c := range n.Children {
+			object.Set(c.Key, orderedValue(c))
+		}
+		return object
+	case ArrayNode:
+		array := make([]interface{}, len(n.Children))
+		for i, c := range n.Children {
+			array[i] = orderedValue(c)
+		}
+		return array
+	}
+	return n.Value
+}
+
+// DiffOptions defines options for DiffWithOptions.
+type DiffOptions struct {
+	// Report values whose comments differ as ChangeComments
+	Comments bool
+}
+
+// Diff returns the changes that turn the document a into b, comparing
+// their values: formatting, comments and the order of object members are
+// ignored. The members of objects are matched by key and the elements of
+// arrays by index, so an element inserted into an array changes all the
+// elements after it. Changes are listed in the order of a, followed by the
+// members added in b.
+func Diff(a, b *Node) []Change {
+	return DiffWithOptions(a, b, DiffOptions{})
+}
+
+// DiffWithOptions is like Diff, but with options.Comments the comments of
+// values are compared as well. Only the text of the comments matters, not
+// the whitespace and blank lines around them.
+func DiffWithOptions(a, b *Node, options DiffOptions) []Change {
+	d := differ{options: options}
+	var aComments, bComments []string
+	if options.Comments {
+		aComments = rootComments(a)
+		bComments = rootComments(b)
+	}
+	d.diff("", a, b, aComments, bComments)
+	return d.changes
+}
+
+type differ struct {
+	options DiffOptions
+	changes []Change
+}
+
+// diff adds the changes between a and b at path, which have the comments
+// aComments and bComments from outside of them.
+func (d *differ) diff(path string, a, b *Node, aComments, bComments []string) {
+	if a.Kind != b.Kind || a.Kind == ValueNode && !sameValue(a.Value, b.Value) {
+		d.changes = append(d.changes, Change{ChangeModified, path, a, b})
+	}
+	if d.options.Comments {
+		aComments = append(aComments, insideComments(a)...)
+		bComments = append(bComments, insideComments(b)...)
+		if strings.Join(aComments, "\n") != strings.Join(bComments, "\n") {
+			d.changes = append(d.changes, Change{ChangeComments, path, a, b})
+		}
+	}
+	if a.Kind != b.Kind {
+		return
+	}
+
+	aChildren := make([][]string, len(a.Children))
+	bChildren := make([][]string, len(b.Children))
+	if d.options.Comments {
+		aChildren = joinComments(childComments(a))
+		bChildren = joinComments(childComments(b))
+	}
+	switch a.Kind {
+	case ArrayNode:
+		for i, c := range a.Children {
+			elemPath := path + "[" + strconv.Itoa(i) + "]"
+			if i < len(b.Children) {
+				d.diff(elemPath, c, b.Children[i], aChildren[i], bChildren[i])
+			} else {
+				d.changes = append(d.changes, Change{ChangeRemoved, elemPath, c, nil})
+			}
+		}
+		for i := len(a.Children); i < len(b.Children); i++ {
+			d.changes = append(d.changes, Change{ChangeAdded, path + "[" + strconv.Itoa(i) + "]", nil, b.Children[i]})
+		}
+	case ObjectNode:
+		// like Get, only the last of duplicate keys counts
+		aIndex := memberIndex(a)
+		bIndex := memberIndex(b)
+		for i, c := range a.Children {
+			if aIndex[c.Key] != i {
+				continue
+			}
+			memberPath := c.Key
+			if path != "" {
+				memberPath = path + "." + c.Key
+			}
+			if j, ok := bIndex[c.Key]; ok {
+				d.diff(memberPath, c, b.Children[j], aChildren[i], bChildren[j])
+			} else {
+				d.changes = append(d.changes, Change{ChangeRemoved, memberPath, c, nil})
+			}
+		}
+		for i, c := range b.Children {
+			if _, ok := aIndex[c.Key]; ok || bIndex[c.Key] != i {
+				continue
+			}
+			memberPath := c.Key
+			if path != "" {
+				memberPath = path + "." + c.Key
+			}
+			d.changes = append(d.changes, Change{ChangeAdded, memberPath, nil, c})
+		}
+	}
+}
+
+// memberIndex returns the index of the last member of the object n with
+// each key.
+func memberIndex(n *Node) map[string]int {
+	index := make(map[string]int, len(n.Children))
+	for i, c := range n.Children {
+		index[c.Key] = i
+	}
+	return index
+}
+
+// sameValue reports whether the values of two ValueNodes are equal.
+func sameValue(a, b interface{}) bool {
+	defer func() {
+		// values of extensions may not be comparable
+		recover()
+	}()
+	return a == b
+}
+
+// rootComments returns the comments before and after the root n.
+func rootComments(n *Node) []string {
+	line, others := splitComments(n.Comments.Before)
+	comments := appendComments(line, others)
+	line, others = splitComments(n.Comments.Line)
+	return nonBlank(appendComments(appendComments(comments, line), others))
+}
+
+// childComments returns the comments before each child of n and those on
+// the line of its value, attributed like Format does. The comments on the
+// lines after the last child belong to n, see insideComments.
+func childComments(n *Node) (before, line [][]string) {
+	before = make([][]string, len(n.Children))
+	line = make([][]string, len(n.Children))
+	var carry []string
+	for i, c := range n.Children {
+		l, others := splitComments(c.Comments.Before)
+		items := appendComments(appendComments(carry, l), others)
+		l, others = splitComments(c.Comments.Key)
+		before[i] = appendComments(appendComments(items, l), others)
+		line[i], carry = splitComments(c.Comments.Line)
+	}
+	return before, line
+}
+
+// joinComments returns the comments of each child, see childComments,
+// without blank lines.
+func joinComments(before, line [][]string) [][]string {
+	for i := range before {
+		before[i] = nonBlank(append(before[i], line[i]...))
+	}
+	return before
+}
+
+// insideComments returns the comments inside the array or object n after
+// its last child.
+func insideComments(n *Node) []string {
+	if n.Kind == ValueNode {
+		return nil
+	}
+	var carry []string
+	if len(n.Children) > 0 {
+		_, carry = splitComments(n.Children[len(n.Children)-1].Comments.Line)
+	}
+	line, others := splitComments(n.Comments.After)
+	return nonBlank(appendComments(appendComments(carry, line), others))
+}
+
+// nonBlank returns the comments in items without the blank lines.
+func nonBlank(items []string) []string {
+	var comments []string
+	for _, item := range items {
+		if item != "" {
+			comments = append(comments, item)
+		}
+	}
+	return comments
+}
@@ -0,0 +1,101 @@
+package hjson
+
+import (
+	"strings"
+	"testing"
+)
+
+func diffLines(t *testing.T, a, b string, options DiffOptions) string {
+	na, err := ParseNode([]byte(a))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nb, err := ParseNode([]byte(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	for _, c := range DiffWithOptions(na, nb, options) {
+		lines = append(lines, c.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestDiff(t *testing.T) {
+	a := `# config
+server: {
+  port: 80 // http
+  hosts: ["a.example.com", "b.example.com"]
+  debug: true
+  tls: { cert: "a.pem" }
+}
+`
+	b := `{
+  "server": {
+    "tls": {"cert": "a.pem", "key": "a.key"},
+    "hosts": ["a.example.com"],
+    "port": 8080.0,
+    "name": "web",
+  },
+}`
+	expected := `~ server.port: 80 -> 8080
+- server.hosts[1]: "b.example.com"
+- server.debug: true
++ server.tls.key: "a.key"
++ server.name: "web"`
+	if actual := diffLines(t, a, b, DiffOptions{}); actual != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, actual)
+	}
+	if actual := diffLines(t, b, b, DiffOptions{}); actual != "" {
+		t.Errorf("expected no changes, got\n%s", actual)
+	}
+
+	for _, c := range [][3]string{
+		{"1", "[1]", "~ .: 1 -> [1]"},
+		{"a: {b: 1}", "a: [1]", `~ a: {"b":1} -> [1]`},
+		{"a: 1", "b: [2, {c: null}]", "- a: 1\n+ b: [2,{\"c\":null}]"},
+		{"a: 1\na: 2", "a: 2", ""},
+		{"a: 1\na: 2", "a: 1", "~ a: 2 -> 1"},
+		{"a: '1'", "a: 1", `~ a: "1" -> 1`},
+	} {
+		if actual := diffLines(t, c[0], c[1], DiffOptions{}); actual != c[2] {
+			t.Errorf("%q to %q: expected\n%s\ngot\n%s", c[0], c[1], c[2], actual)
+		}
+	}
+}
+
+func TestDiffComments(t *testing.T) {
+	a := `# config
+a: 1 // one
+
+# two
+b: 2, c: [
+  3 # three
+]
+`
+	b := `// config
+{
+  a: 1 // one
+  # two
+  b: 2
+  c: [
+    3, # three
+  ]
+}
+`
+	expected := `# .: comments changed`
+	if actual := diffLines(t, a, b, DiffOptions{Comments: true}); actual != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, actual)
+	}
+
+	b = strings.Replace(b, "// config", "# config", 1)
+	b = strings.Replace(b, "# two", "# 2", 1)
+	b = strings.Replace(b, "b: 2", "b: 22", 1)
+	expected = "~ b: 2 -> 22\n# b: comments changed"
+	if actual := diffLines(t, a, b, DiffOptions{Comments: true}); actual != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, actual)
+	}
+	if actual := diffLines(t, a, b, DiffOptions{}); actual != "~ b: 2 -> 22" {
+		t.Errorf("expected only the value, got\n%s", actual)
+	}
+}
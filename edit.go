@@ -0,0 +1,78 @@
+package hjson
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// An EditOp is a change of a single member or element for Edit and
+// EditFile.
+type EditOp struct {
+	// "insert", "update" or "delete"
+	Op string
+	// The path of the member or element, see Node.Lookup. Insert adds a
+	// member that does not exist yet, adding the missing objects along the
+	// path, or puts an element into an array before the element at the
+	// index, which may be the length of the array to append it.
+	Path string
+	// For insert and update, the new value, converted with NewNode
+	Value interface{}
+}
+
+// Edit returns the Hjson document data changed by op. Only the text of the
+// changed member or element is written anew, in the style of the document;
+// all other bytes are kept as they are, so that reviews of automated
+// changes show just the lines that changed.
+func Edit(data []byte, op EditOp) ([]byte, error) {
+	root, err := ParseNode(data)
+	if err != nil {
+		return nil, err
+	}
+	c, err := root.Lookup(op.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch op.Op {
+	case "insert":
+		if c != nil && c.parent != nil && c.parent.Kind == ObjectNode {
+			return nil, fmt.Errorf("Cannot insert '%s': it exists already", op.Path)
+		}
+		n, err := NewNode(op.Value)
+		if err != nil {
+			return nil, err
+		}
+		err = root.AttachAt(op.Path, n)
+	case "update":
+		if c == nil {
+			return nil, fmt.Errorf("Cannot update '%s': no such member or element", op.Path)
+		}
+		err = c.SetValue(op.Value)
+	case "delete":
+		_, err = root.Detach(op.Path)
+	default:
+		return nil, fmt.Errorf("Unknown edit operation %q", op.Op)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return root.Marshal()
+}
+
+// EditFile changes the Hjson file at path with Edit, keeping its
+// permissions.
+func EditFile(path string, op EditOp) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	out, err := Edit(data, op)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return ioutil.WriteFile(path, out, info.Mode().Perm())
+}
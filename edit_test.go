@@ -0,0 +1,67 @@
+package hjson
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEdit(t *testing.T) {
+	src := "# config\nserver: {\n  host: \"example.com\" # the host\n  port: 80\n  tags: [\"a\", \"b\"]\n}\n\n# logging\nlevel: info\n"
+	for _, test := range []struct {
+		op       EditOp
+		expected string
+	}{
+		{EditOp{"update", "server.host", "other.org"}, "# config\nserver: {\n  host: \"other.org\" # the host\n  port: 80\n  tags: [\"a\", \"b\"]\n}\n\n# logging\nlevel: info\n"},
+		{EditOp{"update", "server.port", 8080}, "# config\nserver: {\n  host: \"example.com\" # the host\n  port: 8080\n  tags: [\"a\", \"b\"]\n}\n\n# logging\nlevel: info\n"},
+		{EditOp{"insert", "server.timeout", "30s"}, "# config\nserver: {\n  host: \"example.com\" # the host\n  port: 80\n  tags: [\"a\", \"b\"]\n  timeout: 30s\n}\n\n# logging\nlevel: info\n"},
+		{EditOp{"insert", "server.tags[1]", "x"}, "# config\nserver: {\n  host: \"example.com\" # the host\n  port: 80\n  tags: [\"a\", \"x\", \"b\"]\n}\n\n# logging\nlevel: info\n"},
+		{EditOp{"insert", "db.name", "main"}, "# config\nserver: {\n  host: \"example.com\" # the host\n  port: 80\n  tags: [\"a\", \"b\"]\n}\n\n# logging\nlevel: info\ndb: {\n  name: main\n}\n"},
+		{EditOp{"delete", "server.port", nil}, "# config\nserver: {\n  host: \"example.com\" # the host\n  tags: [\"a\", \"b\"]\n}\n\n# logging\nlevel: info\n"},
+		{EditOp{"delete", "server.tags[0]", nil}, "# config\nserver: {\n  host: \"example.com\" # the host\n  port: 80\n  tags: [\"b\"]\n}\n\n# logging\nlevel: info\n"},
+	} {
+		out, err := Edit([]byte(src), test.op)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != test.expected {
+			t.Errorf("%v: expected\n%s\ngot\n%s", test.op, test.expected, out)
+		}
+	}
+
+	for _, op := range []EditOp{
+		{"insert", "server.port", 1},
+		{"update", "server.user", "x"},
+		{"delete", "server.tags[5]", nil},
+		{"rename", "level", "x"},
+		{"update", "server[", 1},
+	} {
+		if _, err := Edit([]byte(src), op); err == nil {
+			t.Errorf("%v: expected an error", op)
+		}
+	}
+}
+
+func TestEditFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.hjson")
+	if err := ioutil.WriteFile(path, []byte("# port\nport: 80\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := EditFile(path, EditOp{Op: "update", Path: "port", Value: 8080}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "# port\nport: 8080\n" {
+		t.Errorf("unexpected %q", data)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("expected the permissions to be kept, got %v, %v", info.Mode(), err)
+	}
+	if err := EditFile(path, EditOp{Op: "delete", Path: "host"}); err == nil {
+		t.Error("expected an error for a missing member")
+	}
+}
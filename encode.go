@@ -2,21 +2,25 @@ package hjson
 
 import (
 	"bytes"
+	"encoding"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"math"
 	"reflect"
 	"regexp"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 	"unicode/utf8"
 )
 
 // EncoderOptions defines options for encoding to Hjson.
 type EncoderOptions struct {
-	// End of line, should be either \n or \r\n
+	// End of line, should be either \n or \r\n, empty for \n
 	Eol string
 	// Place braces on the same line
 	BracesSameLine bool
@@ -30,10 +34,147 @@ type EncoderOptions struct {
 	AllowMinusZero bool
 	// Encode unknown values as 'null'
 	UnknownAsNull bool
+	// With UnknownAsNull, called for unknown values to get a string that is
+	// encoded instead of null, for example DescribeUnknown
+	UnknownStringer func(v reflect.Value) string
+	// Called for values that cannot be encoded (funcs, channels, complex
+	// numbers, unsafe pointers) with the path of the value, for example
+	// "servers[2].handler". It can return a replacement to encode instead,
+	// skip the value (dropping the member or array element) or fail with an
+	// error. Takes precedence over UnknownAsNull.
+	OnUnsupportedType func(path string, v reflect.Value) (replacement interface{}, skip bool, err error)
+	// Encode types that implement fmt.Stringer, but neither json.Marshaler
+	// nor encoding.TextMarshaler, as the string returned by their String
+	// method
+	UseStringer bool
+	// Encode values that implement the error interface as the string
+	// returned by their Error method
+	UseErrorString bool
+	// Deprecated: use SortKeys with SortKeysAlphabeticalIgnoreCase, which
+	// this option selects. It cannot be combined with other SortKeys modes.
+	SortKeysIgnoreCase bool
+	// How map keys are ordered, see SortKeysMode
+	SortKeys SortKeysMode
+	// With SortKeysCustom, reports whether the map key a goes before b
+	KeyLess func(a, b string) bool
+	// Names of the registered extensions to enable, see Extension
+	Extensions []string
+	// Output format version, 0 for the latest (see FormatVersion1)
+	FormatVersion int
+	// Format of time.Time values: the name of a layout of the time package
+	// ("RFC3339", "DateOnly", ...), a layout like "2006-01-02 15:04", or
+	// "unix" and "unixms" for numbers of seconds or milliseconds since
+	// 1970; "" for RFC 3339 with fractional seconds
+	TimeFormat string
+	// Encode time.Duration values as strings like "1h30m0s" instead of
+	// numbers of nanoseconds
+	DurationAsString bool
+	// Fail when arrays and objects are nested deeper than this (0 for no
+	// limit)
+	MaxDepth int
+	// Write json.RawMessage values as the JSON they hold instead of
+	// reformatting them like other values, which FormatVersion1 and
+	// FormatVersion2 always do, without the checks of this option
+	RawMessageVerbatim bool
+	// Encoding of byte slices: "base64" or "hex" for a string, "array" for
+	// an array of numbers; "" for base64, or array with FormatVersion1
+	BytesFormat string
+	// Syntax of the output, Hjson, JSON, JSONC or JSON5
+	OutputFormat OutputFormat
 }
 
-// DefaultOptions returns the default encoding options.
+// OutputFormat selects the syntax written by the encoder.
+type OutputFormat int
+
+const (
+	// OutputHjson writes Hjson
+	OutputHjson OutputFormat = iota
+	// OutputJSON writes JSON: strings and keys are quoted, members and
+	// elements are separated by commas and comment tags are left out.
+	// Braces are always placed on the line of their key, and with an empty
+	// IndentBy the output is a single line. The Writer does not support it.
+	OutputJSON
+	// OutputJSONC writes JSON with comments, as read by editors like VS Code
+	// for their settings: like OutputJSON, but comment tags are written as
+	// // comments, and Format keeps the comments of the document. Output on
+	// a single line has no comments.
+	OutputJSONC
+	// OutputJSON5 writes JSON5: like OutputJSONC, but keys that are
+	// identifiers are not quoted, and infinite numbers and NaN are written as
+	// Infinity and NaN instead of null.
+	OutputJSON5
+)
+
+// SortKeysMode tells the encoder how to order the keys of maps. Struct
+// fields, OrderedMap members and iterator pairs always keep their order.
+type SortKeysMode int
+
+const (
+	// SortKeysAlphabetical sorts keys byte-wise
+	SortKeysAlphabetical SortKeysMode = iota
+	// SortKeysNone writes keys in the order Go iterates over the map, which
+	// changes from one call to the next
+	SortKeysNone
+	// SortKeysCustom sorts keys with EncoderOptions.KeyLess, keys that are
+	// neither less nor greater than each other are sorted alphabetically
+	SortKeysCustom
+	// SortKeysAlphabeticalIgnoreCase sorts keys case-insensitively, keys
+	// that only differ in case are sorted byte-wise
+	SortKeysAlphabeticalIgnoreCase
+)
+
+// KeysFirst returns an EncoderOptions.KeyLess function that puts the given
+// keys first, in the given order, followed by all other keys.
+func KeysFirst(keys ...string) func(a, b string) bool {
+	rank := make(map[string]int, len(keys))
+	for i, key := range keys {
+		if _, ok := rank[key]; !ok {
+			rank[key] = i
+		}
+	}
+	return func(a, b string) bool {
+		ra, okA := rank[a]
+		rb, okB := rank[b]
+		return okA && (!okB || ra < rb)
+	}
+}
+
+// Format versions for EncoderOptions.FormatVersion. Encoding the same value
+// with the same options and the same format version gives byte-identical
+// output in all releases of this package, so generated files can be hashed
+// or signed. Changes to the output are only made under a new version.
+const (
+	// FormatVersion1 is the first versioned output format.
+	FormatVersion1 = 1
+	// FormatVersion2 encodes byte slices as base64 strings instead of
+	// arrays of numbers.
+	FormatVersion2 = 2
+	// FormatVersion3 encodes values like encoding/json does: the JSON of
+	// MarshalJSON, including json.RawMessage, is written as Hjson with
+	// sorted keys, json.Number as a number, the text of MarshalText as a
+	// string, and structs with their exported fields only, promoting the
+	// fields of embedded structs. Before, the JSON was written as it is,
+	// MarshalText was not used and all fields were written, embedded
+	// structs as a member.
+	FormatVersion3 = 3
+	// LatestFormatVersion is the newest format version, used when
+	// FormatVersion is 0.
+	LatestFormatVersion = FormatVersion3
+)
+
+// DefaultOptions returns the default encoding options, as set by
+// SetDefaultEncoderOptions. They are used by Marshal.
 func DefaultOptions() EncoderOptions {
+	if opt, ok := defaultEncoderOptions.Load().(EncoderOptions); ok {
+		opt.Extensions = append([]string(nil), opt.Extensions...)
+		return opt
+	}
+	return builtinEncoderOptions()
+}
+
+// builtinEncoderOptions returns the default encoding options of this
+// package.
+func builtinEncoderOptions() EncoderOptions {
 	opt := EncoderOptions{}
 	opt.Eol = "\n"
 	opt.BracesSameLine = false
@@ -42,6 +183,19 @@ func DefaultOptions() EncoderOptions {
 	opt.IndentBy = "  "
 	opt.AllowMinusZero = false
 	opt.UnknownAsNull = false
+	opt.UseStringer = false
+	opt.UseErrorString = false
+	opt.SortKeysIgnoreCase = false
+	opt.SortKeys = SortKeysAlphabetical
+	opt.KeyLess = nil
+	opt.Extensions = nil
+	opt.FormatVersion = 0
+	opt.TimeFormat = ""
+	opt.DurationAsString = false
+	opt.BytesFormat = ""
+	opt.MaxDepth = 0
+	opt.RawMessageVerbatim = false
+	opt.OutputFormat = OutputHjson
 	return opt
 }
 
@@ -49,9 +203,222 @@ type hjsonEncoder struct {
 	bytes.Buffer // output
 	EncoderOptions
 	indent int
+	depth  int      // deepest nesting of arrays and objects so far
+	path   []string // keys and [index] of the value being encoded
+
+	jsonOutput bool // OutputFormat is OutputJSON, OutputJSONC or OutputJSON5
+
+	// pointers, maps and slices being encoded, to detect cycles
+	ptrLevel int
+	ptrSeen  map[cycleKey]bool
+
+	extensions []*Extension
+}
+
+// startDetectingCyclesAfter is the nesting of pointers, maps and slices
+// after which the encoder starts to look for cycles. Like encoding/json it
+// waits, so that values without cycles are not slowed down.
+const startDetectingCyclesAfter = 1000
+
+// cycleKey identifies the memory a pointer, map or slice refers to.
+type cycleKey struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+// enter records that the pointer, map or slice value is being encoded and
+// fails if it already is, which means the value contains itself.
+func (e *hjsonEncoder) enter(value reflect.Value) error {
+	e.ptrLevel++
+	if e.ptrLevel <= startDetectingCyclesAfter {
+		return nil
+	}
+	key := cycleKey{value.Pointer(), value.Type(), 0}
+	if value.Kind() == reflect.Slice {
+		key.len = value.Len()
+	}
+	if e.ptrSeen[key] {
+		e.ptrLevel--
+		// like encoding/json the type is named, the path is over
+		// startDetectingCyclesAfter elements long
+		return fmt.Errorf("Encountered a cycle via %s", value.Type())
+	}
+	if e.ptrSeen == nil {
+		e.ptrSeen = map[cycleKey]bool{}
+	}
+	e.ptrSeen[key] = true
+	return nil
+}
+
+// leave undoes enter after the value was encoded.
+func (e *hjsonEncoder) leave(value reflect.Value) {
+	if e.ptrLevel > startDetectingCyclesAfter {
+		key := cycleKey{value.Pointer(), value.Type(), 0}
+		if value.Kind() == reflect.Slice {
+			key.len = value.Len()
+		}
+		delete(e.ptrSeen, key)
+	}
+	e.ptrLevel--
+}
+
+// nest increases the indentation for the members of an array or object,
+// failing if that exceeds MaxDepth.
+func (e *hjsonEncoder) nest() error {
+	if e.MaxDepth > 0 && e.indent >= e.MaxDepth {
+		path := strings.Join(e.path, "")
+		if path == "" {
+			return fmt.Errorf("Exceeded the maximum nesting depth of %d", e.MaxDepth)
+		}
+		return fmt.Errorf("Exceeded the maximum nesting depth of %d at %s", e.MaxDepth, path)
+	}
+	e.indent++
+	if e.indent > e.depth {
+		e.depth = e.indent
+	}
+	return nil
 }
 
-var needsEscape, needsQuotes, needsEscapeML, startsWithKeyword, needsEscapeName *regexp.Regexp
+// newHjsonEncoder validates the options and returns an encoder using them.
+// All entry points create their encoder here, so that every option always
+// reaches the encoder.
+func newHjsonEncoder(options EncoderOptions) (*hjsonEncoder, error) {
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+	exts, err := lookupExtensions(options.Extensions)
+	if err != nil {
+		return nil, err
+	}
+	options.Eol = options.eol()
+	if options.SortKeysIgnoreCase {
+		options.SortKeys = SortKeysAlphabeticalIgnoreCase
+	}
+	return &hjsonEncoder{
+		EncoderOptions: options,
+		jsonOutput:     options.OutputFormat != OutputHjson,
+		extensions:     exts,
+	}, nil
+}
+
+// eol returns the end of line of the options, an empty Eol is "\n".
+func (options EncoderOptions) eol() string {
+	if options.Eol == "" {
+		return "\n"
+	}
+	return options.Eol
+}
+
+// validate reports options that would produce invalid Hjson.
+func (options EncoderOptions) validate() error {
+	if options.Eol != "" && options.Eol != "\n" && options.Eol != "\r\n" {
+		return fmt.Errorf("Invalid EncoderOptions: Eol must be \"\\n\" or \"\\r\\n\", not %q", options.Eol)
+	}
+	if strings.Trim(options.IndentBy, " \t") != "" {
+		return fmt.Errorf("Invalid EncoderOptions: IndentBy must only contain spaces and tabs, not %q", options.IndentBy)
+	}
+	if options.FormatVersion < 0 || options.FormatVersion > LatestFormatVersion {
+		return fmt.Errorf("Invalid EncoderOptions: unknown FormatVersion %d", options.FormatVersion)
+	}
+	if options.OutputFormat < OutputHjson || options.OutputFormat > OutputJSON5 {
+		return fmt.Errorf("Invalid EncoderOptions: unknown OutputFormat %d", options.OutputFormat)
+	}
+	if options.MaxDepth < 0 {
+		return fmt.Errorf("Invalid EncoderOptions: MaxDepth must not be negative, not %d", options.MaxDepth)
+	}
+	switch options.BytesFormat {
+	case "", "base64", "hex", "array":
+	default:
+		return fmt.Errorf("Invalid EncoderOptions: unknown BytesFormat %q", options.BytesFormat)
+	}
+	if options.SortKeys < SortKeysAlphabetical || options.SortKeys > SortKeysAlphabeticalIgnoreCase {
+		return fmt.Errorf("Invalid EncoderOptions: unknown SortKeys mode %d", options.SortKeys)
+	}
+	if options.SortKeysIgnoreCase && options.SortKeys != SortKeysAlphabetical && options.SortKeys != SortKeysAlphabeticalIgnoreCase {
+		return errors.New("Invalid EncoderOptions: SortKeysIgnoreCase only applies to SortKeysAlphabetical, use SortKeysAlphabeticalIgnoreCase instead")
+	}
+	if options.SortKeys == SortKeysCustom && options.KeyLess == nil {
+		return errors.New("Invalid EncoderOptions: SortKeysCustom requires KeyLess")
+	}
+	return nil
+}
+
+func (e *hjsonEncoder) pushKey(name string) {
+	if len(e.path) > 0 {
+		name = "." + name
+	}
+	e.path = append(e.path, name)
+}
+
+func (e *hjsonEncoder) pushIndex(i int) {
+	e.path = append(e.path, "["+strconv.Itoa(i)+"]")
+}
+
+func (e *hjsonEncoder) popPath() {
+	e.path = e.path[:len(e.path)-1]
+}
+
+func isUnsupported(value reflect.Value) bool {
+	for (value.Kind() == reflect.Interface || value.Kind() == reflect.Ptr) && !value.IsNil() {
+		value = value.Elem()
+	}
+	if value.IsValid() && (value.Type().Implements(hjsonMarshaler) || value.Type().Implements(marshaler) || value.Type().Implements(textMarshaler)) {
+		return false
+	}
+	switch value.Kind() {
+	case reflect.Chan, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+		return true
+	case reflect.Func:
+		return !value.IsNil() && iteratorArgs(value.Type()) == 0
+	}
+	return false
+}
+
+// replaceUnsupported calls OnUnsupportedType for a value that cannot be
+// encoded. It returns the value to encode instead and whether the value
+// should be skipped.
+func (e *hjsonEncoder) replaceUnsupported(value reflect.Value) (reflect.Value, bool, error) {
+	if e.OnUnsupportedType == nil || !isUnsupported(value) {
+		return value, false, nil
+	}
+	for value.Kind() == reflect.Interface || value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	path := strings.Join(e.path, "")
+	replacement, skip, err := e.OnUnsupportedType(path, value)
+	if err != nil || skip {
+		return value, skip, err
+	}
+	rv := reflect.ValueOf(replacement)
+	if isUnsupported(rv) {
+		return rv, false, errors.New("OnUnsupportedType returned unsupported type " + rv.Type().String() + " for " + path)
+	}
+	return rv, false, nil
+}
+
+// DescribeUnknown returns a placeholder for a value that cannot be encoded,
+// for use as EncoderOptions.UnknownStringer in debug dumps: the name of a
+// func, the type, length and capacity of a channel, or the value of a
+// complex number.
+func DescribeUnknown(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Func:
+		if fn := runtime.FuncForPC(v.Pointer()); fn != nil {
+			return "func " + fn.Name()
+		}
+	case reflect.Chan:
+		if v.IsNil() {
+			return v.Type().String() + " (nil)"
+		}
+		return fmt.Sprintf("%s (len %d, cap %d)", v.Type(), v.Len(), v.Cap())
+	case reflect.Complex64, reflect.Complex128:
+		return fmt.Sprint(v.Complex())
+	}
+	return v.Type().String()
+}
+
+var needsEscape, needsQuotes, needsEscapeML, startsWithKeyword, needsEscapeName, json5Identifier *regexp.Regexp
 
 func init() {
 	var commonRange = `\x7f-\x9f\x{00ad}\x{0600}-\x{0604}\x{070f}\x{17b4}\x{17b5}\x{200c}-\x{200f}\x{2028}-\x{202f}\x{2060}-\x{206f}\x{feff}\x{fff0}-\x{ffff}`
@@ -65,6 +432,8 @@ func init() {
 	// starts with a keyword and optionally is followed by a comment
 	startsWithKeyword = regexp.MustCompile(`^(true|false|null)\s*((,|\]|\}|#|//|/\*).*)?$`)
 	needsEscapeName = regexp.MustCompile(`[,\{\[\}\]\s:#"']|//|/\*`)
+	// JSON5 keys that need no quotes
+	json5Identifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
 }
 
 var meta = map[byte][]byte{
@@ -94,7 +463,10 @@ func (e *hjsonEncoder) quote(value string, separator string, isRootObject bool)
 	// Check if we can insert this string without quotes
 	// see hjson syntax (must not parse as true, false, null or number)
 
-	if len(value) == 0 {
+	if e.jsonOutput {
+		e.WriteString(separator)
+		e.WriteString(e.jsonString(value))
+	} else if len(value) == 0 {
 		e.WriteString(separator + `""`)
 	} else if e.QuoteAlways ||
 		needsQuotes.MatchString(value) ||
@@ -147,9 +519,20 @@ func (e *hjsonEncoder) mlString(value string, separator string) {
 	e.WriteString("'''")
 }
 
+// jsonString returns value as a JSON string.
+func (e *hjsonEncoder) jsonString(value string) string {
+	if needsEscape.MatchString(value) {
+		value = e.quoteReplace(value)
+	}
+	return `"` + value + `"`
+}
+
 func (e *hjsonEncoder) quoteName(name string) string {
-	if len(name) == 0 {
-		return `""`
+	if e.OutputFormat == OutputJSON5 && json5Identifier.MatchString(name) {
+		return name
+	}
+	if len(name) == 0 || e.jsonOutput {
+		return e.jsonString(name)
 	}
 
 	// Check if we can insert this name without quotes
@@ -164,7 +547,13 @@ func (e *hjsonEncoder) quoteName(name string) string {
 	return name
 }
 
-type sortAlpha []reflect.Value
+// mapKey is a map key and the member name it encodes as.
+type mapKey struct {
+	name string
+	key  reflect.Value
+}
+
+type sortAlpha []mapKey
 
 func (s sortAlpha) Len() int {
 	return len(s)
@@ -173,17 +562,124 @@ func (s sortAlpha) Swap(i, j int) {
 	s[i], s[j] = s[j], s[i]
 }
 func (s sortAlpha) Less(i, j int) bool {
-	return s[i].String() < s[j].String()
+	return s[i].name < s[j].name
+}
+
+type sortIgnoreCase struct{ sortAlpha }
+
+func (s sortIgnoreCase) Less(i, j int) bool {
+	a, b := strings.ToLower(s.sortAlpha[i].name), strings.ToLower(s.sortAlpha[j].name)
+	if a != b {
+		return a < b
+	}
+	return s.sortAlpha.Less(i, j)
+}
+
+type sortCustom struct {
+	sortAlpha
+	less func(a, b string) bool
+}
+
+func (s sortCustom) Less(i, j int) bool {
+	return s.less(s.sortAlpha[i].name, s.sortAlpha[j].name)
+}
+
+// keyName returns the member name for a map key. Like with encoding/json,
+// keys can be strings, implement encoding.TextMarshaler or be integers.
+func keyName(key reflect.Value) (string, error) {
+	if key.Kind() == reflect.String {
+		return key.String(), nil
+	}
+	if key.Type().Implements(textMarshaler) {
+		if key.Kind() == reflect.Ptr && key.IsNil() {
+			return "", nil
+		}
+		b, err := key.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(b), err
+	}
+	switch key.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(key.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(key.Uint(), 10), nil
+	}
+	return "", errors.New("Unsupported map key type " + key.Type().String())
+}
+
+// isKeyType reports whether keyName accepts keys of type t.
+func isKeyType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return t.Implements(textMarshaler)
+}
+
+// writeItemIndent starts a member or element of an array or object on a new
+// line. For JSON output it adds a comma after the previous one.
+func (e *hjsonEncoder) writeItemIndent() {
+	e.writeComma()
+	e.writeIndent(e.indent)
+}
+
+// writeComma adds a comma after the previous member or element of an array
+// or object, for JSON output.
+func (e *hjsonEncoder) writeComma() {
+	if b := e.Bytes(); e.jsonOutput && len(b) > 0 && b[len(b)-1] != '[' && b[len(b)-1] != '{' {
+		e.WriteString(",")
+	}
+}
+
+// writesComments reports whether comments are written: JSON has none, and
+// JSONC and JSON5 on a single line cannot end their comments.
+func (e *hjsonEncoder) writesComments() bool {
+	return !e.jsonOutput || e.OutputFormat != OutputJSON && e.IndentBy != ""
+}
+
+// commentTag returns what starts a comment line in the output.
+func (e *hjsonEncoder) commentTag() string {
+	if e.jsonOutput {
+		return "//"
+	}
+	return "#"
+}
+
+// keySeparator returns what is written between a key and its value.
+func (e *hjsonEncoder) keySeparator() string {
+	if e.jsonOutput && e.IndentBy == "" {
+		return ""
+	}
+	return " "
+}
+
+// braceOnNewLine reports whether the opening brace or bracket of a value is
+// placed on a line of its own.
+func (e *hjsonEncoder) braceOnNewLine(noIndent bool) bool {
+	return !noIndent && !e.BracesSameLine && !e.jsonOutput
 }
 
 func (e *hjsonEncoder) writeIndent(indent int) {
+	if e.jsonOutput && e.IndentBy == "" {
+		// compact JSON
+		return
+	}
 	e.WriteString(e.Eol)
 	for i := 0; i < indent; i++ {
 		e.WriteString(e.IndentBy)
 	}
 }
 
-func (e *hjsonEncoder) useMarshaler(value reflect.Value, separator string) error {
+// legacyTypes reports whether values of types with their own encoding are
+// written like before FormatVersion3.
+func (e *hjsonEncoder) legacyTypes() bool {
+	return e.FormatVersion == FormatVersion1 || e.FormatVersion == FormatVersion2
+}
+
+// writeMarshalerJSON writes the output of MarshalJSON as it is, like
+// FormatVersion1 and FormatVersion2 do.
+func (e *hjsonEncoder) writeMarshalerJSON(value reflect.Value, separator string) error {
 	b, err := value.Interface().(json.Marshaler).MarshalJSON()
 	if err != nil {
 		return err
@@ -193,26 +689,166 @@ func (e *hjsonEncoder) useMarshaler(value reflect.Value, separator string) error
 	return nil
 }
 
+// useMarshaler encodes the output of MarshalJSON as Hjson.
+func (e *hjsonEncoder) useMarshaler(value reflect.Value, noIndent bool, separator string, isRootObject bool) error {
+	b, err := value.Interface().(json.Marshaler).MarshalJSON()
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v interface{}
+	if err = dec.Decode(&v); err == nil && dec.More() {
+		err = errors.New("trailing data")
+	}
+	if err != nil {
+		return errors.New("Invalid output of MarshalJSON for type " + value.Type().String() + ": " + err.Error())
+	}
+	return e.str(reflect.ValueOf(v), noIndent, separator, isRootObject)
+}
+
 var marshaler = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+var numberType = reflect.TypeOf(json.Number(""))
+var textMarshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+var stringer = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// implementation returns value, or its address, if it implements iface.
+func implementation(value reflect.Value, iface reflect.Type) (reflect.Value, bool) {
+	if value.CanAddr() && !value.Type().Implements(iface) {
+		value = value.Addr()
+	}
+	return value, value.CanInterface() && value.Type().Implements(iface)
+}
+
+// asStringer returns value, or its address, as a fmt.Stringer if it should be
+// encoded with its String method.
+func asStringer(value reflect.Value) (fmt.Stringer, bool) {
+	value, ok := implementation(value, stringer)
+	if !ok || value.Type().Implements(textMarshaler) {
+		return nil, false
+	}
+	return value.Interface().(fmt.Stringer), true
+}
 
 func (e *hjsonEncoder) str(value reflect.Value, noIndent bool, separator string, isRootObject bool) error {
 
 	// Produce a string from value.
 
+	if !value.IsValid() {
+		e.WriteString(separator)
+		e.WriteString("null")
+		return nil
+	}
+
 	kind := value.Kind()
 
-	if kind == reflect.Interface || kind == reflect.Ptr {
+	for kind == reflect.Interface || kind == reflect.Ptr {
 		if value.IsNil() {
 			e.WriteString(separator)
 			e.WriteString("null")
 			return nil
 		}
+		if kind == reflect.Ptr {
+			if err := e.enter(value); err != nil {
+				return err
+			}
+			defer e.leave(value)
+		}
 		value = value.Elem()
 		kind = value.Kind()
 	}
 
-	if value.Type().Implements(marshaler) {
-		return e.useMarshaler(value, separator)
+	if e.extensions != nil && e.formatExtensions(value, separator) {
+		return nil
+	}
+
+	if e.UseErrorString {
+		if v, ok := implementation(value, errorType); ok {
+			e.quote(v.Interface().(error).Error(), separator, isRootObject)
+			return nil
+		}
+	}
+
+	if value.Type() == timeType && e.TimeFormat != "" {
+		e.writeTime(value.Interface().(time.Time), e.TimeFormat, separator, isRootObject)
+		return nil
+	}
+
+	if value.Type() == durationType && e.DurationAsString {
+		e.quote(time.Duration(value.Int()).String(), separator, isRootObject)
+		return nil
+	}
+
+	if value.Type() == orderedMapType {
+		return e.writeOrderedMap(value.Interface().(OrderedMap), noIndent, separator)
+	}
+
+	if value.Type() == rawMessageType && e.RawMessageVerbatim {
+		return e.writeRawMessage(value.Interface().(json.RawMessage), separator)
+	}
+
+	if m, ok := implementation(value, hjsonMarshaler); ok {
+		return e.useHjsonMarshaler(m, noIndent, separator)
+	}
+
+	if e.legacyTypes() {
+		if value.Type().Implements(marshaler) && value.CanInterface() {
+			return e.writeMarshalerJSON(value, separator)
+		}
+	} else if m, ok := implementation(value, marshaler); ok {
+		return e.useMarshaler(m, noIndent, separator, isRootObject)
+	}
+
+	if m, ok := implementation(value, textMarshaler); ok && !e.legacyTypes() {
+		b, err := m.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return err
+		}
+		e.quote(string(b), separator, isRootObject)
+		return nil
+	}
+
+	if value.Type() == numberType && !e.legacyTypes() {
+		// json.Number holds the text of a number
+		e.WriteString(separator)
+		e.WriteString(value.String())
+		return nil
+	}
+
+	if e.UseStringer {
+		if s, ok := asStringer(value); ok {
+			e.quote(s.String(), separator, isRootObject)
+			return nil
+		}
+	}
+
+	if isSQLNull(value.Type()) && value.CanInterface() {
+		v, err := sqlNullValue(value)
+		if err != nil {
+			return err
+		}
+		return e.str(reflect.ValueOf(v), noIndent, separator, isRootObject)
+	}
+
+	if isOptional(value.Type()) {
+		if !value.Field(1).Bool() {
+			e.WriteString(separator)
+			e.WriteString("null")
+			return nil
+		}
+		return e.str(value.Field(0), noIndent, separator, isRootObject)
+	}
+
+	if kind == reflect.Func {
+		if n := iteratorArgs(value.Type()); n > 0 {
+			if value.IsNil() {
+				e.WriteString(separator)
+				e.WriteString("null")
+				return nil
+			}
+			return e.iterator(value, n, noIndent, separator)
+		}
 	}
 
 	switch kind {
@@ -232,7 +868,13 @@ func (e *hjsonEncoder) str(value reflect.Value, noIndent bool, separator string,
 		// JSON numbers must be finite. Encode non-finite numbers as null.
 		e.WriteString(separator)
 		number := value.Float()
-		if math.IsInf(number, 0) || math.IsNaN(number) {
+		if e.OutputFormat == OutputJSON5 && math.IsInf(number, 1) {
+			e.WriteString("Infinity")
+		} else if e.OutputFormat == OutputJSON5 && math.IsInf(number, -1) {
+			e.WriteString("-Infinity")
+		} else if e.OutputFormat == OutputJSON5 && math.IsNaN(number) {
+			e.WriteString("NaN")
+		} else if math.IsInf(number, 0) || math.IsNaN(number) {
 			e.WriteString("null")
 		} else if !e.AllowMinusZero && number == -0 {
 			e.WriteString("0")
@@ -256,17 +898,29 @@ func (e *hjsonEncoder) str(value reflect.Value, noIndent bool, separator string,
 
 	case reflect.Slice, reflect.Array:
 
+		if kind == reflect.Slice && isByteSlice(value.Type()) && e.writeBytes(value, e.bytesFormat(), separator, isRootObject) {
+			break
+		}
+
 		len := value.Len()
 		if len == 0 {
 			e.WriteString(separator)
 			e.WriteString("[]")
 			break
 		}
+		if kind == reflect.Slice {
+			if err := e.enter(value); err != nil {
+				return err
+			}
+			defer e.leave(value)
+		}
 
 		indent1 := e.indent
-		e.indent++
+		if err := e.nest(); err != nil {
+			return err
+		}
 
-		if !noIndent && !e.BracesSameLine {
+		if e.braceOnNewLine(noIndent) {
 			e.writeIndent(indent1)
 		} else {
 			e.WriteString(separator)
@@ -275,10 +929,18 @@ func (e *hjsonEncoder) str(value reflect.Value, noIndent bool, separator string,
 
 		// Join all of the element texts together, separated with newlines
 		for i := 0; i < len; i++ {
-			e.writeIndent(e.indent)
-			if err := e.str(value.Index(i), true, "", false); err != nil {
+			e.pushIndex(i)
+			elem, skip, err := e.replaceUnsupported(value.Index(i))
+			if err != nil {
 				return err
 			}
+			if !skip {
+				e.writeItemIndent()
+				if err := e.str(elem, true, "", false); err != nil {
+					return err
+				}
+			}
+			e.popPath()
 		}
 
 		e.writeIndent(indent1)
@@ -294,27 +956,57 @@ func (e *hjsonEncoder) str(value reflect.Value, noIndent bool, separator string,
 			e.WriteString("{}")
 			break
 		}
+		if err := e.enter(value); err != nil {
+			return err
+		}
+		defer e.leave(value)
 
 		indent1 := e.indent
-		e.indent++
-		if !noIndent && !e.BracesSameLine {
+		if err := e.nest(); err != nil {
+			return err
+		}
+		if e.braceOnNewLine(noIndent) {
 			e.writeIndent(indent1)
 		} else {
 			e.WriteString(separator)
 		}
 		e.WriteString("{")
 
-		keys := value.MapKeys()
-		sort.Sort(sortAlpha(keys))
+		keys := make([]mapKey, len)
+		for i, key := range value.MapKeys() {
+			name, err := keyName(key)
+			if err != nil {
+				return err
+			}
+			keys[i] = mapKey{name, key}
+		}
+		switch {
+		case e.SortKeys == SortKeysNone:
+		case e.SortKeys == SortKeysAlphabeticalIgnoreCase:
+			sort.Sort(sortIgnoreCase{keys})
+		default:
+			sort.Sort(sortAlpha(keys))
+			if e.SortKeys == SortKeysCustom {
+				sort.Stable(sortCustom{keys, e.KeyLess})
+			}
+		}
 
 		// Join all of the member texts together, separated with newlines
 		for i := 0; i < len; i++ {
-			e.writeIndent(e.indent)
-			e.WriteString(e.quoteName(keys[i].String()))
-			e.WriteString(":")
-			if err := e.str(value.MapIndex(keys[i]), false, " ", false); err != nil {
+			e.pushKey(keys[i].name)
+			elem, skip, err := e.replaceUnsupported(value.MapIndex(keys[i].key))
+			if err != nil {
 				return err
 			}
+			if !skip {
+				e.writeItemIndent()
+				e.WriteString(e.quoteName(keys[i].name))
+				e.WriteString(":")
+				if err := e.str(elem, false, e.keySeparator(), false); err != nil {
+					return err
+				}
+			}
+			e.popPath()
 		}
 
 		e.writeIndent(indent1)
@@ -323,16 +1015,21 @@ func (e *hjsonEncoder) str(value reflect.Value, noIndent bool, separator string,
 
 	case reflect.Struct:
 
-		l := value.NumField()
-		if l == 0 {
+		fields := cachedStructFields(value.Type()).list
+		if e.legacyTypes() {
+			fields = cachedLegacyStructFields(value.Type()).list
+		}
+		if len(fields) == 0 {
 			e.WriteString(separator)
 			e.WriteString("{}")
 			break
 		}
 
 		indent1 := e.indent
-		e.indent++
-		if !noIndent && !e.BracesSameLine {
+		if err := e.nest(); err != nil {
+			return err
+		}
+		if e.braceOnNewLine(noIndent) {
 			e.writeIndent(indent1)
 		} else {
 			e.WriteString(separator)
@@ -340,46 +1037,58 @@ func (e *hjsonEncoder) str(value reflect.Value, noIndent bool, separator string,
 		e.WriteString("{")
 
 		// Join all of the member texts together, separated with newlines
-		for i := 0; i < l; i++ {
-			curStructField := value.Type().Field(i)
-			curField := value.Field(i)
-
-			name := curStructField.Name
-			jsonTag := curStructField.Tag.Get("json")
-			jsonComment := curStructField.Tag.Get("comment")
-			omitEmpty := false
-			if jsonTag == "-" {
+		for i, f := range fields {
+			curField := fieldByIndex(value, f.index, false)
+			if !curField.IsValid() {
+				// in a nil embedded struct pointer
 				continue
 			}
-			splits := strings.Split(jsonTag, ",")
-			if splits[0] != "" {
-				name = splits[0]
+			if f.omitEmpty && isEmptyValue(curField) {
+				continue
 			}
-			if len(splits) > 1 {
-				for _, opt := range splits[1:] {
-					if opt == "omitempty" {
-						omitEmpty = true
-					}
-				}
+			e.pushKey(f.name)
+			curField, skip, err := e.replaceUnsupported(curField)
+			if err != nil {
+				return err
 			}
-			if omitEmpty && isEmptyValue(curField) {
+			if skip {
+				e.popPath()
 				continue
 			}
-			if len(jsonComment) > 0 {
-				for _, line := range strings.Split(jsonComment, e.Eol) {
+			if len(f.comment) > 0 && e.writesComments() {
+				// the comma after the previous member goes before the
+				// comment
+				e.writeComma()
+				// the comment may use either end of line
+				comment := strings.Replace(f.comment, "\r\n", "\n", -1)
+				for _, line := range strings.Split(comment, "\n") {
 					e.writeIndent(e.indent)
-					e.WriteString(fmt.Sprintf("# %s", line))
+					if line == "" {
+						e.WriteString(e.commentTag())
+					} else {
+						e.WriteString(e.commentTag() + " " + line)
+					}
 				}
+				e.writeIndent(e.indent)
+			} else {
+				e.writeItemIndent()
 			}
-			e.writeIndent(e.indent)
-			e.WriteString(e.quoteName(name))
+			e.WriteString(e.quoteName(f.name))
 			e.WriteString(":")
-			if err := e.str(curField, false, " ", false); err != nil {
+			if f.quoted {
+				err = e.quotedValue(curField, e.keySeparator())
+			} else if f.format != "" {
+				err = e.formattedValue(curField, f.format, e.keySeparator())
+			} else {
+				err = e.str(curField, false, e.keySeparator(), false)
+			}
+			if err != nil {
 				return err
 			}
-			if len(jsonComment) > 0 && i < l-1 {
+			if len(f.comment) > 0 && i < len(fields)-1 && !e.jsonOutput {
 				e.WriteString(e.Eol)
 			}
+			e.popPath()
 		}
 
 		e.writeIndent(indent1)
@@ -388,8 +1097,23 @@ func (e *hjsonEncoder) str(value reflect.Value, noIndent bool, separator string,
 		e.indent = indent1
 
 	default:
-		if e.UnknownAsNull {
+		if e.OnUnsupportedType != nil && isUnsupported(value) {
+			// only the root value gets here, members and elements are
+			// replaced before their key is written
+			replacement, skip, err := e.replaceUnsupported(value)
+			if err != nil {
+				return err
+			}
+			if skip {
+				replacement = reflect.Value{}
+			}
+			return e.str(replacement, noIndent, separator, isRootObject)
+		}
+		if e.UnknownAsNull && e.UnknownStringer != nil {
+			e.quote(e.UnknownStringer(value), separator, isRootObject)
+		} else if e.UnknownAsNull {
 			// Use null as a placeholder for non-JSON values.
+			e.WriteString(separator)
 			e.WriteString("null")
 		} else {
 			return errors.New("Unsupported type " + value.Type().String())
@@ -398,7 +1122,122 @@ func (e *hjsonEncoder) str(value reflect.Value, noIndent bool, separator string,
 	return nil
 }
 
+// quotedValue writes a number or bool as a string, for fields with the
+// string tag option.
+func (e *hjsonEncoder) quotedValue(value reflect.Value, separator string) error {
+	start := e.Len()
+	if err := e.str(value, true, "", false); err != nil {
+		return err
+	}
+	text := string(e.Bytes()[start:])
+	e.Truncate(start)
+	if text == "null" || text[0] == '"' {
+		// a nil pointer or a value encoded as a string by a marshaler
+		e.WriteString(separator + text)
+	} else {
+		e.quote(text, separator, false)
+	}
+	return nil
+}
+
+// iteratorArgs returns 1 if t has the shape of an iter.Seq, 2 if it has the
+// shape of an iter.Seq2 with keys like those of maps and 0 otherwise.
+func iteratorArgs(t reflect.Type) int {
+	if t.NumIn() != 1 || t.NumOut() != 0 || t.IsVariadic() {
+		return 0
+	}
+	yield := t.In(0)
+	if yield.Kind() != reflect.Func || yield.IsVariadic() ||
+		yield.NumOut() != 1 || yield.Out(0).Kind() != reflect.Bool {
+		return 0
+	}
+	switch {
+	case yield.NumIn() == 1:
+		return 1
+	case yield.NumIn() == 2 && isKeyType(yield.In(0)):
+		return 2
+	}
+	return 0
+}
+
+// iterator encodes an iter.Seq as an array and an iter.Seq2 as an object,
+// writing the items in the order they are yielded.
+func (e *hjsonEncoder) iterator(value reflect.Value, nargs int, noIndent bool, separator string) error {
+	begin, end := "[", "]"
+	if nargs == 2 {
+		begin, end = "{", "}"
+	}
+
+	indent1 := e.indent
+	count := 0   // items written
+	yielded := 0 // items yielded, for the path
+	var err error
+	yieldType := value.Type().In(0)
+	yield := reflect.MakeFunc(yieldType, func(args []reflect.Value) []reflect.Value {
+		if err == nil {
+			item := args[0]
+			var name string
+			if nargs == 1 {
+				e.pushIndex(yielded)
+			} else {
+				name, err = keyName(args[0])
+				e.pushKey(name)
+				item = args[1]
+			}
+			yielded++
+			var skip bool
+			if err == nil {
+				item, skip, err = e.replaceUnsupported(item)
+			}
+			if err == nil && !skip && count == 0 {
+				if err = e.nest(); err == nil {
+					if e.braceOnNewLine(noIndent) {
+						e.writeIndent(indent1)
+					} else {
+						e.WriteString(separator)
+					}
+					e.WriteString(begin)
+				}
+			}
+			if err == nil && !skip {
+				count++
+				e.writeItemIndent()
+				if nargs == 1 {
+					err = e.str(item, true, "", false)
+				} else {
+					e.WriteString(e.quoteName(name))
+					e.WriteString(":")
+					err = e.str(item, false, e.keySeparator(), false)
+				}
+			}
+			e.popPath()
+		}
+		return []reflect.Value{reflect.ValueOf(err == nil).Convert(yieldType.Out(0))}
+	})
+	value.Call([]reflect.Value{yield})
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		e.WriteString(separator)
+		e.WriteString(begin + end)
+		return nil
+	}
+	e.writeIndent(indent1)
+	e.WriteString(end)
+	e.indent = indent1
+	return nil
+}
+
 func isEmptyValue(v reflect.Value) bool {
+	if isSQLNull(v.Type()) && v.CanInterface() {
+		val, err := sqlNullValue(v)
+		return err == nil && val == nil
+	}
+	if isOptional(v.Type()) {
+		return !v.Field(1).Bool()
+	}
 	switch v.Kind() {
 	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
 		return v.Len() == 0
@@ -440,7 +1279,51 @@ func Marshal(v interface{}) ([]byte, error) {
 // Array and slice values encode as JSON arrays.
 //
 // Map values encode as JSON objects. The map's key type must be a
-// string. The map keys are sorted and used as JSON object keys.
+// string, an integer or implement encoding.TextMarshaler. The map keys
+// are sorted and used as JSON object keys. OrderedMap values encode as
+// JSON objects with the members in their order.
+//
+// Struct values encode as JSON objects with one member per exported
+// field, in the order of the fields. Like with encoding/json, the json
+// tag of a field can give the member name, omit the field ("-") or omit
+// it when it is empty ("omitempty"), and the fields of embedded structs
+// are promoted to the outer object. An hjson tag takes the place of the
+// json tag, for names that differ between Hjson and JSON; `hjson:"-"`
+// omits a field and `hjson:",inline"` also promotes the fields of a
+// struct field that is not embedded. Like with encoding/json, the string
+// tag option encodes a number or bool field as a string. The comment tag of a field is
+// written as a comment before its member. With FormatVersion1 and
+// FormatVersion2 all fields are written, including unexported ones,
+// embedded structs as a member named after their type, and only the name
+// and omitempty of the json tag are used.
+//
+// time.Time values encode as RFC 3339 strings, or in the format of
+// options.TimeFormat. The format tag option sets it for one field, for
+// example `json:"start,format=DateOnly"` or `json:"start,format=unixms"`;
+// format=string encodes a time.Duration field like "1h30m0s", which
+// options.DurationAsString does for all of them.
+//
+// Byte slices encode as base64 strings, like with encoding/json, or in the
+// format of options.BytesFormat or of the field's format tag option:
+// "base64", "hex" or "array".
+//
+// Values implementing Marshaler are written as the Hjson returned by
+// MarshalHJSON, indented to their place, or as its JSON form with
+// options.OutputFormat set to OutputJSON.
+//
+// Values implementing encoding.TextMarshaler, and not json.Marshaler,
+// encode as Hjson strings, except with FormatVersion1 and FormatVersion2.
+//
+// Values implementing json.Marshaler, with a value or a pointer receiver,
+// encode as the Hjson form of the JSON returned by MarshalJSON. Objects in
+// that JSON get sorted keys. This includes json.RawMessage, unless
+// options.RawMessageVerbatim is set to write its JSON as it is. With
+// FormatVersion1 and FormatVersion2 the JSON is written as it is, and only
+// value receivers are used.
+//
+// The Null types of database/sql (sql.NullString, sql.NullInt64, ...)
+// encode as their value, or as null if they are not valid. omitempty omits
+// them when they are not valid. The same goes for Optional.
 //
 // Pointer values encode as the value pointed to.
 // A nil pointer encodes as the null JSON value.
@@ -448,21 +1331,95 @@ func Marshal(v interface{}) ([]byte, error) {
 // Interface values encode as the value contained in the interface.
 // A nil interface value encodes as the null JSON value.
 //
-// JSON cannot represent cyclic data structures and Marshal does not
-// handle them. Passing cyclic structures to Marshal will result in
-// an infinite recursion.
+// Iterator functions are consumed as they are encoded: an iter.Seq
+// encodes as a JSON array and an iter.Seq2 with keys like those of maps
+// encodes as a JSON object whose members appear in the order they were
+// yielded.
+//
+// JSON cannot represent cyclic data structures. Like encoding/json,
+// Marshal fails with an error naming the type of the value through which
+// a cycle was found.
 //
 func MarshalWithOptions(v interface{}, options EncoderOptions) ([]byte, error) {
-	e := &hjsonEncoder{}
-	e.indent = 0
-	e.Eol = options.Eol
-	e.BracesSameLine = options.BracesSameLine
-	e.QuoteAlways = options.QuoteAlways
-	e.IndentBy = options.IndentBy
-
-	err := e.str(reflect.ValueOf(v), true, "", true)
+	hook := loadMetricsHook()
+	if hook == nil {
+		buf, _, err := marshal(v, options)
+		return buf, err
+	}
+	start := time.Now()
+	buf, depth, err := marshal(v, options)
+	hook(Metrics{"encode", time.Since(start), len(buf), depth, err})
+	return buf, err
+}
+
+// marshal implements MarshalWithOptions and also returns the nesting depth
+// of the output.
+func marshal(v interface{}, options EncoderOptions) ([]byte, int, error) {
+	return marshalAppend(nil, v, options)
+}
+
+// MarshalAppend appends the Hjson encoding of v, using default options, to
+// dst and returns the extended buffer.
+//
+// See MarshalAppendWithOptions.
+//
+func MarshalAppend(dst []byte, v interface{}) ([]byte, error) {
+	return MarshalAppendWithOptions(dst, v, DefaultOptions())
+}
+
+// MarshalAppendWithOptions appends the Hjson encoding of v to dst and
+// returns the extended buffer. Passing the buffer of the previous call,
+// truncated to buf[:0], reuses its memory instead of allocating a new one
+// for each document. On error dst is returned unchanged.
+//
+// See MarshalWithOptions for details about the conversion of Go values to
+// Hjson.
+//
+func MarshalAppendWithOptions(dst []byte, v interface{}, options EncoderOptions) ([]byte, error) {
+	hook := loadMetricsHook()
+	if hook == nil {
+		buf, _, err := marshalAppend(dst, v, options)
+		return buf, err
+	}
+	start := time.Now()
+	buf, depth, err := marshalAppend(dst, v, options)
+	hook(Metrics{"encode", time.Since(start), len(buf) - len(dst), depth, err})
+	return buf, err
+}
+
+// marshalAppend implements MarshalAppendWithOptions and also returns the
+// nesting depth of the output.
+func marshalAppend(dst []byte, v interface{}, options EncoderOptions) ([]byte, int, error) {
+	e, err := newHjsonEncoder(options)
 	if err != nil {
-		return nil, err
+		return dst, 0, err
+	}
+	e.Buffer = *bytes.NewBuffer(dst)
+
+	if err = e.str(reflect.ValueOf(v), true, "", true); err != nil {
+		return dst, e.depth, err
+	}
+	return e.Bytes(), e.depth, nil
+}
+
+// WriteValue writes the Hjson encoding of v to w as a fragment that can be
+// embedded into other output, for example by templating engines.
+//
+// indent is the nesting level of the fragment: lines after the first are
+// indented by indent+1 (or more) times options.IndentBy, as if the value
+// was a member of an object at that level. The first line is not indented
+// and nothing follows the value. Like after a key, multiline strings start
+// with a line break.
+//
+func WriteValue(w io.Writer, v interface{}, indent int, options EncoderOptions) error {
+	e, err := newHjsonEncoder(options)
+	if err != nil {
+		return err
+	}
+	e.indent = indent
+	if err = e.str(reflect.ValueOf(v), true, "", false); err != nil {
+		return err
 	}
-	return e.Bytes(), nil
+	_, err = w.Write(e.Bytes())
+	return err
 }
@@ -1,8 +1,17 @@
 package hjson
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 type TestStruct struct {
@@ -132,20 +141,941 @@ func (s TestMarshalStruct) MarshalJSON() ([]byte, error) {
 	return []byte(`"foobar"`), nil
 }
 
+type testPtrMarshaler struct {
+	N int
+}
+
+func (m *testPtrMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf(`{"n": %d, "big": 12345678901234567890, "list": ["a b", "", 1.5]}`, m.N)), nil
+}
+
+type testBadMarshaler struct{}
+
+func (testBadMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte(`{"a": `), nil
+}
+
 func TestEncodeMarshal(t *testing.T) {
 	input := TestMarshalStruct{}
 	buf, err := Marshal(input)
 	if err != nil {
 		t.Error(err)
 	}
-	if !reflect.DeepEqual(buf, []byte(`"foobar"`)) {
+	if !reflect.DeepEqual(buf, []byte(`foobar`)) {
 		t.Error("Marshaler interface error")
 	}
 	buf, err = Marshal(&input)
 	if err != nil {
 		t.Error(err)
 	}
-	if !reflect.DeepEqual(buf, []byte(`"foobar"`)) {
+	if !reflect.DeepEqual(buf, []byte(`foobar`)) {
 		t.Error("Marshaler interface error")
 	}
+	opt := DefaultOptions()
+	opt.FormatVersion = FormatVersion1
+	buf, err = MarshalWithOptions(input, opt)
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(buf, []byte(`"foobar"`)) {
+		t.Error("Marshaler interface error with FormatVersion1")
+	}
+
+	value := struct {
+		M testPtrMarshaler
+		L []testPtrMarshaler
+	}{testPtrMarshaler{1}, []testPtrMarshaler{{2}}}
+	buf, err = Marshal(&value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{
+  M:
+  {
+    big: 12345678901234567890
+    list:
+    [
+      a b
+      ""
+      1.5
+    ]
+    n: 1
+  }
+  L:
+  [
+    {
+      big: 12345678901234567890
+      list:
+      [
+        a b
+        ""
+        1.5
+      ]
+      n: 2
+    }
+  ]
+}`
+	if string(buf) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf)
+	}
+
+	if _, err = Marshal(testBadMarshaler{}); err == nil {
+		t.Error("expected an error for invalid MarshalJSON output")
+	}
+}
+
+func TestEncodeIterator(t *testing.T) {
+	seq := func(yield func(int) bool) {
+		for i := 1; i <= 3; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+	seq2 := func(yield func(string, interface{}) bool) {
+		_ = yield("zebra", 1) && yield("apple", seq)
+	}
+	empty := func(yield func(string) bool) {}
+
+	buf, err := Marshal(map[string]interface{}{"a": seq2, "b": empty})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\n  a:\n  {\n    zebra: 1\n    apple:\n    [\n      1\n      2\n      3\n    ]\n  }\n  b: []\n}"
+	if string(buf) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf)
+	}
+
+	ports := func(yield func(int, string) bool) {
+		_ = yield(443, "https") && yield(80, "http")
+	}
+	if buf, err = Marshal(ports); err != nil || string(buf) != "{\n  443: https\n  80: http\n}" {
+		t.Errorf("unexpected %q, %v", buf, err)
+	}
+	var decoded map[int]string
+	if err = Unmarshal(buf, &decoded); err != nil || decoded[80] != "http" {
+		t.Errorf("unexpected %v, %v", decoded, err)
+	}
+
+	var stopped bool
+	failing := func(yield func(interface{}) bool) {
+		stopped = !yield(make(chan int)) && !yield(1)
+	}
+	if _, err = Marshal(failing); err == nil {
+		t.Error("expected an error for an unsupported element")
+	}
+	if !stopped {
+		t.Error("expected yield to return false after an error")
+	}
+
+	// a nil iterator is null, other funcs are unsupported even if nil
+	var nilSeq func(yield func(int) bool)
+	if buf, err = Marshal(map[string]interface{}{"a": nilSeq}); err != nil || string(buf) != "{\n  a: null\n}" {
+		t.Errorf("unexpected %q, %v", buf, err)
+	}
+	var handler func()
+	if _, err = Marshal(map[string]interface{}{"a": handler}); err == nil || !strings.Contains(err.Error(), "Unsupported type") {
+		t.Errorf("expected an error for a nil func, got %v", err)
+	}
+}
+
+func TestWriteValue(t *testing.T) {
+	var buf bytes.Buffer
+	value := map[string]interface{}{"a": []int{1, 2}, "b": "multi\nline"}
+	if err := WriteValue(&buf, value, 1, DefaultOptions()); err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\n    a:\n    [\n      1\n      2\n    ]\n    b:\n      '''\n      multi\n      line\n      '''\n  }"
+	if buf.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf.String())
+	}
+
+	buf.Reset()
+	if err := WriteValue(&buf, "multi\nline", 0, DefaultOptions()); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "\n  '''\n  multi\n  line\n  '''" {
+		t.Errorf("unexpected multiline fragment %q", buf.String())
+	}
+}
+
+func TestOnUnsupportedType(t *testing.T) {
+	type server struct {
+		Name    string
+		Handler func()
+		Done    chan bool
+	}
+	value := map[string]interface{}{
+		"servers": []interface{}{server{Name: "a", Handler: func() {}, Done: make(chan bool)}},
+		"n":       complex(1, 2),
+	}
+	var paths []string
+	opt := DefaultOptions()
+	opt.OnUnsupportedType = func(path string, v reflect.Value) (interface{}, bool, error) {
+		paths = append(paths, path)
+		switch v.Kind() {
+		case reflect.Func:
+			return "<func>", false, nil
+		case reflect.Complex128:
+			return fmt.Sprint(v.Complex()), false, nil
+		}
+		return nil, true, nil
+	}
+	buf, err := MarshalWithOptions(value, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\n  n: (1+2i)\n  servers:\n  [\n    {\n      Name: a\n      Handler: <func>\n    }\n  ]\n}"
+	if string(buf) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf)
+	}
+	if !reflect.DeepEqual(paths, []string{"n", "servers[0].Handler", "servers[0].Done"}) {
+		t.Errorf("unexpected paths %v", paths)
+	}
+
+	opt.OnUnsupportedType = func(path string, v reflect.Value) (interface{}, bool, error) {
+		return nil, false, errors.New("no " + path)
+	}
+	if _, err = MarshalWithOptions(value, opt); err == nil || err.Error() != "no n" {
+		t.Errorf("expected the callback error, got %v", err)
+	}
+
+	opt.OnUnsupportedType = func(path string, v reflect.Value) (interface{}, bool, error) {
+		return v.Interface(), false, nil
+	}
+	if _, err = MarshalWithOptions(make(chan int), opt); err == nil {
+		t.Error("expected an error for an unsupported replacement")
+	}
+}
+
+func TestEncoderOptionsRoundTrip(t *testing.T) {
+	// Set every option to a non-default value. New kinds of options must be
+	// added here, so an option cannot be forgotten by the encoder.
+	options := DefaultOptions()
+	want := reflect.ValueOf(&options).Elem()
+	for i := 0; i < want.NumField(); i++ {
+		f := want.Field(i)
+		switch f.Kind() {
+		case reflect.Bool:
+			f.SetBool(!f.Bool())
+		case reflect.String:
+			switch want.Type().Field(i).Name {
+			case "Eol":
+				f.SetString("\r\n")
+			case "BytesFormat":
+				f.SetString("hex")
+			default:
+				f.SetString("\t")
+			}
+		case reflect.Int:
+			switch want.Type().Field(i).Name {
+			case "OutputFormat":
+				f.SetInt(int64(OutputJSON))
+			case "SortKeys":
+				// the only mode SortKeysIgnoreCase may be combined with
+				f.SetInt(int64(SortKeysAlphabeticalIgnoreCase))
+			default:
+				f.SetInt(LatestFormatVersion)
+			}
+		case reflect.Slice:
+			f.Set(reflect.ValueOf([]string{"test-option"}))
+		case reflect.Func:
+			f.Set(reflect.MakeFunc(f.Type(), func(args []reflect.Value) []reflect.Value {
+				return []reflect.Value{reflect.Zero(f.Type().Out(0)), reflect.ValueOf(true), reflect.Zero(f.Type().Out(2))}
+			}))
+		default:
+			t.Fatalf("unhandled option %s of kind %s", want.Type().Field(i).Name, f.Kind())
+		}
+	}
+
+	e, err := newHjsonEncoder(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := reflect.ValueOf(e.EncoderOptions)
+	for i := 0; i < want.NumField(); i++ {
+		w, g := want.Field(i), got.Field(i)
+		same := false
+		if w.Kind() == reflect.Func {
+			same = w.Pointer() == g.Pointer()
+		} else {
+			same = reflect.DeepEqual(w.Interface(), g.Interface())
+		}
+		if !same {
+			t.Errorf("option %s was not passed to the encoder", want.Type().Field(i).Name)
+		}
+	}
+
+	opt := DefaultOptions()
+	opt.AllowMinusZero = true
+	if buf, _ := MarshalWithOptions(math.Copysign(0, -1), opt); string(buf) != "-0" {
+		t.Errorf("AllowMinusZero: expected -0, got %s", buf)
+	}
+	opt = DefaultOptions()
+	opt.UnknownAsNull = true
+	if buf, err := MarshalWithOptions([]interface{}{1, make(chan int)}, opt); err != nil || string(buf) != "[\n  1\n  null\n]" {
+		t.Errorf("UnknownAsNull: got %q, %v", buf, err)
+	}
+
+	// the zero EncoderOptions end lines with \n
+	zero, err := MarshalWithOptions(map[string]interface{}{"a": []int{1, 2}}, EncoderOptions{})
+	if lf, _ := MarshalWithOptions(map[string]interface{}{"a": []int{1, 2}}, EncoderOptions{Eol: "\n"}); err != nil || string(zero) != string(lf) || !strings.Contains(string(zero), "\n") {
+		t.Errorf("empty Eol: got %q, %v", zero, err)
+	}
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf, EncoderOptions{}).Encode(1); err != nil || buf.String() != "1\n" {
+		t.Errorf("empty Eol: Encoder wrote %q, %v", buf.String(), err)
+	}
+
+	for _, opt := range []EncoderOptions{{Eol: "\n", IndentBy: "--"}, {Eol: "\r", IndentBy: " "}, {Eol: "\n", FormatVersion: LatestFormatVersion + 1}} {
+		if _, err := MarshalWithOptions(1, opt); err == nil {
+			t.Errorf("expected an error for options %+v", opt)
+		}
+		if err := NewWriter(ioutil.Discard, opt).WriteInt(1); err == nil {
+			t.Errorf("expected a Writer error for options %+v", opt)
+		}
+	}
+}
+
+func TestFormatVersion1(t *testing.T) {
+	// This output must never change, see FormatVersion1.
+	value := map[string]interface{}{
+		"b":     []interface{}{1, 2.5, -0.0, true, nil},
+		"a":     "text",
+		"quote": "'x' \"y\"",
+		"multi": "line1\nline2",
+		"empty": map[string]interface{}{},
+		"key x": "",
+		"num":   "123",
+	}
+	opt := DefaultOptions()
+	opt.FormatVersion = FormatVersion1
+	buf, err := MarshalWithOptions(value, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\n  a: text\n  b:\n  [\n    1\n    2.5\n    0\n    true\n    null\n  ]\n  empty: {}\n  \"key x\": \"\"\n  multi:\n    '''\n    line1\n    line2\n    '''\n  num: \"123\"\n  quote: ''''x' \"y\"'''\n}"
+	if string(buf) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf)
+	}
+}
+
+type testJSONMarshaler struct{}
+
+func (testJSONMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte(`{"x":"y z","a":[1,2]}`), nil
+}
+
+type testV1Inner struct{ A int }
+
+type testV1Outer struct {
+	testV1Inner
+	B      int `json:"b,string"`
+	hidden string
+}
+
+func TestFormatVersion1Types(t *testing.T) {
+	// This output must never change, see FormatVersion1.
+	value := map[string]interface{}{
+		"marshaler": testJSONMarshaler{},
+		"time":      time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		"number":    json.Number("1.5"),
+		"struct":    testV1Outer{testV1Inner{1}, 2, "h"},
+		"ip":        net.IP{1, 2, 3, 4},
+		"raw":       json.RawMessage(`{"b": 1, "a" : [ 2 ]}`),
+	}
+	for _, version := range []int{FormatVersion1, FormatVersion2} {
+		opt := DefaultOptions()
+		opt.FormatVersion = version
+		buf, err := MarshalWithOptions(value, opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// byte slices are only encoded as base64 from FormatVersion2 on
+		ip := "ip:\n  [\n    1\n    2\n    3\n    4\n  ]"
+		if version == FormatVersion2 {
+			ip = "ip: AQIDBA=="
+		}
+		expected := "{\n  " + ip + "\n  marshaler: {\"x\":\"y z\",\"a\":[1,2]}\n  number: \"1.5\"\n  raw: {\"b\": 1, \"a\" : [ 2 ]}\n  struct:\n  {\n    testV1Inner:\n    {\n      A: 1\n    }\n    b: 2\n    hidden: h\n  }\n  time: \"2020-01-02T03:04:05Z\"\n}"
+		if string(buf) != expected {
+			t.Errorf("FormatVersion%d: expected\n%s\ngot\n%s", version, expected, buf)
+		}
+	}
+}
+
+func TestUnknownStringer(t *testing.T) {
+	ch := make(chan int, 4)
+	ch <- 1
+	value := []interface{}{TestUnknownStringer, ch, complex(1, 2), (chan<- bool)(nil)}
+	opt := DefaultOptions()
+	opt.UnknownAsNull = true
+	opt.UnknownStringer = DescribeUnknown
+	buf, err := MarshalWithOptions(value, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "[\n  func github.com/hjson/hjson-go.TestUnknownStringer\n  chan int (len 1, cap 4)\n  (1+2i)\n  chan<- bool (nil)\n]"
+	if string(buf) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf)
+	}
+
+	if _, err := NewEncoderOptions(WithUnknownStringer(DescribeUnknown)); err == nil {
+		t.Error("expected an error for UnknownStringer without UnknownAsNull")
+	}
+}
+
+type testColor int
+
+func (c testColor) String() string { return [...]string{"red", "green"}[c] }
+
+type testID struct{ n int }
+
+func (id *testID) String() string { return fmt.Sprintf("id-%d", id.n) }
+
+func TestUseStringer(t *testing.T) {
+	value := struct {
+		Color testColor
+		ID    testID
+		Time  time.Time
+	}{1, testID{7}, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)}
+	opt := DefaultOptions()
+	opt.UseStringer = true
+	buf, err := MarshalWithOptions(&value, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\n  Color: green\n  ID: id-7\n  Time: 2020-01-02T03:04:05Z\n}"
+	if string(buf) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf)
+	}
+
+	buf, _ = Marshal(testColor(0))
+	if string(buf) != "0" {
+		t.Errorf("expected 0 without UseStringer, got %s", buf)
+	}
+}
+
+func TestUseErrorString(t *testing.T) {
+	value := map[string]interface{}{
+		"err":    errors.New("disk full"),
+		"errs":   []error{errors.New("a: b"), nil},
+		"status": "failed",
+	}
+	opt := DefaultOptions()
+	opt.UseErrorString = true
+	buf, err := MarshalWithOptions(value, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\n  err: disk full\n  errs:\n  [\n    a: b\n    null\n  ]\n  status: failed\n}"
+	if string(buf) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf)
+	}
+}
+
+func TestSortKeysIgnoreCase(t *testing.T) {
+	value := map[string]int{"Zebra": 1, "apple": 2, "Apple": 3, "banana": 4}
+	expected := "{\n  Apple: 3\n  apple: 2\n  banana: 4\n  Zebra: 1\n}"
+	opt := DefaultOptions()
+	opt.SortKeys = SortKeysAlphabeticalIgnoreCase
+	deprecated := DefaultOptions()
+	deprecated.SortKeysIgnoreCase = true
+	for _, opt := range []EncoderOptions{opt, deprecated} {
+		buf, err := MarshalWithOptions(value, opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(buf) != expected {
+			t.Errorf("expected\n%s\ngot\n%s", expected, buf)
+		}
+	}
+
+	deprecated.SortKeys = SortKeysNone
+	if _, err := MarshalWithOptions(value, deprecated); err == nil {
+		t.Error("expected an error for SortKeysIgnoreCase with SortKeysNone")
+	}
+	if _, err := NewEncoderOptions(WithSortKeysIgnoreCase(), WithSortKeys(SortKeysNone)); err == nil {
+		t.Error("expected conflicting options")
+	}
+}
+
+func TestSortKeys(t *testing.T) {
+	value := map[string]int{"b": 1, "version": 2, "a": 3, "name": 4}
+	opt := DefaultOptions()
+	opt.SortKeys = SortKeysCustom
+	opt.KeyLess = KeysFirst("name", "version")
+	buf, err := MarshalWithOptions(value, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\n  name: 4\n  version: 2\n  a: 3\n  b: 1\n}"
+	if string(buf) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf)
+	}
+
+	opt.SortKeys = SortKeysNone
+	if buf, err = MarshalWithOptions(value, opt); err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]int
+	if err = Unmarshal(buf, &decoded); err != nil || !reflect.DeepEqual(decoded, value) {
+		t.Errorf("unexpected output\n%s", buf)
+	}
+
+	opt.SortKeys = SortKeysCustom
+	opt.KeyLess = nil
+	if _, err = MarshalWithOptions(value, opt); err == nil {
+		t.Error("expected an error for SortKeysCustom without KeyLess")
+	}
+}
+
+type testBase struct {
+	ID   int
+	Name string `json:"name"`
+}
+
+type testMeta struct {
+	Label   string `json:",omitempty"`
+	Version int    `json:"version,omitempty"`
+}
+
+type testOuter struct {
+	testBase
+	ID int
+}
+
+type testHidden struct {
+	Hidden int
+}
+
+func TestEncodeStructFields(t *testing.T) {
+	type item struct {
+		testBase
+		*testMeta
+		testHidden `json:"-"`
+		Extra      string `json:",omitempty"`
+		Skip       int    `json:"-"`
+		Dash       int    `json:"-,"`
+		private    int
+	}
+	value := item{testBase: testBase{1, "a"}, private: 2}
+	buf, err := Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\n  ID: 1\n  name: a\n  -: 0\n}"
+	if string(buf) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf)
+	}
+
+	value.testMeta = &testMeta{"", 3}
+	buf, _ = Marshal(value)
+	expected = "{\n  ID: 1\n  name: a\n  version: 3\n  -: 0\n}"
+	if string(buf) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf)
+	}
+
+	var decoded item
+	if err := Unmarshal([]byte("id: 4\nname: b\nversion: 5"), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	// like encoding/json, a nil pointer to an unexported embedded struct
+	// is not allocated
+	if decoded.ID != 4 || decoded.testBase.Name != "b" || decoded.testMeta != nil {
+		t.Errorf("unexpected value %+v", decoded)
+	}
+
+	buf, _ = Marshal(testOuter{testBase{1, "a"}, 2})
+	expected = "{\n  name: a\n  ID: 2\n}"
+	if string(buf) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf)
+	}
+}
+
+func TestHjsonTags(t *testing.T) {
+	type limits struct {
+		MaxConns int `hjson:"max_conns"`
+	}
+	type config struct {
+		Name   string `json:"name" hjson:"title"`
+		Secret string `json:"secret" hjson:"-"`
+		Limits limits `json:"limits" hjson:",inline"`
+		Port   int    `json:"port,omitempty" hjson:"port"`
+	}
+	buf, err := Marshal(config{"app", "s3cr3t", limits{10}, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\n  title: app\n  max_conns: 10\n  port: 0\n}"
+	if string(buf) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf)
+	}
+
+	var decoded config
+	if err = Unmarshal([]byte("title: b\nsecret: x\nmax_conns: 5\nname: c"), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded != (config{"b", "", limits{5}, 0}) {
+		t.Errorf("unexpected value %+v", decoded)
+	}
+}
+
+func TestStringTagOption(t *testing.T) {
+	type item struct {
+		ID      int64    `json:"id,string"`
+		Ratio   float64  `json:"ratio,string"`
+		Enabled bool     `json:"enabled,string"`
+		Count   *uint    `json:"count,string"`
+		Name    string   `json:"name,string"`
+		Tags    []string `json:"tags,string"`
+	}
+	buf, err := Marshal(item{ID: 1234567890123, Ratio: 0.5, Enabled: true, Name: "x", Tags: []string{"a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\n  id: \"1234567890123\"\n  ratio: \"0.5\"\n  enabled: \"true\"\n  count: null\n  name: x\n  tags:\n  [\n    a\n  ]\n}"
+	if string(buf) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf)
+	}
+
+	var decoded item
+	if err = Unmarshal(buf, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.ID != 1234567890123 || decoded.Ratio != 0.5 || !decoded.Enabled || decoded.Count != nil {
+		t.Errorf("unexpected value %+v", decoded)
+	}
+	if err = Unmarshal([]byte("id: 5\ncount: '7'"), &decoded); err != nil || decoded.ID != 5 || *decoded.Count != 7 {
+		t.Errorf("unexpected value %+v, %v", decoded, err)
+	}
+	if err = Unmarshal([]byte(`id: "five"`), &decoded); err == nil {
+		t.Error("expected an error for a string that is not a number")
+	}
+}
+
+func TestCommentTag(t *testing.T) {
+	type config struct {
+		Rate int    `json:"rate" comment:"requests per second\n\nper client"`
+		Name string `comment:"the name"`
+		Port int
+	}
+	opt := DefaultOptions()
+	opt.Eol = "\r\n"
+	buf, err := MarshalWithOptions(config{1000, "x", 80}, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\r\n  # requests per second\r\n  #\r\n  # per client\r\n  rate: 1000\r\n\r\n  # the name\r\n  Name: x\r\n\r\n  Port: 80\r\n}"
+	if string(buf) != expected {
+		t.Errorf("expected\n%q\ngot\n%q", expected, buf)
+	}
+	var decoded config
+	if err = Unmarshal(buf, &decoded); err != nil || decoded != (config{1000, "x", 80}) {
+		t.Errorf("unexpected value %+v, %v", decoded, err)
+	}
+}
+
+func TestCycles(t *testing.T) {
+	type node struct {
+		Name string
+		Next *node
+	}
+	a := &node{Name: "a"}
+	a.Next = &node{Name: "b", Next: a}
+	m := map[string]interface{}{}
+	m["self"] = m
+	s := []interface{}{nil}
+	s[0] = s
+
+	for _, test := range []struct {
+		value interface{}
+		typ   string
+	}{
+		{a, "*hjson.node"},
+		{m, "map[string]interface {}"},
+		{s, "[]interface {}"},
+	} {
+		_, err := Marshal(test.value)
+		if err == nil || err.Error() != "Encountered a cycle via "+test.typ {
+			t.Errorf("expected a cycle error via %s, got %v", test.typ, err)
+		}
+	}
+
+	// values shared without a cycle are fine
+	shared := &node{Name: "shared"}
+	if _, err := Marshal([]*node{shared, shared}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEncodeMaxDepth(t *testing.T) {
+	value := map[string]interface{}{"a": []interface{}{map[string]interface{}{"b": 1}}}
+	opt := DefaultOptions()
+	opt.MaxDepth = 3
+	if _, err := MarshalWithOptions(value, opt); err != nil {
+		t.Errorf("expected depth 3 to be allowed, got %v", err)
+	}
+	opt.MaxDepth = 2
+	_, err := MarshalWithOptions(value, opt)
+	if err == nil || err.Error() != "Exceeded the maximum nesting depth of 2 at a[0]" {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	var deep interface{} = "x"
+	for i := 0; i < 100; i++ {
+		deep = []interface{}{deep}
+	}
+	opt.MaxDepth = 50
+	if _, err = MarshalWithOptions(deep, opt); err == nil {
+		t.Error("expected an error for deep nesting")
+	}
+
+	w := NewWriter(ioutil.Discard, opt)
+	for i := 0; i < 50; i++ {
+		if err = w.BeginArray(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.BeginArray(); err == nil {
+		t.Error("expected a Writer error for deep nesting")
+	}
+
+	if _, err = NewEncoderOptions(WithEncodeMaxDepth(0)); err == nil {
+		t.Error("expected an error for MaxDepth 0")
+	}
+}
+
+func TestMarshalAppend(t *testing.T) {
+	buf := []byte("prefix ")
+	buf, err := MarshalAppend(buf, map[string]int{"a": 1})
+	if err != nil || string(buf) != "prefix {\n  a: 1\n}" {
+		t.Errorf("unexpected %q, %v", buf, err)
+	}
+
+	// the buffer is reused
+	buf = make([]byte, 0, 64)
+	out, err := MarshalAppend(buf, []int{1, 2})
+	if err != nil || string(out) != "[\n  1\n  2\n]" || &out[0] != &buf[:1][0] {
+		t.Errorf("unexpected %q, %v", out, err)
+	}
+
+	out, err = MarshalAppend(out, make(chan int))
+	if err == nil || string(out) != "[\n  1\n  2\n]" {
+		t.Errorf("expected an error and the unchanged buffer, got %q, %v", out, err)
+	}
+
+	opt := DefaultOptions()
+	appendAllocs := testing.AllocsPerRun(100, func() {
+		buf, _ = MarshalAppendWithOptions(buf[:0], []int{1, 2}, opt)
+	})
+	marshalAllocs := testing.AllocsPerRun(100, func() {
+		MarshalWithOptions([]int{1, 2}, opt)
+	})
+	if appendAllocs >= marshalAllocs {
+		t.Errorf("expected fewer allocations than Marshal, got %v and %v", appendAllocs, marshalAllocs)
+	}
+}
+
+type testConflictA struct {
+	Name  string
+	Both  int
+	Depth int
+}
+
+type testConflictB struct {
+	Name string `json:"Name"`
+	Both int
+}
+
+type testConflictDeep struct {
+	testConflictA
+}
+
+func TestEmbeddedConflicts(t *testing.T) {
+	// the same field sets as encoding/json
+	value := struct {
+		testConflictA
+		testConflictB
+		*testConflictDeep
+		Depth string
+	}{
+		testConflictA{"a", 1, 2},
+		testConflictB{"b", 3},
+		&testConflictDeep{testConflictA{"deep", 4, 5}},
+		"outer",
+	}
+	js, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected, actual map[string]interface{}
+	if err = json.Unmarshal(js, &expected); err != nil {
+		t.Fatal(err)
+	}
+	if err = Unmarshal(buf, &actual); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+
+	decoded := value
+	decoded.testConflictDeep = nil
+	if err = Unmarshal([]byte("Name: x\nBoth: 9\nDepth: y"), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.testConflictB.Name != "x" || decoded.testConflictA.Name != "a" || decoded.Depth != "y" || decoded.testConflictA.Both != 1 {
+		t.Errorf("unexpected value %+v", decoded)
+	}
+}
+
+type testOutputJSON struct {
+	Name   string            `comment:"the name"`
+	Empty  string            `json:"empty key,omitempty"`
+	Tags   []string          `comment:"some tags"`
+	Attrs  map[string]int    `json:"attrs"`
+	Value  testHjsonValue    `json:"value"`
+	Nested *testOutputJSON   `json:",omitempty"`
+	Extra  map[string]string `json:",omitempty"`
+}
+
+func TestOutputJSON(t *testing.T) {
+	value := testOutputJSON{
+		Name:  "quoteless # text, with \"quotes\"",
+		Tags:  []string{"a", "true", "3"},
+		Attrs: map[string]int{"x": 1, "y z": 2},
+		Value: testHjsonValue{"{\n  # a comment\n  a: text\n  b: [1, 2.50]\n}"},
+		Nested: &testOutputJSON{
+			Name:  "",
+			Attrs: map[string]int{},
+			Value: testHjsonValue{"'''\nmulti\nline\n'''"},
+		},
+	}
+	expected := map[string]string{
+		"  ": `{
+  "Name": "quoteless # text, with \"quotes\"",
+  "Tags": [
+    "a",
+    "true",
+    "3"
+  ],
+  "attrs": {
+    "x": 1,
+    "y z": 2
+  },
+  "value": {
+    "a": "text",
+    "b": [
+      1,
+      2.50
+    ]
+  },
+  "Nested": {
+    "Name": "",
+    "Tags": [],
+    "attrs": {},
+    "value": "multi\nline"
+  }
+}`,
+		"": `{"Name":"quoteless # text, with \"quotes\"","Tags":["a","true","3"],"attrs":{"x":1,"y z":2},"value":{"a":"text","b":[1,2.50]},"Nested":{"Name":"","Tags":[],"attrs":{},"value":"multi\nline"}}`,
+	}
+	for indent, exp := range expected {
+		opt := DefaultOptions()
+		opt.IndentBy = indent
+		opt.OutputFormat = OutputJSON
+		buf, err := MarshalWithOptions(value, opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(buf) != exp {
+			t.Errorf("indent %q: expected\n%s\ngot\n%s", indent, exp, buf)
+		}
+		if !json.Valid(buf) {
+			t.Errorf("indent %q: invalid JSON\n%s", indent, buf)
+		}
+	}
+
+	opt, err := NewEncoderOptions(WithOutputFormat(OutputJSON), WithIndent(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := make(chan interface{}, 3)
+	ch <- map[string]interface{}{"a": []byte{1}}
+	ch <- "x"
+	ch <- nil
+	close(ch)
+	var out bytes.Buffer
+	if err = NewEncoder(&out, opt).EncodeStream(ch); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != `[{"a":"AQ=="},"x",null]`+"\n" {
+		t.Errorf("unexpected stream %q", out.String())
+	}
+
+	opt.OutputFormat = OutputJSON5 + 1
+	if _, err = MarshalWithOptions(1, opt); err == nil {
+		t.Error("expected an error for an unknown OutputFormat")
+	}
+}
+
+func TestOutputJSONC(t *testing.T) {
+	type config struct {
+		Port  int     `comment:"the port"`
+		Rate  float64 `json:"rate-limit" comment:"per second\n\nor 0"`
+		Hosts []string
+	}
+	value := config{80, math.Inf(1), []string{"a"}}
+	expected := map[OutputFormat]string{
+		OutputJSONC: `{
+  // the port
+  "Port": 80,
+  // per second
+  //
+  // or 0
+  "rate-limit": null,
+  "Hosts": [
+    "a"
+  ]
+}`,
+		OutputJSON5: `{
+  // the port
+  Port: 80,
+  // per second
+  //
+  // or 0
+  "rate-limit": Infinity,
+  Hosts: [
+    "a"
+  ]
+}`,
+	}
+	for format, exp := range expected {
+		opt := DefaultOptions()
+		opt.OutputFormat = format
+		out, err := MarshalWithOptions(value, opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != exp {
+			t.Errorf("%d: expected\n%s\ngot\n%s", format, exp, out)
+		}
+	}
+
+	opt := DefaultOptions()
+	opt.OutputFormat = OutputJSON5
+	opt.IndentBy = ""
+	out, err := MarshalWithOptions([]interface{}{value, math.NaN(), math.Inf(-1)}, opt)
+	if err != nil || string(out) != `[{Port:80,"rate-limit":Infinity,Hosts:["a"]},NaN,-Infinity]` {
+		t.Errorf("unexpected %s, %v", out, err)
+	}
+	decOpt := DefaultDecoderOptions()
+	decOpt.AcceptJSON5 = true
+	var v []interface{}
+	if err = UnmarshalWithOptions(out, &v, decOpt); err != nil || len(v) != 3 || !math.IsInf(v[2].(float64), -1) {
+		t.Errorf("unexpected %v, %v", v, err)
+	}
 }
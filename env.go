@@ -0,0 +1,115 @@
+package hjson
+
+import (
+	"os"
+	"strings"
+)
+
+// expandEnv replaces the references to environment variables in the string
+// value s, see DecoderOptions.ExpandEnv.
+func (p *hjsonParser) expandEnv(s string) (string, error) {
+	if !strings.Contains(s, "$") {
+		return s, nil
+	}
+	lookup := p.LookupEnv
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	var buf []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) || s[i+1] != '$' && s[i+1] != '{' {
+			buf = append(buf, s[i])
+			continue
+		}
+		if s[i+1] == '$' {
+			buf = append(buf, '$')
+			i++
+			continue
+		}
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return "", p.errCode("env-brace", s)
+		}
+		ref := s[i+2 : i+end]
+		i += end
+
+		name, op, arg := ref, "", ""
+		if colon := strings.IndexByte(ref, ':'); colon >= 0 {
+			name, op, arg = ref[:colon], ref[colon:], ref[colon+1:]
+			if arg != "" {
+				op, arg = ref[colon:colon+2], arg[1:]
+			}
+		}
+		if !isEnvName(name) || op != "" && op != ":-" && op != ":?" {
+			return "", p.errCode("env-reference", ref)
+		}
+		value, ok := lookup(name)
+		if !ok || value == "" {
+			switch op {
+			case ":-":
+				value = arg
+			case ":?":
+				if arg == "" {
+					return "", p.errCode("env-unset", name)
+				}
+				return "", p.errCode("env-message", name, arg)
+			}
+		}
+		buf = append(buf, value...)
+	}
+	// s has been recorded already, see allocString
+	if len(buf) > len(s) {
+		if err := p.alloc(len(buf) - len(s)); err != nil {
+			return "", err
+		}
+	}
+	return string(buf), nil
+}
+
+// isEnvName reports whether name is the name of an environment variable
+// that can be referenced: letters, digits and underscores, not starting
+// with a digit.
+func isEnvName(name string) bool {
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// quoteless returns the value of a quoteless string after expanding the
+// environment variables in it. Text that reads like a number, true, false or
+// null after the expansion, like ${PORT:-8080}, is that value.
+func (p *hjsonParser) quoteless(str string) (interface{}, string, error) {
+	if !p.ExpandEnv {
+		return p.parseExtensions(str)
+	}
+	expanded, err := p.expandEnv(str)
+	if err != nil {
+		return nil, "", err
+	} else if expanded == str {
+		return p.parseExtensions(str)
+	}
+	switch expanded {
+	case "true":
+		return true, expanded, nil
+	case "false":
+		return false, expanded, nil
+	case "null":
+		return nil, expanded, nil
+	}
+	if n, err := tryParseNumber([]byte(expanded), false); err == nil {
+		return n, expanded, nil
+	}
+	if p.AcceptJSON5 {
+		if n, literal, err := tryParseJSON5Number([]byte(expanded)); err == nil {
+			return n, literal, nil
+		}
+	}
+	return p.parseExtensions(expanded)
+}
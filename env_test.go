@@ -0,0 +1,89 @@
+package hjson
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandEnv(t *testing.T) {
+	env := map[string]string{
+		"DATABASE_URL": "postgres://db/app",
+		"HOST":         "example.com",
+		"DEBUG":        "true",
+		"EMPTY":        "",
+	}
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+	decOpt, err := NewDecoderOptions(WithExpandEnv(), WithLookupEnv(lookup))
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := `{
+  url: "${DATABASE_URL}"
+  port: ${PORT:-8080}
+  debug: ${DEBUG}
+  home: https://${HOST}/~${USER:-nobody}
+  price: "$5 or $$10, ${EMPTY:-none}${MISSING}"
+  multi:
+    '''
+    ${HOST}
+    '''
+  "${HOST}": key
+  number: 1${EMPTY}
+}`
+	var v map[string]interface{}
+	if err = UnmarshalWithOptions([]byte(input), &v, decOpt); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"url":     "postgres://db/app",
+		"port":    8080.0,
+		"debug":   true,
+		"home":    "https://example.com/~nobody",
+		"price":   "$5 or $10, none",
+		"multi":   "example.com",
+		"${HOST}": "key",
+		"number":  1.0,
+	}
+	if !reflect.DeepEqual(v, expected) {
+		t.Errorf("expected\n%#v\ngot\n%#v", expected, v)
+	}
+
+	var s struct {
+		Port int
+		Name string
+	}
+	env["PORT"] = "9090"
+	if err = UnmarshalWithOptions([]byte("Port: ${PORT}\nName: ${PORT}"), &s, decOpt); err != nil || s.Port != 9090 || s.Name != "9090" {
+		t.Errorf("unexpected %+v, %v", s, err)
+	}
+
+	for input, expected := range map[string]string{
+		`a: "${DATABASE_URL"`:        "Missing '}' after '${'",
+		`a: "${1X}"`:                 "Bad environment variable reference '${1X}'",
+		`a: "${X:+y}"`:               "Bad environment variable reference '${X:+y}'",
+		`a: "${MISSING:?}"`:          "Environment variable MISSING is not set",
+		`a: "${EMPTY:?is required}"`: "Environment variable EMPTY is required",
+	} {
+		if err = UnmarshalWithOptions([]byte(input), &v, decOpt); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%s: expected an error with %q, got %v", input, expected, err)
+		}
+	}
+
+	// os.LookupEnv without WithLookupEnv, nothing without ExpandEnv
+	os.Setenv("HJSON_TEST_ENV", "x")
+	defer os.Unsetenv("HJSON_TEST_ENV")
+	decOpt = DefaultDecoderOptions()
+	decOpt.ExpandEnv = true
+	var str string
+	if err = UnmarshalWithOptions([]byte(`"${HJSON_TEST_ENV}"`), &str, decOpt); err != nil || str != "x" {
+		t.Errorf("expected x, got %q, %v", str, err)
+	}
+	if err = Unmarshal([]byte(`"${HJSON_TEST_ENV}"`), &str); err != nil || str != "${HJSON_TEST_ENV}" {
+		t.Errorf("expected no expansion, got %q, %v", str, err)
+	}
+}
@@ -0,0 +1,122 @@
+package hjson
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// An Extension adds a kind of literal to Hjson, for example date literals or
+// byte sizes like 10GiB. Extensions are registered with RegisterExtension
+// and enabled by name with DecoderOptions.Extensions and
+// EncoderOptions.Extensions.
+type Extension struct {
+	// Name used to enable the extension
+	Name string
+	// Called for each quoteless value that would otherwise be decoded as a
+	// string, with the trimmed value. If ok is true, value is decoded
+	// instead of the string. value is stored as is in interfaces and in
+	// destinations of its type; a float64 is also stored in numbers, and
+	// strings get the literal. A non-nil err fails the decoding.
+	Parse func(literal string) (value interface{}, ok bool, err error)
+	// Called for each value before it is encoded. If ok is true, literal is
+	// written as is in place of the value; it must decode back to the value
+	// with Parse.
+	Format func(v reflect.Value) (literal string, ok bool)
+}
+
+var extensions struct {
+	sync.RWMutex
+	byName map[string]*Extension
+}
+
+// RegisterExtension makes an extension available by its name. It panics if
+// the name is empty or already registered, or if ext has neither Parse nor
+// Format.
+func RegisterExtension(ext Extension) {
+	if ext.Name == "" || ext.Parse == nil && ext.Format == nil {
+		panic("hjson: RegisterExtension needs a name and Parse or Format")
+	}
+	extensions.Lock()
+	defer extensions.Unlock()
+	if extensions.byName == nil {
+		extensions.byName = make(map[string]*Extension)
+	}
+	if _, dup := extensions.byName[ext.Name]; dup {
+		panic("hjson: RegisterExtension called twice for " + ext.Name)
+	}
+	extensions.byName[ext.Name] = &ext
+}
+
+// Extensions returns the sorted names of the registered extensions.
+func Extensions() []string {
+	extensions.RLock()
+	defer extensions.RUnlock()
+	names := make([]string, 0, len(extensions.byName))
+	for name := range extensions.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupExtensions returns the registered extensions with the given names.
+func lookupExtensions(names []string) ([]*Extension, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	extensions.RLock()
+	defer extensions.RUnlock()
+	list := make([]*Extension, len(names))
+	for i, name := range names {
+		if list[i] = extensions.byName[name]; list[i] == nil {
+			return nil, errUnknownExtension(name)
+		}
+	}
+	return list, nil
+}
+
+type errUnknownExtension string
+
+func (e errUnknownExtension) Error() string {
+	return "Unknown extension " + string(e)
+}
+
+// parseExtensions returns the value of the first enabled extension that
+// parses the quoteless string str, or str itself.
+func (p *hjsonParser) parseExtensions(str string) (interface{}, string, error) {
+	for _, ext := range p.extensions {
+		if ext.Parse == nil {
+			continue
+		}
+		value, ok, err := ext.Parse(str)
+		if err != nil {
+			return nil, "", p.errAt(err.Error())
+		}
+		if ok {
+			return value, str, nil
+		}
+	}
+	return str, str, nil
+}
+
+// formatExtensions writes value with the first enabled extension that
+// formats it and reports whether one did.
+func (e *hjsonEncoder) formatExtensions(value reflect.Value, separator string) bool {
+	for _, ext := range e.extensions {
+		if ext.Format == nil {
+			continue
+		}
+		if literal, ok := ext.Format(value); ok {
+			if e.jsonOutput && !json.Valid([]byte(literal)) {
+				// a literal that is only valid in Hjson
+				literal = e.jsonString(literal)
+			}
+			e.WriteString(separator)
+			e.WriteString(literal)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package hjson
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func init() {
+	RegisterExtension(Extension{Name: "test-option", Format: func(reflect.Value) (string, bool) { return "", false }})
+	RegisterExtension(Extension{
+		Name: "test-date",
+		Parse: func(literal string) (interface{}, bool, error) {
+			t, err := time.Parse("2006-01-02", literal)
+			return t, err == nil, nil
+		},
+		Format: func(v reflect.Value) (string, bool) {
+			t, ok := v.Interface().(time.Time)
+			if !ok || !t.Equal(t.Truncate(24*time.Hour)) {
+				return "", false
+			}
+			return t.Format("2006-01-02"), true
+		},
+	})
+	RegisterExtension(Extension{
+		Name: "test-size",
+		Parse: func(literal string) (interface{}, bool, error) {
+			if !strings.HasSuffix(literal, "GiB") {
+				return nil, false, nil
+			}
+			n, err := strconv.ParseFloat(strings.TrimSuffix(literal, "GiB"), 64)
+			if err != nil {
+				return nil, false, errors.New("Bad size " + literal)
+			}
+			return n * (1 << 30), true, nil
+		},
+	})
+}
+
+func TestExtension(t *testing.T) {
+	opt := DefaultDecoderOptions()
+	opt.Extensions = []string{"test-date", "test-size"}
+	data := []byte("day: 2024-01-02\nsize: 2GiB\nname: 2024-01-02 release")
+
+	var generic map[string]interface{}
+	if err := UnmarshalWithOptions(data, &generic, opt); err != nil {
+		t.Fatal(err)
+	}
+	day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	expected := map[string]interface{}{"day": day, "size": float64(2 << 30), "name": "2024-01-02 release"}
+	if !reflect.DeepEqual(generic, expected) {
+		t.Errorf("expected %v, got %v", expected, generic)
+	}
+
+	var typed struct {
+		Day  time.Time
+		Size int64
+		Name string
+	}
+	if err := UnmarshalWithOptions([]byte("day: 2024-01-02\nsize: 2GiB\nname: 2024-01-02"), &typed, opt); err != nil {
+		t.Fatal(err)
+	}
+	if !typed.Day.Equal(day) || typed.Size != 2<<30 || typed.Name != "2024-01-02" {
+		t.Errorf("unexpected value %+v", typed)
+	}
+
+	if err := UnmarshalWithOptions([]byte("size: xGiB"), &generic, opt); err == nil || !strings.HasPrefix(err.Error(), "Bad size xGiB") {
+		t.Errorf("expected the extension error, got %v", err)
+	}
+	if err := Unmarshal([]byte("day: 2024-01-02"), &generic); err != nil || generic["day"] != "2024-01-02" {
+		t.Errorf("expected a string without the extension, got %v, %v", generic, err)
+	}
+	opt.Extensions = []string{"missing"}
+	if err := UnmarshalWithOptions(data, &generic, opt); err == nil {
+		t.Error("expected an error for an unknown extension")
+	}
+
+	if decOpt, err := NewDecoderOptions(WithDecodeExtensions("test-date", "test-size")); err != nil || !reflect.DeepEqual(decOpt.Extensions, []string{"test-date", "test-size"}) {
+		t.Errorf("unexpected options %v, %v", decOpt.Extensions, err)
+	}
+	if _, err := NewDecoderOptions(WithDecodeExtensions("missing")); err == nil || err.Error() != "Invalid option: Unknown extension missing" {
+		t.Errorf("expected an error for an unknown extension, got %v", err)
+	}
+
+	encOpt, err := NewEncoderOptions(WithExtensions("test-date"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewEncoderOptions(WithExtensions()); err == nil {
+		t.Error("expected an error for no extensions")
+	}
+	buf, err := MarshalWithOptions(map[string]interface{}{"day": day, "at": day.Add(time.Hour)}, encOpt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "{\n  at: 2024-01-02T01:00:00Z\n  day: 2024-01-02\n}" {
+		t.Errorf("unexpected output %s", buf)
+	}
+}
@@ -0,0 +1,519 @@
+package hjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Format returns the Hjson document src formatted like MarshalWithOptions
+// formats values, keeping its comments: members and elements are written
+// one per line at the indentation of options.IndentBy, without commas, with
+// keys and strings quoted only where needed and braces placed as set by
+// options.BracesSameLine. Comments are written on their own lines before
+// the member or element they precede, or at the end of the line of the
+// value they follow. Single blank lines between members and elements are
+// kept, as are the literals of numbers and a root object without braces.
+// The result ends with an end of line.
+//
+// Formatting the result again gives the same bytes, so Format can check
+// files in pre-commit hooks.
+//
+// With OutputJSONC or OutputJSON5 the document is written in that syntax,
+// with # comments written as // comments, so that the comments survive in
+// files for tools that read JSON with comments but not Hjson. With
+// OutputJSON the comments are dropped.
+func Format(src []byte, options EncoderOptions) ([]byte, error) {
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+	root, err := ParseNode(src)
+	if err != nil {
+		return nil, err
+	}
+	if options.OutputFormat != OutputHjson {
+		return formatJSON(root, options)
+	}
+	options.Eol = options.eol()
+	f := formatter{options}
+
+	line, others := splitComments(root.Comments.Before)
+	root.Comments.Before = f.head(append(line, others...))
+	f.layout(root, 0)
+	if root.braceless {
+		// the comments at the end are in After
+		root.Comments.Line = ""
+		if len(root.Children) > 0 || root.Comments.After != "" {
+			root.Comments.Line = f.Eol
+		}
+	} else {
+		line, others = splitComments(root.Comments.Line)
+		root.Comments.Line = sameLine(line)
+		for _, item := range trimTrailingBlank(others) {
+			root.Comments.Line += f.Eol + item
+		}
+		root.Comments.Line += f.Eol
+	}
+	root.style = &f.EncoderOptions
+	return root.Marshal()
+}
+
+// formatJSON writes the document root for Format in one of the JSON
+// syntaxes.
+func formatJSON(root *Node, options EncoderOptions) ([]byte, error) {
+	e, err := newHjsonEncoder(options)
+	if err != nil {
+		return nil, err
+	}
+	var head, line, tail []string
+	if e.writesComments() {
+		line, others := splitComments(root.Comments.Before)
+		head = trimLeadingBlank(append(line, others...))
+	}
+	if e.writesComments() && !root.braceless {
+		// the comments at the end of a braceless root are in After
+		line, tail = splitComments(root.Comments.Line)
+		tail = trimTrailingBlank(tail)
+	}
+	for _, item := range head {
+		if item != "" {
+			e.WriteString(jsonComment(item))
+		}
+		e.WriteString(e.Eol)
+	}
+	if err = e.writeJSONNode(root); err != nil {
+		return nil, err
+	}
+	for _, comment := range line {
+		e.WriteString(" " + jsonComment(comment))
+	}
+	for _, item := range tail {
+		if item != "" {
+			e.WriteString(e.Eol + jsonComment(item))
+		} else {
+			e.WriteString(e.Eol)
+		}
+	}
+	e.WriteString(e.Eol)
+	return e.Bytes(), nil
+}
+
+// writeJSONNode writes the value of n in one of the JSON syntaxes, with
+// the comments inside of it.
+func (e *hjsonEncoder) writeJSONNode(n *Node) error {
+	if n.Kind == ValueNode {
+		if _, ok := n.Value.(float64); ok && n.unchanged() && json.Valid([]byte(n.literal)) {
+			// keep the literal, like 1.50
+			e.WriteString(n.literal)
+			return nil
+		}
+		return e.str(reflect.ValueOf(n.Value), true, "", false)
+	}
+
+	open, close := "{", "}"
+	if n.Kind == ArrayNode {
+		open, close = "[", "]"
+	}
+	before, line := childComments(n)
+	var inside []string
+	if e.writesComments() {
+		inside = insideComments(n)
+	}
+	if len(n.Children) == 0 && len(inside) == 0 {
+		e.WriteString(open + close)
+		return nil
+	}
+	indent1 := e.indent
+	e.WriteString(open)
+	if err := e.nest(); err != nil {
+		return err
+	}
+	for i, c := range n.Children {
+		if e.writesComments() {
+			items := before[i]
+			if i == 0 {
+				items = trimLeadingBlank(items)
+			}
+			for _, item := range items {
+				if item == "" {
+					e.WriteString(e.Eol)
+				} else {
+					e.writeIndent(e.indent)
+					e.WriteString(jsonComment(item))
+				}
+			}
+		}
+		e.writeIndent(e.indent)
+		if n.Kind == ObjectNode {
+			e.WriteString(e.quoteName(c.Key) + ":" + e.keySeparator())
+		}
+		if err := e.writeJSONNode(c); err != nil {
+			return err
+		}
+		if i+1 < len(n.Children) {
+			e.WriteString(",")
+		}
+		if e.writesComments() {
+			for _, comment := range line[i] {
+				e.WriteString(" " + jsonComment(comment))
+			}
+		}
+	}
+	for _, item := range inside {
+		e.writeIndent(e.indent)
+		e.WriteString(jsonComment(item))
+	}
+	e.indent = indent1
+	e.writeIndent(e.indent)
+	e.WriteString(close)
+	return nil
+}
+
+// jsonComment returns the Hjson comment as a comment of JSONC and JSON5,
+// which have no # comments.
+func jsonComment(comment string) string {
+	if strings.HasPrefix(comment, "#") {
+		return "//" + comment[1:]
+	}
+	return comment
+}
+
+// formatter rewrites the whitespace and comments of parsed Nodes to the
+// layout written by Format.
+type formatter struct {
+	EncoderOptions
+}
+
+// layout rewrites n, a node at the given depth, and its children.
+func (f *formatter) layout(n *Node, depth int) {
+	n.keyGap, n.keyLiteral = "", ""
+	if _, ok := n.Value.(string); ok {
+		// written again like Marshal does
+		n.literal = ""
+	}
+	if n.Kind == ValueNode {
+		return
+	}
+
+	childDepth := depth + 1
+	if n.braceless {
+		childDepth = depth
+	}
+	var carry []string // comments on lines after a value and its comma
+	for i, c := range n.Children {
+		line, others := splitComments(c.Comments.Before)
+		items := appendComments(appendComments(carry, line), others)
+		line, others = splitComments(c.Comments.Key)
+		items = appendComments(items, line)
+		for _, item := range others {
+			// blank lines after the key are dropped
+			if item != "" {
+				items = append(items, item)
+			}
+		}
+		if i == 0 {
+			items = trimLeadingBlank(items)
+			if !n.braceless {
+				items = trimTrailingBlank(items)
+			}
+		}
+		if i == 0 && n.braceless {
+			c.Comments.Before = f.head(items)
+		} else {
+			c.Comments.Before = f.lines(items, childDepth)
+		}
+
+		c.Comments.Key = " "
+		if !f.BracesSameLine && (len(c.Children) > 0 || len(commentsInside(c)) > 0) {
+			c.Comments.Key = f.indent(childDepth)
+		}
+		f.layout(c, childDepth)
+
+		line, carry = splitComments(c.Comments.Line)
+		c.Comments.Line = sameLine(line)
+	}
+
+	line, others := splitComments(n.Comments.After)
+	items := trimTrailingBlank(appendComments(appendComments(carry, line), others))
+	if len(n.Children) == 0 {
+		items = trimLeadingBlank(items)
+	}
+	if len(n.Children) == 0 && len(items) == 0 {
+		n.Comments.After = ""
+		return
+	}
+	n.Comments.After = ""
+	for _, item := range items {
+		if item == "" {
+			n.Comments.After += f.Eol
+		} else {
+			n.Comments.After += f.indent(childDepth) + item
+		}
+	}
+	if !n.braceless {
+		n.Comments.After += f.indent(depth)
+	}
+}
+
+// commentsInside returns the comments inside the array or object n that
+// has no children.
+func commentsInside(n *Node) []string {
+	if n.Kind == ValueNode || len(n.Children) > 0 {
+		return nil
+	}
+	line, others := splitComments(n.Comments.After)
+	return trimTrailingBlank(append(line, others...))
+}
+
+// indent returns the text that starts a new line at depth.
+func (f *formatter) indent(depth int) string {
+	return f.Eol + strings.Repeat(f.IndentBy, depth)
+}
+
+// lines returns the text written before a member or element at depth: the
+// comments in items, each on its own line, and the start of its line.
+func (f *formatter) lines(items []string, depth int) string {
+	text := ""
+	for _, item := range items {
+		if item == "" {
+			text += f.Eol
+		} else {
+			text += f.indent(depth) + item
+		}
+	}
+	return text + f.indent(depth)
+}
+
+// head returns the comments in items, each on its own line, for the start
+// of the document.
+func (f *formatter) head(items []string) string {
+	text := ""
+	for _, item := range trimLeadingBlank(items) {
+		text += item + f.Eol
+	}
+	return text
+}
+
+// sameLine returns the comments written after a value on its line.
+func sameLine(comments []string) string {
+	if len(comments) == 0 {
+		return ""
+	}
+	return " " + strings.Join(comments, " ")
+}
+
+// appendComments appends the comments in more to items, keeping a single
+// blank line where both have one.
+func appendComments(items, more []string) []string {
+	for _, item := range more {
+		if item != "" || len(items) == 0 || items[len(items)-1] != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// trimLeadingBlank removes the blank lines at the start of items.
+func trimLeadingBlank(items []string) []string {
+	for len(items) > 0 && items[0] == "" {
+		items = items[1:]
+	}
+	return items
+}
+
+// trimTrailingBlank removes the blank lines at the end of items.
+func trimTrailingBlank(items []string) []string {
+	for len(items) > 0 && items[len(items)-1] == "" {
+		items = items[:len(items)-1]
+	}
+	return items
+}
+
+// splitComments returns the comments in text, source text between two
+// values, without the whitespace and commas around them. The comments
+// before the first line break are returned in line. In others an empty
+// string stands for one or more blank lines.
+func splitComments(text string) (line, others []string) {
+	newlines := 0 // since the last comment
+	atLine := true
+	for i := 0; i < len(text); {
+		var end int
+		switch {
+		case text[i] == '\n':
+			newlines++
+			atLine = false
+			i++
+			continue
+		case text[i] == '#' || strings.HasPrefix(text[i:], "//"):
+			end = strings.IndexByte(text[i:], '\n')
+			if end < 0 {
+				end = len(text)
+			} else {
+				end += i
+			}
+		case strings.HasPrefix(text[i:], "/*"):
+			end = strings.Index(text[i+2:], "*/")
+			if end < 0 {
+				end = len(text)
+			} else {
+				end += i + 4
+			}
+		default:
+			// spaces, tabs, carriage returns and commas
+			i++
+			continue
+		}
+		comment := strings.TrimRight(text[i:end], " \t\r")
+		if atLine {
+			line = append(line, comment)
+		} else {
+			if newlines > 1 {
+				others = append(others, "")
+			}
+			others = append(others, comment)
+		}
+		newlines = 0
+		i = end
+	}
+	if newlines > 1 {
+		others = append(others, "")
+	}
+	return line, others
+}
+
+// FormatRange formats the members and elements of the Hjson document data
+// whose source intersects the bytes from start up to end, like Format does,
+// and leaves the rest of data as it is, for editors to format a selection
+// or the member just typed. Objects and arrays that the range only partly
+// covers are not formatted themselves, only their members in the range;
+// with an empty range, the members around start are formatted. Lines after
+// the first of a formatted member keep the indentation of its first line.
+//
+// options.OutputFormat must be OutputHjson.
+func FormatRange(data []byte, start, end int, options EncoderOptions) ([]byte, error) {
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+	if options.OutputFormat != OutputHjson {
+		return nil, fmt.Errorf("FormatRange can only write Hjson")
+	}
+	if start < 0 || start > end || end > len(data) {
+		return nil, fmt.Errorf("Invalid range %d to %d of %d bytes", start, end, len(data))
+	}
+	root, err := ParseNode(data)
+	if err != nil {
+		return nil, err
+	}
+	_, span := root.Span()
+	if root.Kind == ValueNode || start <= span.Start.Offset && span.End.Offset <= end && start < end {
+		return Format(data, options)
+	}
+
+	r := rangeFormatter{data: data, start: start, end: end, options: options}
+	if err = r.format(root); err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	at := 0
+	for _, e := range r.edits {
+		out.Write(data[at:e.start])
+		out.WriteString(e.text)
+		at = e.end
+	}
+	out.Write(data[at:])
+	return out.Bytes(), nil
+}
+
+// rangeFormatter finds the members to format for FormatRange.
+type rangeFormatter struct {
+	data       []byte
+	start, end int
+	options    EncoderOptions
+	edits      []textEdit // in the order of data
+}
+
+// A textEdit replaces the bytes from start up to end with text.
+type textEdit struct {
+	start, end int
+	text       string
+}
+
+// format adds the edits for the children of n in the range.
+func (r *rangeFormatter) format(n *Node) error {
+	for _, c := range n.Children {
+		key, value := c.Span()
+		start, end := value.Start.Offset, value.End.Offset
+		if n.Kind == ObjectNode {
+			start = key.Start.Offset
+		}
+		switch {
+		case r.start < r.end && (end <= r.start || r.end <= start):
+			continue
+		case r.start == r.end && (r.start < start || end < r.start):
+			continue
+		case c.Kind != ValueNode && (start < r.start || r.end < end) && r.intersects(c):
+			// only partly in the range
+			if err := r.format(c); err != nil {
+				return err
+			}
+			continue
+		}
+		text, err := r.member(n.Kind == ObjectNode, start, end)
+		if err != nil {
+			return err
+		}
+		r.edits = append(r.edits, textEdit{start, end, text})
+	}
+	return nil
+}
+
+// intersects reports whether a child of n is in the range.
+func (r *rangeFormatter) intersects(n *Node) bool {
+	for _, c := range n.Children {
+		key, value := c.Span()
+		start, end := value.Start.Offset, value.End.Offset
+		if n.Kind == ObjectNode {
+			start = key.Start.Offset
+		}
+		if r.start < r.end && start < r.end && r.start < end || r.start == r.end && start <= r.start && r.start <= end {
+			return true
+		}
+	}
+	return false
+}
+
+// member returns the formatted source of the member or element from start
+// up to end.
+func (r *rangeFormatter) member(inObject bool, start, end int) (string, error) {
+	open, close := "[", "]"
+	if inObject {
+		open, close = "{", "}"
+	}
+	// at the same column, for the indentation of multiline strings
+	lineStart := bytes.LastIndexByte(r.data[:start], '\n') + 1
+	src := open + "\n" + strings.Repeat(" ", start-lineStart) + string(r.data[start:end]) + "\n" + close
+	out, err := Format([]byte(src), r.options)
+	if err != nil {
+		return "", err
+	}
+	eol := r.options.eol()
+	lines := strings.Split(string(out), eol)
+	// without the lines of the braces and the end of line at the end
+	lines = lines[1 : len(lines)-2]
+
+	lineEnd := lineStart
+	for lineEnd < start && (r.data[lineEnd] == ' ' || r.data[lineEnd] == '\t') {
+		lineEnd++
+	}
+	indent := string(r.data[lineStart:lineEnd])
+	for i, line := range lines {
+		line = strings.TrimPrefix(line, r.options.IndentBy)
+		if i > 0 && line != "" {
+			line = indent + line
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, eol), nil
+}
@@ -0,0 +1,242 @@
+package hjson
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	src := "# head\n\n{\n   # about a\n a: 1.50, // one\n\n\n  'b'   :   'text' # two\n c: {x:[1,2,\"3\"], y: {}}, d: [ # inside\n ], e: [\n\n]\n  f: '''\n  ml\n  str\n  '''\n\n  /* tail */\n}\n# end"
+	expected := `# head
+
+{
+  # about a
+  a: 1.50 // one
+
+  b: "text" # two
+  c:
+  {
+    x:
+    [
+      1
+      2
+      "3"
+    ]
+    y: {}
+  }
+  d:
+  [
+    # inside
+  ]
+  e: []
+  f:
+    '''
+    ml
+    str
+    '''
+
+  /* tail */
+}
+# end
+`
+	out, err := Format([]byte(src), DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out)
+	}
+
+	opt := DefaultOptions()
+	opt.BracesSameLine = true
+	opt.IndentBy = "\t"
+	opt.Eol = "\r\n"
+	out, err = Format([]byte("a: {b: 1 # c\n}\n\n// end\n"), opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "a: {\r\n\tb: 1 # c\r\n}\r\n\r\n// end\r\n" {
+		t.Errorf("unexpected %q", out)
+	}
+
+	for _, src := range []string{"", "# only\n", "'x' # c", "[1, 2]"} {
+		if out, err = Format([]byte(src), DefaultOptions()); err != nil {
+			t.Errorf("%q: %v", src, err)
+		} else if again, _ := Format(out, DefaultOptions()); string(again) != string(out) {
+			t.Errorf("%q: not stable\n%s\n%s", src, out, again)
+		}
+	}
+
+	if _, err = Format([]byte("{a: 1"), DefaultOptions()); err == nil {
+		t.Error("expected a syntax error")
+	}
+}
+
+func TestFormatJSON(t *testing.T) {
+	src := `# header
+{
+  port: 80 # http
+  /* hosts */
+  hosts: [
+    a.example.com
+
+    "b.example.com" // b
+  ]
+  limits: { rate: 1.50 }
+  "odd key": {
+    # nothing yet
+  }
+} // end
+# more
+`
+	expected := map[OutputFormat]string{
+		OutputJSON: `{
+  "port": 80,
+  "hosts": [
+    "a.example.com",
+    "b.example.com"
+  ],
+  "limits": {
+    "rate": 1.50
+  },
+  "odd key": {}
+}
+`,
+		OutputJSONC: `// header
+{
+  "port": 80, // http
+  /* hosts */
+  "hosts": [
+    "a.example.com",
+
+    "b.example.com" // b
+  ],
+  "limits": {
+    "rate": 1.50
+  },
+  "odd key": {
+    // nothing yet
+  }
+} // end
+// more
+`,
+		OutputJSON5: `// header
+{
+  port: 80, // http
+  /* hosts */
+  hosts: [
+    "a.example.com",
+
+    "b.example.com" // b
+  ],
+  limits: {
+    rate: 1.50
+  },
+  "odd key": {
+    // nothing yet
+  }
+} // end
+// more
+`,
+	}
+	for format, exp := range expected {
+		opt := DefaultOptions()
+		opt.OutputFormat = format
+		out, err := Format([]byte(src), opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != exp {
+			t.Errorf("%d: expected\n%s\ngot\n%s", format, exp, out)
+		}
+		if again, err := Format(out, opt); err != nil || string(again) != string(out) {
+			t.Errorf("%d: not stable\n%s\n%s", format, out, again)
+		}
+	}
+
+	opt := DefaultOptions()
+	opt.OutputFormat = OutputJSONC
+	opt.IndentBy = ""
+	if out, err := Format([]byte("# c\na: [1, 'x'] # c"), opt); err != nil || string(out) != `{"a":[1,"x"]}`+"\n" {
+		t.Errorf("unexpected %q, %v", out, err)
+	}
+}
+
+func TestFormatAssets(t *testing.T) {
+	files := strings.Split(string(getContent("assets/testlist.txt")), "\n")
+	for _, file := range files {
+		if strings.HasPrefix(file, "fail") || strings.HasPrefix(file, "stringify/quotes") || strings.HasPrefix(file, "extra/") {
+			continue
+		}
+		name := strings.TrimSuffix(file, "_test"+file[strings.LastIndex(file, "."):])
+		src := getTestContent(name)
+		out, err := Format(src, DefaultOptions())
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		var expected, actual interface{}
+		if err = Unmarshal(src, &expected); err != nil {
+			t.Fatal(err)
+		}
+		if err = Unmarshal(out, &actual); err != nil {
+			t.Errorf("%s: %v\n%s", name, err, out)
+		} else if !reflect.DeepEqual(expected, actual) {
+			t.Errorf("%s: expected\n%v\ngot\n%v", name, expected, actual)
+		}
+		if again, err := Format(out, DefaultOptions()); err != nil || string(again) != string(out) {
+			t.Errorf("%s: not stable %v\n%s\n---\n%s", name, err, out, again)
+		}
+		if strings.Count(string(src), "#") > 0 && !strings.Contains(string(out), "#") {
+			t.Errorf("%s: lost the comments\n%s", name, out)
+		}
+	}
+}
+
+func TestFormatRange(t *testing.T) {
+	src := "# config\na:   1\nb: {\n  x:1,   y:   [1,2]\n  z:   '''\n       ml\n       more\n       '''\n}\nc:    'text'\n"
+	opt := DefaultOptions()
+	opt.BracesSameLine = true
+	for _, test := range []struct {
+		sel, expected string
+	}{
+		// only the members in the range, in place
+		{"y", "# config\na:   1\nb: {\n  x:1,   y: [\n    1\n    2\n  ]\n  z:   '''\n       ml\n       more\n       '''\n}\nc:    'text'\n"},
+		{"z", "# config\na:   1\nb: {\n  x:1,   y:   [1,2]\n  z:\n    '''\n    ml\n    more\n    '''\n}\nc:    'text'\n"},
+		{"1\nb", "# config\na: 1\nb: {\n  x: 1\n  y: [\n    1\n    2\n  ]\n  z:\n    '''\n    ml\n    more\n    '''\n}\nc:    'text'\n"},
+		// an empty range formats the member around it
+		{"  'text'", "# config\na:   1\nb: {\n  x:1,   y:   [1,2]\n  z:   '''\n       ml\n       more\n       '''\n}\nc: text\n"},
+		{"# config", src},
+	} {
+		start := strings.Index(src, test.sel)
+		end := start + len(test.sel)
+		if test.sel == "  'text'" {
+			end = start
+		}
+		out, err := FormatRange([]byte(src), start, end, opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != test.expected {
+			t.Errorf("%q: expected\n%s\ngot\n%s", test.sel, test.expected, out)
+		}
+	}
+
+	// the whole document is formatted like Format does
+	all, err := FormatRange([]byte(src), 0, len(src), opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, _ := Format([]byte(src), opt); string(all) != string(expected) {
+		t.Errorf("expected\n%s\ngot\n%s", expected, all)
+	}
+
+	if _, err := FormatRange([]byte(src), 5, 1, opt); err == nil {
+		t.Error("expected an error for an invalid range")
+	}
+	opt.OutputFormat = OutputJSON
+	if _, err := FormatRange([]byte(src), 0, 1, opt); err == nil {
+		t.Error("expected an error for JSON output")
+	}
+}
@@ -0,0 +1,469 @@
+package hjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// A Result is a value found by Get.
+type Result struct {
+	value   interface{}
+	literal string // the source text of a number
+	exists  bool
+}
+
+// Get returns the value at path in the Hjson document data, with a path
+// like "database.replicas[2].host" as taken by Node.Lookup. A path that
+// leads to no value gives a Result for which Exists is false; Get fails
+// only if data is not valid Hjson or the path is malformed.
+//
+// Only the value at path is decoded, the rest of the document is skipped
+// with a scanner that checks its syntax without building values, which is
+// many times faster than Unmarshal for large documents.
+//
+//	port, err := hjson.Get(data, "server.port")
+//	...
+//	if port.Exists() {
+//		listen(port.Int())
+//	}
+func Get(data []byte, path string) (Result, error) {
+	elems, err := splitPath(path)
+	if err != nil {
+		return Result{}, err
+	}
+	exts, err := lookupExtensions(DefaultDecoderOptions().Extensions)
+	if err != nil {
+		return Result{}, err
+	}
+	g := getter{p: &hjsonParser{DecoderOptions: DefaultDecoderOptions(), data: data, extensions: exts}}
+	g.p.UseNumber = true
+	g.p.UseOrderedMap = false
+	if err = g.root(elems, true); err != nil {
+		return Result{}, err
+	}
+	return g.result, nil
+}
+
+// getter implements Get: it decodes the value at a path and skips the
+// others.
+type getter struct {
+	p      *hjsonParser
+	result Result // the value found so far
+}
+
+// root looks for the value at elems in the document if match is set, see
+// value, or skips the document. Like rootValue it accepts an object without
+// braces or a single value.
+func (g *getter) root(elems []pathElem, match bool) error {
+	p := g.p
+	p.resetAt()
+	p.white()
+	if p.ch == '{' || p.ch == '[' {
+		return p.checkTrailingErr(g.value(elems, match))
+	}
+	err := p.checkTrailingErr(g.object(true, elems, match))
+	if err != nil {
+		// test if the input is a single value instead, with no value at a
+		// path
+		p.resetAt()
+		if p.checkTrailingErr(g.value(nil, false)) == nil {
+			g.result = Result{}
+			return nil
+		}
+	}
+	return err
+}
+
+// value decodes the value at the current character if match is set and
+// elems is empty. Otherwise it looks for the value at elems below it if
+// match is set, or skips it.
+func (g *getter) value(elems []pathElem, match bool) error {
+	p := g.p
+	p.white()
+	if match && len(elems) == 0 {
+		value, err := p.readValue(reflect.Value{})
+		if err != nil {
+			return err
+		}
+		g.result = Result{exists: true}
+		if n, ok := value.(json.Number); ok {
+			g.result.literal = string(n)
+		}
+		g.result.value = toFloats(value)
+		return nil
+	}
+	switch p.ch {
+	case '{':
+		return g.object(false, elems, match)
+	case '[':
+		return g.array(elems, match)
+	case '"', '\'':
+		return p.skipString(true)
+	}
+	return p.skipTfnns()
+}
+
+// object looks for the value at elems in the object at the current
+// character, see value. Like Unmarshal the last of duplicate keys is used.
+func (g *getter) object(withoutBraces bool, elems []pathElem, match bool) error {
+	p := g.p
+	if err := p.enter(); err != nil {
+		return err
+	}
+	defer p.leave()
+	want := ""
+	if match = match && len(elems) > 0 && elems[0].index < 0; match {
+		want = elems[0].key
+	}
+
+	if !withoutBraces {
+		p.next()
+	}
+	p.white()
+	for p.ch > 0 {
+		if p.ch == '}' && !withoutBraces {
+			p.next()
+			return nil
+		}
+		found, err := p.skipKey(match, want)
+		if err != nil {
+			return err
+		}
+		p.white()
+		if p.ch != ':' {
+			return p.errCode("expected-colon", string(p.ch))
+		}
+		p.next()
+		if found {
+			// a later member replaces the earlier ones
+			g.result = Result{}
+			err = g.value(elems[1:], true)
+		} else {
+			err = g.value(nil, false)
+		}
+		if err != nil {
+			return err
+		}
+		p.white()
+		if p.ch == ',' {
+			p.next()
+			p.white()
+		}
+	}
+	if withoutBraces {
+		return nil
+	}
+	return p.errCode("object-eof")
+}
+
+// array looks for the value at elems in the array at the current
+// character, see value.
+func (g *getter) array(elems []pathElem, match bool) error {
+	p := g.p
+	if err := p.enter(); err != nil {
+		return err
+	}
+	defer p.leave()
+	match = match && len(elems) > 0 && elems[0].index >= 0
+
+	p.next()
+	p.white()
+	for i := 0; p.ch > 0; i++ {
+		if p.ch == ']' {
+			p.next()
+			return nil
+		}
+		var err error
+		if match && i == elems[0].index {
+			err = g.value(elems[1:], true)
+		} else {
+			err = g.value(nil, false)
+		}
+		if err != nil {
+			return err
+		}
+		p.white()
+		if p.ch == ',' {
+			p.next()
+			p.white()
+		}
+	}
+	return p.errCode("array-eof")
+}
+
+// checkTrailingErr is checkTrailing for parsers that return no value.
+func (p *hjsonParser) checkTrailingErr(err error) error {
+	_, err = p.checkTrailing(nil, err)
+	return err
+}
+
+// skipKey skips the key of an object member at the current character. If
+// match is set it reports whether the key is want.
+func (p *hjsonParser) skipKey(match bool, want string) (bool, error) {
+	if p.ch == '"' || p.ch == '\'' {
+		if !match {
+			return false, p.skipString(false)
+		}
+		key, err := p.readString(false)
+		return key == want, err
+	}
+	start := p.at - 1
+	end := start
+	for end < len(p.data) && p.data[end] > ' ' && !isPunctuatorChar(p.data[end]) {
+		end++
+	}
+	colon := end
+	for colon < len(p.data) && p.data[colon] > 0 && p.data[colon] <= ' ' {
+		colon++
+	}
+	if colon == len(p.data) || p.data[colon] != ':' || end == start {
+		// let readKeyname report the error
+		key, err := p.readKeyname()
+		return key == want && match, err
+	}
+	p.at = colon
+	p.next()
+	return match && string(p.data[start:end]) == want, nil
+}
+
+// skipString skips the quoted string at the current character like
+// readString reads it, without building its value.
+func (p *hjsonParser) skipString(allowML bool) error {
+	start := p.at
+	exitCh := p.ch
+	for p.next() {
+		switch p.ch {
+		case exitCh:
+			end := p.at - 1
+			p.next()
+			if allowML && exitCh == '\'' && p.ch == '\'' && end == start {
+				// a multiline string, which ends at the next '''
+				i := bytes.Index(p.data[p.at:], []byte("'''"))
+				if i < 0 {
+					p.at = len(p.data)
+					p.next()
+					return p.errCode("bad-multiline-string")
+				}
+				p.at += i + 3
+				p.next()
+			}
+			return nil
+		case '\\':
+			if p.AcceptJSON5 {
+				// see readJSON5Escape
+				p.next()
+				continue
+			}
+			p.next()
+			if p.ch == 'u' {
+				for i := 0; i < 4; i++ {
+					p.next()
+					if !isHexDigit(p.ch) {
+						return p.errCode("bad-unicode-escape", string(p.ch))
+					}
+				}
+			} else if _, ok := escapee[p.ch]; !ok {
+				return p.errCode("bad-escape", string(p.ch))
+			}
+		case '\n', '\r':
+			return p.errCode("string-newline")
+		default:
+			for p.at < len(p.data) && p.data[p.at] != exitCh && p.data[p.at] != '\\' && p.data[p.at] != '\n' && p.data[p.at] != '\r' {
+				p.at++
+			}
+		}
+	}
+	return p.errCode("bad-string")
+}
+
+// skipTfnns skips the quoteless value at the current character like
+// readTfnns reads it, without building its value.
+func (p *hjsonParser) skipTfnns() error {
+	chf := p.ch
+	if p.AcceptJSON5 || len(p.extensions) > 0 || p.ExpandEnv || p.IncludeFS != nil ||
+		chf == 0 || isPunctuatorChar(chf) {
+		_, _, err := p.readTfnns()
+		return err
+	}
+	start := p.at - 1
+	if chf == 'f' || chf == 'n' || chf == 't' || chf == '-' || chf >= '0' && chf <= '9' {
+		// a keyword or number ends at the same characters as in readTfnns
+		end := start + 1
+		for ; end < len(p.data); end++ {
+			if c := p.data[end]; tfnnsEnd[c] &&
+				(c != '/' || end+1 < len(p.data) && (p.data[end+1] == '/' || p.data[end+1] == '*')) {
+				break
+			}
+		}
+		value := p.data[start:end]
+		trimmed := value
+		for len(trimmed) > 0 && trimmed[len(trimmed)-1] <= ' ' {
+			trimmed = trimmed[:len(trimmed)-1]
+		}
+		var ok bool
+		switch chf {
+		case 't', 'f', 'n':
+			ok = string(trimmed) == "true" || string(trimmed) == "false" || string(trimmed) == "null"
+		default:
+			ok = isShortNumber(trimmed)
+			if !ok {
+				_, err := tryParseNumber(value, false)
+				ok = err == nil
+			}
+		}
+		if ok {
+			p.at = end
+			p.next()
+			return nil
+		}
+	}
+	// a string, which always ends at the end of the line
+	end := len(p.data)
+	for _, c := range []byte{'\n', '\r', 0} {
+		if i := bytes.IndexByte(p.data[start:end], c); i >= 0 {
+			end = start + i
+		}
+	}
+	p.at = end
+	p.next()
+	return nil
+}
+
+// Exists reports whether there is a value at the path given to Get.
+func (r Result) Exists() bool {
+	return r.exists
+}
+
+// Value returns the value like Unmarshal decodes it into an interface{}:
+// as a bool, float64, string, []interface{}, map[string]interface{} or nil.
+func (r Result) Value() interface{} {
+	return r.value
+}
+
+// toFloats returns v, decoded with UseNumber, with its json.Number values
+// converted to float64 in place.
+func toFloats(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		f, _ := strconv.ParseFloat(string(v), 64)
+		return f
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = toFloats(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = toFloats(value)
+		}
+	}
+	return v
+}
+
+// String returns a string as it is, a number as written in the document
+// and other values as Marshal writes them; "" if there is no value.
+func (r Result) String() string {
+	if s, ok := r.value.(string); ok {
+		return s
+	} else if r.literal != "" {
+		return r.literal
+	} else if !r.exists {
+		return ""
+	}
+	b, err := Marshal(r.value)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// Int returns a number, or a string holding one, as an int64, truncating
+// fractions and saturating at the limits of int64, and true as 1. Integers
+// are converted exactly. Other values give 0.
+func (r Result) Int() int64 {
+	var text string
+	switch v := r.value.(type) {
+	case float64:
+		text = r.literal
+	case string:
+		text = v
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	default:
+		return 0
+	}
+	if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return n
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	switch {
+	case err != nil && f == 0, math.IsNaN(f):
+		return 0
+	case f >= math.MaxInt64:
+		return math.MaxInt64
+	case f <= math.MinInt64:
+		return math.MinInt64
+	}
+	return int64(f)
+}
+
+// Float returns a number, or a string holding one, as a float64, and true
+// as 1. Other values give 0.
+func (r Result) Float() float64 {
+	switch v := r.value.(type) {
+	case float64:
+		return v
+	case string:
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	case bool:
+		if v {
+			return 1
+		}
+	}
+	return 0
+}
+
+// Bool returns true for true, numbers other than 0 and the strings that
+// strconv.ParseBool takes as true, like "true" and "1".
+func (r Result) Bool() bool {
+	switch v := r.value.(type) {
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		b, _ := strconv.ParseBool(v)
+		return b
+	}
+	return false
+}
+
+// tfnnsEnd holds the characters that end a keyword or number in readTfnns;
+// '/' only if a comment starts with it.
+var tfnnsEnd = [256]bool{'\r': true, '\n': true, 0: true, ',': true, '}': true, ']': true, '#': true, '/': true}
+
+// isShortNumber reports whether text is a number without an exponent that
+// tryParseNumber takes, with few enough digits to skip the check with it.
+func isShortNumber(text []byte) bool {
+	if len(text) > 0 && text[0] == '-' {
+		text = text[1:]
+	}
+	if len(text) == 0 || len(text) > 15 || text[0] == '.' || len(text) > 1 && text[0] == '0' && text[1] != '.' {
+		return false
+	}
+	dot := -1
+	for i, c := range text {
+		if c == '.' && dot < 0 {
+			dot = i
+		} else if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return dot != len(text)-1
+}
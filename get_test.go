@@ -0,0 +1,153 @@
+package hjson
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	data := []byte(`# config
+database: {
+  name: app
+  replicas: [
+    { host: "a.example.com", port: 5432 }
+    { host: "b.example.com", port: 5433, primary: true }
+    { host: "c.example.com", weight: 0.5 }
+  ]
+  id: 9007199254740993
+  big: 1e30
+  enabled: "yes"
+  empty: null
+}
+`)
+	for path, expected := range map[string]struct {
+		str   string
+		i     int64
+		f     float64
+		b     bool
+		value interface{}
+	}{
+		"database.name":                {"app", 0, 0, false, "app"},
+		"database.replicas[1].host":    {"b.example.com", 0, 0, false, "b.example.com"},
+		"database.replicas[1].port":    {"5433", 5433, 5433, true, 5433.0},
+		"database.replicas[1].primary": {"true", 1, 1, true, true},
+		"database.replicas[2].weight":  {"0.5", 0, 0.5, true, 0.5},
+		"database.id":                  {"9007199254740993", 9007199254740993, 9007199254740992, true, 9007199254740992.0},
+		"database.big":                 {"1e30", math.MaxInt64, 1e30, true, 1e30},
+		"database.enabled":             {"yes", 0, 0, false, "yes"},
+		"database.empty":               {"null", 0, 0, false, nil},
+		"database.replicas[0]":         {"{\n  host: a.example.com\n  port: 5432\n}", 0, 0, false, map[string]interface{}{"host": "a.example.com", "port": 5432.0}},
+	} {
+		r, err := Get(data, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !r.Exists() || r.String() != expected.str || r.Int() != expected.i || r.Float() != expected.f || r.Bool() != expected.b || !reflect.DeepEqual(r.Value(), expected.value) {
+			t.Errorf("%s: expected %+v, got %q %d %v %v %#v", path, expected, r.String(), r.Int(), r.Float(), r.Bool(), r.Value())
+		}
+	}
+
+	for _, path := range []string{"missing", "database.replicas[3]", "database.name.x", "database[0]", "database.replicas.host"} {
+		r, err := Get(data, path)
+		if err != nil || r.Exists() || r.String() != "" || r.Int() != 0 || r.Value() != nil {
+			t.Errorf("%s: expected no value, got %#v, %v", path, r, err)
+		}
+	}
+	if r, err := Get([]byte("[1, 2]"), ""); err != nil || !reflect.DeepEqual(r.Value(), []interface{}{1.0, 2.0}) {
+		t.Errorf("expected the root for the empty path, got %#v, %v", r, err)
+	}
+	if _, err := Get(data, "a..b"); err == nil {
+		t.Error("expected an error for a malformed path")
+	}
+	if _, err := Get([]byte("{a: 1"), "a"); err == nil {
+		t.Error("expected an error for invalid Hjson")
+	}
+	if r, err := Get([]byte(`{ n: "42", t: "1" }`), "n"); err != nil || r.Int() != 42 || r.Float() != 42 {
+		t.Errorf("expected 42, got %#v, %v", r, err)
+	}
+}
+
+// getPaths returns the paths of the values below v, decoded by Unmarshal,
+// with the values.
+func getPaths(prefix string, v interface{}, paths map[string]interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if key == "" || strings.ContainsAny(key, ".[]") {
+				continue
+			}
+			path := key
+			if prefix != "" {
+				path = prefix + "." + key
+			}
+			paths[path] = value
+			getPaths(path, value, paths)
+		}
+	case []interface{}:
+		for i, value := range v {
+			path := fmt.Sprintf("%s[%d]", prefix, i)
+			paths[path] = value
+			getPaths(path, value, paths)
+		}
+	}
+}
+
+func TestGetAssets(t *testing.T) {
+	files := strings.Split(string(getContent("assets/testlist.txt")), "\n")
+	for _, file := range files {
+		if file == "" || strings.HasPrefix(file, "stringify/quotes") || strings.HasPrefix(file, "extra/") {
+			continue
+		}
+		name := strings.TrimSuffix(file, "_test"+file[strings.LastIndex(file, "."):])
+		input := getTestContent(name)
+		var v interface{}
+		if err := Unmarshal(input, &v); err != nil {
+			if _, err = Get(input, "a"); err == nil {
+				t.Errorf("%s: expected an error", name)
+			}
+			continue
+		}
+		paths := map[string]interface{}{}
+		getPaths("", v, paths)
+		paths["missing"] = nil
+		for path, expected := range paths {
+			r, err := Get(input, path)
+			if err != nil {
+				t.Errorf("%s: %s: %v", name, path, err)
+			} else if r.Exists() != (path != "missing") || !reflect.DeepEqual(r.Value(), expected) {
+				t.Errorf("%s: %s: expected %#v, got %#v", name, path, expected, r.Value())
+			}
+		}
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	var buf bytes.Buffer
+	buf.WriteString("items: [\n")
+	for i := 0; i < 10000; i++ {
+		fmt.Fprintf(&buf, "  { id: %d, name: \"item %d\", tags: [\"a\", \"b\"], price: %d.5, active: true } # item\n", i, i, i)
+	}
+	buf.WriteString("]\n")
+	data := buf.Bytes()
+	b.Run("Get", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			if r, err := Get(data, "items[5000].price"); err != nil || r.Float() != 5000.5 {
+				b.Fatal(r, err)
+			}
+		}
+	})
+	b.Run("Unmarshal", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			var v interface{}
+			if err := Unmarshal(data, &v); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
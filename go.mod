@@ -1,3 +1,3 @@
 module github.com/hjson/hjson-go
 
-go 1.6
+go 1.20
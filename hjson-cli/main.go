@@ -1,31 +1,51 @@
 package main
 
 import (
-	"bytes"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"github.com/hjson/hjson-go"
+	"io"
 	"io/ioutil"
 	"os"
 )
 
-func fixJSON(data []byte) []byte {
-	data = bytes.Replace(data, []byte("\\u003c"), []byte("<"), -1)
-	data = bytes.Replace(data, []byte("\\u003e"), []byte(">"), -1)
-	data = bytes.Replace(data, []byte("\\u0026"), []byte("&"), -1)
-	data = bytes.Replace(data, []byte("\\u0008"), []byte("\\b"), -1)
-	data = bytes.Replace(data, []byte("\\u000c"), []byte("\\f"), -1)
-	return data
-}
-
 func main() {
 
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "fmt":
+			formatFiles(os.Args[2:])
+			return
+		case "validate":
+			validateFiles(os.Args[2:])
+			return
+		case "diff":
+			diffFiles(os.Args[2:])
+			return
+		case "merge":
+			mergeFiles(os.Args[2:])
+			return
+		case "get":
+			getValue(os.Args[2:])
+			return
+		case "set":
+			setValue(os.Args[2:])
+			return
+		}
+	}
+
 	flag.Usage = func() {
-		fmt.Println("usage: hjson-cli [OPTIONS] [INPUT]")
-		fmt.Println("hjson can be used to convert JSON from/to Hjson.")
+		fmt.Println("usage: hjson-cli [OPTIONS] [INPUT...]")
+		fmt.Println("       hjson-cli fmt [OPTIONS] [INPUT...]")
+		fmt.Println("       hjson-cli validate [OPTIONS] [INPUT...]")
+		fmt.Println("       hjson-cli diff [OPTIONS] FILE1 FILE2")
+		fmt.Println("       hjson-cli merge [OPTIONS] BASE OVERRIDE...")
+		fmt.Println("       hjson-cli get [OPTIONS] FILE PATH")
+		fmt.Println("       hjson-cli set FILE PATH VALUE")
+		fmt.Println("hjson can be used to convert JSON, YAML and TOML from/to Hjson.")
 		fmt.Println("")
-		fmt.Println("hjson will read the given JSON/Hjson input file or read from stdin.")
+		fmt.Println("hjson will read the given JSON/Hjson input files or read from stdin.")
 		fmt.Println("")
 		fmt.Println("Options:")
 		flag.PrintDefaults()
@@ -34,63 +54,514 @@ func main() {
 	var help = flag.Bool("h", false, "Show this screen.")
 	var showJSON = flag.Bool("j", false, "Output as formatted JSON.")
 	var showCompact = flag.Bool("c", false, "Output as JSON.")
+	var showJSONC = flag.Bool("jsonc", false, "Output as JSON with comments, keeping the comments of Hjson input.")
+	var showJSON5 = flag.Bool("json5", false, "Output as JSON5, keeping the comments of Hjson input.")
+	var sortKeys = flag.Bool("sort", false, "Sort the keys of objects instead of keeping their order.")
+	var showYAML = flag.Bool("yaml", false, "Output as YAML, keeping the comments of Hjson input.")
+	var fromYAML = flag.Bool("fromYaml", false, "Read the input as YAML.")
+	var showTOML = flag.Bool("toml", false, "Output as TOML, keeping the comments of Hjson input.")
+	var fromTOML = flag.Bool("fromToml", false, "Read the input as TOML.")
 
 	var indentBy = flag.String("indentBy", "  ", "The indent string.")
-	var bracesSameLine = flag.Bool("bracesSameLine", false, "Print braces on the same line.")
-	var omitRootBraces = flag.Bool("omitRootBraces", false, "Omit braces at the root.")
-	var quoteAlways = flag.Bool("quoteAlways", false, "Always quote string values.")
+	var eol = flag.String("eol", "lf", "The end of line, lf or crlf.")
+	var bracesSameLine bool
+	flag.BoolVar(&bracesSameLine, "bracesSameLine", false, "Print braces on the same line.")
+	flag.BoolVar(&bracesSameLine, "sl", false, "Same as -bracesSameLine.")
+	var quoteAlways bool
+	flag.BoolVar(&quoteAlways, "quoteAlways", false, "Always quote string values.")
+	flag.BoolVar(&quoteAlways, "quote", false, "Same as -quoteAlways.")
 	var allowMinusZero = flag.Bool("allowMinusZero", false, "Allow -0.")
 
 	// var showVersion = flag.Bool("V", false, "Show version.")
 
 	flag.Parse()
-	if *help || flag.NArg() > 1 {
+	if *help {
 		flag.Usage()
 		os.Exit(1)
 	}
 
+	opt := hjson.DefaultOptions()
+	opt.IndentBy = *indentBy
+	opt.BracesSameLine = bracesSameLine
+	opt.QuoteAlways = quoteAlways
+	opt.AllowMinusZero = *allowMinusZero
+	opt.Eol = parseEol(*eol)
+	if *showCompact {
+		opt.OutputFormat = hjson.OutputJSON
+		opt.IndentBy = ""
+	} else if *showJSON {
+		opt.OutputFormat = hjson.OutputJSON
+	} else if *showJSONC {
+		opt.OutputFormat = hjson.OutputJSONC
+	} else if *showJSON5 {
+		opt.OutputFormat = hjson.OutputJSON5
+	}
+
+	decOpt := hjson.DefaultDecoderOptions()
+	decOpt.UseOrderedMap = !*sortKeys
+
+	c := converter{opt: opt, decOpt: decOpt}
+	for _, format := range []struct {
+		name     string
+		from, to bool
+	}{{"yaml", *fromYAML, *showYAML}, {"toml", *fromTOML, *showTOML}} {
+		if format.from {
+			if c.from != "" {
+				fail(fmt.Errorf("cannot use -fromYaml with -fromToml"))
+			}
+			c.from = format.name
+		}
+		if format.to {
+			if c.to != "" {
+				fail(fmt.Errorf("cannot use -yaml with -toml"))
+			}
+			if opt.OutputFormat != hjson.OutputHjson {
+				fail(fmt.Errorf("cannot use -%s with -j, -c, -jsonc or -json5", format.name))
+			}
+			c.to = format.name
+		}
+	}
+
+	if flag.NArg() == 0 {
+		c.convert(os.Stdin)
+	}
+	for _, name := range flag.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			fail(err)
+		}
+		c.convert(f)
+		f.Close()
+	}
+}
+
+// converter converts its input to the output format of opt, or to YAML
+// or TOML.
+type converter struct {
+	opt    hjson.EncoderOptions
+	decOpt hjson.DecoderOptions
+	// The format of the input other than JSON and Hjson, "yaml" or "toml"
+	from string
+	// The format of the output other than opt, "yaml" or "toml"
+	to string
+}
+
+// convert writes the input converted to stdout.
+func (c converter) convert(input io.Reader) {
 	var err error
-	var data []byte
-	if flag.NArg() == 1 {
-		data, err = ioutil.ReadFile(flag.Arg(0))
-	} else {
-		data, err = ioutil.ReadAll(os.Stdin)
+	// compressed input is decompressed transparently
+	if input, err = hjson.DecompressReader(input); err != nil {
+		fail(err)
 	}
+	data, err := ioutil.ReadAll(input)
 	if err != nil {
-		panic(err)
+		fail(err)
+	}
+
+	if c.from != "" {
+		hjsonOpt := c.opt
+		if hjsonOpt.OutputFormat != hjson.OutputHjson {
+			hjsonOpt = hjson.DefaultOptions()
+		}
+		if c.from == "yaml" {
+			data, err = hjson.FromYAML(data, hjsonOpt)
+		} else {
+			data, err = hjson.FromTOML(data, hjsonOpt)
+		}
+		if err != nil {
+			fail(err)
+		}
+		if c.to == "" && c.opt.OutputFormat == hjson.OutputHjson && c.decOpt.UseOrderedMap {
+			// already formatted, with the comments of the input
+			os.Stdout.Write(data)
+			return
+		}
+	}
+	if c.to != "" {
+		if c.to == "yaml" {
+			data, err = hjson.ToYAML(data)
+		} else {
+			data, err = hjson.ToTOML(data)
+		}
+		if err != nil {
+			fail(err)
+		}
+		os.Stdout.Write(data)
+		return
+	}
+	if (c.opt.OutputFormat == hjson.OutputJSONC || c.opt.OutputFormat == hjson.OutputJSON5) && c.decOpt.UseOrderedMap {
+		// formatted with the comments of the input
+		if data, err = hjson.Format(data, c.opt); err != nil {
+			fail(err)
+		}
+		os.Stdout.Write(data)
+		return
 	}
 
 	var value interface{}
+	if err = hjson.UnmarshalWithOptions(data, &value, c.decOpt); err != nil {
+		fail(err)
+	}
+	if err = hjson.NewEncoder(os.Stdout, c.opt).Encode(value); err != nil {
+		fail(err)
+	}
+}
 
-	if err := hjson.Unmarshal(data, &value); err != nil {
-		panic(err)
+// formatFiles implements the fmt subcommand, which formats Hjson files
+// keeping their comments.
+func formatFiles(args []string) {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Println("usage: hjson-cli fmt [OPTIONS] [INPUT...]")
+		fmt.Println("hjson fmt formats Hjson files, keeping their comments.")
+		fmt.Println("")
+		fmt.Println("hjson fmt will read the given Hjson input files or read from stdin.")
+		fmt.Println("")
+		fmt.Println("Options:")
+		flags.PrintDefaults()
 	}
+	var write = flags.Bool("w", false, "Write the result to the input files instead of stdout.")
+	var list = flags.Bool("l", false, "List the files whose formatting differs and exit with status 1 if there are any.")
+	var indentBy = flags.String("indentBy", "  ", "The indent string.")
+	var eol = flags.String("eol", "lf", "The end of line, lf or crlf.")
+	var bracesSameLine = flags.Bool("bracesSameLine", false, "Print braces on the same line.")
+	var quoteAlways = flags.Bool("quoteAlways", false, "Always quote string values.")
+	flags.Parse(args)
 
-	var out []byte
-	if *showCompact {
-		out, err = json.Marshal(value)
+	opt := hjson.DefaultOptions()
+	opt.IndentBy = *indentBy
+	opt.Eol = parseEol(*eol)
+	opt.BracesSameLine = *bracesSameLine
+	opt.QuoteAlways = *quoteAlways
+
+	if flags.NArg() == 0 {
+		if *write {
+			fail(fmt.Errorf("cannot use -w with stdin"))
+		}
+		data, err := ioutil.ReadAll(os.Stdin)
 		if err != nil {
-			panic(err)
+			fail(err)
 		}
-		out = fixJSON(out)
-	} else if *showJSON {
-		out, err = json.MarshalIndent(value, "", *indentBy)
+		out, err := hjson.Format(data, opt)
 		if err != nil {
-			panic(err)
+			fail(err)
 		}
-		out = fixJSON(out)
+		if *list {
+			if string(out) != string(data) {
+				fmt.Println("<stdin>")
+				os.Exit(1)
+			}
+			return
+		}
+		os.Stdout.Write(out)
+		return
+	}
+
+	differs := false
+	for _, name := range flags.Args() {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			fail(err)
+		}
+		out, err := hjson.Format(data, opt)
+		if err != nil {
+			fail(fmt.Errorf("%s: %v", name, err))
+		}
+		changed := string(out) != string(data)
+		differs = differs || changed
+		if *list && changed {
+			fmt.Println(name)
+		}
+		if *write && changed {
+			info, err := os.Stat(name)
+			if err != nil {
+				fail(err)
+			}
+			if err = ioutil.WriteFile(name, out, info.Mode().Perm()); err != nil {
+				fail(err)
+			}
+		}
+		if !*list && !*write {
+			os.Stdout.Write(out)
+		}
+	}
+	if *list && differs {
+		os.Exit(1)
+	}
+}
+
+// fileDiagnostic is a Diagnostic of the validate subcommand with -json.
+type fileDiagnostic struct {
+	File string `json:"file"`
+	hjson.Diagnostic
+}
+
+// validateFiles implements the validate subcommand, which reports the
+// problems found by hjson.ValidateWithOptions and exits with status 1 if there are
+// errors.
+func validateFiles(args []string) {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Println("usage: hjson-cli validate [OPTIONS] [INPUT...]")
+		fmt.Println("hjson validate checks Hjson files and reports errors and warnings as")
+		fmt.Println("FILE:LINE:COLUMN: SEVERITY: MESSAGE")
+		fmt.Println("")
+		fmt.Println("hjson validate will read the given Hjson input files or read from stdin.")
+		fmt.Println("")
+		fmt.Println("Options:")
+		flags.PrintDefaults()
+	}
+	var asJSON = flags.Bool("json", false, "Output the problems as a JSON array.")
+	var maxErrors = flags.Int("maxErrors", 1, "Report up to this many syntax errors of each file.")
+	flags.Parse(args)
+	options := hjson.DefaultDecoderOptions()
+	options.MaxErrors = *maxErrors
+
+	names := flags.Args()
+	if len(names) == 0 {
+		names = []string{"<stdin>"}
+	}
+	all := []fileDiagnostic{}
+	failed := false
+	for _, name := range names {
+		var data []byte
+		var err error
+		if flags.NArg() == 0 {
+			data, err = ioutil.ReadAll(os.Stdin)
+		} else {
+			data, err = ioutil.ReadFile(name)
+		}
+		if err != nil {
+			fail(err)
+		}
+		for _, d := range hjson.ValidateWithOptions(data, options) {
+			failed = failed || d.Severity == hjson.SeverityError
+			all = append(all, fileDiagnostic{name, d})
+		}
+	}
+
+	if *asJSON {
+		out, err := json.MarshalIndent(all, "", "  ")
+		if err != nil {
+			fail(err)
+		}
+		fmt.Println(string(out))
 	} else {
+		for _, d := range all {
+			fmt.Printf("%s:%d:%d: %s: %s\n", d.File, d.Line, d.Column, d.Severity, d.Message)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// diffFiles implements the diff subcommand, which prints the changes
+// between the values of two files, one per line, and exits with status 1 if
+// there are any.
+func diffFiles(args []string) {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Println("usage: hjson-cli diff [OPTIONS] FILE1 FILE2")
+		fmt.Println("hjson diff prints the values that were added (+), removed (-) or changed (~)")
+		fmt.Println("from FILE1 to FILE2, ignoring their formatting and comments.")
+		fmt.Println("")
+		fmt.Println("Options:")
+		flags.PrintDefaults()
+	}
+	var comments = flags.Bool("comments", false, "Also print the values with changed comments (#).")
+	flags.Parse(args)
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	changes := hjson.DiffWithOptions(readNode(flags.Arg(0)), readNode(flags.Arg(1)),
+		hjson.DiffOptions{Comments: *comments})
+	for _, c := range changes {
+		fmt.Println(c)
+	}
+	if len(changes) > 0 {
+		os.Exit(1)
+	}
+}
+
+// mergeFiles implements the merge subcommand, which prints the first file
+// with the others merged into it in turn.
+func mergeFiles(args []string) {
+	flags := flag.NewFlagSet("merge", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Println("usage: hjson-cli merge [OPTIONS] BASE OVERRIDE...")
+		fmt.Println("hjson merge merges the OVERRIDE files into BASE, in order, and prints the")
+		fmt.Println("result with the comments of BASE. Objects are merged recursively.")
+		fmt.Println("")
+		fmt.Println("Options:")
+		flags.PrintDefaults()
+	}
+	var arrays = flags.String("arrays", "replace", "How to merge arrays: replace, append or index.")
+	var showJSON = flags.Bool("j", false, "Output as formatted JSON.")
+	flags.Parse(args)
+	if flags.NArg() < 2 {
+		flags.Usage()
+		os.Exit(1)
+	}
+	var strategy hjson.MergeStrategy
+	switch *arrays {
+	case "replace":
+		strategy = hjson.MergeReplace
+	case "append":
+		strategy = hjson.MergeAppend
+	case "index":
+		strategy = hjson.MergeByIndex
+	default:
+		fail(fmt.Errorf("unknown array strategy %q", *arrays))
+	}
+
+	root := readNode(flags.Arg(0))
+	for _, name := range flags.Args()[1:] {
+		if err := hjson.DeepMerge(root, readNode(name), strategy); err != nil {
+			fail(fmt.Errorf("%s: %v", name, err))
+		}
+	}
+	if *showJSON {
 		opt := hjson.DefaultOptions()
-		opt.IndentBy = *indentBy
-		opt.BracesSameLine = *bracesSameLine
-		opt.EmitRootBraces = !*omitRootBraces
-		opt.QuoteAlways = *quoteAlways
-		opt.AllowMinusZero = *allowMinusZero
-		out, err = hjson.MarshalWithOptions(value, opt)
-		if err != nil {
-			panic(err)
+		opt.OutputFormat = hjson.OutputJSON
+		if err := hjson.NewEncoder(os.Stdout, opt).Encode(nodeValue(root)); err != nil {
+			fail(err)
+		}
+		return
+	}
+	out, err := root.Marshal()
+	if err != nil {
+		fail(err)
+	}
+	os.Stdout.Write(out)
+}
+
+// getValue implements the get subcommand, which prints the value at a path
+// like server.ports[0] in a file. Strings are printed as they are, other
+// values as Hjson or JSON.
+func getValue(args []string) {
+	flags := flag.NewFlagSet("get", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Println("usage: hjson-cli get [OPTIONS] FILE PATH")
+		fmt.Println("hjson get prints the value at PATH, like server.ports[0], in FILE.")
+		fmt.Println("")
+		fmt.Println("Options:")
+		flags.PrintDefaults()
+	}
+	var showJSON = flags.Bool("j", false, "Output as formatted JSON.")
+	flags.Parse(args)
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	n := lookup(readNode(flags.Arg(0)), flags.Arg(1))
+	if n == nil {
+		fail(fmt.Errorf("%s: %s not found", flags.Arg(0), flags.Arg(1)))
+	}
+	if s, ok := n.Value.(string); ok && !*showJSON {
+		fmt.Println(s)
+		return
+	}
+	opt := hjson.DefaultOptions()
+	if *showJSON {
+		opt.OutputFormat = hjson.OutputJSON
+	}
+	if err := hjson.NewEncoder(os.Stdout, opt).Encode(nodeValue(n)); err != nil {
+		fail(err)
+	}
+}
+
+// setValue implements the set subcommand, which changes the value at a path
+// in a file, keeping its comments and formatting. The new value is Hjson,
+// so 8080 is a number and "8080" a string.
+func setValue(args []string) {
+	if len(args) != 3 {
+		fmt.Println("usage: hjson-cli set FILE PATH VALUE")
+		fmt.Println("hjson set changes the value at PATH, like server.ports[0], in FILE to the")
+		fmt.Println("Hjson VALUE, keeping the comments and formatting of FILE.")
+		os.Exit(1)
+	}
+	name, path := args[0], args[1]
+
+	// parse the value on a line of its own, where a:b is a string
+	value, err := hjson.ParseNode([]byte("[\n" + args[2] + "\n]"))
+	if err != nil || len(value.Children) != 1 {
+		fail(fmt.Errorf("invalid value %q", args[2]))
+	}
+	root := readNode(name)
+	if err = root.SetPath(path, nodeValue(value.Children[0])); err != nil {
+		fail(err)
+	}
+	out, err := root.Marshal()
+	if err != nil {
+		fail(err)
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		fail(err)
+	}
+	if err = ioutil.WriteFile(name, out, info.Mode().Perm()); err != nil {
+		fail(err)
+	}
+}
+
+// readNode parses the file name.
+func readNode(name string) *hjson.Node {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		fail(err)
+	}
+	root, err := hjson.ParseNode(data)
+	if err != nil {
+		fail(fmt.Errorf("%s: %v", name, err))
+	}
+	return root
+}
+
+// lookup returns the node at path below root, or nil if there is none.
+func lookup(root *hjson.Node, path string) *hjson.Node {
+	n, err := root.Lookup(path)
+	if err != nil {
+		fail(err)
+	}
+	return n
+}
+
+// nodeValue returns the value of n, keeping the order of object members.
+func nodeValue(n *hjson.Node) interface{} {
+	switch n.Kind {
+	case hjson.ObjectNode:
+		object := hjson.NewOrderedMap()
+		for _, c := range n.Children {
+			object.Set(c.Key, nodeValue(c))
+		}
+		return object
+	case hjson.ArrayNode:
+		array := make([]interface{}, len(n.Children))
+		for i, c := range n.Children {
+			array[i] = nodeValue(c)
 		}
+		return array
 	}
+	return n.Value
+}
+
+// parseEol returns the end of line named by the -eol flag.
+func parseEol(name string) string {
+	switch name {
+	case "lf":
+		return "\n"
+	case "crlf":
+		return "\r\n"
+	}
+	fail(fmt.Errorf("unknown end of line %q", name))
+	return ""
+}
 
-	fmt.Println(string(out))
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "hjson:", err)
+	os.Exit(1)
 }
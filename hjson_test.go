@@ -6,10 +6,15 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"math"
+	"net"
 	"os"
 	"path/filepath"
+	"reflect"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 )
 
 func getContent(file string) []byte {
@@ -115,3 +120,543 @@ func TestNilValue(t *testing.T) {
 		panic("Passing v = <nil> to Unmarshal should return an error")
 	}
 }
+
+func BenchmarkUnmarshal(b *testing.B) {
+	var buf bytes.Buffer
+	line := strings.Repeat("lorem ipsum dolor sit amet ", 8)
+	buf.WriteString("{\n")
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&buf, "  # %s\n  q%d: \"%s\"\n  s%d: %s\n", line, i, line, i, line)
+	}
+	buf.WriteString("}\n")
+	data := buf.Bytes()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var v interface{}
+		if err := Unmarshal(data, &v); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestMaxAlloc(t *testing.T) {
+	data := []byte(`{a: "` + strings.Repeat("x", 1000) + `", b: [1, 2, 3]}`)
+	var v interface{}
+
+	opt := DefaultDecoderOptions()
+	opt.MaxAlloc = 2000
+	if err := UnmarshalWithOptions(data, &v, opt); err != nil {
+		t.Error(err)
+	}
+
+	opt.MaxAlloc = 500
+	err := UnmarshalWithOptions(data, &v, opt)
+	if err == nil || !strings.Contains(err.Error(), "memory budget") {
+		t.Errorf("expected a memory budget error, got %v", err)
+	}
+
+	// the budget is checked before the memory is allocated
+	opt.MaxAlloc = 1000
+	for _, s := range []string{strings.Repeat("x", 1<<22), strings.Repeat(`x\n`, 1<<20)} {
+		data = []byte(`{a: "` + s + `"}`)
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		err = UnmarshalWithOptions(data, &v, opt)
+		runtime.ReadMemStats(&after)
+		if err == nil || !strings.Contains(err.Error(), "memory budget") {
+			t.Errorf("expected a memory budget error, got %v", err)
+		}
+		if n := after.TotalAlloc - before.TotalAlloc; n > 1<<20 {
+			t.Errorf("expected the string not to be allocated, %d bytes were", n)
+		}
+	}
+	var blocks [][4096]byte
+	if err = UnmarshalWithOptions([]byte("[[], [], []]"), &blocks, opt); err == nil || !strings.Contains(err.Error(), "memory budget") {
+		t.Errorf("expected a memory budget error for the elements, got %v", err)
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	data := []byte("a: [[{b: [1]}]]")
+	var v interface{}
+
+	opt := DefaultDecoderOptions()
+	opt.MaxDepth = 5
+	if err := UnmarshalWithOptions(data, &v, opt); err != nil {
+		t.Error(err)
+	}
+
+	opt.MaxDepth = 4
+	err := UnmarshalWithOptions(data, &v, opt)
+	if err == nil || !strings.Contains(err.Error(), "maximum nesting depth of 4") {
+		t.Errorf("expected a depth error, got %v", err)
+	}
+}
+
+func TestInputLimits(t *testing.T) {
+	data := []byte("name: '''\n  abcdef\n  '''\nlist: [\n  \"abc\"\n  abcdefgh\n]\nabcde: 1")
+	var v interface{}
+
+	opt := DefaultDecoderOptions()
+	opt.MaxInputBytes = len(data)
+	opt.MaxStringLen = 8
+	if err := UnmarshalWithOptions(data, &v, opt); err != nil {
+		t.Error(err)
+	}
+
+	opt.MaxInputBytes = len(data) - 1
+	err := UnmarshalWithOptions(data, &v, opt)
+	if err == nil || !strings.Contains(err.Error(), "exceeds the limit of") {
+		t.Errorf("expected an input size error, got %v", err)
+	}
+
+	opt.MaxInputBytes = 0
+	for _, n := range []int{4, 5, 7} {
+		opt.MaxStringLen = n
+		err = UnmarshalWithOptions(data, &v, opt)
+		if err == nil || !strings.Contains(err.Error(), "maximum string length") {
+			t.Errorf("%d: expected a string length error, got %v", n, err)
+		}
+	}
+	var typed struct {
+		N int `json:",string"`
+	}
+	opt.MaxStringLen = 2
+	if err = UnmarshalWithOptions([]byte("N: '123'"), &typed, opt); err == nil {
+		t.Error("expected a string length error for a quoted field")
+	}
+}
+
+func TestDuplicateKeys(t *testing.T) {
+	data := []byte("a: 1\nb: {c: 2}\na: 3")
+	opt := DefaultDecoderOptions()
+	for policy, expected := range map[DuplicateKeyPolicy]float64{DuplicateKeyLast: 3, DuplicateKeyFirst: 1} {
+		opt.DuplicateKeys = policy
+		var generic map[string]interface{}
+		if err := UnmarshalWithOptions(data, &generic, opt); err != nil || generic["a"] != expected {
+			t.Errorf("policy %d: expected %v, got %v, %v", policy, expected, generic["a"], err)
+		}
+		var typed struct{ A int }
+		if err := UnmarshalWithOptions(data, &typed, opt); err != nil || typed.A != int(expected) {
+			t.Errorf("policy %d: expected %v, got %v, %v", policy, expected, typed.A, err)
+		}
+		var m map[string]interface{}
+		if err := UnmarshalWithOptions(data, &m, opt); err != nil || m["a"] != expected {
+			t.Errorf("policy %d: expected %v, got %v, %v", policy, expected, m["a"], err)
+		}
+	}
+
+	opt.DuplicateKeys = DuplicateKeyError
+	var v interface{}
+	err := UnmarshalWithOptions(data, &v, opt)
+	if err == nil || !strings.HasPrefix(err.Error(), "Found duplicate key 'a' (first found at line 1) at line 3") {
+		t.Errorf("expected a duplicate key error, got %v", err)
+	}
+
+	data = []byte(`server: {
+  port: 80
+  tls: { cert: "a.pem" }
+  hosts: ["a"]
+}
+name: x
+server: {
+  tls: { key: "a.key" }
+  hosts: ["b"]
+}
+name: y`)
+	opt.DuplicateKeys = DuplicateKeyMerge
+	expected := map[string]interface{}{
+		"server": map[string]interface{}{
+			"port":  80.0,
+			"tls":   map[string]interface{}{"cert": "a.pem", "key": "a.key"},
+			"hosts": []interface{}{"b"},
+		},
+		"name": "y",
+	}
+	if err = UnmarshalWithOptions(data, &v, opt); err != nil || !reflect.DeepEqual(v, expected) {
+		t.Errorf("expected %v, got %v, %v", expected, v, err)
+	}
+	var m map[string]interface{}
+	if err = UnmarshalWithOptions(data, &m, opt); err != nil || !reflect.DeepEqual(m, expected) {
+		t.Errorf("expected %v, got %v, %v", expected, m, err)
+	}
+	var typed struct {
+		Server struct {
+			Port  int
+			TLS   map[string]string
+			Hosts []string
+		}
+		Name string
+	}
+	if err = UnmarshalWithOptions(data, &typed, opt); err != nil || typed.Server.Port != 80 ||
+		!reflect.DeepEqual(typed.Server.TLS, map[string]string{"cert": "a.pem", "key": "a.key"}) ||
+		!reflect.DeepEqual(typed.Server.Hosts, []string{"b"}) || typed.Name != "y" {
+		t.Errorf("unexpected %+v, %v", typed, err)
+	}
+	opt.UseOrderedMap = true
+	if err = UnmarshalWithOptions(data, &v, opt); err != nil {
+		t.Fatal(err)
+	}
+	server, _ := v.(*OrderedMap).Get("server")
+	if tls, _ := server.(*OrderedMap).Get("tls"); !reflect.DeepEqual(tls.(*OrderedMap).Keys, []string{"cert", "key"}) {
+		t.Errorf("unexpected %v", tls)
+	}
+	var om OrderedMap
+	if err = UnmarshalWithOptions([]byte("a: {b: 1}\na: {c: 2}"), &struct{ A *OrderedMap }{&om}, opt); err != nil || !reflect.DeepEqual(om.Keys, []string{"b", "c"}) {
+		t.Errorf("unexpected %v, %v", om, err)
+	}
+}
+
+func TestDisallowUnknownFields(t *testing.T) {
+	var v struct {
+		Name  string
+		Inner struct{ Port int }
+	}
+	data := []byte("name: x\ninner: {\n  port: 80\n  prot: 81\n}")
+	if err := Unmarshal(data, &v); err != nil {
+		t.Error(err)
+	}
+
+	opt := DefaultDecoderOptions()
+	opt.DisallowUnknownFields = true
+	err := UnmarshalWithOptions(data, &v, opt)
+	if err == nil || !strings.HasPrefix(err.Error(), "Unknown field 'prot' for type struct { Port int }, did you mean 'Port'? at line 4,") {
+		t.Errorf("expected an unknown field error, got %v", err)
+	}
+	type config struct {
+		Timeout  int
+		Host     string `json:"hostname"`
+		MaxConns int
+	}
+	for input, expected := range map[string]string{
+		"{timout: 1}":    "Unknown field 'timout' for type hjson.config, did you mean 'Timeout'? at line 1",
+		"{hostnmae: a}":  "Unknown field 'hostnmae' for type hjson.config, did you mean 'hostname'? at line 1",
+		"{max_conns: 1}": "Unknown field 'max_conns' for type hjson.config, did you mean 'MaxConns'? at line 1",
+		"{host: a}":      "Unknown field 'host' for type hjson.config at line 1",
+		"{x: 1}":         "Unknown field 'x' for type hjson.config at line 1",
+		// a root object without braces is not read as a single string
+		"timout: 1":           "Unknown field 'timout' for type hjson.config, did you mean 'Timeout'? at line 1",
+		"# config\nhost: a\n": "Unknown field 'host' for type hjson.config at line 2",
+	} {
+		var c config
+		if err = UnmarshalWithOptions([]byte(input), &c, opt); err == nil || !strings.HasPrefix(err.Error(), expected) {
+			t.Errorf("%s: expected an error with %q, got %v", input, expected, err)
+		}
+	}
+	var p struct{ Port int }
+	if err = UnmarshalWithOptions([]byte("prot: 1"), &p, opt); err == nil || !strings.HasPrefix(err.Error(), "Unknown field 'prot' for type struct { Port int }, did you mean 'Port'?") {
+		t.Errorf("expected an unknown field error, got %v", err)
+	}
+	var m map[string]interface{}
+	if err = UnmarshalWithOptions(data, &m, opt); err != nil {
+		t.Errorf("expected no error for a map, got %v", err)
+	}
+}
+
+func TestUseNumber(t *testing.T) {
+	data := []byte("id: 12345678901234567890\nratio: 1.50\nlist: [1, -2e3]\nname: 7 dwarfs")
+	opt := DefaultDecoderOptions()
+	opt.UseNumber = true
+	opt.OnWarning = func(w Warning) {
+		t.Errorf("unexpected warning %v", w)
+	}
+	var v interface{}
+	if err := UnmarshalWithOptions(data, &v, opt); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"id":    json.Number("12345678901234567890"),
+		"ratio": json.Number("1.50"),
+		"list":  []interface{}{json.Number("1"), json.Number("-2e3")},
+		"name":  "7 dwarfs",
+	}
+	if !reflect.DeepEqual(v, expected) {
+		t.Errorf("expected\n%#v\ngot\n%#v", expected, v)
+	}
+
+	var s struct {
+		ID    interface{}
+		Ratio float64
+	}
+	opt.OnWarning = nil
+	if err := UnmarshalWithOptions(data, &s, opt); err != nil {
+		t.Fatal(err)
+	}
+	if s.ID != json.Number("12345678901234567890") || s.Ratio != 1.5 {
+		t.Errorf("unexpected %#v", s)
+	}
+
+	out, err := Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "id: 12345678901234567890") {
+		t.Errorf("expected the literal to be kept, got\n%s", out)
+	}
+}
+
+func TestSyntaxError(t *testing.T) {
+	var v interface{}
+	err := Unmarshal([]byte("a: 1\nb: [\n  2\n  3}\n"), &v)
+	var se *SyntaxError
+	if !errors.As(err, &se) {
+		t.Fatalf("expected a SyntaxError, got %#v", err)
+	}
+	if se.Line != 4 || se.Column != 4 || se.Offset != 17 || se.Snippet != "  3}\n" {
+		t.Errorf("unexpected position %#v", se)
+	}
+	if err.Error() != se.Msg+" at line 4,4 >>> "+se.Snippet {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+
+	// the first line counts like the others
+	err = Unmarshal([]byte("{a: 1]"), &v)
+	if !errors.As(err, &se) || se.Line != 1 || se.Column != 6 || se.Offset != 5 || se.Snippet != "{a: 1]" {
+		t.Errorf("unexpected position %#v", err)
+	}
+	err = Unmarshal([]byte("\n[1}"), &v)
+	if !errors.As(err, &se) || se.Line != 2 || se.Column != 3 || se.Offset != 3 || se.Snippet != "[1}" {
+		t.Errorf("unexpected position %#v", err)
+	}
+
+	var s struct{ A int }
+	err = Unmarshal([]byte("a: x"), &s)
+	if !errors.As(err, &se) || se.Line != 1 || !strings.HasPrefix(se.Msg, "Cannot unmarshal") {
+		t.Errorf("expected the position of the type error, got %#v", err)
+	}
+
+	// a member without a value at the end of the input
+	if err = Unmarshal([]byte("a: 1\nb:"), &v); !errors.As(err, &se) {
+		t.Errorf("expected a SyntaxError, got %#v", err)
+	}
+	if err = Unmarshal([]byte("http://example.com"), &v); err != nil || v != "http://example.com" {
+		t.Errorf("expected a string, got %#v, %v", v, err)
+	}
+}
+
+func TestUnmarshalTypeError(t *testing.T) {
+	type server struct {
+		Host    string
+		Timeout int
+	}
+	var c struct {
+		Servers []server
+		Limits  map[string]uint8
+	}
+	for input, expected := range map[string]string{
+		"servers: [\n  { host: \"a\", timeout: 5 }\n  { host: \"b\", timeout: \"5s\" }\n]": "servers[1].timeout",
+		"limits: {\n  conns: 300\n}": "limits.conns",
+		"servers: [[1]]":             "servers[0]",
+		"servers: 1":                 "servers",
+	} {
+		err := Unmarshal([]byte(input), &c)
+		var ute *UnmarshalTypeError
+		if !errors.As(err, &ute) || ute.Path != expected || !strings.HasPrefix(err.Error(), expected+": Cannot unmarshal") {
+			t.Errorf("%q: expected a type error at %s, got %v", input, expected, err)
+		}
+	}
+
+	var n int
+	err := Unmarshal([]byte(`"x"`), &n)
+	var ute *UnmarshalTypeError
+	if !errors.As(err, &ute) || ute.Path != "" || ute.Value != "string" || ute.Type != reflect.TypeOf(0) || ute.Line != 1 {
+		t.Errorf("unexpected %#v", err)
+	}
+	if err.Error() != ute.SyntaxError.Error() {
+		t.Errorf("expected no path in %q", err.Error())
+	}
+}
+
+func TestLargeIntegers(t *testing.T) {
+	type ints struct {
+		U   uint64
+		I   int64
+		Min int64
+		P   *int64
+		E   int
+	}
+	value := ints{math.MaxUint64, 9007199254740993, math.MinInt64, nil, 1000}
+	buf, err := Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\n  U: 18446744073709551615\n  I: 9007199254740993\n  Min: -9223372036854775808\n  P: null\n  E: 1000\n}"
+	if string(buf) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf)
+	}
+
+	opt := DefaultDecoderOptions()
+	opt.OnWarning = func(w Warning) {
+		t.Errorf("unexpected warning %v", w)
+	}
+	var decoded ints
+	data := strings.Replace(string(buf), "null", "9007199254740995", 1)
+	data = strings.Replace(data, "1000", "1e3", 1)
+	if err = UnmarshalWithOptions([]byte(data), &decoded, opt); err != nil {
+		t.Fatal(err)
+	}
+	value.P = new(int64)
+	*value.P = 9007199254740995
+	if !reflect.DeepEqual(decoded, value) {
+		t.Errorf("expected %+v, got %+v", value, decoded)
+	}
+
+	for _, data := range []string{"u: 18446744073709551616", "u: -1", "i: 9223372036854775808", "e: 1.5"} {
+		if err := Unmarshal([]byte(data), &decoded); err == nil {
+			t.Errorf("%s: expected an error", data)
+		}
+	}
+}
+
+type testDecodeInner struct {
+	Name string
+	Tags []string
+}
+
+type testDecodeStruct struct {
+	Count   int `json:"count"`
+	Ratio   float32
+	Enabled bool
+	Version string
+	Inner   testDecodeInner
+	Ptr     *testDecodeInner
+	Nil     *int
+	Pair    [2]int
+	Ports   map[string]uint16
+	Any     interface{}
+	Skipped string `json:"-"`
+	private int
+}
+
+func TestUnmarshalStruct(t *testing.T) {
+	data := []byte(`
+	# comments are fine
+	count: 3
+	ratio: 0.5
+	ENABLED: true
+	version: 1.10
+	inner: {
+		name: foo bar
+		tags: [
+			a
+			"b"
+		]
+	}
+	ptr: { name: "baz" }
+	nil: null
+	pair: [1, 2, 3]
+	ports: { http: 80, https: 443 }
+	any: [1, {x: "y"}]
+	Skipped: no
+	private: 1
+	unknown: { ignored: [1, 2] }
+	`)
+	v := testDecodeStruct{Nil: new(int)}
+	if err := Unmarshal(data, &v); err != nil {
+		t.Fatal(err)
+	}
+	expected := testDecodeStruct{
+		Count:   3,
+		Ratio:   0.5,
+		Enabled: true,
+		Version: "1.10",
+		Inner:   testDecodeInner{Name: "foo bar", Tags: []string{"a", "b"}},
+		Ptr:     &testDecodeInner{Name: "baz"},
+		Pair:    [2]int{1, 2},
+		Ports:   map[string]uint16{"http": 80, "https": 443},
+		Any:     []interface{}{1.0, map[string]interface{}{"x": "y"}},
+	}
+	if !reflect.DeepEqual(v, expected) {
+		t.Errorf("expected\n%#v\ngot\n%#v", expected, v)
+	}
+}
+
+func TestUnmarshalTypeMismatch(t *testing.T) {
+	var v testDecodeStruct
+	for _, data := range []string{
+		`count: abc`,
+		`count: 1.5`,
+		`inner: [1]`,
+		`ports: { http: 70000 }`,
+		`pair: {}`,
+	} {
+		if err := Unmarshal([]byte(data), &v); err == nil {
+			t.Errorf("expected an error for %q", data)
+		}
+	}
+
+	var s string
+	if err := Unmarshal([]byte(`a: 1`), &s); err == nil {
+		t.Error("expected an error for an object decoded into a string")
+	}
+	if err := Unmarshal([]byte(`a 1`), &s); err != nil || s != "a 1" {
+		t.Errorf("expected a quoteless string, got %q (%v)", s, err)
+	}
+
+	var n *int
+	if err := Unmarshal([]byte(`5`), &n); err != nil || n == nil || *n != 5 {
+		t.Errorf("expected a pointer to 5, got %v (%v)", n, err)
+	}
+	if err := Unmarshal([]byte(`null`), &n); err != nil || n != nil {
+		t.Errorf("expected a nil pointer, got %v (%v)", n, err)
+	}
+}
+
+type testTextKey struct{ zone, name string }
+
+func (k testTextKey) MarshalText() ([]byte, error) {
+	return []byte(k.zone + "/" + k.name), nil
+}
+
+func (k *testTextKey) UnmarshalText(text []byte) error {
+	parts := strings.Split(string(text), "/")
+	if len(parts) != 2 {
+		return errors.New("Bad key " + string(text))
+	}
+	k.zone, k.name = parts[0], parts[1]
+	return nil
+}
+
+func TestTextMarshaler(t *testing.T) {
+	type hosts struct {
+		Primary net.IP
+		Byname  map[string]net.IP
+		Byzone  map[testTextKey]string
+		Ports   map[int]string
+		Since   time.Time
+	}
+	value := hosts{
+		Primary: net.ParseIP("10.0.0.1"),
+		Byname:  map[string]net.IP{"a": net.ParseIP("::1")},
+		Byzone:  map[testTextKey]string{{"eu", "b"}: "10.0.0.2"},
+		Ports:   map[int]string{443: "https", 80: "http"},
+		Since:   time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	buf, err := Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\n  Primary: 10.0.0.1\n  Byname:\n  {\n    a: \"::1\"\n  }\n  Byzone:\n  {\n    eu/b: 10.0.0.2\n  }\n  Ports:\n  {\n    443: https\n    80: http\n  }\n  Since: 2020-01-02T03:04:05Z\n}"
+	if string(buf) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf)
+	}
+
+	var decoded hosts
+	if err = Unmarshal(buf, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded.Byzone, value.Byzone) || !reflect.DeepEqual(decoded.Ports, value.Ports) ||
+		!decoded.Primary.Equal(value.Primary) || !decoded.Byname["a"].Equal(value.Byname["a"]) || !decoded.Since.Equal(value.Since) {
+		t.Errorf("expected %v, got %v", value, decoded)
+	}
+
+	for _, data := range []string{"Primary: 1.2.3", "Primary: {}", "Since: 12", "Ports: {x: y}", "Byzone: {\"x\": y}"} {
+		if err = Unmarshal([]byte(data), &decoded); err == nil {
+			t.Errorf("expected an error for %s", data)
+		}
+	}
+	if _, err = Marshal(map[float64]int{1: 1}); err == nil {
+		t.Error("expected an error for a float map key")
+	}
+}
@@ -0,0 +1,110 @@
+// Package hjsonbind lets web frameworks accept Hjson request bodies and send
+// Hjson responses.
+//
+// Binding implements the binding.Binding and binding.BindingBody interfaces
+// of Gin, and Render implements its render.Render interface, without this
+// package depending on Gin:
+//
+//	b := hjsonbind.Binding{Validate: binding.Validator.ValidateStruct}
+//	if err := c.ShouldBindWith(&config, b); err != nil { ... }
+//	c.Render(http.StatusOK, hjsonbind.Render{Data: config, Options: hjson.DefaultOptions()})
+//
+// For Echo, whose interfaces take an echo.Context, wrap the default binder
+// and send Hjson with Blob:
+//
+//	type binder struct{ echo.DefaultBinder }
+//
+//	func (b *binder) Bind(i interface{}, c echo.Context) error {
+//		if hjsonbind.IsHjson(c.Request()) {
+//			return hjsonbind.Binding{}.Bind(c.Request(), i)
+//		}
+//		return b.DefaultBinder.Bind(i, c)
+//	}
+//
+//	e.Binder = &binder{}
+//	...
+//	out, err := hjson.Marshal(config)
+//	return c.Blob(http.StatusOK, hjsonbind.ContentType, out)
+package hjsonbind
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+
+	"github.com/hjson/hjson-go"
+)
+
+// ContentType is the media type of Hjson documents.
+const ContentType = "application/hjson"
+
+// IsHjson reports whether the body of req is Hjson, according to its
+// Content-Type header.
+func IsHjson(req *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	return err == nil && (mediaType == ContentType || mediaType == "text/hjson")
+}
+
+// Binding decodes Hjson request bodies.
+type Binding struct {
+	// Options used to decode the body
+	Options hjson.DecoderOptions
+	// Called with the decoded value if not nil, for example with the
+	// validator of the framework
+	Validate func(obj interface{}) error
+}
+
+// Name returns the name of the binding.
+func (Binding) Name() string {
+	return "hjson"
+}
+
+// Bind decodes the body of req into obj.
+func (b Binding) Bind(req *http.Request, obj interface{}) error {
+	if req == nil || req.Body == nil {
+		return errors.New("Invalid request")
+	}
+	var r io.Reader = req.Body
+	if b.Options.MaxInputBytes > 0 {
+		// stop reading early, the decoder reports the limit
+		r = io.LimitReader(r, int64(b.Options.MaxInputBytes)+1)
+	}
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return b.BindBody(body, obj)
+}
+
+// BindBody decodes body into obj.
+func (b Binding) BindBody(body []byte, obj interface{}) error {
+	if err := hjson.UnmarshalWithOptions(body, obj, b.Options); err != nil {
+		return err
+	}
+	if b.Validate != nil {
+		return b.Validate(obj)
+	}
+	return nil
+}
+
+// Render writes Data as an Hjson response.
+type Render struct {
+	Data    interface{}
+	Options hjson.EncoderOptions
+}
+
+// Render writes the content type and the Hjson encoding of r.Data to w.
+func (r Render) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	return hjson.NewEncoder(w, r.Options).Encode(r.Data)
+}
+
+// WriteContentType sets the Content-Type header of w to Hjson.
+func (r Render) WriteContentType(w http.ResponseWriter) {
+	header := w.Header()
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", ContentType+"; charset=utf-8")
+	}
+}
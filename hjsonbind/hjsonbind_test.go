@@ -0,0 +1,60 @@
+package hjsonbind
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hjson/hjson-go"
+)
+
+// The interfaces of Gin that the adapters implement.
+var (
+	_ interface {
+		Name() string
+		Bind(*http.Request, interface{}) error
+		BindBody([]byte, interface{}) error
+	} = Binding{}
+	_ interface {
+		Render(http.ResponseWriter) error
+		WriteContentType(http.ResponseWriter)
+	} = Render{}
+)
+
+func TestBinding(t *testing.T) {
+	req := httptest.NewRequest("POST", "/", strings.NewReader("# comment\nname: x\nport: 80\n"))
+	req.Header.Set("Content-Type", "application/hjson; charset=utf-8")
+	if !IsHjson(req) {
+		t.Error("expected an Hjson request")
+	}
+	var config struct {
+		Name string
+		Port int
+	}
+	if err := (Binding{}).Bind(req, &config); err != nil {
+		t.Fatal(err)
+	}
+	if config.Name != "x" || config.Port != 80 {
+		t.Errorf("unexpected value %+v", config)
+	}
+
+	b := Binding{Validate: func(interface{}) error { return errors.New("invalid") }}
+	if err := b.BindBody([]byte("name: x"), &config); err == nil || err.Error() != "invalid" {
+		t.Errorf("expected the validation error, got %v", err)
+	}
+}
+
+func TestRender(t *testing.T) {
+	w := httptest.NewRecorder()
+	if err := (Render{Data: map[string]int{"a": 1}, Options: hjson.DefaultOptions()}).Render(w); err != nil {
+		t.Fatal(err)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/hjson; charset=utf-8" {
+		t.Errorf("unexpected content type %s", ct)
+	}
+	if w.Body.String() != "{\n  a: 1\n}\n" {
+		t.Errorf("unexpected body %q", w.Body.String())
+	}
+}
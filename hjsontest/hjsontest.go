@@ -0,0 +1,132 @@
+// Package hjsontest checks that this module handles JSON documents exactly
+// like encoding/json, for users who adopt Hjson as a drop-in for their JSON
+// pipelines.
+package hjsontest
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/hjson/hjson-go"
+)
+
+// A Divergence is a difference between the results of encoding/json and of
+// this module.
+type Divergence struct {
+	// Stage is "decode" for the decoded values, "hjson" for the values
+	// decoded again from the Hjson encoding and "json" for the JSON
+	// encoding of the decoded values.
+	Stage string
+	// Path of the value, for example "servers[2].port"
+	Path string
+	// The result of encoding/json and of this module
+	JSON, Hjson interface{}
+}
+
+func (d Divergence) String() string {
+	return fmt.Sprintf("%s %s: encoding/json gives %#v, hjson gives %#v", d.Stage, d.Path, d.JSON, d.Hjson)
+}
+
+// Compare decodes the JSON document data with encoding/json and with
+// hjson.Unmarshal and reports all differences between the results. It also
+// compares the values after a round trip through hjson.Marshal, and the JSON
+// encodings of both results.
+//
+// It fails if data is not valid JSON or if this module fails to decode or
+// encode it.
+func Compare(data []byte) ([]Divergence, error) {
+	var want, got interface{}
+	if err := json.Unmarshal(data, &want); err != nil {
+		return nil, fmt.Errorf("Not a JSON document: %v", err)
+	}
+	if err := hjson.Unmarshal(data, &got); err != nil {
+		return nil, err
+	}
+	var diffs []Divergence
+	diff(&diffs, "decode", "", want, got)
+
+	buf, err := hjson.Marshal(got)
+	if err != nil {
+		return diffs, err
+	}
+	var again interface{}
+	if err = hjson.Unmarshal(buf, &again); err != nil {
+		return diffs, fmt.Errorf("Cannot decode the Hjson encoding: %v", err)
+	}
+	diff(&diffs, "hjson", "", want, again)
+
+	wantJSON, err := json.Marshal(want)
+	if err != nil {
+		return diffs, err
+	}
+	gotJSON, err := json.Marshal(got)
+	if err != nil {
+		return diffs, err
+	}
+	if string(wantJSON) != string(gotJSON) {
+		diffs = append(diffs, Divergence{"json", "", string(wantJSON), string(gotJSON)})
+	}
+	return diffs, nil
+}
+
+// Check calls Compare and reports its error and each divergence as a test
+// error.
+func Check(t TB, data []byte) {
+	t.Helper()
+	diffs, err := Compare(data)
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	for _, d := range diffs {
+		t.Errorf("%v", d)
+	}
+}
+
+// TB is the part of testing.TB used by Check.
+type TB interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+func diff(diffs *[]Divergence, stage, path string, want, got interface{}) {
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(w)+len(g))
+		for k := range w {
+			keys = append(keys, k)
+		}
+		for k := range g {
+			if _, ok := w[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			diff(diffs, stage, p, w[k], g[k])
+		}
+		return
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok || len(g) != len(w) {
+			break
+		}
+		for i := range w {
+			diff(diffs, stage, path+"["+strconv.Itoa(i)+"]", w[i], g[i])
+		}
+		return
+	}
+	if !reflect.DeepEqual(want, got) {
+		*diffs = append(*diffs, Divergence{stage, path, want, got})
+	}
+}
@@ -0,0 +1,28 @@
+package hjsontest
+
+import "testing"
+
+func TestCheck(t *testing.T) {
+	for _, doc := range []string{
+		`{"a": 1, "b": [true, false, null], "c": {"d": "e"}}`,
+		`["", " x ", "'''", "#", "//", "a\nb", " ", "1", "true", "-0.5e3"]`,
+		`{"": 0, "a b": -1.25, "a:b": 1e20, "{": "}"}`,
+		`[[], {}, [[]], [{}]]`,
+		`"root string"`,
+		`{"dup": 1, "dup": 2}`,
+	} {
+		Check(t, []byte(doc))
+	}
+}
+
+func TestCompare(t *testing.T) {
+	if _, err := Compare([]byte(`a: 1`)); err == nil {
+		t.Error("expected an error for a non-JSON document")
+	}
+
+	var diffs []Divergence
+	diff(&diffs, "decode", "", map[string]interface{}{"a": []interface{}{1.0, "x"}}, map[string]interface{}{"a": []interface{}{1.0, "y"}, "b": true})
+	if len(diffs) != 2 || diffs[0].Path != "a[1]" || diffs[1].Path != "b" || diffs[1].JSON != nil {
+		t.Errorf("unexpected divergences %v", diffs)
+	}
+}
@@ -0,0 +1,11 @@
+// Package hjsontext bridges Hjson and the token and value streams of
+// encoding/json/jsontext, so code written against the json/v2 APIs can
+// consume and produce Hjson.
+//
+// Hjson is read and written token by token, so large documents do not have
+// to fit into memory, object members keep their order and numbers keep
+// their literal.
+//
+// encoding/json/jsontext only exists with GOEXPERIMENT=jsonv2, without it
+// the package is empty.
+package hjsontext
@@ -0,0 +1,230 @@
+//go:build goexperiment.jsonv2
+
+package hjsontext
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/json/jsontext"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/hjson/hjson-go"
+)
+
+// NewDecoder returns a jsontext.Decoder that reads the JSON equivalent of
+// the Hjson read from r, decompressed by hjson.DecompressReader. The Hjson
+// is converted as the jsontext.Decoder reads it, with the tokens of an
+// hjson.Decoder; syntax errors of the Hjson are returned by the methods of
+// the jsontext.Decoder.
+//
+// Numbers are always read as with options.UseNumber, so that they keep
+// their literal.
+func NewDecoder(r io.Reader, options hjson.DecoderOptions, opts ...jsontext.Options) (*jsontext.Decoder, error) {
+	r, err := hjson.DecompressReader(r)
+	if err != nil {
+		return nil, err
+	}
+	options.UseNumber = true
+	return jsontext.NewDecoder(&tokenReader{dec: hjson.NewDecoder(r, options)}, opts...), nil
+}
+
+// tokenReader reads the JSON text of the tokens of dec.
+type tokenReader struct {
+	dec    *hjson.Decoder
+	buf    []byte
+	err    error
+	values int          // values of the top level
+	scopes []tokenScope // the objects and arrays that are open
+}
+
+// tokenScope is an object or array that tokenReader has opened.
+type tokenScope struct {
+	object bool
+	n      int // keys and values written into it so far
+}
+
+func (r *tokenReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.buf = r.buf[:0]
+		r.err = r.next()
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// next appends the JSON of the next token to buf.
+func (r *tokenReader) next() error {
+	tok, err := r.dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); ok && (d == '}' || d == ']') {
+		r.scopes = r.scopes[:len(r.scopes)-1]
+		r.buf = append(r.buf, byte(d))
+		return nil
+	}
+
+	if len(r.scopes) == 0 {
+		if r.values > 0 {
+			r.buf = append(r.buf, '\n')
+		}
+		r.values++
+	} else {
+		s := &r.scopes[len(r.scopes)-1]
+		switch {
+		case s.object && s.n%2 == 1:
+			r.buf = append(r.buf, ':')
+		case s.n > 0:
+			r.buf = append(r.buf, ',')
+		}
+		s.n++
+	}
+
+	switch v := tok.(type) {
+	case json.Delim:
+		r.scopes = append(r.scopes, tokenScope{object: v == '{'})
+		r.buf = append(r.buf, byte(v))
+	case json.Number:
+		r.buf = append(r.buf, v...)
+	default:
+		js, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		r.buf = append(r.buf, js...)
+	}
+	return nil
+}
+
+// An Encoder writes JSON tokens and values, like a jsontext.Encoder, as
+// Hjson. The output is written with an hjson.Writer as the tokens arrive;
+// each value of the top level is followed by an end of line.
+type Encoder struct {
+	w       io.Writer
+	options hjson.EncoderOptions
+	out     *hjson.Writer // of the current value of the top level
+	scopes  []tokenScope  // the objects and arrays that are open
+}
+
+// NewEncoder returns an Encoder that writes Hjson to w.
+func NewEncoder(w io.Writer, options hjson.EncoderOptions) *Encoder {
+	return &Encoder{w: w, options: options}
+}
+
+// WriteToken writes the next token, which must be valid at this point like
+// with jsontext.Encoder.WriteToken. Strings at the place of an object key
+// are written as the key.
+func (enc *Encoder) WriteToken(tok jsontext.Token) error {
+	if enc.out == nil {
+		enc.out = hjson.NewWriter(enc.w, enc.options)
+	}
+	kind := tok.Kind()
+	switch kind {
+	case '}', ']':
+		var err error
+		if kind == '}' {
+			err = enc.out.EndObject()
+		} else {
+			err = enc.out.EndArray()
+		}
+		if err != nil {
+			return err
+		}
+		enc.scopes = enc.scopes[:len(enc.scopes)-1]
+		return enc.endValue()
+	}
+
+	if len(enc.scopes) > 0 {
+		s := &enc.scopes[len(enc.scopes)-1]
+		if s.object && s.n%2 == 0 {
+			if kind != '"' {
+				return fmt.Errorf("hjsontext: expected a string for an object key, not %v", kind)
+			}
+			s.n++
+			return enc.out.WriteKey(tok.String())
+		}
+		s.n++
+	}
+
+	var err error
+	switch kind {
+	case '{', '[':
+		if kind == '{' {
+			err = enc.out.BeginObject()
+		} else {
+			err = enc.out.BeginArray()
+		}
+		if err == nil {
+			enc.scopes = append(enc.scopes, tokenScope{object: kind == '{'})
+		}
+		return err
+	case '"':
+		err = enc.out.WriteString(tok.String())
+	case '0':
+		err = enc.out.WriteValue(json.Number(tok.String()))
+	case 't', 'f':
+		err = enc.out.WriteBool(tok.Bool())
+	case 'n':
+		err = enc.out.WriteNull()
+	default:
+		return errors.New("hjsontext: invalid token")
+	}
+	if err != nil {
+		return err
+	}
+	return enc.endValue()
+}
+
+// WriteValue writes the next value, which must be valid JSON, token by
+// token.
+func (enc *Encoder) WriteValue(v jsontext.Value) error {
+	if !v.IsValid() {
+		return errors.New("hjsontext: invalid JSON value")
+	}
+	dec := jsontext.NewDecoder(bytes.NewReader(v))
+	for {
+		tok, err := dec.ReadToken()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err = enc.WriteToken(tok); err != nil {
+			return err
+		}
+	}
+}
+
+// endValue ends the value of the top level once its last token has been
+// written.
+func (enc *Encoder) endValue() error {
+	if len(enc.scopes) > 0 {
+		return nil
+	}
+	out := enc.out
+	enc.out = nil
+	if err := out.Close(); err != nil {
+		return err
+	}
+	eol := enc.options.Eol
+	if eol == "" {
+		eol = "\n"
+	}
+	_, err := io.WriteString(enc.w, eol)
+	return err
+}
+
+// Close reports an error if an object or array has not been ended. It does
+// not close the underlying writer.
+func (enc *Encoder) Close() error {
+	if enc.out != nil {
+		return enc.out.Close()
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+//go:build goexperiment.jsonv2
+
+package hjsontext
+
+import (
+	"bytes"
+	"encoding/json/jsontext"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hjson/hjson-go"
+)
+
+func TestDecoder(t *testing.T) {
+	dec, err := NewDecoder(strings.NewReader("# config\nb: [1, 9007199254740993, 1.10]\na: text\nc: {}\n"), hjson.DefaultDecoderOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kinds []string
+	for {
+		tok, err := dec.ReadToken()
+		if err != nil {
+			break
+		}
+		kinds = append(kinds, tok.String())
+	}
+	// in the order of the source, with the literals of the numbers
+	expected := "{ b [ 1 9007199254740993 1.10 ] a text c { } }"
+	if got := strings.Join(kinds, " "); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+
+	dec, err = NewDecoder(strings.NewReader("[1]\n{a: [true, null]}\n"), hjson.DefaultDecoderOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var values []string
+	for {
+		v, err := dec.ReadValue()
+		if err != nil {
+			break
+		}
+		values = append(values, string(v))
+	}
+	if got := strings.Join(values, " "); got != `[1] {"a":[true,null]}` {
+		t.Errorf("unexpected values %s", got)
+	}
+
+	dec, err = NewDecoder(strings.NewReader("{a: 1"), hjson.DefaultDecoderOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = dec.ReadValue()
+	var se *hjson.SyntaxError
+	if !errors.As(err, &se) {
+		t.Errorf("expected a syntax error, got %v", err)
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf, hjson.DefaultOptions())
+	for _, tok := range []jsontext.Token{jsontext.BeginObject, jsontext.String("b"), jsontext.Int(1), jsontext.String("a")} {
+		if err := enc.WriteToken(tok); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// written as the tokens arrive
+	if buf.String() != "{\n  b: 1\n  a:" {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+	if err := enc.WriteValue(jsontext.Value(`{"y": 9007199254740993, "x": [1.10, "z"]}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.WriteToken(jsontext.EndObject); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.WriteValue(jsontext.Value(`["x"]`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\n  b: 1\n  a:\n  {\n    y: 9007199254740993\n    x:\n    [\n      1.10\n      z\n    ]\n  }\n}\n[\n  x\n]\n"
+	if buf.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf.String())
+	}
+
+	enc = NewEncoder(&buf, hjson.DefaultOptions())
+	if err := enc.WriteToken(jsontext.BeginArray); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Close(); err == nil {
+		t.Error("expected an error for an unclosed array")
+	}
+	enc = NewEncoder(&buf, hjson.DefaultOptions())
+	enc.WriteToken(jsontext.BeginObject)
+	if err := enc.WriteToken(jsontext.Int(1)); err == nil {
+		t.Error("expected an error for a number as key")
+	}
+	if err := NewEncoder(&buf, hjson.DefaultOptions()).WriteValue(jsontext.Value(`[1`)); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+}
@@ -0,0 +1,101 @@
+package hjson
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// FetchOptions defines options for GetAndDecode.
+type FetchOptions struct {
+	// Maximum size of the decompressed response body in bytes (0 for
+	// DefaultMaxFetchSize)
+	MaxSize int64
+	// Options used to decode the responses
+	Decoder DecoderOptions
+}
+
+// DefaultMaxFetchSize is the size limit used by GetAndDecode when
+// FetchOptions.MaxSize is 0.
+const DefaultMaxFetchSize = 10 << 20
+
+// DefaultFetchOptions returns the default options for GetAndDecode.
+func DefaultFetchOptions() FetchOptions {
+	opt := FetchOptions{}
+	opt.MaxSize = DefaultMaxFetchSize
+	opt.Decoder = DefaultDecoderOptions()
+	return opt
+}
+
+// GetAndDecode fetches url with client (http.DefaultClient if nil) and
+// decodes the response body into v.
+//
+// The body is decoded with UnmarshalWithOptions and options.Decoder
+// whatever its content type, as JSON is valid Hjson. gzip and deflate
+// content encodings are decompressed, and so are bodies recognized by
+// DecompressReader. It fails if the status is not 2xx or the decompressed
+// body is larger than options.MaxSize.
+func GetAndDecode(ctx context.Context, client *http.Client, url string, v interface{}, options FetchOptions) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	maxSize := options.MaxSize
+	if maxSize == 0 {
+		maxSize = DefaultMaxFetchSize
+	}
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/hjson, application/json;q=0.9, */*;q=0.1")
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+
+	var body io.Reader = resp.Body
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "", "identity":
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		body = zr
+	case "deflate":
+		zr, err := zlib.NewReader(body)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		body = zr
+	default:
+		return fmt.Errorf("GET %s: unsupported Content-Encoding %q", url, resp.Header.Get("Content-Encoding"))
+	}
+
+	if body, err = DecompressReader(body); err != nil {
+		return err
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(body, maxSize+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > maxSize {
+		return fmt.Errorf("GET %s: response is larger than %d bytes", url, maxSize)
+	}
+
+	return UnmarshalWithOptions(data, v, options.Decoder)
+}
@@ -0,0 +1,64 @@
+package hjson
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetAndDecode(t *testing.T) {
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte("# compressed\nname: zipped\n"))
+	zw.Close()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/config.hjson":
+			w.Header().Set("Content-Type", "application/hjson")
+			w.Write([]byte("# comment\nname: plain\n"))
+		case "/config.json":
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.Write([]byte(`{"name": "json"}`))
+		case "/extra.json":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"name": "json", "extra": 1}`))
+		case "/gzip":
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(gz.Bytes())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	for path, name := range map[string]string{"/config.hjson": "plain", "/config.json": "json", "/gzip": "zipped"} {
+		var v struct{ Name string }
+		if err := GetAndDecode(ctx, nil, ts.URL+path, &v, DefaultFetchOptions()); err != nil {
+			t.Errorf("%s: %v", path, err)
+		} else if v.Name != name {
+			t.Errorf("%s: expected %s, got %s", path, name, v.Name)
+		}
+	}
+
+	// JSON responses are decoded with the options as well
+	var config struct{ Name string }
+	strict := DefaultFetchOptions()
+	strict.Decoder.DisallowUnknownFields = true
+	if err := GetAndDecode(ctx, ts.Client(), ts.URL+"/extra.json", &config, strict); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+	var v interface{}
+	if err := GetAndDecode(ctx, ts.Client(), ts.URL+"/missing", &v, DefaultFetchOptions()); err == nil {
+		t.Error("expected a status error")
+	}
+	opt := DefaultFetchOptions()
+	opt.MaxSize = 10
+	if err := GetAndDecode(ctx, ts.Client(), ts.URL+"/gzip", &v, opt); err == nil {
+		t.Error("expected a size error")
+	}
+}
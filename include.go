@@ -0,0 +1,118 @@
+package hjson
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"reflect"
+	"strings"
+)
+
+// includeKey is the key of the members that merge files into an object,
+// see DecoderOptions.IncludeFS.
+const includeKey = "!include"
+
+// includeError is returned when a file cannot be included or decoded.
+type includeError struct {
+	error
+}
+
+func (e includeError) Unwrap() error {
+	return e.error
+}
+
+// includeName returns the file name of the quoteless value str if it is an
+// !include directive.
+func (p *hjsonParser) includeName(str string) (string, bool) {
+	if p.IncludeFS == nil || !strings.HasPrefix(str, includeKey+" ") {
+		return "", false
+	}
+	return strings.TrimSpace(str[len(includeKey):]), true
+}
+
+// include decodes the file name of IncludeFS into dest like the input of
+// Unmarshal, or returns its value if dest is not valid. With merge the
+// file is merged into dest, see DuplicateKeyMerge. name is relative to the
+// directory of the file being parsed.
+func (p *hjsonParser) include(dest reflect.Value, name string, merge bool) (interface{}, error) {
+	file := path.Clean(name)
+	if len(p.includes) > 0 {
+		file = path.Join(path.Dir(p.includes[len(p.includes)-1]), name)
+	}
+	if !fs.ValidPath(file) {
+		return nil, includeError{p.errCode("include-path", name)}
+	}
+	for i, f := range p.includes {
+		if f == file {
+			cycle := append(append([]string(nil), p.includes[i:]...), file)
+			return nil, includeError{p.errCode("include-cycle", strings.Join(cycle, " -> "))}
+		}
+	}
+	data, err := fs.ReadFile(p.IncludeFS, file)
+	if err != nil {
+		return nil, includeError{p.errCode("include-failed", name, err)}
+	}
+	if p.MaxInputBytes > 0 && len(data) > p.MaxInputBytes {
+		return nil, limitError{p.errCode("include-size", file, len(data), p.MaxInputBytes)}
+	}
+
+	sub := &hjsonParser{DecoderOptions: p.DecoderOptions, data: data, extensions: p.extensions}
+	sub.Positions = nil
+	sub.includes = append(append([]string(nil), p.includes...), file)
+	sub.resetAt()
+	// the budget of MaxAlloc is shared with the including file
+	sub.allocated = p.allocated
+	sub.mergeNext = merge
+	value, err := sub.rootValue(dest)
+	if err != nil {
+		if _, ok := err.(limitError); ok {
+			return nil, limitError{fmt.Errorf("%s: %v", file, err)}
+		}
+		return nil, includeError{fmt.Errorf("%s: %v", file, err)}
+	}
+	p.allocated = sub.allocated
+	return value, nil
+}
+
+// readInclude parses the value of an !include member of an object, a file
+// name or an array of them, and merges the files into the object: into
+// dest if it is valid, otherwise into object or ordered.
+func (p *hjsonParser) readInclude(dest reflect.Value, object map[string]interface{}, ordered *OrderedMap) error {
+	value, err := p.readValue(reflect.Value{})
+	if err != nil {
+		return err
+	}
+	names, ok := value.([]interface{})
+	if !ok {
+		names = []interface{}{value}
+	}
+	for _, name := range names {
+		name, ok := name.(string)
+		if !ok {
+			return includeError{p.errCode("include-name", includeKey)}
+		}
+		if dest.IsValid() {
+			if _, err = p.include(dest, name, true); err != nil {
+				return err
+			}
+			continue
+		}
+		if value, err = p.include(reflect.Value{}, name, false); err != nil {
+			return err
+		}
+		switch value := value.(type) {
+		case map[string]interface{}:
+			if object != nil {
+				mergeValues(object, value)
+				continue
+			}
+		case *OrderedMap:
+			if ordered != nil {
+				mergeValues(ordered, value)
+				continue
+			}
+		}
+		return includeError{p.errCode("include-merge", name)}
+	}
+	return nil
+}
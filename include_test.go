@@ -0,0 +1,99 @@
+package hjson
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestIncludeFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"base.hjson": {Data: []byte(`
+server: {
+  host: localhost
+  port: 80
+}
+tags: ["base"]
+`)},
+		"conf/db.hjson":    {Data: []byte("{ host: \"db\", user: !include user.hjson\n}")},
+		"conf/user.hjson":  {Data: []byte(`"admin"`)},
+		"conf/local.hjson": {Data: []byte("server: { port: 8080 }")},
+		"cycle/a.hjson":    {Data: []byte("x: !include b.hjson")},
+		"cycle/b.hjson":    {Data: []byte("y: !include a.hjson")},
+		"list.hjson":       {Data: []byte("[1, 2]")},
+		"bad.hjson":        {Data: []byte("{ a: ")},
+	}
+	decOpt, err := NewDecoderOptions(WithIncludeFS(fsys))
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := `
+!include: base.hjson
+db: !include conf/db.hjson
+!include: ["conf/local.hjson"]
+server: { debug: true }
+tags: ["main"]
+`
+	var v interface{}
+	if err = UnmarshalWithOptions([]byte(input), &v, decOpt); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"server": map[string]interface{}{"host": "localhost", "port": 8080.0, "debug": true},
+		"tags":   []interface{}{"main"},
+		"db":     map[string]interface{}{"host": "db", "user": "admin"},
+	}
+	if !reflect.DeepEqual(v, expected) {
+		t.Errorf("expected\n%#v\ngot\n%#v", expected, v)
+	}
+
+	type server struct {
+		Host  string
+		Port  int
+		Debug bool
+	}
+	var c struct {
+		Server server
+		Tags   []string
+		DB     map[string]string
+	}
+	if err = UnmarshalWithOptions([]byte(input), &c, decOpt); err != nil {
+		t.Fatal(err)
+	}
+	if c.Server != (server{"localhost", 8080, true}) || !reflect.DeepEqual(c.Tags, []string{"main"}) ||
+		!reflect.DeepEqual(c.DB, map[string]string{"host": "db", "user": "admin"}) {
+		t.Errorf("unexpected %+v", c)
+	}
+
+	decOpt.UseOrderedMap = true
+	if err = UnmarshalWithOptions([]byte("a: 1\n!include: base.hjson"), &v, decOpt); err != nil {
+		t.Fatal(err)
+	}
+	if om, ok := v.(*OrderedMap); !ok || !reflect.DeepEqual(om.Keys, []string{"a", "server", "tags"}) {
+		t.Errorf("unexpected %#v", v)
+	}
+	decOpt.UseOrderedMap = false
+
+	for input, expected := range map[string]string{
+		"a: !include cycle/a.hjson":  "Include cycle cycle/a.hjson -> cycle/b.hjson -> cycle/a.hjson",
+		"a: !include missing.hjson":  "Cannot include 'missing.hjson': open missing.hjson: file does not exist",
+		"a: !include ../base.hjson":  "Invalid include path '../base.hjson'",
+		"!include: list.hjson":       "Cannot merge 'list.hjson' into an object, it is not an object",
+		"!include: [1]":              "Expected a file name or an array of file names after !include",
+		"a: !include bad.hjson":      "bad.hjson: Found EOF while looking for a value",
+		"a: !include conf/db.hjson}": "Cannot include 'conf/db.hjson}'",
+	} {
+		if err = UnmarshalWithOptions([]byte(input), &v, decOpt); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%s: expected an error with %q, got %v", input, expected, err)
+		}
+	}
+
+	// without IncludeFS the directives are strings
+	if err = Unmarshal([]byte("a: !include base.hjson"), &v); err != nil || !reflect.DeepEqual(v, map[string]interface{}{"a": "!include base.hjson"}) {
+		t.Errorf("unexpected %#v, %v", v, err)
+	}
+	if _, err = NewDecoderOptions(WithIncludeFS(nil)); err == nil {
+		t.Error("expected an error for a nil IncludeFS")
+	}
+}
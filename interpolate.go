@@ -0,0 +1,150 @@
+package hjson
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Interpolate replaces the references to other values of the document in
+// the strings below n, like "${paths.base}/logs". A reference holds a path
+// from n as taken by Lookup. A string that is a single reference takes the
+// value it refers to, which may be a number, bool, null, object or array;
+// otherwise the referenced values are written into the string, which works
+// for strings, numbers, bools and null. Referenced strings are interpolated
+// first. $$ stands for a single $.
+//
+// Interpolate fails for references to missing values and for cyclic
+// references, like a: ${b} and b: ${a}, naming the values involved. Values
+// resolved before the error keep their new value. To decode the document
+// afterwards, pass the output of Marshal to Unmarshal.
+func (n *Node) Interpolate() error {
+	in := interpolator{root: n, state: make(map[*Node]int)}
+	return in.resolve(n, "")
+}
+
+const (
+	resolving = 1
+	resolved  = 2
+)
+
+// interpolator implements Node.Interpolate.
+type interpolator struct {
+	root  *Node
+	state map[*Node]int // resolving or resolved, for the nodes visited
+	stack []*Node       // the nodes being resolved
+	paths []string      // their paths
+}
+
+// resolve replaces the references in n, the node at path, and its
+// children.
+func (in *interpolator) resolve(n *Node, path string) error {
+	switch in.state[n] {
+	case resolved:
+		return nil
+	case resolving:
+		var cycle []string
+		for i := len(in.stack) - 1; i >= 0; i-- {
+			if in.paths[i] != "" {
+				cycle = append([]string{in.paths[i]}, cycle...)
+			}
+			if in.stack[i] == n {
+				break
+			}
+		}
+		return fmt.Errorf("Cyclic reference %s -> %s", strings.Join(cycle, " -> "), path)
+	}
+	in.state[n] = resolving
+	in.stack, in.paths = append(in.stack, n), append(in.paths, path)
+	defer func() {
+		in.stack, in.paths = in.stack[:len(in.stack)-1], in.paths[:len(in.paths)-1]
+	}()
+
+	var err error
+	switch n.Kind {
+	case ValueNode:
+		if s, ok := n.Value.(string); ok && strings.Contains(s, "$") {
+			err = in.resolveString(n, s, path)
+		}
+	default:
+		for i, c := range n.Children {
+			cpath := path + "[" + strconv.Itoa(i) + "]"
+			if n.Kind == ObjectNode && path == "" {
+				cpath = c.Key
+			} else if n.Kind == ObjectNode {
+				cpath = path + "." + c.Key
+			}
+			if err = in.resolve(c, cpath); err != nil {
+				break
+			}
+		}
+	}
+	in.state[n] = resolved
+	return err
+}
+
+// resolveString replaces the references in s, the string value of n.
+func (in *interpolator) resolveString(n *Node, s, path string) error {
+	if strings.HasPrefix(s, "${") && strings.IndexByte(s, '}') == len(s)-1 {
+		target, err := in.lookup(s[2:len(s)-1], path)
+		if err != nil {
+			return err
+		}
+		if _, ok := target.Value.(string); !ok || target.Kind != ValueNode {
+			return n.SetValue(orderedValue(target))
+		}
+	}
+
+	var buf []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) || s[i+1] != '$' && s[i+1] != '{' {
+			buf = append(buf, s[i])
+			continue
+		}
+		if s[i+1] == '$' {
+			buf = append(buf, '$')
+			i++
+			continue
+		}
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return fmt.Errorf("Missing '}' after '${' in '%s'", path)
+		}
+		ref := s[i+2 : i+end]
+		i += end
+		target, err := in.lookup(ref, path)
+		if err != nil {
+			return err
+		}
+		if target.Kind != ValueNode {
+			return fmt.Errorf("Cannot resolve '${%s}' in '%s': an object or array cannot be part of a string", ref, path)
+		}
+		if str, ok := target.Value.(string); ok {
+			buf = append(buf, str...)
+		} else {
+			text, err := Marshal(target.Value)
+			if err != nil {
+				return err
+			}
+			buf = append(buf, text...)
+		}
+	}
+	n.Value = string(buf)
+	return nil
+}
+
+// lookup returns the resolved node at the path ref, referenced by the value
+// at path.
+func (in *interpolator) lookup(ref, path string) (*Node, error) {
+	target, err := in.root.Lookup(ref)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot resolve '${%s}' in '%s': %v", ref, path, err)
+	}
+	if target == nil {
+		return nil, fmt.Errorf("Cannot resolve '${%s}' in '%s': there is no value at that path", ref, path)
+	}
+	if err = in.resolve(target, ref); err != nil {
+		return nil, err
+	}
+	return target, nil
+}
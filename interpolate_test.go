@@ -0,0 +1,88 @@
+package hjson
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInterpolate(t *testing.T) {
+	input := `{
+  logs: ${paths.base}/logs # the log directory
+  paths: {
+    base: /var/${name}
+    cache: "${paths.base}/cache"
+  }
+  name: app
+  port: 8080
+  listen: ":${port}"
+  copy: ${port}
+  debug: ${flags.debug}
+  flags: { debug: true, level: null }
+  info: debug=${flags.debug} level=${flags.level}
+  servers: [
+    { host: "a", port: "${port}" }
+    "${servers[0].host}:${servers[0].port}"
+  ]
+  defaults: ${flags}
+  price: "$5, $$5"
+}
+`
+	expected := `{
+  logs: /var/app/logs # the log directory
+  paths: {
+    base: /var/app
+    cache: /var/app/cache
+  }
+  name: app
+  port: 8080
+  listen: ":8080"
+  copy: 8080
+  debug: true
+  flags: { debug: true, level: null }
+  info: debug=true level=null
+  servers: [
+    { host: "a", port: 8080 }
+    a:8080
+  ]
+  defaults: {
+    debug: true
+    level: null
+  }
+  price: $5, $5
+}
+`
+	root, err := ParseNode([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = root.Interpolate(); err != nil {
+		t.Fatal(err)
+	}
+	out, err := root.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out)
+	}
+
+	for input, expected := range map[string]string{
+		"a: ${b}":                    "Cannot resolve '${b}' in 'a': there is no value at that path",
+		"a: x${b.c}":                 "Cannot resolve '${b.c}' in 'a': there is no value at that path",
+		"a: ${b}\nb: ${a}":           "Cyclic reference a -> b -> a",
+		"a: ${a}":                    "Cyclic reference a -> a",
+		"a: { b: \"${a}\" }":         "Cyclic reference a -> a.b -> a",
+		"a: ${b}\nb: x${c}\nc: ${a}": "Cyclic reference a -> b -> c -> a",
+		"a: x${b}\nb: [1]":           "Cannot resolve '${b}' in 'a': an object or array cannot be part of a string",
+		"a: ${b\nb: 1":               "Missing '}' after '${' in 'a'",
+		"a: [\"${b[x]}\"]\nb: [1]":   "Cannot resolve '${b[x]}' in 'a[0]': Invalid index in path 'b[x]'",
+	} {
+		root, err := ParseNode([]byte(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = root.Interpolate(); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%q: expected an error with %q, got %v", input, expected, err)
+		}
+	}
+}
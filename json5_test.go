@@ -0,0 +1,92 @@
+package hjson
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAcceptJSON5(t *testing.T) {
+	input := `// JSON5
+{
+  unquoted: 'and you can quote me on that',
+  singleQuotes: 'I can use "double quotes" here',
+  lineBreaks: "Look, Mom! \
+No \\n's!",
+  escapes: '\x41\v\0\q\'',
+  hexadecimal: 0xdecaf,
+  leadingDecimalPoint: .8675309, andTrailing: 8675309.,
+  positiveSign: +1,
+  negativeHex: -0x10,
+  backwardsCompatible: "with JSON",
+  list: [Infinity, -Infinity, +.5, 5.e1,],
+}
+`
+	decOpt, err := NewDecoderOptions(WithAcceptJSON5())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v interface{}
+	if err = UnmarshalWithOptions([]byte(input), &v, decOpt); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"unquoted":            "and you can quote me on that",
+		"singleQuotes":        `I can use "double quotes" here`,
+		"lineBreaks":          `Look, Mom! No \n's!`,
+		"escapes":             "A\v\x00q'",
+		"hexadecimal":         912559.0,
+		"leadingDecimalPoint": 0.8675309,
+		"andTrailing":         8675309.0,
+		"positiveSign":        1.0,
+		"negativeHex":         -16.0,
+		"backwardsCompatible": "with JSON",
+		"list":                []interface{}{math.Inf(1), math.Inf(-1), 0.5, 50.0},
+	}
+	if !reflect.DeepEqual(v, expected) {
+		t.Errorf("expected\n%#v\ngot\n%#v", expected, v)
+	}
+
+	var nan float64
+	if err = UnmarshalWithOptions([]byte("NaN"), &nan, decOpt); err != nil || !math.IsNaN(nan) {
+		t.Errorf("expected NaN, got %v, %v", nan, err)
+	}
+	var s struct {
+		A int64
+		B json.Number
+		C string
+	}
+	if err = UnmarshalWithOptions([]byte("{A: 0x7fffffffffffffff, B: +.5, C: 0x1F}"), &s, decOpt); err != nil {
+		t.Fatal(err)
+	}
+	if s.A != math.MaxInt64 || s.B != "0.5" || s.C != "31" {
+		t.Errorf("unexpected %+v", s)
+	}
+	decOpt.UseNumber = true
+	if err = UnmarshalWithOptions([]byte("[+1, .5]"), &v, decOpt); err != nil || !reflect.DeepEqual(v, []interface{}{json.Number("1"), json.Number("0.5")}) {
+		t.Errorf("unexpected %#v, %v", v, err)
+	}
+
+	// without AcceptJSON5 these are quoteless strings or errors
+	if err = Unmarshal([]byte("[0x1F\n+1\nInfinity\n]"), &v); err != nil || !reflect.DeepEqual(v, []interface{}{"0x1F", "+1", "Infinity"}) {
+		t.Errorf("unexpected %#v, %v", v, err)
+	}
+	for _, input := range []string{`"\x41"`, `"\v"`} {
+		if err = Unmarshal([]byte(input), &v); err == nil {
+			t.Errorf("%s: expected an error without AcceptJSON5", input)
+		}
+	}
+
+	for input, expected := range map[string]string{
+		`"\x4"`:  "Bad \\x char",
+		`"\01"`:  "Bad escape \\0",
+		`"\1"`:   "Bad escape \\1",
+		"{a: .}": "End of input while parsing an object",
+	} {
+		if err = UnmarshalWithOptions([]byte(input), &v, decOpt); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%s: expected an error with %q, got %v", input, expected, err)
+		}
+	}
+}
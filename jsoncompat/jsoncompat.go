@@ -0,0 +1,85 @@
+// Package jsoncompat offers the functions of encoding/json that work on
+// whole documents, with the same signatures but reading and writing Hjson,
+// so a project can switch to Hjson by changing an import path:
+//
+//	import json "github.com/hjson/hjson-go/jsoncompat"
+//
+// Like encoding/json, Compact and Indent keep the order of object members
+// and the text of numbers. Comments are not kept.
+package jsoncompat
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/hjson/hjson-go"
+)
+
+// Marshal returns the Hjson encoding of v, see hjson.Marshal.
+func Marshal(v interface{}) ([]byte, error) {
+	return hjson.Marshal(v)
+}
+
+// MarshalIndent is like Marshal but indents the output with indent, which
+// must be made of spaces and tabs, and begins each line after the first
+// with prefix.
+func MarshalIndent(v interface{}, prefix, indent string) ([]byte, error) {
+	options := hjson.DefaultOptions()
+	options.IndentBy = indent
+	b, err := hjson.MarshalWithOptions(v, options)
+	if err != nil {
+		return nil, err
+	}
+	return addPrefix(b, prefix), nil
+}
+
+// Unmarshal parses the Hjson data and stores the result in the value
+// pointed to by v, see hjson.Unmarshal.
+func Unmarshal(data []byte, v interface{}) error {
+	return hjson.Unmarshal(data, v)
+}
+
+// Valid reports whether data is valid Hjson.
+func Valid(data []byte) bool {
+	var v interface{}
+	return hjson.Unmarshal(data, &v) == nil
+}
+
+// Compact appends to dst the Hjson src with the least indentation: one
+// value per line, no indentation and braces on the line of their key.
+func Compact(dst *bytes.Buffer, src []byte) error {
+	return reformat(dst, src, hjson.CompactOptions(), "")
+}
+
+// Indent appends to dst the Hjson src indented with indent, which must be
+// made of spaces and tabs. Each line after the first begins with prefix.
+func Indent(dst *bytes.Buffer, src []byte, prefix, indent string) error {
+	options := hjson.DefaultOptions()
+	options.IndentBy = indent
+	return reformat(dst, src, options, prefix)
+}
+
+// reformat decodes src and appends it to dst encoded with options.
+func reformat(dst *bytes.Buffer, src []byte, options hjson.EncoderOptions, prefix string) error {
+	decOptions := hjson.DefaultDecoderOptions()
+	decOptions.UseNumber = true
+	decOptions.UseOrderedMap = true
+	var v interface{}
+	if err := hjson.UnmarshalWithOptions(src, &v, decOptions); err != nil {
+		return err
+	}
+	b, err := hjson.MarshalWithOptions(v, options)
+	if err != nil {
+		return err
+	}
+	dst.Write(addPrefix(b, prefix))
+	return nil
+}
+
+// addPrefix begins each line of b after the first with prefix.
+func addPrefix(b []byte, prefix string) []byte {
+	if prefix == "" {
+		return b
+	}
+	return []byte(strings.Replace(string(b), "\n", "\n"+prefix, -1))
+}
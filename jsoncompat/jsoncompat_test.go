@@ -0,0 +1,57 @@
+package jsoncompat
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMarshal(t *testing.T) {
+	value := map[string]interface{}{"a": []int{1, 2}, "b": "text"}
+	b, err := Marshal(value)
+	if err != nil || string(b) != "{\n  a:\n  [\n    1\n    2\n  ]\n  b: text\n}" {
+		t.Errorf("unexpected %q, %v", b, err)
+	}
+	b, err = MarshalIndent(value, "> ", "\t")
+	if err != nil || string(b) != "{\n> \ta:\n> \t[\n> \t\t1\n> \t\t2\n> \t]\n> \tb: text\n> }" {
+		t.Errorf("unexpected %q, %v", b, err)
+	}
+	if _, err = MarshalIndent(value, "", "--"); err == nil {
+		t.Error("expected an error for an invalid indent")
+	}
+
+	var decoded map[string]interface{}
+	if err = Unmarshal([]byte("a: 1\nb: text"), &decoded); err != nil || decoded["b"] != "text" {
+		t.Errorf("unexpected %v, %v", decoded, err)
+	}
+}
+
+func TestReformat(t *testing.T) {
+	src := []byte("# comment\nz: 1.50\na: {b: [1, 2]}\n")
+	if !Valid(src) || Valid([]byte("{a: 1")) {
+		t.Error("unexpected result of Valid")
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("x")
+	if err := Compact(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+	expected := "x{\nz: 1.50\na: {\nb: [\n1\n2\n]\n}\n}"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+
+	buf.Reset()
+	if err := Indent(&buf, src, " ", "    "); err != nil {
+		t.Fatal(err)
+	}
+	expected = "{\n     z: 1.50\n     a:\n     {\n         b:\n         [\n             1\n             2\n         ]\n     }\n }"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+
+	buf.Reset()
+	if err := Compact(&buf, []byte("{a: 1")); err == nil || buf.Len() != 0 {
+		t.Errorf("expected an error and no output, got %q, %v", buf.String(), err)
+	}
+}
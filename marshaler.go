@@ -0,0 +1,118 @@
+package hjson
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// Marshaler is the interface implemented by types that can encode
+// themselves as Hjson, including comments and quoteless strings, which
+// json.Marshaler cannot express.
+//
+// MarshalHJSON returns a single Hjson value, indented as if it was the root
+// value. The encoder checks that it is valid and indents it to its place
+// in the surrounding document.
+type Marshaler interface {
+	MarshalHJSON() ([]byte, error)
+}
+
+// Unmarshaler is the interface implemented by types that can decode an
+// Hjson representation of themselves. UnmarshalHJSON receives the source
+// text of a single value, with its comments. It must copy the data if it
+// wishes to retain it after returning.
+type Unmarshaler interface {
+	UnmarshalHJSON([]byte) error
+}
+
+// RawValue is the Hjson source text of a single value, with its comments.
+// It is written as it is, after checking it and indenting it to its place,
+// so hand-written Hjson can be spliced into generated documents. Decoding
+// into a RawValue stores the source text of the value, to decode it later.
+type RawValue []byte
+
+// MarshalHJSON returns r, or null if r is empty.
+func (r RawValue) MarshalHJSON() ([]byte, error) {
+	if len(r) == 0 {
+		return []byte("null"), nil
+	}
+	return r, nil
+}
+
+// UnmarshalHJSON stores a copy of data in r.
+func (r *RawValue) UnmarshalHJSON(data []byte) error {
+	if r == nil {
+		return errors.New("hjson.RawValue: UnmarshalHJSON on nil pointer")
+	}
+	*r = append((*r)[:0], data...)
+	return nil
+}
+
+var hjsonMarshaler = reflect.TypeOf((*Marshaler)(nil)).Elem()
+var hjsonUnmarshaler = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+
+// useHjsonMarshaler writes the output of MarshalHJSON.
+func (e *hjsonEncoder) useHjsonMarshaler(value reflect.Value, noIndent bool, separator string) error {
+	b, err := value.Interface().(Marshaler).MarshalHJSON()
+	if err != nil {
+		return err
+	}
+	b = bytes.TrimSpace(b)
+	p := &hjsonParser{data: b}
+	p.UseNumber, p.UseOrderedMap = true, true
+	p.resetAt()
+	var v interface{}
+	if len(b) == 0 {
+		err = errors.New("no value")
+	} else {
+		v, err = p.checkTrailing(p.readValue(reflect.Value{}))
+	}
+	if err != nil {
+		return errors.New("Invalid output of MarshalHJSON for type " + value.Type().String() + ": " + err.Error())
+	}
+	if e.jsonOutput {
+		// comments and quoteless strings cannot be written as JSON
+		return e.str(reflect.ValueOf(v), noIndent, separator, false)
+	}
+
+	if (b[0] == '{' || b[0] == '[') && e.braceOnNewLine(noIndent) {
+		e.writeIndent(e.indent)
+	} else {
+		e.WriteString(separator)
+	}
+	lines := strings.Split(strings.Replace(string(b), "\r\n", "\n", -1), "\n")
+	e.WriteString(lines[0])
+	for _, line := range lines[1:] {
+		if line == "" {
+			e.WriteString(e.Eol)
+		} else {
+			e.writeIndent(e.indent)
+			e.WriteString(line)
+		}
+	}
+	return nil
+}
+
+// isUnmarshaler reports whether the addressable v is filled with
+// UnmarshalHJSON.
+func isUnmarshaler(v reflect.Value) bool {
+	return v.Kind() != reflect.Interface && v.CanAddr() && v.Addr().Type().Implements(hjsonUnmarshaler)
+}
+
+// readUnmarshaler parses a value and passes its source text to the
+// UnmarshalHJSON method of dest.
+func (p *hjsonParser) readUnmarshaler(dest reflect.Value) error {
+	start := p.at - 1
+	if _, err := p.readValue(reflect.Value{}); err != nil {
+		return err
+	}
+	end := len(p.data)
+	if p.ch > 0 {
+		end = p.at - 1
+	}
+	if err := dest.Addr().Interface().(Unmarshaler).UnmarshalHJSON(bytes.TrimSpace(p.data[start:end])); err != nil {
+		return p.errAt(err.Error())
+	}
+	return nil
+}
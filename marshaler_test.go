@@ -0,0 +1,109 @@
+package hjson
+
+import (
+	"errors"
+	"testing"
+)
+
+type testHjsonValue struct {
+	raw string
+}
+
+func (v testHjsonValue) MarshalHJSON() ([]byte, error) {
+	if v.raw == "error" {
+		return nil, errors.New("failed")
+	}
+	return []byte(v.raw), nil
+}
+
+func (v *testHjsonValue) UnmarshalHJSON(data []byte) error {
+	v.raw = string(data)
+	return nil
+}
+
+func TestMarshaler(t *testing.T) {
+	object := testHjsonValue{"{\n  # the answer\n  a: 42\n  b: quoteless text\n}"}
+	value := struct {
+		Obj  testHjsonValue
+		List []testHjsonValue
+		Str  testHjsonValue
+	}{object, []testHjsonValue{object}, testHjsonValue{"it's text"}}
+	buf, err := Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{
+  Obj:
+  {
+    # the answer
+    a: 42
+    b: quoteless text
+  }
+  List:
+  [
+    {
+      # the answer
+      a: 42
+      b: quoteless text
+    }
+  ]
+  Str: it's text
+}`
+	if string(buf) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf)
+	}
+
+	var decoded struct {
+		Obj  testHjsonValue
+		List []testHjsonValue
+		Str  *testHjsonValue
+	}
+	if err = Unmarshal(buf, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Obj.raw != "{\n    # the answer\n    a: 42\n    b: quoteless text\n  }" || len(decoded.List) != 1 || decoded.Str.raw != "it's text" {
+		t.Errorf("unexpected value %+v", decoded)
+	}
+
+	var root testHjsonValue
+	if err = Unmarshal([]byte("# root\na: 1\n"), &root); err != nil || root.raw != "# root\na: 1\n" {
+		t.Errorf("unexpected root %q, %v", root.raw, err)
+	}
+
+	for _, bad := range []testHjsonValue{{"{a: 1"}, {"a: 1\nb: 2"}, {""}, {"error"}} {
+		if _, err = Marshal(bad); err == nil {
+			t.Errorf("expected an error for %q", bad.raw)
+		}
+	}
+}
+
+func TestRawValue(t *testing.T) {
+	doc := struct {
+		Name    string
+		Options RawValue
+		Empty   RawValue
+	}{
+		Name:    "app",
+		Options: RawValue("{\n  # keep this\n  level: 3\n  path: /var/log # logs\n}"),
+	}
+	buf, err := Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\n  Name: app\n  Options:\n  {\n    # keep this\n    level: 3\n    path: /var/log # logs\n  }\n  Empty: null\n}"
+	if string(buf) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf)
+	}
+
+	doc.Options = nil
+	if err = Unmarshal(buf, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if string(doc.Options) != "{\n    # keep this\n    level: 3\n    path: /var/log # logs\n  }" || string(doc.Empty) != "null" {
+		t.Errorf("unexpected %q %q", doc.Options, doc.Empty)
+	}
+
+	if _, err = Marshal(RawValue("{a: 1")); err == nil {
+		t.Error("expected an error for invalid Hjson")
+	}
+}
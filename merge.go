@@ -0,0 +1,67 @@
+package hjson
+
+import (
+	"errors"
+	"strconv"
+)
+
+// MergeStrategy tells DeepMerge how to merge an array into another.
+type MergeStrategy int
+
+const (
+	// MergeReplace replaces the array with the merged one
+	MergeReplace MergeStrategy = iota
+	// MergeAppend appends the elements of the merged array
+	MergeAppend
+	// MergeByIndex merges the elements at the same index and appends the
+	// remaining elements of the merged array
+	MergeByIndex
+)
+
+// DeepMerge merges src into dst, for layered configuration like defaults
+// that are overridden by the settings of an environment. The members of an
+// object in src are merged into the members of dst with the same key, or
+// added at the end of dst; an array in src is merged into an array of dst
+// as set by strategy; any other value of src replaces the value of dst.
+//
+// dst keeps its comments and formatting, as with Node.Set, and the values
+// taken from src are written like new nodes, without the comments of src.
+func DeepMerge(dst, src *Node, strategy MergeStrategy) error {
+	if strategy < MergeReplace || strategy > MergeByIndex {
+		return errors.New("Invalid MergeStrategy: " + strconv.Itoa(int(strategy)))
+	}
+	return deepMerge(dst, src, strategy)
+}
+
+func deepMerge(dst, src *Node, strategy MergeStrategy) error {
+	var err error
+	switch {
+	case dst.Kind == ObjectNode && src.Kind == ObjectNode:
+		for _, c := range src.Children {
+			if d := dst.Get(c.Key); d != nil {
+				err = deepMerge(d, c, strategy)
+			} else {
+				err = dst.Set(c.Key, orderedValue(c))
+			}
+			if err != nil {
+				return err
+			}
+		}
+	case dst.Kind == ArrayNode && src.Kind == ArrayNode && strategy != MergeReplace:
+		for i, c := range src.Children {
+			if strategy == MergeByIndex && i < len(dst.Children) {
+				err = deepMerge(dst.Children[i], c, strategy)
+			} else {
+				err = dst.Append(orderedValue(c))
+			}
+			if err != nil {
+				return err
+			}
+		}
+	case dst.Kind == ValueNode && src.Kind == ValueNode && sameValue(dst.Value, src.Value):
+		// keep the literal of dst
+	default:
+		return dst.SetValue(orderedValue(src))
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package hjson
+
+import "strconv"
+
+// A Conflict is a value that Merge3 found changed differently in ours and
+// in theirs.
+type Conflict struct {
+	// The path of the value, like "server.port" (see Node.Lookup), or ""
+	// for the root
+	Path string
+	// The value in each document, nil where it does not exist. Ours is the
+	// node of the merged tree, so that the conflict can be resolved by
+	// changing it.
+	Base, Ours, Theirs *Node
+}
+
+// Merge3 merges the changes that turn the document base into theirs into a
+// copy of ours, like Git merges concurrent edits of a file, and returns
+// the merged tree with the conflicts, in the order of ours. The documents
+// are not changed.
+//
+// The members of objects are matched by key and the elements of arrays by
+// index when the array has the same length in all three documents;
+// otherwise the arrays are merged as a whole. A value changed in only one
+// of ours and theirs gets the changed value, a value changed in both the
+// value of ours, with a Conflict unless both changed it the same way.
+// Comments are merged the same way, without conflicts: changed in both,
+// they keep those of ours. The merged tree keeps the formatting of ours,
+// values taken from theirs keep their comments and are written like new
+// nodes, see ParseNode.
+func Merge3(base, ours, theirs *Node) (*Node, []Conflict) {
+	var m merger3
+	merged := ours.Clone()
+	m.merge("", nil, base, merged, theirs)
+	return merged, m.conflicts
+}
+
+type merger3 struct {
+	conflicts []Conflict
+}
+
+// merge merges the changes from b to t into o at path, where o is a node of
+// the merged tree or nil and parent the object that holds it, or nil for
+// the root.
+func (m *merger3) merge(path string, parent, b, o, t *Node) {
+	if b != nil && o != nil && t != nil && b.Kind == o.Kind && b.Kind == t.Kind && b.Kind != ValueNode &&
+		(b.Kind == ObjectNode || len(b.Children) == len(o.Children) && len(b.Children) == len(t.Children)) {
+		m.mergeComments(b, o, t)
+		m.mergeChildren(path, b, o, t)
+		return
+	}
+	switch {
+	case sameNodeValue(o, t) || sameNodeValue(b, t):
+		// unchanged in theirs, or changed the same way
+	case sameNodeValue(b, o) && t == nil:
+		parent.remove(parent.indexOf(o))
+		return
+	case sameNodeValue(b, o) && o == nil:
+		parent.insert(len(parent.Children), t.Clone())
+		return
+	case sameNodeValue(b, o):
+		c := t.Clone()
+		c.forgetLayout()
+		o.SetValue(c)
+	default:
+		m.conflicts = append(m.conflicts, Conflict{path, b, o, t})
+		return
+	}
+	if b != nil && o != nil && t != nil {
+		m.mergeComments(b, o, t)
+	}
+}
+
+// mergeChildren merges the members or elements of the objects or arrays b,
+// o and t.
+func (m *merger3) mergeChildren(path string, b, o, t *Node) {
+	if o.Kind == ArrayNode {
+		for i := range b.Children {
+			m.merge(path+"["+strconv.Itoa(i)+"]", o, b.Children[i], o.Children[i], t.Children[i])
+		}
+		return
+	}
+	// like Get, only the last of duplicate keys counts
+	oIndex := memberIndex(o)
+	var members []*Node
+	for i, c := range o.Children {
+		if oIndex[c.Key] == i {
+			members = append(members, c)
+		}
+	}
+	for _, c := range members {
+		m.merge(memberPath(path, c.Key), o, b.Get(c.Key), c, t.Get(c.Key))
+	}
+	tIndex := memberIndex(t)
+	for i, c := range t.Children {
+		if _, ok := oIndex[c.Key]; !ok && tIndex[c.Key] == i {
+			m.merge(memberPath(path, c.Key), o, b.Get(c.Key), nil, c)
+		}
+	}
+}
+
+// mergeComments merges the changes from the comments of b to those of t
+// into o.
+func (m *merger3) mergeComments(b, o, t *Node) {
+	bBefore, bLine, bAfter := nodeComments(b)
+	before, line, after := nodeComments(o)
+	tBefore, tLine, tAfter := nodeComments(t)
+	if before == bBefore && line == bLine {
+		before, line = tBefore, tLine
+	}
+	if after == bAfter {
+		after = tAfter
+	}
+	o.setComments(before, line, after, o.parent == nil)
+}
+
+// memberPath returns the path of the member key of the object at path.
+func memberPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// sameNodeValue reports whether a and b have the same value, see Diff, or
+// are both nil.
+func sameNodeValue(a, b *Node) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return len(Diff(a, b)) == 0
+}
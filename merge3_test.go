@@ -0,0 +1,110 @@
+package hjson
+
+import (
+	"testing"
+)
+
+func TestMerge3(t *testing.T) {
+	base := `# config
+server: {
+  port: 80 // http
+  host: "a.example.com"
+  hosts: ["a", "b"]
+  debug: false
+  mode: "dev"
+  tls: { cert: "a.pem" }
+}
+`
+	ours := `# config
+server: {
+  port: 8080 // http
+  host: "a.example.com"
+  hosts: ["a", "c"]
+  debug: false
+  mode: "prod"
+  tls: { cert: "a.pem" }
+  name: "web"
+}
+`
+	theirs := `# config, edited
+server: {
+  port: 80 // http
+  # the host
+  host: "b.example.com"
+  hosts: ["a", "b", "d"]
+  mode: "test"
+  tls: { cert: "a.pem", key: "a.key" }
+  name: "web"
+  limits: { conns: 10 }
+}
+`
+	var nodes [3]*Node
+	for i, data := range []string{base, ours, theirs} {
+		var err error
+		if nodes[i], err = ParseNode([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	merged, conflicts := Merge3(nodes[0], nodes[1], nodes[2])
+	out, err := merged.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `# config, edited
+server: {
+  port: 8080 // http
+  # the host
+  host: "b.example.com"
+  hosts: ["a", "c"]
+  mode: "prod"
+  tls: { cert: "a.pem", key: "a.key" }
+  name: "web"
+  limits: {
+    conns: 10
+  }
+}
+`
+	if string(out) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out)
+	}
+
+	expectedConflicts := []struct {
+		path, base, ours, theirs string
+	}{
+		{"server.hosts", `["a","b"]`, `["a","c"]`, `["a","b","d"]`},
+		{"server.mode", `"dev"`, `"prod"`, `"test"`},
+	}
+	if len(conflicts) != len(expectedConflicts) {
+		t.Fatalf("unexpected conflicts %+v", conflicts)
+	}
+	for i, c := range conflicts {
+		e := expectedConflicts[i]
+		if c.Path != e.path || compactNode(c.Base) != e.base || compactNode(c.Ours) != e.ours || compactNode(c.Theirs) != e.theirs {
+			t.Errorf("expected %+v, got %s %s %s %s", e, c.Path, compactNode(c.Base), compactNode(c.Ours), compactNode(c.Theirs))
+		}
+	}
+	// resolved in the merged tree
+	if err = conflicts[1].Ours.SetValue("test"); err != nil || merged.Get("server").Get("mode").Value != "test" {
+		t.Errorf("expected the conflict to be resolved, got %v", err)
+	}
+	if out, _ = nodes[1].Marshal(); string(out) != ours {
+		t.Errorf("expected ours to be unchanged, got\n%s", out)
+	}
+
+	// deleted on one side, changed on the other
+	for _, c := range [][4]string{
+		{"a: 1\nb: 2", "b: 2", "a: 3\nb: 2", "a"},
+		{"a: 1\nb: 2", "a: 3\nb: 2", "b: 2", "a"},
+		{"a: 1", "a: 1\nb: 2", "a: 1\nb: 3", "b"},
+		{"a: 1", "a: 1\nb: 2", "a: 1\nb: 2", ""},
+	} {
+		var nodes [3]*Node
+		for i, data := range c[:3] {
+			nodes[i], _ = ParseNode([]byte(data))
+		}
+		_, conflicts := Merge3(nodes[0], nodes[1], nodes[2])
+		if c[3] == "" && len(conflicts) != 0 || c[3] != "" && (len(conflicts) != 1 || conflicts[0].Path != c[3]) {
+			t.Errorf("%q: unexpected conflicts %+v", c, conflicts)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package hjson
+
+import (
+	"testing"
+)
+
+func TestDeepMerge(t *testing.T) {
+	base := `# defaults
+server: {
+  port: 80 // http
+  hosts: [
+    "a.example.com"
+  ]
+  limits: {
+    conns: 10
+    rate: 1.50
+  }
+}
+log: [
+  {
+    level: "info"
+    file: "a.log"
+  }
+]
+`
+	override := `
+server: {
+  port: 8080
+  hosts: ["b.example.com"]
+  limits: { rate: 1.5, burst: 20 }
+  tls: { cert: "a.pem" }
+}
+log: [{ level: "debug" }, { level: "error" }]
+`
+	for _, c := range []struct {
+		strategy MergeStrategy
+		expected string
+	}{
+		{MergeReplace, `# defaults
+server: {
+  port: 8080 // http
+  hosts: [
+    "b.example.com"
+  ]
+  limits: {
+    conns: 10
+    rate: 1.50
+    burst: 20
+  }
+  tls: {
+    cert: "a.pem"
+  }
+}
+log: [
+  {
+    level: "debug"
+  }
+  {
+    level: "error"
+  }
+]
+`},
+		{MergeAppend, `# defaults
+server: {
+  port: 8080 // http
+  hosts: [
+    "a.example.com"
+    "b.example.com"
+  ]
+  limits: {
+    conns: 10
+    rate: 1.50
+    burst: 20
+  }
+  tls: {
+    cert: "a.pem"
+  }
+}
+log: [
+  {
+    level: "info"
+    file: "a.log"
+  }
+  {
+    level: "debug"
+  }
+  {
+    level: "error"
+  }
+]
+`},
+		{MergeByIndex, `# defaults
+server: {
+  port: 8080 // http
+  hosts: [
+    "b.example.com"
+  ]
+  limits: {
+    conns: 10
+    rate: 1.50
+    burst: 20
+  }
+  tls: {
+    cert: "a.pem"
+  }
+}
+log: [
+  {
+    level: "debug"
+    file: "a.log"
+  }
+  {
+    level: "error"
+  }
+]
+`},
+	} {
+		dst, err := ParseNode([]byte(base))
+		if err != nil {
+			t.Fatal(err)
+		}
+		src, err := ParseNode([]byte(override))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = DeepMerge(dst, src, c.strategy); err != nil {
+			t.Fatal(err)
+		}
+		out, err := dst.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != c.expected {
+			t.Errorf("%d: expected\n%s\ngot\n%s", c.strategy, c.expected, out)
+		}
+	}
+
+	dst, _ := NewNode(map[string]interface{}{"a": 1})
+	src, _ := NewNode([]interface{}{1})
+	if err := DeepMerge(dst, src, MergeAppend); err != nil || dst.Kind != ArrayNode {
+		t.Errorf("expected the array to replace the object, got %v", err)
+	}
+	if err := DeepMerge(dst, src, MergeStrategy(3)); err == nil {
+		t.Error("expected an error for an invalid strategy")
+	}
+	if err := dst.Children[0].Append(2); err == nil {
+		t.Error("expected an error appending to a value")
+	}
+}
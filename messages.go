@@ -0,0 +1,71 @@
+package hjson
+
+import "fmt"
+
+// A MessageCatalog holds the messages of syntax errors by their Code, see
+// DecoderOptions.Messages. A message is a format for fmt.Sprintf with the
+// Args of the error as its operands, like "Expected ':' instead of
+// '%[1]s'"; explicit argument indexes let a translation change the order
+// of the Args. The position of an error is kept in the other fields of
+// SyntaxError whatever its message.
+type MessageCatalog map[string]string
+
+// defaultMessages holds the English messages of all codes.
+var defaultMessages = MessageCatalog{
+	"array-eof":             "End of input while parsing an array (did you forget a closing ']'?)",
+	"bad-escape":            "Bad escape \\%[1]s",
+	"bad-hex-escape":        "Bad \\x char %[1]s",
+	"bad-multiline-string":  "Bad multiline string",
+	"bad-string":            "Bad string",
+	"bad-unicode-escape":    "Bad \\u char %[1]s",
+	"comment-eof":           "Found EOF after '/'",
+	"duplicate-key":         "Found duplicate key '%[1]s' (first found at line %[2]d)",
+	"empty-key":             "Found ':' but no key name (for an empty key name use quotes)",
+	"env-brace":             "Missing '}' after '${' in %[1]s",
+	"env-message":           "Environment variable %[1]s %[2]s",
+	"env-reference":         "Bad environment variable reference '${%[1]s}'",
+	"env-unset":             "Environment variable %[1]s is not set",
+	"expected-colon":        "Expected ':' instead of '%[1]s'",
+	"include-cycle":         "Include cycle %[1]s",
+	"include-failed":        "Cannot include '%[1]s': %[2]v",
+	"include-merge":         "Cannot merge '%[1]s' into an object, it is not an object",
+	"include-name":          "Expected a file name or an array of file names after %[1]s",
+	"include-path":          "Invalid include path '%[1]s'",
+	"include-size":          "Included file %[1]s of %[2]d bytes exceeds the limit of %[3]d bytes",
+	"key-char":              "Found '%[1]s' where a key name was expected (check your syntax or use quotes if the key name includes {}[],: or whitespace)",
+	"key-eof":               "Found EOF while looking for a key name (check your syntax)",
+	"key-whitespace":        "Found whitespace in your key name (use quotes to include)",
+	"max-alloc":             "Exceeded the memory budget of %[1]d bytes",
+	"max-depth":             "Exceeded the maximum nesting depth of %[1]d",
+	"max-string-len":        "Exceeded the maximum string length of %[1]d bytes",
+	"object-eof":            "End of input while parsing an object (did you forget a closing '}'?)",
+	"string-newline":        "Bad string containing newline",
+	"trailing-characters":   "Syntax error, found trailing characters",
+	"unknown-field":         "Unknown field '%[1]s' for type %[2]s",
+	"unknown-field-suggest": "Unknown field '%[1]s' for type %[2]s, did you mean '%[3]s'?",
+	"unmarshal-type":        "Cannot unmarshal %[1]s into Go value of type %[2]s",
+	"value-eof":             "Found EOF while looking for a value",
+	"value-punctuator":      "Found a punctuator character '%[1]s' when expecting a quoteless string (check your syntax)",
+}
+
+// DefaultMessages returns the English messages of all codes, to start a
+// MessageCatalog from.
+func DefaultMessages() MessageCatalog {
+	catalog := make(MessageCatalog, len(defaultMessages))
+	for code, message := range defaultMessages {
+		catalog[code] = message
+	}
+	return catalog
+}
+
+// errCode returns the SyntaxError with code at the current character, with
+// its message from p.Messages or defaultMessages.
+func (p *hjsonParser) errCode(code string, args ...interface{}) error {
+	format, ok := p.Messages[code]
+	if !ok {
+		format = defaultMessages[code]
+	}
+	err := p.errAt(fmt.Sprintf(format, args...)).(*SyntaxError)
+	err.Code, err.Args = code, args
+	return err
+}
@@ -0,0 +1,52 @@
+package hjson
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMessages(t *testing.T) {
+	var v interface{}
+	err := Unmarshal([]byte("{\n  \"a\" 1\n}"), &v)
+	var se *SyntaxError
+	if !errors.As(err, &se) || se.Code != "expected-colon" || !reflect.DeepEqual(se.Args, []interface{}{"1"}) {
+		t.Fatalf("unexpected %#v", err)
+	}
+	if se.Msg != "Expected ':' instead of '1'" {
+		t.Errorf("unexpected message %q", se.Msg)
+	}
+
+	catalog := DefaultMessages()
+	catalog["expected-colon"] = "':' erwartet statt '%[1]s'"
+	catalog["unmarshal-type"] = "Typ %[2]s kann %[1]s nicht aufnehmen"
+	decOpt, err := NewDecoderOptions(WithMessages(catalog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = UnmarshalWithOptions([]byte("{\n  \"a\" 1\n}"), &v, decOpt)
+	if !errors.As(err, &se) || se.Msg != "':' erwartet statt '1'" || se.Line != 2 || se.Column != 7 {
+		t.Errorf("unexpected %#v", err)
+	}
+	var config struct{ Port int }
+	err = UnmarshalWithOptions([]byte("port: x"), &config, decOpt)
+	var te *UnmarshalTypeError
+	if !errors.As(err, &te) || te.Msg != "Typ int kann string nicht aufnehmen" || te.Path != "port" {
+		t.Errorf("unexpected %#v", err)
+	}
+	// codes missing from the catalog keep their English messages
+	delete(catalog, "object-eof")
+	if err = UnmarshalWithOptions([]byte("{a: 1"), &v, decOpt); !errors.As(err, &se) || se.Msg != "End of input while parsing an object (did you forget a closing '}'?)" {
+		t.Errorf("unexpected %v", err)
+	}
+
+	if DefaultMessages()["expected-colon"] != "Expected ':' instead of '%[1]s'" {
+		t.Error("expected DefaultMessages to return a copy")
+	}
+	if _, err := NewDecoderOptions(WithMessages(MessageCatalog{"no-such-code": "x"})); err == nil {
+		t.Error("expected an error for an unknown code")
+	}
+	if _, err := NewDecoderOptions(WithMessages(nil)); err == nil {
+		t.Error("expected an error for a nil catalog")
+	}
+}
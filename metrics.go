@@ -0,0 +1,37 @@
+package hjson
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Metrics describes one call of UnmarshalWithOptions or MarshalWithOptions
+// (and of the functions using them), see SetMetricsHook.
+type Metrics struct {
+	// "decode" or "encode"
+	Op string
+	// Time spent in the call
+	Duration time.Duration
+	// Size of the input for decode and of the output for encode, in bytes
+	Size int
+	// Deepest nesting of arrays and objects (0 for a single value)
+	Depth int
+	// The error returned by the call, if any
+	Err error
+}
+
+var metricsHook atomic.Value // holds a func(Metrics)
+
+// SetMetricsHook installs fn to be called after every decode and encode,
+// for example to export metrics about the configuration or serialization
+// layer of a service without wrapping every call site. fn is called
+// synchronously and must be safe for concurrent use. A nil fn removes the
+// hook.
+func SetMetricsHook(fn func(m Metrics)) {
+	metricsHook.Store(fn)
+}
+
+func loadMetricsHook() func(Metrics) {
+	fn, _ := metricsHook.Load().(func(Metrics))
+	return fn
+}
@@ -0,0 +1,40 @@
+package hjson
+
+import "testing"
+
+func TestSetMetricsHook(t *testing.T) {
+	var got []Metrics
+	SetMetricsHook(func(m Metrics) { got = append(got, m) })
+	defer SetMetricsHook(nil)
+
+	var v interface{}
+	data := []byte("a: [1, {b: [2]}]")
+	if err := Unmarshal(data, &v); err != nil {
+		t.Fatal(err)
+	}
+	buf, err := Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Unmarshal([]byte("{"), &v)
+	Marshal(make(chan int))
+
+	if len(got) != 4 {
+		t.Fatalf("expected 4 calls, got %d", len(got))
+	}
+	if m := got[0]; m.Op != "decode" || m.Size != len(data) || m.Depth != 4 || m.Err != nil {
+		t.Errorf("unexpected decode metrics %+v", m)
+	}
+	if m := got[1]; m.Op != "encode" || m.Size != len(buf) || m.Depth != 4 || m.Err != nil {
+		t.Errorf("unexpected encode metrics %+v", m)
+	}
+	if got[2].Err == nil || got[3].Err == nil {
+		t.Errorf("expected errors, got %v and %v", got[2].Err, got[3].Err)
+	}
+
+	SetMetricsHook(nil)
+	Unmarshal(data, &v)
+	if len(got) != 4 {
+		t.Error("expected no call after removing the hook")
+	}
+}
@@ -0,0 +1,1346 @@
+package hjson
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NodeKind tells which kind of value a Node holds.
+type NodeKind int
+
+const (
+	// ValueNode holds a string, number, bool or null in Node.Value
+	ValueNode NodeKind = iota
+	// ObjectNode holds the members of an object in Node.Children
+	ObjectNode
+	// ArrayNode holds the elements of an array in Node.Children
+	ArrayNode
+)
+
+// Comments holds the comments around a Node. For nodes read by ParseNode
+// they are the source text between the values, including whitespace, line
+// breaks and separating commas, for example "\n  # port\n  ". For new nodes
+// each line is written on its own line at the indentation of the node, so
+// "# port" is enough.
+type Comments struct {
+	// Before the key of a member, or before the value of an array element
+	// or the root
+	Before string
+	// Between the colon after the key and the value of a member
+	Key string
+	// After the value on the same line; for the root, everything after the
+	// value
+	Line string
+	// Inside an object or array, after the last member or element
+	After string
+}
+
+// A Node is an Hjson value that keeps the order of object members and the
+// comments of the source, so that a document can be changed and written
+// back without losing them:
+//
+//	root, err := hjson.ParseNode(data)
+//	...
+//	err = root.Set("port", 8080)
+//	...
+//	data, err = root.Marshal()
+type Node struct {
+	Kind NodeKind
+	// The name of a member of an object
+	Key string
+	// bool, float64, string or nil, for ValueNode
+	Value interface{}
+	// The members or elements, for ObjectNode and ArrayNode
+	Children []*Node
+	Comments Comments
+
+	parsed       bool   // Before, Key, Line and keyGap are source text
+	parsedInside bool   // After is source text
+	braceless    bool   // a root object without braces
+	keyGap       string // source text between the key and the colon
+
+	// The source text of the key and value, written while Key and Value
+	// are unchanged
+	keyLiteral  string
+	parsedKey   string
+	literal     string
+	parsedValue interface{}
+
+	// The source of the key and value, see Span
+	keySpan, valueSpan Span
+
+	// The options matching the style of the source, for new nodes
+	style *EncoderOptions
+
+	// The object or array holding n, see Path
+	parent *Node
+	frozen bool // see Freeze
+}
+
+// A Span is the place of a key or value in the input of ParseNode, from
+// its first character up to the character after it.
+type Span struct {
+	Start Position
+	End   Position
+}
+
+// ParseNode parses the Hjson-encoded data into a tree of Nodes.
+//
+// Writing the tree with Node.Marshal gives back the same bytes as long as
+// it is unchanged: keys and values keep their quotes and number format, and
+// comments their whitespace and blank lines. New nodes are formatted like
+// the source, using its indentation, brace placement, quoting and line
+// breaks.
+func ParseNode(data []byte) (*Node, error) {
+	p := &hjsonParser{data: data}
+	p.resetAt()
+	p.white()
+	before := string(data[:p.pos()])
+
+	var n *Node
+	var end int
+	var err error
+	switch p.ch {
+	case '{', '[':
+		n, end, err = p.readNode()
+	default:
+		// assume we have a root object without braces
+		if n, err = p.readObjectNode(true); err == nil {
+			end = len(data)
+			break
+		}
+		// test if we are dealing with a single value instead
+		p.resetAt()
+		p.white()
+		if n2, end2, err2 := p.readNode(); err2 == nil && p.checkTrailingNode() == nil {
+			n, end, err = n2, end2, nil
+		}
+	}
+	if err == nil {
+		err = p.checkTrailingNode()
+	}
+	if err != nil {
+		return nil, err
+	}
+	n.Comments.Before = before
+	n.Comments.Line = string(data[end:])
+	if n.braceless {
+		// from the first member to the end of the last one
+		n.valueSpan = Span{Start: Position{Offset: len(before)}, End: Position{Offset: len(before)}}
+		if len(n.Children) > 0 {
+			n.valueSpan.End = n.Children[len(n.Children)-1].valueSpan.End
+		}
+	}
+	n.setStyle(detectStyle(data, n))
+	n.setPositions(lineStarts(data))
+	return n, nil
+}
+
+// lineStarts returns the offsets where the lines of data start.
+func lineStarts(data []byte) []int {
+	starts := []int{0}
+	for i, c := range data {
+		if c == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+// setPositions sets the lines and columns of the spans of n and its
+// children from their offsets.
+func (n *Node) setPositions(starts []int) {
+	positions := []*Position{&n.valueSpan.Start, &n.valueSpan.End}
+	if n.keyLiteral != "" {
+		positions = append(positions, &n.keySpan.Start, &n.keySpan.End)
+	}
+	for _, p := range positions {
+		line := sort.Search(len(starts), func(i int) bool { return starts[i] > p.Offset })
+		p.Line, p.Column = line, p.Offset-starts[line-1]+1
+	}
+	for _, c := range n.Children {
+		c.setPositions(starts)
+	}
+}
+
+// Span returns where the key and the value of n are in the input of
+// ParseNode, for tools like language servers that map nodes back to the
+// source. The key span is only set for object members, the value span of a
+// root object without braces goes from its first to the end of its last
+// member. The spans are not updated when the tree changes; nodes that were
+// not parsed, like those added with Set, have zero spans.
+func (n *Node) Span() (key, value Span) {
+	return n.keySpan, n.valueSpan
+}
+
+// setStyle sets the style of n and its children.
+func (n *Node) setStyle(style *EncoderOptions) {
+	n.style = style
+	for _, c := range n.Children {
+		c.setStyle(style)
+	}
+}
+
+// detectStyle returns the built-in options changed to match the style of
+// the parsed data: its end of line, the indentation of the first indented
+// member or element, the brace placement of the first object or array
+// after a key, and whether strings are always quoted. The defaults of
+// SetDefaultEncoderOptions are not used, they would mix with the source.
+func detectStyle(data []byte, root *Node) *EncoderOptions {
+	opt := builtinEncoderOptions()
+	opt.OutputFormat = OutputHjson
+	opt.Eol = "\n"
+	if bytes.Contains(data, []byte("\r\n")) {
+		opt.Eol = "\r\n"
+	}
+	indentFound, bracesFound := false, false
+	quoted, quoteless := false, false
+	var walk func(n *Node, depth int)
+	walk = func(n *Node, depth int) {
+		if !n.braceless && n.Kind != ValueNode {
+			depth++
+		}
+		for _, c := range n.Children {
+			if i := strings.LastIndex(c.Comments.Before, "\n"); !indentFound && depth > 0 && i >= 0 {
+				indent := strings.TrimSuffix(c.Comments.Before[i+1:], "\r")
+				if indent != "" && strings.Trim(indent, " \t") == "" && len(indent)%depth == 0 {
+					opt.IndentBy = indent[:len(indent)/depth]
+					indentFound = true
+				}
+			}
+			if !bracesFound && n.Kind == ObjectNode && c.Kind != ValueNode && len(c.Children) > 0 {
+				opt.BracesSameLine = !strings.Contains(c.Comments.Key, "\n")
+				bracesFound = true
+			}
+			if _, ok := c.Value.(string); ok && c.literal != "" {
+				if c.literal[0] == '"' {
+					quoted = true
+				} else if c.literal[0] != '\'' {
+					quoteless = true
+				}
+			}
+			walk(c, depth)
+		}
+	}
+	walk(root, 0)
+	opt.QuoteAlways = quoted && !quoteless
+	return &opt
+}
+
+// NewNode returns a Node holding v, formatted like Marshal does with the
+// built-in options when it is written. The defaults of
+// SetDefaultEncoderOptions are not used, so that they cannot change the
+// values of the tree. If v is a *Node it is returned as is.
+func NewNode(v interface{}) (*Node, error) {
+	if n, ok := v.(*Node); ok {
+		return n, nil
+	}
+	b, err := MarshalWithOptions(v, builtinEncoderOptions())
+	if err != nil {
+		return nil, err
+	}
+	n, err := ParseNode(b)
+	if err != nil {
+		return nil, err
+	}
+	n.forgetLayout()
+	return n, nil
+}
+
+// forgetLayout makes n and its children new nodes, keeping their comments.
+func (n *Node) forgetLayout() {
+	n.parsed, n.parsedInside, n.braceless, n.keyGap = false, false, false, ""
+	n.keyLiteral, n.literal, n.style = "", "", nil
+	n.keySpan, n.valueSpan = Span{}, Span{}
+	n.Comments = Comments{
+		Before: commentText(n.Comments.Before, n.Comments.Key),
+		Line:   commentText(n.Comments.Line),
+		After:  commentText(n.Comments.After),
+	}
+	for _, c := range n.Children {
+		c.forgetLayout()
+	}
+}
+
+// commentText returns the comments in the source texts, one per line,
+// without the whitespace, commas and blank lines around them.
+func commentText(texts ...string) string {
+	var items []string
+	for _, text := range texts {
+		line, others := splitComments(text)
+		items = appendComments(appendComments(items, line), others)
+	}
+	return strings.Join(nonBlank(items), "\n")
+}
+
+// trimComment removes the whitespace and blank lines around the lines of a
+// comment.
+func trimComment(text string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Get returns the member key of the object n, or nil if there is none. Like
+// Unmarshal, it uses the last member if key appears more than once.
+func (n *Node) Get(key string) *Node {
+	if n.Kind != ObjectNode {
+		return nil
+	}
+	for i := len(n.Children) - 1; i >= 0; i-- {
+		if n.Children[i].Key == key {
+			return n.Children[i]
+		}
+	}
+	return nil
+}
+
+// Set sets the member key of the object n to v, converted with NewNode. An
+// existing member keeps its key and comments, otherwise a new member is
+// added at the end.
+func (n *Node) Set(key string, v interface{}) error {
+	if n.frozen {
+		return errFrozen
+	}
+	if n.Kind != ObjectNode {
+		return errors.New("Cannot set key '" + key + "' of a node that is not an object")
+	}
+	if c := n.Get(key); c != nil {
+		return c.SetValue(v)
+	}
+	c, err := NewNode(v)
+	if err != nil {
+		return err
+	}
+	c.Key = key
+	c.parsed, c.keyGap = false, ""
+	c.parent = n
+	n.Children = append(n.Children, c)
+	return nil
+}
+
+// Append adds v, converted with NewNode, as the last element of the array
+// n.
+func (n *Node) Append(v interface{}) error {
+	if n.frozen {
+		return errFrozen
+	}
+	if n.Kind != ArrayNode {
+		return errors.New("Cannot append to a node that is not an array")
+	}
+	c, err := NewNode(v)
+	if err != nil {
+		return err
+	}
+	c.parsed = false
+	c.parent = n
+	n.Children = append(n.Children, c)
+	return nil
+}
+
+// Delete removes the members key from the object n and reports whether there
+// were any. It panics if n is frozen.
+func (n *Node) Delete(key string) bool {
+	if n.frozen {
+		panic(errFrozen)
+	}
+	if n.Kind != ObjectNode {
+		return false
+	}
+	children := n.Children[:0]
+	for _, c := range n.Children {
+		if c.Key != key {
+			children = append(children, c)
+		}
+	}
+	deleted := len(children) < len(n.Children)
+	for i := len(children); i < len(n.Children); i++ {
+		n.Children[i] = nil
+	}
+	n.Children = children
+	return deleted
+}
+
+// SetValue replaces the value of n with v, converted with NewNode, keeping
+// the key of n and the comments around it.
+func (n *Node) SetValue(v interface{}) error {
+	if n.frozen {
+		return errFrozen
+	}
+	nv, err := NewNode(v)
+	if err != nil {
+		return err
+	}
+	n.Kind, n.Value, n.Children = nv.Kind, nv.Value, nv.Children
+	for _, c := range n.Children {
+		c.parent = n
+	}
+	n.Comments.After, n.parsedInside = nv.Comments.After, nv.parsedInside
+	n.literal, n.parsedValue = nv.literal, nv.parsedValue
+	n.braceless = n.braceless && n.Kind == ObjectNode
+	return nil
+}
+
+// errFrozen is returned when a frozen node is changed, see Freeze.
+var errFrozen = errors.New("Cannot change a frozen node, change a Clone of it instead")
+
+// Clone returns a copy of the tree rooted at n that can be changed without
+// changing n. It is written like n, with the same comments and formatting,
+// and is not frozen. A clone of a member or element is the root of its own
+// tree.
+func (n *Node) Clone() *Node {
+	return n.clone(nil, false)
+}
+
+// Freeze returns a read-only copy of the tree rooted at n, which can be
+// shared by goroutines. The methods that change a frozen node fail, or
+// panic for Delete; its fields must not be assigned either. Changes need a
+// Clone, which is not frozen.
+func (n *Node) Freeze() *Node {
+	return n.clone(nil, true)
+}
+
+// Frozen reports whether n is part of a tree returned by Freeze.
+func (n *Node) Frozen() bool {
+	return n.frozen
+}
+
+func (n *Node) clone(parent *Node, frozen bool) *Node {
+	c := *n
+	c.parent, c.frozen = parent, frozen
+	if n.Children != nil {
+		c.Children = make([]*Node, len(n.Children))
+		for i, child := range n.Children {
+			c.Children[i] = child.clone(&c, frozen)
+		}
+	}
+	return &c
+}
+
+// A pathElem is a key or an index of a path, see Node.Lookup.
+type pathElem struct {
+	key   string
+	index int // -1 for a key
+}
+
+// formatPath joins keys and indexes into a path like "server.ports[0]",
+// the reverse of splitPath.
+func formatPath(elems []pathElem) string {
+	var path bytes.Buffer
+	for _, elem := range elems {
+		if elem.index >= 0 {
+			path.WriteByte('[')
+			path.WriteString(strconv.Itoa(elem.index))
+			path.WriteByte(']')
+			continue
+		}
+		if path.Len() > 0 {
+			path.WriteByte('.')
+		}
+		path.WriteString(elem.key)
+	}
+	return path.String()
+}
+
+// splitPath splits a path like "server.ports[0]" into its keys and indexes.
+// The empty path is the root and has none.
+func splitPath(path string) ([]pathElem, error) {
+	if path == "" {
+		return nil, nil
+	}
+	var elems []pathElem
+	for _, part := range strings.Split(path, ".") {
+		key := part
+		var indexes []pathElem
+		if i := strings.IndexByte(part, '['); i >= 0 {
+			key = part[:i]
+			for rest := part[i:]; rest != ""; {
+				end := strings.IndexByte(rest, ']')
+				if rest[0] != '[' || end < 0 {
+					return nil, errors.New("Invalid path '" + path + "'")
+				}
+				index, err := strconv.Atoi(rest[1:end])
+				if err != nil || index < 0 {
+					return nil, errors.New("Invalid index in path '" + path + "'")
+				}
+				indexes = append(indexes, pathElem{"", index})
+				rest = rest[end+1:]
+			}
+		}
+		if key == "" && (len(elems) > 0 || len(indexes) == 0) || strings.IndexByte(key, ']') >= 0 {
+			return nil, errors.New("Invalid path '" + path + "'")
+		}
+		if key != "" {
+			elems = append(elems, pathElem{key, -1})
+		}
+		elems = append(elems, indexes...)
+	}
+	return elems, nil
+}
+
+// child returns the member or element of n for elem, or nil if there is
+// none.
+func (n *Node) child(elem pathElem) *Node {
+	if elem.index < 0 {
+		return n.Get(elem.key)
+	}
+	if n.Kind != ArrayNode || elem.index >= len(n.Children) {
+		return nil
+	}
+	return n.Children[elem.index]
+}
+
+// Lookup returns the node at path below n, or nil if there is none. A path
+// is made of object keys separated by dots and array indexes in brackets,
+// like "server.ports[0]", the form used in the errors of Marshal; keys
+// containing dots or brackets cannot be looked up. The empty path is n
+// itself. It fails only if the path is malformed.
+func (n *Node) Lookup(path string) (*Node, error) {
+	elems, err := splitPath(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, elem := range elems {
+		if n = n.child(elem); n == nil {
+			break
+		}
+	}
+	return n, nil
+}
+
+// Path returns the path of n from the root of its tree, in the form taken
+// by Lookup, like "server.ports[0]"; "" for the root. It is the form used
+// by the errors of Marshal and by Diff. Nodes are known to their parent
+// when they are parsed or added with the methods of Node, not when they
+// are put into Children directly; a node removed from its parent is the
+// root of its own tree.
+func (n *Node) Path() string {
+	var elems []pathElem
+	for c := n; c.parent != nil; c = c.parent {
+		i := c.parent.indexOf(c)
+		if i < 0 {
+			break
+		}
+		if c.parent.Kind == ArrayNode {
+			elems = append(elems, pathElem{"", i})
+		} else {
+			elems = append(elems, pathElem{c.Key, -1})
+		}
+	}
+	for i, j := 0, len(elems)-1; i < j; i, j = i+1, j-1 {
+		elems[i], elems[j] = elems[j], elems[i]
+	}
+	return formatPath(elems)
+}
+
+// indexOf returns the index of the child c of n, or -1 if c is not one of
+// its children.
+func (n *Node) indexOf(c *Node) int {
+	for i, child := range n.Children {
+		if child == c {
+			return i
+		}
+	}
+	return -1
+}
+
+// SetPath sets the value at path below n (see Lookup) to v, converted with
+// NewNode. Missing objects along the path are added, like the last key is
+// by Set; array elements must exist.
+func (n *Node) SetPath(path string, v interface{}) error {
+	elems, err := splitPath(path)
+	if err != nil {
+		return err
+	}
+	if len(elems) == 0 {
+		return n.SetValue(v)
+	}
+	if n, err = n.makePath("set", path, elems); err != nil {
+		return err
+	}
+	last := elems[len(elems)-1]
+	c := n.child(last)
+	switch {
+	case c == nil && last.index >= 0:
+		return fmt.Errorf("Cannot set '%s': no element %d", path, last.index)
+	case c == nil:
+		return n.Set(last.key, v)
+	}
+	return c.SetValue(v)
+}
+
+// makePath returns the object or array that holds the node at elems below
+// n, adding the missing objects along the way. op names the operation in
+// the errors.
+func (n *Node) makePath(op, path string, elems []pathElem) (*Node, error) {
+	for _, elem := range elems[:len(elems)-1] {
+		c := n.child(elem)
+		switch {
+		case c == nil && elem.index >= 0:
+			return nil, fmt.Errorf("Cannot %s '%s': no element %d", op, path, elem.index)
+		case c == nil:
+			if err := n.Set(elem.key, map[string]interface{}{}); err != nil {
+				return nil, err
+			}
+			c = n.Get(elem.key)
+		}
+		n = c
+	}
+	return n, nil
+}
+
+// Detach removes the member or element at path below n (see Lookup) and
+// returns it as the root of a tree of its own, for example to move a
+// section of a document into a file of its own. It keeps its comments and
+// is written like new nodes are in n, see ParseNode.
+func (n *Node) Detach(path string) (*Node, error) {
+	if n.frozen {
+		return nil, errFrozen
+	}
+	elems, err := splitPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(elems) == 0 {
+		return nil, errors.New("Cannot detach the root")
+	}
+	for _, elem := range elems[:len(elems)-1] {
+		if n = n.child(elem); n == nil {
+			break
+		}
+	}
+	var c *Node
+	if n != nil {
+		c = n.child(elems[len(elems)-1])
+	}
+	if c == nil {
+		return nil, fmt.Errorf("Cannot detach '%s': no such member or element", path)
+	}
+	n.remove(n.indexOf(c))
+	style := c.style
+	c.forgetLayout()
+	c.setStyle(style)
+	c.Key, c.parent = "", nil
+	return c, nil
+}
+
+// remove removes the i-th child of n. A parsed child after it that was on
+// the same line keeps the line break before the removed child, and the
+// comma after the child before it is removed with the last child.
+func (n *Node) remove(i int) {
+	c := n.Children[i]
+	if i+1 < len(n.Children) {
+		next := n.Children[i+1]
+		if br := strings.LastIndexByte(c.Comments.Before, '\n'); c.parsed && next.parsed && br >= 0 && !strings.Contains(next.Comments.Before, "\n") {
+			next.Comments.Before = c.Comments.Before[br:] + strings.TrimLeft(next.Comments.Before, " \t,")
+		}
+	} else if i > 0 {
+		if prev := n.Children[i-1]; prev.parsed && strings.Trim(prev.Comments.Line, " \t,") == "" {
+			prev.Comments.Line = ""
+		}
+	}
+	copy(n.Children[i:], n.Children[i+1:])
+	n.Children[len(n.Children)-1] = nil
+	n.Children = n.Children[:len(n.Children)-1]
+}
+
+// AttachAt puts subtree at path below n (see Lookup), the reverse of
+// Detach: as the member with the last key of path, replacing a member with
+// that key, or into an array before the element at the last index, which
+// may be the length of the array to append it. Missing objects along the
+// path are added like by SetPath. subtree keeps its comments and is
+// written like new nodes are in n, see ParseNode. It must be the root of a
+// tree, like the trees returned by Detach and ParseNode.
+func (n *Node) AttachAt(path string, subtree *Node) error {
+	if n.frozen {
+		return errFrozen
+	}
+	if subtree.parent != nil && subtree.parent.indexOf(subtree) >= 0 {
+		return errors.New("Cannot attach a node that is part of another tree, Detach it first")
+	}
+	elems, err := splitPath(path)
+	if err != nil {
+		return err
+	}
+	if len(elems) == 0 {
+		return errors.New("Cannot attach at the root")
+	}
+	if n, err = n.makePath("attach at", path, elems); err != nil {
+		return err
+	}
+	for p := n; p != nil; p = p.parent {
+		if p == subtree {
+			return errors.New("Cannot attach a node below itself")
+		}
+	}
+	last := elems[len(elems)-1]
+	i := len(n.Children)
+	switch {
+	case last.index < 0 && n.Kind != ObjectNode:
+		return errors.New("Cannot set key '" + last.key + "' of a node that is not an object")
+	case last.index < 0:
+		if c := n.Get(last.key); c != nil {
+			i = n.indexOf(c)
+			n.remove(i)
+		}
+	case n.Kind != ArrayNode || last.index > len(n.Children):
+		return fmt.Errorf("Cannot attach at '%s': no element %d", path, last.index)
+	default:
+		i = last.index
+	}
+	subtree.Key = last.key
+	n.insert(i, subtree)
+	return nil
+}
+
+// insert makes the tree c the i-th child of n, written like new nodes in
+// the style of n.
+func (n *Node) insert(i int, c *Node) {
+	c.forgetLayout()
+	c.setStyle(n.style)
+	c.parent = n
+	n.Children = append(n.Children, nil)
+	copy(n.Children[i+1:], n.Children[i:])
+	n.Children[i] = c
+}
+
+// splitPointer splits a JSON Pointer (RFC 6901) like "/servers/0/port" into
+// its reference tokens, unescaping ~1 to / and ~0 to ~.
+func splitPointer(ptr string) ([]string, error) {
+	if ptr == "" {
+		return nil, nil
+	}
+	if ptr[0] != '/' {
+		return nil, errors.New("Invalid JSON Pointer '" + ptr + "': it must start with '/'")
+	}
+	tokens := strings.Split(ptr[1:], "/")
+	for i, tok := range tokens {
+		for j := 0; j < len(tok); j++ {
+			if tok[j] == '~' && (j+1 == len(tok) || tok[j+1] != '0' && tok[j+1] != '1') {
+				return nil, errors.New("Invalid escape in JSON Pointer '" + ptr + "'")
+			}
+		}
+		tokens[i] = strings.Replace(strings.Replace(tok, "~1", "/", -1), "~0", "~", -1)
+	}
+	return tokens, nil
+}
+
+// arrayIndex returns the array index of a reference token of a JSON
+// Pointer: digits without leading zeros.
+func arrayIndex(tok string) (int, bool) {
+	if tok == "" || len(tok) > 1 && tok[0] == '0' || strings.Trim(tok, "0123456789") != "" {
+		return 0, false
+	}
+	index, err := strconv.Atoi(tok)
+	return index, err == nil
+}
+
+// pointerChild returns the member or element of n for the reference token
+// tok, or nil if there is none.
+func (n *Node) pointerChild(tok string) *Node {
+	if n.Kind == ArrayNode {
+		if index, ok := arrayIndex(tok); ok && index < len(n.Children) {
+			return n.Children[index]
+		}
+		return nil
+	}
+	return n.Get(tok)
+}
+
+// Pointer returns the node referenced by the JSON Pointer (RFC 6901) ptr
+// below n, like "/servers/0/port", or nil if there is none. The empty
+// pointer references n itself. Unlike the paths of Lookup, pointers can
+// reference any key, with "/" escaped as ~1 and "~" as ~0. It fails only
+// if the pointer is malformed.
+func (n *Node) Pointer(ptr string) (*Node, error) {
+	tokens, err := splitPointer(ptr)
+	if err != nil {
+		return nil, err
+	}
+	for _, tok := range tokens {
+		if n = n.pointerChild(tok); n == nil {
+			break
+		}
+	}
+	return n, nil
+}
+
+// BuildIndex returns the nodes of the tree rooted at n by their JSON
+// Pointer (see Pointer), "" for n itself, so that many values of a large
+// document can be looked up without walking the tree each time. Like Get,
+// only the last of duplicate keys is indexed. The index is not updated
+// when the tree changes.
+func BuildIndex(n *Node) map[string]*Node {
+	index := map[string]*Node{}
+	addToIndex(index, "", n)
+	return index
+}
+
+func addToIndex(index map[string]*Node, ptr string, n *Node) {
+	index[ptr] = n
+	switch n.Kind {
+	case ArrayNode:
+		for i, c := range n.Children {
+			addToIndex(index, ptr+"/"+strconv.Itoa(i), c)
+		}
+	case ObjectNode:
+		last := memberIndex(n)
+		for i, c := range n.Children {
+			if last[c.Key] == i {
+				addToIndex(index, ptr+"/"+escapePointerToken(c.Key), c)
+			}
+		}
+	}
+}
+
+// escapePointerToken escapes a key for a JSON Pointer, the reverse of
+// splitPointer.
+func escapePointerToken(key string) string {
+	return strings.Replace(strings.Replace(key, "~", "~0", -1), "/", "~1", -1)
+}
+
+// SetPointer sets the value referenced by the JSON Pointer ptr below n (see
+// Pointer) to v, converted with NewNode, like SetPath does: missing objects
+// along the pointer are added and array elements must exist, except that
+// the last token "-" appends v to an array.
+func (n *Node) SetPointer(ptr string, v interface{}) error {
+	tokens, err := splitPointer(ptr)
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		return n.SetValue(v)
+	}
+	for i, tok := range tokens {
+		last := i == len(tokens)-1
+		c := n.pointerChild(tok)
+		switch {
+		case n.Kind == ArrayNode && last && tok == "-":
+			return n.Append(v)
+		case n.Kind == ArrayNode && c == nil:
+			return fmt.Errorf("Cannot set '%s': no element %s", ptr, tok)
+		case last && c == nil:
+			return n.Set(tok, v)
+		case last:
+			return c.SetValue(v)
+		case c == nil:
+			if err = n.Set(tok, map[string]interface{}{}); err != nil {
+				return err
+			}
+			c = n.Get(tok)
+		}
+		n = c
+	}
+	return nil
+}
+
+// Interface returns the value of n like Unmarshal decodes it into an
+// interface{}: as a bool, float64, string, []interface{},
+// map[string]interface{} or nil.
+func (n *Node) Interface() interface{} {
+	switch n.Kind {
+	case ObjectNode:
+		object := make(map[string]interface{}, len(n.Children))
+		for _, c := range n.Children {
+			object[c.Key] = c.Interface()
+		}
+		return object
+	case ArrayNode:
+		array := make([]interface{}, len(n.Children))
+		for i, c := range n.Children {
+			array[i] = c.Interface()
+		}
+		return array
+	}
+	return n.Value
+}
+
+// Marshal returns the Hjson encoding of the tree rooted at n. The parts
+// read by ParseNode are written as in the source, new nodes and changed
+// values are formatted like the source (see ParseNode) or, for trees made
+// with NewNode, like Marshal does with the built-in options.
+func (n *Node) Marshal() ([]byte, error) {
+	options := builtinEncoderOptions()
+	if n.style != nil {
+		options = *n.style
+	}
+	e, err := newHjsonEncoder(options)
+	if err != nil {
+		return nil, err
+	}
+	if n.parsed {
+		e.WriteString(n.Comments.Before)
+	} else if n.Comments.Before != "" {
+		e.writeNodeComment(n.Comments.Before)
+		e.WriteString(e.Eol)
+	}
+	line := nodeLine(n)
+	if err = e.writeNode(n, "", line+"\n", true); err != nil {
+		return nil, err
+	}
+	e.WriteString(line)
+	return e.Bytes(), nil
+}
+
+// nodeLine returns the text written after the value of n on its line.
+func nodeLine(n *Node) string {
+	line := n.Comments.Line
+	if !n.parsed && line != "" && line[0] != ' ' && line[0] != '\t' {
+		line = " " + line
+	}
+	return line
+}
+
+// nodeFollow returns the text written after the i-th child of n and its
+// line comment.
+func nodeFollow(n *Node, i int) string {
+	if i+1 < len(n.Children) {
+		if next := n.Children[i+1]; next.parsed && (n.Children[i].parsed || strings.Contains(next.Comments.Before, "\n")) {
+			return next.Comments.Before
+		}
+		return "\n"
+	}
+	if !n.parsedInside {
+		return "\n"
+	}
+	switch {
+	case n.braceless:
+		return n.Comments.After + "\n"
+	case n.Kind == ArrayNode:
+		return n.Comments.After + "]"
+	}
+	return n.Comments.After + "}"
+}
+
+// writeNodeComment writes each line of a comment of a new node on its own
+// line.
+func (e *hjsonEncoder) writeNodeComment(text string) {
+	for _, line := range strings.Split(trimComment(text), "\n") {
+		if line != "" {
+			e.writeNodeIndent()
+			e.WriteString(line)
+		}
+	}
+}
+
+// writeNodeIndent starts a line of a new node, except at the start of the
+// output.
+func (e *hjsonEncoder) writeNodeIndent() {
+	if e.Len() == 0 {
+		e.WriteString(strings.Repeat(e.IndentBy, e.indent))
+	} else {
+		e.writeIndent(e.indent)
+	}
+}
+
+// startsLine reports whether text starts with a line break after spaces and
+// tabs, so that a quoteless string can be written before it.
+func startsLine(text string) bool {
+	text = strings.TrimLeft(text, " \t")
+	return strings.HasPrefix(text, "\n") || strings.HasPrefix(text, "\r")
+}
+
+// unchanged reports whether the value of n is the one it was parsed with.
+func (n *Node) unchanged() bool {
+	if n.literal == "" || n.Kind != ValueNode {
+		return false
+	}
+	if t := reflect.TypeOf(n.Value); t != nil && !t.Comparable() {
+		return false
+	}
+	return n.Value == n.parsedValue
+}
+
+// writeNode writes the value of n after separator. follow is the text
+// written after the value, quoteless strings are only used if it starts
+// with a line break.
+func (e *hjsonEncoder) writeNode(n *Node, separator, follow string, isRoot bool) error {
+	if n.Kind == ValueNode {
+		s, ok := n.Value.(string)
+		if n.unchanged() && (!ok || n.literal[0] == '"' || n.literal[0] == '\'' || startsLine(follow)) {
+			e.WriteString(separator + n.literal)
+			return nil
+		}
+		if ok && !startsLine(follow) {
+			quoteAlways := e.QuoteAlways
+			e.QuoteAlways = true
+			e.quote(s, separator, isRoot)
+			e.QuoteAlways = quoteAlways
+			return nil
+		}
+		return e.str(reflect.ValueOf(n.Value), true, separator, isRoot)
+	}
+
+	open, close := "{", "}"
+	if n.Kind == ArrayNode {
+		open, close = "[", "]"
+	}
+	if !n.parsedInside && len(n.Children) == 0 && n.Comments.After == "" {
+		if !n.braceless {
+			e.WriteString(separator + open + close)
+		}
+		return nil
+	}
+	indent1 := e.indent
+	if !n.braceless {
+		e.WriteString(separator + open)
+		if err := e.nest(); err != nil {
+			return err
+		}
+	}
+	inline := n.parsedInside && writtenInline(n)
+	blanks := ""     // of the last parsed node before new nodes, like in { a: 1 }
+	ownLine := false // the last child is on a line started here
+	for i, c := range n.Children {
+		if c.parsed && i > 0 && !inline && !n.Children[i-1].parsed && !strings.Contains(c.Comments.Before, "\n") {
+			// a new node before it ends its line
+			e.writeIndent(e.indent)
+			e.WriteString(strings.TrimLeft(c.Comments.Before, " \t,"))
+			ownLine = true
+		} else if c.parsed {
+			e.WriteString(c.Comments.Before)
+			ownLine = false
+		} else if inline {
+			// like [1, 2], new nodes continue the line
+			if i > 0 && !strings.Contains(nodeLine(n.Children[i-1]), ",") {
+				if trimmed := e.trimBlanks(); trimmed != "" {
+					blanks = trimmed
+				}
+				e.WriteString(",")
+			}
+			if i > 0 && !bytes.HasSuffix(e.Bytes(), []byte(" ")) {
+				e.WriteString(" ")
+			}
+			if err := e.writeInlineMember(n, c); err != nil {
+				return err
+			}
+			if i+1 < len(n.Children) && n.Children[i+1].parsed {
+				e.WriteString(", ")
+			}
+			continue
+		} else {
+			if i > 0 && n.Children[i-1].parsed {
+				e.trimBlanks()
+			}
+			e.writeNodeComment(c.Comments.Before)
+			e.writeNodeIndent()
+			ownLine = true
+		}
+		separator := ""
+		if n.Kind == ObjectNode {
+			if c.keyLiteral != "" && c.Key == c.parsedKey {
+				e.WriteString(c.keyLiteral)
+			} else {
+				e.WriteString(e.quoteName(c.Key))
+			}
+			e.WriteString(c.keyGap)
+			e.WriteString(":")
+			separator = " "
+			if c.parsed {
+				separator = c.Comments.Key
+			} else if c.Kind != ValueNode && len(c.Children) > 0 && !e.BracesSameLine {
+				e.writeIndent(e.indent)
+				separator = ""
+			}
+		}
+		line := nodeLine(c)
+		if err := e.writeNode(c, separator, line+nodeFollow(n, i), false); err != nil {
+			return err
+		}
+		e.WriteString(line)
+	}
+	if n.parsedInside {
+		if last := len(n.Children) - 1; inline && !n.Children[last].parsed {
+			e.WriteString(blanks)
+		}
+		e.WriteString(n.Comments.After)
+		if !inline && ownLine && !strings.Contains(n.Comments.After, "\n") {
+			// the nodes at the end are on their own lines
+			e.trimBlanks()
+			if !n.braceless {
+				e.writeIndent(indent1)
+			}
+		}
+	} else {
+		e.writeNodeComment(n.Comments.After)
+		if !n.braceless {
+			e.writeIndent(indent1)
+		}
+	}
+	e.indent = indent1
+	if !n.braceless {
+		e.WriteString(close)
+	}
+	return nil
+}
+
+// writtenInline reports whether the parsed container n is written on a
+// single line in the source, like [1, 2], so that new nodes are added to
+// that line. New nodes with comments make n expand to more lines.
+func writtenInline(n *Node) bool {
+	if n.braceless || strings.Contains(n.Comments.After, "\n") {
+		return false
+	}
+	parsed := false
+	for _, c := range n.Children {
+		if !c.parsed {
+			if c.Comments.Before != "" || c.Comments.Line != "" || c.Comments.After != "" || c.Kind != ValueNode && !newNodeInline(c) {
+				return false
+			}
+			continue
+		}
+		parsed = true
+		if strings.Contains(c.Comments.Before+c.Comments.Key+c.Comments.Line+c.literal, "\n") {
+			return false
+		}
+		if c.Kind != ValueNode && len(c.Children) > 0 && !writtenInline(c) {
+			return false
+		}
+	}
+	return parsed
+}
+
+// newNodeInline reports whether the new container n has no comments, and
+// neither have its children, so that it can be written on a single line.
+func newNodeInline(n *Node) bool {
+	if n.Comments.Before != "" || n.Comments.Line != "" || n.Comments.After != "" {
+		return false
+	}
+	for _, c := range n.Children {
+		if c.parsed || !newNodeInline(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// writeInlineNode writes the new node n after separator on a single line,
+// as in {a: 1, b: [2, 3]}.
+func (e *hjsonEncoder) writeInlineNode(n *Node, separator string) error {
+	switch n.Kind {
+	case ObjectNode, ArrayNode:
+	default:
+		if s, ok := n.Value.(string); ok {
+			e.WriteString(separator + e.jsonString(s))
+			return nil
+		}
+		return e.writeNode(n, separator, ",", false)
+	}
+	open, close := "{", "}"
+	if n.Kind == ArrayNode {
+		open, close = "[", "]"
+	}
+	e.WriteString(separator + open)
+	for i, c := range n.Children {
+		if i > 0 {
+			e.WriteString(", ")
+		}
+		if err := e.writeInlineMember(n, c); err != nil {
+			return err
+		}
+	}
+	e.WriteString(close)
+	return nil
+}
+
+// writeInlineMember writes the new child c of n with its key, if n is an
+// object, for writeInlineNode.
+func (e *hjsonEncoder) writeInlineMember(n, c *Node) error {
+	if n.Kind != ObjectNode {
+		return e.writeInlineNode(c, "")
+	}
+	e.WriteString(e.quoteName(c.Key) + ":")
+	return e.writeInlineNode(c, " ")
+}
+
+// trimBlanks removes the spaces and tabs at the end of the output and
+// returns them.
+func (e *hjsonEncoder) trimBlanks() string {
+	b := e.Bytes()
+	n := len(b)
+	for n > 0 && (b[n-1] == ' ' || b[n-1] == '\t') {
+		n--
+	}
+	blanks := string(b[n:])
+	e.Truncate(n)
+	return blanks
+}
+
+// pos returns the offset of the current character in the input.
+func (p *hjsonParser) pos() int {
+	if p.ch == 0 {
+		return len(p.data)
+	}
+	return p.at - 1
+}
+
+func (p *hjsonParser) checkTrailingNode() error {
+	p.white()
+	if p.ch > 0 {
+		return p.errCode("trailing-characters")
+	}
+	return nil
+}
+
+// readNode parses a value into a Node and also returns the offset of the
+// end of the value in the input.
+func (p *hjsonParser) readNode() (*Node, int, error) {
+	start := p.pos()
+	var n *Node
+	var err error
+	switch p.ch {
+	case '{':
+		n, err = p.readObjectNode(false)
+	case '[':
+		n, err = p.readArrayNode()
+	default:
+		return p.readValueNode()
+	}
+	if err != nil {
+		return nil, p.pos(), err
+	}
+	n.valueSpan = Span{Start: Position{Offset: start}, End: Position{Offset: p.pos()}}
+	return n, p.pos(), nil
+}
+
+// readValueNode parses a string, number, bool or null into a Node, like
+// readNode.
+func (p *hjsonParser) readValueNode() (*Node, int, error) {
+	start := p.pos()
+	var value interface{}
+	var err error
+	end := start
+	if p.ch == '"' || p.ch == '\'' {
+		value, err = p.readString(true)
+		end = p.pos()
+	} else {
+		var literal string
+		value, literal, err = p.readTfnns()
+		end += len(literal)
+	}
+	if err != nil {
+		return nil, end, err
+	}
+	literal := string(p.data[start:end])
+	span := Span{Start: Position{Offset: start}, End: Position{Offset: end}}
+	return &Node{Kind: ValueNode, Value: value, parsed: true, literal: literal, parsedValue: value, valueSpan: span}, end, nil
+}
+
+// readLine parses the spaces, comments and comma that follow a value on its
+// line, or a comma on a later line, and returns the source text from end,
+// the end of the value.
+func (p *hjsonParser) readLine(end int) string {
+	comma := false
+	for {
+		if p.ch == ' ' || p.ch == '\t' {
+			p.next()
+		} else if p.ch == ',' && !comma {
+			comma = true
+			p.next()
+		} else if !p.skipComment() {
+			if comma {
+				break
+			}
+			// in Hjson the comma may also be on a later line
+			at, ch := p.at, p.ch
+			p.white()
+			if p.ch != ',' {
+				p.at, p.ch = at, ch
+				break
+			}
+		}
+	}
+	return string(p.data[end:p.pos()])
+}
+
+func (p *hjsonParser) readObjectNode(withoutBraces bool) (*Node, error) {
+	n := &Node{Kind: ObjectNode, parsed: true, parsedInside: true, braceless: withoutBraces}
+	if !withoutBraces {
+		// assuming ch == '{'
+		p.next()
+	}
+	for {
+		start := p.pos()
+		p.white()
+		gap := string(p.data[start:p.pos()])
+		if p.ch == 0 {
+			if !withoutBraces {
+				return nil, p.errCode("object-eof")
+			}
+			n.Comments.After = gap
+			return n, nil
+		}
+		if p.ch == '}' && !withoutBraces {
+			n.Comments.After = gap
+			p.next()
+			return n, nil
+		}
+		keyStart := p.pos()
+		quoted := p.ch == '"' || p.ch == '\''
+		key, err := p.readKeyname()
+		if err != nil {
+			return nil, err
+		}
+		keyEnd := p.pos()
+		if !quoted {
+			keyEnd = keyStart + len(key)
+		}
+		p.white()
+		if p.ch != ':' {
+			return nil, p.errCode("expected-colon", string(p.ch))
+		}
+		keyGap := string(p.data[keyEnd:p.pos()])
+		p.next()
+		colonEnd := p.pos()
+		p.white()
+		valueStart := p.pos()
+		c, end, err := p.readNode()
+		if err != nil {
+			return nil, err
+		}
+		c.Key, c.keyGap = key, keyGap
+		c.keyLiteral, c.parsedKey = string(p.data[keyStart:keyEnd]), key
+		c.keySpan = Span{Start: Position{Offset: keyStart}, End: Position{Offset: keyEnd}}
+		c.Comments.Before = gap
+		c.Comments.Key = string(p.data[colonEnd:valueStart])
+		c.Comments.Line = p.readLine(end)
+		c.parent = n
+		n.Children = append(n.Children, c)
+	}
+}
+
+func (p *hjsonParser) readArrayNode() (*Node, error) {
+	n := &Node{Kind: ArrayNode, parsed: true, parsedInside: true}
+	// assuming ch == '['
+	p.next()
+	for {
+		start := p.pos()
+		p.white()
+		gap := string(p.data[start:p.pos()])
+		if p.ch == 0 {
+			return nil, p.errCode("array-eof")
+		}
+		if p.ch == ']' {
+			n.Comments.After = gap
+			p.next()
+			return n, nil
+		}
+		c, end, err := p.readNode()
+		if err != nil {
+			return nil, err
+		}
+		c.Comments.Before = gap
+		c.Comments.Line = p.readLine(end)
+		c.parent = n
+		n.Children = append(n.Children, c)
+	}
+}
@@ -0,0 +1,703 @@
+package hjson
+
+import (
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestNodeAssets(t *testing.T) {
+	files := strings.Split(string(getContent("assets/testlist.txt")), "\n")
+	for _, file := range files {
+		if file == "" || strings.HasPrefix(file, "stringify/quotes") || strings.HasPrefix(file, "extra/") {
+			continue
+		}
+		name := strings.TrimSuffix(file, "_test"+filepath.Ext(file))
+		data := getTestContent(name)
+		var expected interface{}
+		err := Unmarshal(data, &expected)
+		node, nodeErr := ParseNode(data)
+		if (err == nil) != (nodeErr == nil) {
+			t.Errorf("%s: Unmarshal error %v, ParseNode error %v", name, err, nodeErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if v := node.Interface(); !reflect.DeepEqual(v, expected) {
+			t.Errorf("%s: expected\n%#v\ngot\n%#v", name, expected, v)
+		}
+		out, err := node.Marshal()
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if string(out) != string(data) {
+			t.Errorf("%s: expected the unchanged input, got\n%s", name, out)
+		}
+
+		// values written again are formatted like the source
+		for _, c := range node.Children {
+			c.SetValue(c.Interface())
+		}
+		if out, err = node.Marshal(); err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		var actual interface{}
+		if err = Unmarshal(out, &actual); err != nil {
+			t.Errorf("%s: %v in\n%s", name, err, out)
+		} else if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("%s: the output decodes to a different value\n%s", name, out)
+		}
+	}
+}
+
+func TestNodeComments(t *testing.T) {
+	data := `# server settings
+{
+  # the port to listen on
+  port: 80 # http
+  hosts: [
+    a.example.com
+    b.example.com
+  ]
+
+  /* limits */
+  limits: {
+    conns: 10, rate: 5
+  }
+}
+# end
+`
+	node, err := ParseNode([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := node.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != data {
+		t.Errorf("expected the unchanged input, got\n%s", out)
+	}
+
+	port := node.Get("port")
+	if port == nil || port.Value != 80.0 || port.Comments.Before != "\n  # the port to listen on\n  " || port.Comments.Line != " # http" {
+		t.Fatalf("unexpected port %#v", port)
+	}
+	if err = node.Set("port", 8080); err != nil {
+		t.Fatal(err)
+	}
+	if err = node.Get("limits").Set("conns", "many # really"); err != nil {
+		t.Fatal(err)
+	}
+	if !node.Delete("hosts") {
+		t.Error("expected hosts to be deleted")
+	}
+	if err = node.Set("debug", map[string]interface{}{"level": 2}); err != nil {
+		t.Fatal(err)
+	}
+	node.Get("debug").Comments.Before = "# added"
+
+	out, err = node.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `# server settings
+{
+  # the port to listen on
+  port: 8080 # http
+
+  /* limits */
+  limits: {
+    conns: "many # really", rate: 5
+  }
+  # added
+  debug: {
+    level: 2
+  }
+}
+# end
+`
+	if string(out) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out)
+	}
+}
+
+func TestNodeNew(t *testing.T) {
+	node, err := NewNode(struct {
+		B int `json:"b" comment:"first"`
+		A []string
+	}{1, []string{"x"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(node.Children) != 2 || node.Children[0].Key != "b" || node.Children[0].Comments.Before != "# first" {
+		t.Fatalf("unexpected %#v", node)
+	}
+	out, err := node.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\n  # first\n  b: 1\n  A:\n  [\n    x\n  ]\n}"
+	if string(out) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out)
+	}
+
+	if err = node.Children[1].Set("a", 1); err == nil {
+		t.Error("expected an error for Set on an array")
+	}
+}
+
+func TestNodeStyle(t *testing.T) {
+	data := "{\r\n    \"name\": \"app\",\r\n    'path': '/tmp'\r\n    size: 1.50\r\n}\r\n"
+	node, err := ParseNode([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = node.Set("owner", map[string]interface{}{"name": "root"}); err != nil {
+		t.Fatal(err)
+	}
+	node.Get("size").Value = 2.0
+	out, err := node.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\r\n    \"name\": \"app\",\r\n    'path': '/tmp'\r\n    size: 2\r\n    owner:\r\n    {\r\n        name: \"root\"\r\n    }\r\n}\r\n"
+	if string(out) != expected {
+		t.Errorf("expected\n%q\ngot\n%q", expected, out)
+	}
+}
+
+func TestNodeStyleDefaults(t *testing.T) {
+	encOpt := DefaultOptions()
+	defer SetDefaultEncoderOptions(encOpt)
+	house := DefaultOptions()
+	house.Eol = "\r\n"
+	house.OutputFormat = OutputJSON5
+	if err := SetDefaultEncoderOptions(house); err != nil {
+		t.Fatal(err)
+	}
+
+	// the style of the source wins over the defaults
+	node, err := ParseNode([]byte("a: 1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = node.Set("m", map[string]interface{}{"x": "y"}); err != nil {
+		t.Fatal(err)
+	}
+	out, err := node.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "a: 1\nm:\n{\n  x: y\n}\n"; string(out) != expected {
+		t.Errorf("expected %q, got %q", expected, out)
+	}
+}
+
+func TestNewNodeDefaults(t *testing.T) {
+	encOpt := DefaultOptions()
+	defer SetDefaultEncoderOptions(encOpt)
+	house := DefaultOptions()
+	house.OutputFormat = OutputJSON5
+	house.QuoteAlways = true
+	if err := SetDefaultEncoderOptions(house); err != nil {
+		t.Fatal(err)
+	}
+
+	node, err := NewNode(map[string]interface{}{"inf": math.Inf(1), "s": "text"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := node.Interface(); !reflect.DeepEqual(v, map[string]interface{}{"inf": nil, "s": "text"}) {
+		t.Errorf("unexpected value %#v", v)
+	}
+	out, err := node.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "{\n  inf: null\n  s: text\n}"; string(out) != expected {
+		t.Errorf("expected %q, got %q", expected, out)
+	}
+}
+
+func TestNodeInline(t *testing.T) {
+	data := "list: [1, 2]\nobj: { a: 1 } # one line\ne: []\nf: {b: 1}\ng: [\n  1\n]\n"
+	node, err := ParseNode([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = node.SetPointer("/list/-", 3); err != nil {
+		t.Fatal(err)
+	}
+	if err = node.Get("obj").Set("b", "x y"); err != nil {
+		t.Fatal(err)
+	}
+	if err = node.Get("obj").Set("c", map[string]interface{}{"d": []int{4, 5}}); err != nil {
+		t.Fatal(err)
+	}
+	if err = node.Get("e").Append(1); err != nil {
+		t.Fatal(err)
+	}
+	if err = node.Get("f").Set("c", 2); err != nil {
+		t.Fatal(err)
+	}
+	node.Get("f").Get("c").Comments.Line = "# new"
+	if err = node.Get("g").Append(2); err != nil {
+		t.Fatal(err)
+	}
+	out, err := node.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// containers on one line stay on it, unless a new node has comments
+	expected := `list: [1, 2, 3]
+obj: { a: 1, b: "x y", c: {d: [4, 5]} } # one line
+e: [
+  1
+]
+f: {b: 1
+  c: 2 # new
+}
+g: [
+  1
+  2
+]
+`
+	if string(out) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out)
+	}
+	var v interface{}
+	if err = Unmarshal(out, &v); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNodePath(t *testing.T) {
+	data := `# config
+server: {
+  # the port to listen on
+  port: 80
+  hosts: [
+    { name: "a.example.com" }
+    [1, 2]
+  ]
+}
+`
+	node, err := ParseNode([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, expected := range map[string]interface{}{
+		"server.port":            80.0,
+		"server.hosts[0].name":   "a.example.com",
+		"server.hosts[1][1]":     2.0,
+		"server.missing":         nil,
+		"server.hosts[2]":        nil,
+		"server.port.x":          nil,
+		"server.hosts.name":      nil,
+		"server.hosts[0][0]":     nil,
+		"server.hosts[0].name.x": nil,
+	} {
+		n, err := node.Lookup(path)
+		if err != nil {
+			t.Errorf("%s: %v", path, err)
+		} else if n == nil && expected != nil || n != nil && n.Value != expected {
+			t.Errorf("%s: expected %v, got %#v", path, expected, n)
+		}
+	}
+	if n, err := node.Lookup(""); err != nil || n != node {
+		t.Errorf("expected the root for the empty path, got %v, %v", n, err)
+	}
+	for _, path := range []string{"a..b", "a[x]", "a[-1]", "a[0", "a]", ".a", "a[0]b"} {
+		if _, err := node.Lookup(path); err == nil {
+			t.Errorf("%q: expected an error", path)
+		}
+	}
+
+	if err = node.SetPath("server.port", 8080); err != nil {
+		t.Fatal(err)
+	}
+	if err = node.SetPath("server.hosts[1][0]", "x"); err != nil {
+		t.Fatal(err)
+	}
+	if err = node.SetPath("server.tls.cert", "a.pem"); err != nil {
+		t.Fatal(err)
+	}
+	if err = node.SetPath("server.url", "http://example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err = node.SetPath("server.hosts[5]", 1); err == nil {
+		t.Error("expected an error for a missing element")
+	}
+	if single, _ := ParseNode([]byte("1")); single.SetPath("", 2) != nil || single.Value != 2.0 {
+		t.Errorf("expected the root to be set for the empty path, got %#v", single.Value)
+	}
+	out, err := node.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `# config
+server: {
+  # the port to listen on
+  port: 8080
+  hosts: [
+    { name: "a.example.com" }
+    ["x", 2]
+  ]
+  tls: {
+    cert: "a.pem"
+  }
+  url: "http://example.com"
+}
+`
+	if string(out) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out)
+	}
+}
+
+func TestNodePathFromRoot(t *testing.T) {
+	node, err := ParseNode([]byte("server: {\n  hosts: [{name: \"a\"}, [1, 2]]\n}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = node.SetPath("server.tls.cert", "a.pem"); err != nil {
+		t.Fatal(err)
+	}
+	if err = node.SetPath("server.hosts[1]", []int{3, 4}); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"", "server", "server.hosts[0].name", "server.hosts[1][1]", "server.tls.cert"} {
+		n, err := node.Lookup(path)
+		if err != nil || n == nil {
+			t.Fatalf("%s: %v, %v", path, n, err)
+		}
+		if n.Path() != path {
+			t.Errorf("expected %q, got %q", path, n.Path())
+		}
+	}
+
+	hosts, _ := node.Lookup("server.hosts")
+	first := hosts.Children[0]
+	hosts.Children = hosts.Children[1:]
+	if first.Path() != "" || hosts.Children[0].Path() != "server.hosts[0]" {
+		t.Errorf("unexpected paths %q and %q after a removal", first.Path(), hosts.Children[0].Path())
+	}
+}
+
+func TestNodeFreeze(t *testing.T) {
+	data := "# config\nport: 80 // http\nhosts: [\"a\", \"b\"]\n"
+	node, err := ParseNode([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	frozen := node.Freeze()
+	if err = node.Set("port", 8080); err != nil {
+		t.Fatal(err)
+	}
+
+	hosts := frozen.Get("hosts")
+	for name, err := range map[string]error{
+		"Set":       frozen.Set("port", 1),
+		"SetValue":  hosts.Children[0].SetValue("c"),
+		"Append":    hosts.Append("c"),
+		"SetPath":   frozen.SetPath("tls.cert", "a.pem"),
+		"DeepMerge": DeepMerge(frozen, node, MergeReplace),
+	} {
+		if err != errFrozen {
+			t.Errorf("%s: expected errFrozen, got %v", name, err)
+		}
+	}
+	func() {
+		defer func() {
+			if recover() != errFrozen {
+				t.Error("expected Delete to panic")
+			}
+		}()
+		frozen.Delete("port")
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if out, err := frozen.Marshal(); err != nil || string(out) != data {
+				t.Errorf("unexpected %q, %v", out, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	clone := frozen.Clone()
+	if clone.Frozen() || !frozen.Frozen() || !hosts.Frozen() {
+		t.Error("expected only the frozen tree to be frozen")
+	}
+	if err = clone.Get("hosts").Append("c"); err != nil {
+		t.Fatal(err)
+	}
+	if out, _ := clone.Marshal(); string(out) != "# config\nport: 80 // http\nhosts: [\"a\", \"b\", \"c\"]\n" {
+		t.Errorf("unexpected clone %q", out)
+	}
+	if len(hosts.Children) != 2 || clone.Get("hosts").Children[2].Path() != "hosts[2]" {
+		t.Error("expected the clone to be a separate tree")
+	}
+}
+
+func TestNodePointer(t *testing.T) {
+	data := `# config
+servers: [
+  {
+    # the port to listen on
+    port: 80
+  }
+]
+"a/b": { "m~n": 1 }
+"": empty
+`
+	node, err := ParseNode([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ptr, expected := range map[string]interface{}{
+		"/servers/0/port": 80.0,
+		"/a~1b/m~0n":      1.0,
+		"/":               "empty",
+		"/servers/1":      nil,
+		"/servers/-":      nil,
+		"/servers/00":     nil,
+		"/servers/port":   nil,
+		"/missing/x":      nil,
+	} {
+		n, err := node.Pointer(ptr)
+		if err != nil {
+			t.Errorf("%s: %v", ptr, err)
+		} else if n == nil && expected != nil || n != nil && n.Value != expected {
+			t.Errorf("%s: expected %v, got %#v", ptr, expected, n)
+		}
+	}
+	if n, err := node.Pointer(""); err != nil || n != node {
+		t.Errorf("expected the root for the empty pointer, got %v, %v", n, err)
+	}
+	for _, ptr := range []string{"servers", "/a~2b", "/a~"} {
+		if _, err := node.Pointer(ptr); err == nil {
+			t.Errorf("%q: expected an error", ptr)
+		}
+	}
+
+	for ptr, v := range map[string]interface{}{
+		"/servers/0/port": 8080,
+		"/servers/-":      map[string]interface{}{"port": 443},
+		"/tls/cert":       "a.pem",
+		"/a~1b/m~0n":      2,
+	} {
+		if err = node.SetPointer(ptr, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = node.SetPointer("/servers/5", 1); err == nil {
+		t.Error("expected an error for a missing element")
+	}
+	out, err := node.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `# config
+servers: [
+  {
+    # the port to listen on
+    port: 8080
+  }
+  {
+    port: 443
+  }
+]
+"a/b": { "m~n": 2 }
+"": empty
+tls: {
+  cert: a.pem
+}
+`
+	if string(out) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out)
+	}
+}
+
+func TestNodeDetach(t *testing.T) {
+	node, err := ParseNode([]byte(`# services
+services: {
+  # the web frontend
+  web: {
+    port: 80 // http
+    hosts: ["a", "b"]
+  }
+  db: { port: 5432 }, cache: { port: 6379 }
+}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	web, err := node.Detach("services.web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if web.Path() != "" {
+		t.Errorf("expected a root, got %q", web.Path())
+	}
+	out, err := web.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "# the web frontend\n{\n  port: 80 // http\n  hosts: [\n    \"a\"\n    \"b\"\n  ]\n}"; string(out) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out)
+	}
+	if _, err = node.Detach("services.db"); err != nil {
+		t.Fatal(err)
+	}
+	if out, _ = node.Marshal(); string(out) != "# services\nservices: {\n  cache: { port: 6379 }\n}\n" {
+		t.Errorf("unexpected rest %q", out)
+	}
+
+	// attached in the style of the other document
+	other, err := ParseNode([]byte("{\n\tlist: [\n\t\t1\n\t], inline: [1, 2]\n}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = other.AttachAt("list[0]", web); err != nil {
+		t.Fatal(err)
+	}
+	if err = other.AttachAt("inline[1]", web); err == nil {
+		t.Error("expected an error for an attached node")
+	}
+	x, _ := NewNode("x")
+	if err = other.AttachAt("inline[1]", x); err != nil {
+		t.Fatal(err)
+	}
+	y, _ := NewNode(true)
+	y.Comments.Before = "# y"
+	if err = other.AttachAt("inline[3]", y); err != nil {
+		t.Fatal(err)
+	}
+	if web.Path() != "list[0]" {
+		t.Errorf("unexpected path %q", web.Path())
+	}
+	out, _ = other.Marshal()
+	expected := "{\n\tlist: [\n\t\t# the web frontend\n\t\t{\n\t\t\tport: 80 // http\n\t\t\thosts: [\n\t\t\t\ta\n\t\t\t\tb\n\t\t\t]\n\t\t}\n\t\t1\n\t], inline: [1,\n\t\tx\n\t\t2\n\t\t# y\n\t\ttrue\n\t]\n}\n"
+	if string(out) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out)
+	}
+	var v interface{}
+	if err = Unmarshal(out, &v); err != nil {
+		t.Error(err)
+	}
+
+	for _, c := range []struct {
+		path    string
+		subtree *Node
+	}{
+		{"", x},
+		{"list[5]", y},
+		{"list.a", y},
+		{"inline[1].a", y},
+	} {
+		if err = other.AttachAt(c.path, c.subtree); err == nil {
+			t.Errorf("%q: expected an error", c.path)
+		}
+	}
+	if err = web.AttachAt("a", other); err == nil {
+		t.Error("expected an error for a node attached below itself")
+	}
+	for _, path := range []string{"", "missing", "list[7]"} {
+		if _, err = other.Detach(path); err == nil {
+			t.Errorf("%q: expected an error", path)
+		}
+	}
+}
+
+func TestBuildIndex(t *testing.T) {
+	node, err := ParseNode([]byte("servers: [{port: 80}, {port: 81}]\n\"a/b~\": {c: 1}\nd: {x: 1}\nd: 2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := BuildIndex(node)
+	expected := []string{"", "/servers", "/servers/0", "/servers/0/port", "/servers/1", "/servers/1/port", "/a~1b~0", "/a~1b~0/c", "/d"}
+	if len(index) != len(expected) {
+		t.Errorf("expected %d nodes, got %v", len(expected), index)
+	}
+	for _, ptr := range expected {
+		n, err := node.Pointer(ptr)
+		if err != nil || n == nil || index[ptr] != n {
+			t.Errorf("%q: expected %v, got %v", ptr, n, index[ptr])
+		}
+	}
+}
+
+func TestNodeSpan(t *testing.T) {
+	data := `# config
+server: {
+  "port": 80 # http
+  hosts: [
+    a.example.com
+    [1, 2]
+  ]
+}
+name: app
+`
+	root, err := ParseNode([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, expected := range map[string][2]Span{
+		"": {{}, {Position{9, 2, 1}, Position{94, 9, 10}}},
+		"server": {
+			{Position{9, 2, 1}, Position{15, 2, 7}},
+			{Position{17, 2, 9}, Position{84, 8, 2}},
+		},
+		"server.port": {
+			{Position{21, 3, 3}, Position{27, 3, 9}},
+			{Position{29, 3, 11}, Position{31, 3, 13}},
+		},
+		"server.hosts[0]": {{}, {Position{54, 5, 5}, Position{67, 5, 18}}},
+		"server.hosts[1]": {{}, {Position{72, 6, 5}, Position{78, 6, 11}}},
+		"name": {
+			{Position{85, 9, 1}, Position{89, 9, 5}},
+			{Position{91, 9, 7}, Position{94, 9, 10}},
+		},
+	} {
+		n := root
+		if path != "" {
+			if n, err = root.Lookup(path); err != nil {
+				t.Fatal(err)
+			}
+		}
+		key, value := n.Span()
+		if key != expected[0] || value != expected[1] {
+			t.Errorf("%q: expected %v, got %v %v", path, expected, key, value)
+			continue
+		}
+		if n.keyLiteral != "" && data[key.Start.Offset:key.End.Offset] != n.keyLiteral {
+			t.Errorf("%q: the key span holds %q", path, data[key.Start.Offset:key.End.Offset])
+		}
+	}
+
+	// new nodes have no source
+	if err = root.Set("debug", true); err != nil {
+		t.Fatal(err)
+	}
+	if key, value := root.Get("debug").Span(); key != (Span{}) || value != (Span{}) {
+		t.Errorf("unexpected %v %v", key, value)
+	}
+
+	root, err = ParseNode([]byte("\n  [1]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, value := root.Span(); value != (Span{Position{3, 2, 3}, Position{6, 2, 6}}) {
+		t.Errorf("unexpected %v", value)
+	}
+}
@@ -0,0 +1,40 @@
+package hjson
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Optional holds a value of type T that may be absent, like the Null types
+// of database/sql. An Optional that is not Valid encodes as null, or is
+// omitted with omitempty; a valid one encodes as its Value. Decoding null
+// makes it invalid, decoding any other value stores it in Value and makes it
+// Valid. A member missing from the input leaves the Optional as it is.
+type Optional[T any] struct {
+	Value T
+	Valid bool
+}
+
+var optionalPkgPath = reflect.TypeOf(Optional[int]{}).PkgPath()
+
+// isOptional reports whether t is an instance of Optional.
+func isOptional(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t.PkgPath() == optionalPkgPath && strings.HasPrefix(t.Name(), "Optional[")
+}
+
+// readOptional reads a value into the Optional dest.
+func (p *hjsonParser) readOptional(dest reflect.Value) error {
+	if p.ch == 'n' {
+		at, allocated := p.at, p.allocated
+		if val, _, err := p.readTfnns(); err == nil && val == nil {
+			dest.Set(reflect.Zero(dest.Type()))
+			return p.alloc(allocValue)
+		}
+		p.at, p.ch, p.allocated = at, 'n', allocated
+	}
+	if _, err := p.readValue(dest.Field(0)); err != nil {
+		return err
+	}
+	dest.Field(1).SetBool(true)
+	return nil
+}
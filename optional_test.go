@@ -0,0 +1,55 @@
+package hjson
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testOptionalStruct struct {
+	Name    Optional[string]
+	Port    Optional[int]
+	Tags    Optional[[]string]
+	Missing Optional[float64] `json:",omitempty"`
+	Ptr     *Optional[bool]
+}
+
+func TestOptional(t *testing.T) {
+	input := testOptionalStruct{
+		Name: Optional[string]{Value: "1.10", Valid: true},
+		Tags: Optional[[]string]{Value: []string{"a"}, Valid: true},
+		Ptr:  &Optional[bool]{Value: false, Valid: true},
+	}
+	buf, err := Marshal(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\n  Name: \"1.10\"\n  Port: null\n  Tags:\n  [\n    a\n  ]\n  Ptr: false\n}"
+	if string(buf) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf)
+	}
+
+	var output testOptionalStruct
+	if err = Unmarshal(buf, &output); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(input, output) {
+		t.Errorf("expected\n%#v\ngot\n%#v", input, output)
+	}
+
+	output.Port = Optional[int]{Value: 1, Valid: true}
+	if err = Unmarshal([]byte(`{Name: null, Port: null, Missing: 0}`), &output); err != nil {
+		t.Fatal(err)
+	}
+	if output.Name.Valid || output.Port != (Optional[int]{}) || output.Missing != (Optional[float64]{Value: 0, Valid: true}) {
+		t.Errorf("unexpected result %#v", output)
+	}
+
+	// a quoteless string starting with n is not null
+	var name Optional[string]
+	if err = Unmarshal([]byte(`nothing`), &name); err != nil || name != (Optional[string]{Value: "nothing", Valid: true}) {
+		t.Errorf("unexpected result %#v, %v", name, err)
+	}
+	if err = Unmarshal([]byte(`{Port: abc}`), &output); err == nil {
+		t.Error("expected an error for Port: abc")
+	}
+}
@@ -0,0 +1,589 @@
+package hjson
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"reflect"
+)
+
+// An EncoderOption changes a setting of the EncoderOptions built by
+// NewEncoderOptions.
+type EncoderOption func(*encoderOptionSet) error
+
+// A DecoderOption changes a setting of the DecoderOptions built by
+// NewDecoderOptions.
+type DecoderOption func(*decoderOptionSet) error
+
+type encoderOptionSet struct {
+	EncoderOptions
+	set map[string]bool
+}
+
+type decoderOptionSet struct {
+	DecoderOptions
+	set map[string]bool
+}
+
+// mark records that the named setting was given, failing if it was already
+// given before.
+func mark(set map[string]bool, name string) error {
+	if set[name] {
+		return fmt.Errorf("Conflicting options: %s is given more than once", name)
+	}
+	set[name] = true
+	return nil
+}
+
+// NewEncoderOptions returns the default encoding options changed by opts,
+// for example:
+//
+//	options, err := hjson.NewEncoderOptions(hjson.WithIndent("\t"), hjson.WithQuoteAlways())
+//
+// It fails if the options are invalid or conflict with each other, for
+// example when the same setting is given twice or when both
+// WithUnknownAsNull and WithOnUnsupportedType are given.
+func NewEncoderOptions(opts ...EncoderOption) (EncoderOptions, error) {
+	s := &encoderOptionSet{DefaultOptions(), map[string]bool{}}
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return EncoderOptions{}, err
+		}
+	}
+	if s.set["UnknownAsNull"] && s.set["OnUnsupportedType"] {
+		return EncoderOptions{}, errors.New("Conflicting options: UnknownAsNull has no effect with OnUnsupportedType")
+	}
+	if s.set["UnknownStringer"] && !s.set["UnknownAsNull"] {
+		return EncoderOptions{}, errors.New("Conflicting options: UnknownStringer has no effect without UnknownAsNull")
+	}
+	if err := s.validate(); err != nil {
+		return EncoderOptions{}, err
+	}
+	return s.EncoderOptions, nil
+}
+
+// WithEol sets the end of line, either "\n" or "\r\n".
+func WithEol(eol string) EncoderOption {
+	return func(s *encoderOptionSet) error {
+		s.Eol = eol
+		return mark(s.set, "Eol")
+	}
+}
+
+// WithBracesSameLine places braces on the same line as the key.
+func WithBracesSameLine() EncoderOption {
+	return func(s *encoderOptionSet) error {
+		s.BracesSameLine = true
+		return mark(s.set, "BracesSameLine")
+	}
+}
+
+// WithQuoteAlways always places strings in quotes.
+func WithQuoteAlways() EncoderOption {
+	return func(s *encoderOptionSet) error {
+		s.QuoteAlways = true
+		return mark(s.set, "QuoteAlways")
+	}
+}
+
+// WithIndent sets the indent string, made of spaces and tabs.
+func WithIndent(indent string) EncoderOption {
+	return func(s *encoderOptionSet) error {
+		s.IndentBy = indent
+		return mark(s.set, "IndentBy")
+	}
+}
+
+// WithAllowMinusZero allows the -0 value.
+func WithAllowMinusZero() EncoderOption {
+	return func(s *encoderOptionSet) error {
+		s.AllowMinusZero = true
+		return mark(s.set, "AllowMinusZero")
+	}
+}
+
+// WithUseStringer encodes types that implement fmt.Stringer, but no
+// marshaling interface, as the string returned by their String method.
+func WithUseStringer() EncoderOption {
+	return func(s *encoderOptionSet) error {
+		s.UseStringer = true
+		return mark(s.set, "UseStringer")
+	}
+}
+
+// WithUseErrorString encodes values that implement the error interface as
+// the string returned by their Error method.
+func WithUseErrorString() EncoderOption {
+	return func(s *encoderOptionSet) error {
+		s.UseErrorString = true
+		return mark(s.set, "UseErrorString")
+	}
+}
+
+// WithSortKeysIgnoreCase sorts map keys case-insensitively.
+//
+// Deprecated: use WithSortKeys(SortKeysAlphabeticalIgnoreCase), which this
+// option is the same as.
+func WithSortKeysIgnoreCase() EncoderOption {
+	return WithSortKeys(SortKeysAlphabeticalIgnoreCase)
+}
+
+// WithSortKeys sets how map keys are ordered. Use WithKeyLess for
+// SortKeysCustom.
+func WithSortKeys(mode SortKeysMode) EncoderOption {
+	return func(s *encoderOptionSet) error {
+		s.SortKeys = mode
+		return mark(s.set, "SortKeys")
+	}
+}
+
+// WithKeyLess sorts map keys with less, for example KeysFirst("name",
+// "version").
+func WithKeyLess(less func(a, b string) bool) EncoderOption {
+	return func(s *encoderOptionSet) error {
+		if less == nil {
+			return errors.New("Invalid option: KeyLess must not be nil")
+		}
+		s.SortKeys = SortKeysCustom
+		s.KeyLess = less
+		return mark(s.set, "SortKeys")
+	}
+}
+
+// WithUnknownAsNull encodes values that cannot be encoded as null.
+func WithUnknownAsNull() EncoderOption {
+	return func(s *encoderOptionSet) error {
+		s.UnknownAsNull = true
+		return mark(s.set, "UnknownAsNull")
+	}
+}
+
+// WithUnknownStringer encodes values that cannot be encoded as the string
+// returned by fn, for example DescribeUnknown. Requires WithUnknownAsNull.
+func WithUnknownStringer(fn func(v reflect.Value) string) EncoderOption {
+	return func(s *encoderOptionSet) error {
+		if fn == nil {
+			return errors.New("Invalid option: UnknownStringer must not be nil")
+		}
+		s.UnknownStringer = fn
+		return mark(s.set, "UnknownStringer")
+	}
+}
+
+// WithOnUnsupportedType sets the callback for values that cannot be
+// encoded, see EncoderOptions.OnUnsupportedType.
+func WithOnUnsupportedType(fn func(path string, v reflect.Value) (replacement interface{}, skip bool, err error)) EncoderOption {
+	return func(s *encoderOptionSet) error {
+		if fn == nil {
+			return errors.New("Invalid option: OnUnsupportedType must not be nil")
+		}
+		s.OnUnsupportedType = fn
+		return mark(s.set, "OnUnsupportedType")
+	}
+}
+
+// WithFormatVersion sets the output format version, see FormatVersion1.
+func WithFormatVersion(version int) EncoderOption {
+	return func(s *encoderOptionSet) error {
+		s.FormatVersion = version
+		return mark(s.set, "FormatVersion")
+	}
+}
+
+// WithTimeFormat sets the format of time.Time values, see
+// EncoderOptions.TimeFormat.
+func WithTimeFormat(format string) EncoderOption {
+	return func(s *encoderOptionSet) error {
+		if format == "" {
+			return errors.New("Invalid option: TimeFormat must not be empty")
+		}
+		s.TimeFormat = format
+		return mark(s.set, "TimeFormat")
+	}
+}
+
+// WithDurationAsString encodes time.Duration values as strings like
+// "1h30m0s".
+func WithDurationAsString() EncoderOption {
+	return func(s *encoderOptionSet) error {
+		s.DurationAsString = true
+		return mark(s.set, "DurationAsString")
+	}
+}
+
+// WithBytesFormat sets the encoding of byte slices: "base64", "hex" or
+// "array".
+func WithBytesFormat(format string) EncoderOption {
+	return func(s *encoderOptionSet) error {
+		s.BytesFormat = format
+		return mark(s.set, "BytesFormat")
+	}
+}
+
+// WithEncodeMaxDepth fails the encoding when arrays and objects are nested
+// deeper than n.
+func WithEncodeMaxDepth(n int) EncoderOption {
+	return func(s *encoderOptionSet) error {
+		if n <= 0 {
+			return fmt.Errorf("Invalid option: MaxDepth must be positive, not %d", n)
+		}
+		s.MaxDepth = n
+		return mark(s.set, "MaxDepth")
+	}
+}
+
+// WithRawMessageVerbatim writes json.RawMessage values as the JSON they
+// hold.
+func WithRawMessageVerbatim() EncoderOption {
+	return func(s *encoderOptionSet) error {
+		s.RawMessageVerbatim = true
+		return mark(s.set, "RawMessageVerbatim")
+	}
+}
+
+// WithOutputFormat selects Hjson, JSON, JSONC or JSON5 output.
+func WithOutputFormat(format OutputFormat) EncoderOption {
+	return func(s *encoderOptionSet) error {
+		s.OutputFormat = format
+		return mark(s.set, "OutputFormat")
+	}
+}
+
+// WithExtensions enables the registered extensions with the given names,
+// see Extension.
+func WithExtensions(names ...string) EncoderOption {
+	return func(s *encoderOptionSet) error {
+		if err := checkExtensionNames(names); err != nil {
+			return err
+		}
+		s.Extensions = append([]string(nil), names...)
+		return mark(s.set, "Extensions")
+	}
+}
+
+// checkExtensionNames reports an error if names is empty or has a name
+// that is not registered.
+func checkExtensionNames(names []string) error {
+	if len(names) == 0 {
+		return errors.New("Invalid option: Extensions must not be empty")
+	}
+	if _, err := lookupExtensions(names); err != nil {
+		return errors.New("Invalid option: " + err.Error())
+	}
+	return nil
+}
+
+// NewDecoderOptions returns the default decoding options changed by opts.
+// It fails if the options are invalid or conflict with each other, for
+// example when the same setting is given twice or when WithLookupEnv is
+// given without WithExpandEnv.
+func NewDecoderOptions(opts ...DecoderOption) (DecoderOptions, error) {
+	s := &decoderOptionSet{DefaultDecoderOptions(), map[string]bool{}}
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return DecoderOptions{}, err
+		}
+	}
+	if s.set["LookupEnv"] && !s.ExpandEnv {
+		return DecoderOptions{}, errors.New("Conflicting options: LookupEnv has no effect without ExpandEnv")
+	}
+	if err := s.validate(); err != nil {
+		return DecoderOptions{}, err
+	}
+	return s.DecoderOptions, nil
+}
+
+// WithMaxAlloc aborts decoding when the decoded value is estimated to use
+// more than n bytes of memory.
+func WithMaxAlloc(n int) DecoderOption {
+	return func(s *decoderOptionSet) error {
+		if n <= 0 {
+			return fmt.Errorf("Invalid option: MaxAlloc must be positive, not %d", n)
+		}
+		s.MaxAlloc = n
+		return mark(s.set, "MaxAlloc")
+	}
+}
+
+// WithOnWarning calls fn for each loss of information that does not fail
+// the decoding, see Warning.
+func WithOnWarning(fn func(w Warning)) DecoderOption {
+	return func(s *decoderOptionSet) error {
+		if fn == nil {
+			return errors.New("Invalid option: OnWarning must not be nil")
+		}
+		s.OnWarning = fn
+		return mark(s.set, "OnWarning")
+	}
+}
+
+// WithMaxDepth aborts decoding when arrays and objects are nested deeper
+// than n.
+func WithMaxDepth(n int) DecoderOption {
+	return func(s *decoderOptionSet) error {
+		if n <= 0 {
+			return fmt.Errorf("Invalid option: MaxDepth must be positive, not %d", n)
+		}
+		s.MaxDepth = n
+		return mark(s.set, "MaxDepth")
+	}
+}
+
+// WithMaxInputBytes aborts decoding inputs longer than n bytes.
+func WithMaxInputBytes(n int) DecoderOption {
+	return func(s *decoderOptionSet) error {
+		if n <= 0 {
+			return fmt.Errorf("Invalid option: MaxInputBytes must be positive, not %d", n)
+		}
+		s.MaxInputBytes = n
+		return mark(s.set, "MaxInputBytes")
+	}
+}
+
+// WithMaxStringLen aborts decoding when a string or key is longer than n
+// bytes.
+func WithMaxStringLen(n int) DecoderOption {
+	return func(s *decoderOptionSet) error {
+		if n <= 0 {
+			return fmt.Errorf("Invalid option: MaxStringLen must be positive, not %d", n)
+		}
+		s.MaxStringLen = n
+		return mark(s.set, "MaxStringLen")
+	}
+}
+
+// WithMaxErrors reports up to n syntax errors instead of only the first
+// one, see DecoderOptions.MaxErrors.
+func WithMaxErrors(n int) DecoderOption {
+	return func(s *decoderOptionSet) error {
+		if n <= 0 {
+			return fmt.Errorf("Invalid option: MaxErrors must be positive, not %d", n)
+		}
+		s.MaxErrors = n
+		return mark(s.set, "MaxErrors")
+	}
+}
+
+// WithPositions records the position of each decoded value in positions,
+// see DecoderOptions.Positions.
+func WithPositions(positions map[string]Position) DecoderOption {
+	return func(s *decoderOptionSet) error {
+		if positions == nil {
+			return errors.New("Invalid option: Positions must not be nil")
+		}
+		s.Positions = positions
+		return mark(s.set, "Positions")
+	}
+}
+
+// WithDuplicateKeys sets how keys that appear more than once in an object
+// are handled.
+func WithDuplicateKeys(policy DuplicateKeyPolicy) DecoderOption {
+	return func(s *decoderOptionSet) error {
+		if policy < DuplicateKeyLast || policy > DuplicateKeyMerge {
+			return fmt.Errorf("Invalid option: unknown DuplicateKeys policy %d", policy)
+		}
+		s.DuplicateKeys = policy
+		return mark(s.set, "DuplicateKeys")
+	}
+}
+
+// WithDisallowUnknownFields fails the decoding when an object decoded into a
+// struct has a key without a matching field.
+func WithDisallowUnknownFields() DecoderOption {
+	return func(s *decoderOptionSet) error {
+		s.DisallowUnknownFields = true
+		return mark(s.set, "DisallowUnknownFields")
+	}
+}
+
+// WithUseNumber stores numbers decoded into interface values as a
+// json.Number instead of a float64.
+func WithUseNumber() DecoderOption {
+	return func(s *decoderOptionSet) error {
+		s.UseNumber = true
+		return mark(s.set, "UseNumber")
+	}
+}
+
+// WithUseOrderedMap stores objects decoded into interface values as an
+// *OrderedMap instead of a map[string]interface{}.
+func WithUseOrderedMap() DecoderOption {
+	return func(s *decoderOptionSet) error {
+		s.UseOrderedMap = true
+		return mark(s.set, "UseOrderedMap")
+	}
+}
+
+// WithDecodeTimeFormat sets the format of the time.Time values to decode,
+// see DecoderOptions.TimeFormat.
+func WithDecodeTimeFormat(format string) DecoderOption {
+	return func(s *decoderOptionSet) error {
+		if format == "" {
+			return errors.New("Invalid option: TimeFormat must not be empty")
+		}
+		s.TimeFormat = format
+		return mark(s.set, "TimeFormat")
+	}
+}
+
+// WithDecodeBytesFormat sets the encoding of the strings decoded into byte
+// slices, "base64" or "hex".
+func WithDecodeBytesFormat(format string) DecoderOption {
+	return func(s *decoderOptionSet) error {
+		if format != "base64" && format != "hex" {
+			return fmt.Errorf("Invalid option: unknown BytesFormat %q", format)
+		}
+		s.BytesFormat = format
+		return mark(s.set, "BytesFormat")
+	}
+}
+
+// WithAcceptJSON5 accepts the JSON5 syntax that Hjson does not have, see
+// DecoderOptions.AcceptJSON5.
+func WithAcceptJSON5() DecoderOption {
+	return func(s *decoderOptionSet) error {
+		s.AcceptJSON5 = true
+		return mark(s.set, "AcceptJSON5")
+	}
+}
+
+// WithDecodeHook calls fn with each decoded value and stores the value it
+// returns, see DecoderOptions.DecodeHook.
+func WithDecodeHook(fn func(from reflect.Kind, to reflect.Type, value interface{}) (interface{}, error)) DecoderOption {
+	return func(s *decoderOptionSet) error {
+		if fn == nil {
+			return errors.New("Invalid option: DecodeHook must not be nil")
+		}
+		s.DecodeHook = fn
+		return mark(s.set, "DecodeHook")
+	}
+}
+
+// WithWeaklyTyped converts between compatible representations of values,
+// like the string "8080" into an int, see DecoderOptions.WeaklyTyped.
+func WithWeaklyTyped() DecoderOption {
+	return func(s *decoderOptionSet) error {
+		s.WeaklyTyped = true
+		return mark(s.set, "WeaklyTyped")
+	}
+}
+
+// WithExpandEnv replaces references to environment variables like
+// ${NAME} in string values, see DecoderOptions.ExpandEnv.
+func WithExpandEnv() DecoderOption {
+	return func(s *decoderOptionSet) error {
+		s.ExpandEnv = true
+		return mark(s.set, "ExpandEnv")
+	}
+}
+
+// WithLookupEnv looks up the environment variables for ExpandEnv with fn
+// instead of os.LookupEnv.
+func WithLookupEnv(fn func(name string) (string, bool)) DecoderOption {
+	return func(s *decoderOptionSet) error {
+		if fn == nil {
+			return errors.New("Invalid option: LookupEnv must not be nil")
+		}
+		s.LookupEnv = fn
+		return mark(s.set, "LookupEnv")
+	}
+}
+
+// WithIncludeFS resolves !include directives with the files of fsys, see
+// DecoderOptions.IncludeFS.
+func WithIncludeFS(fsys fs.FS) DecoderOption {
+	return func(s *decoderOptionSet) error {
+		if fsys == nil {
+			return errors.New("Invalid option: IncludeFS must not be nil")
+		}
+		s.IncludeFS = fsys
+		return mark(s.set, "IncludeFS")
+	}
+}
+
+// WithDecodeExtensions enables the registered extensions with the given
+// names, see Extension.
+func WithDecodeExtensions(names ...string) DecoderOption {
+	return func(s *decoderOptionSet) error {
+		if err := checkExtensionNames(names); err != nil {
+			return err
+		}
+		s.Extensions = append([]string(nil), names...)
+		return mark(s.set, "Extensions")
+	}
+}
+
+// WithMessages replaces the messages of syntax errors with those of
+// catalog, see DecoderOptions.Messages.
+func WithMessages(catalog MessageCatalog) DecoderOption {
+	return func(s *decoderOptionSet) error {
+		if catalog == nil {
+			return errors.New("Invalid option: Messages must not be nil")
+		}
+		s.Messages = catalog
+		return mark(s.set, "Messages")
+	}
+}
+
+// PrettyOptions returns options for output meant to be read and edited by
+// people: braces on the same line as their key and two space indentation.
+// It only changes the layout, which DefaultDecoderOptions reads as well.
+func PrettyOptions() EncoderOptions {
+	opt := DefaultOptions()
+	opt.BracesSameLine = true
+	return opt
+}
+
+// CompactOptions returns options for the smallest output that still has one
+// value per line: no indentation and braces on the same line as their key.
+// It only changes the layout, which DefaultDecoderOptions reads as well.
+func CompactOptions() EncoderOptions {
+	opt := DefaultOptions()
+	opt.BracesSameLine = true
+	opt.IndentBy = ""
+	return opt
+}
+
+// JSONCompatOptions returns options for output that looks as close to JSON
+// as Hjson allows, for readers used to JSON: all strings are quoted and
+// braces are placed like in JSON. See JSONCompatDecoderOptions for reading.
+func JSONCompatOptions() EncoderOptions {
+	opt := DefaultOptions()
+	opt.BracesSameLine = true
+	opt.QuoteAlways = true
+	return opt
+}
+
+// RoundTripOptions returns options for output that decodes back to the
+// encoded value: -0 is kept, and values that cannot be encoded are an error
+// instead of becoming null. See RoundTripDecoderOptions for reading.
+func RoundTripOptions() EncoderOptions {
+	opt := DefaultOptions()
+	opt.AllowMinusZero = true
+	opt.UnknownAsNull = false
+	return opt
+}
+
+// JSONCompatDecoderOptions returns options for reading input that is JSON,
+// or close to it, like encoding/json does: JSON5 is accepted as well, the
+// last of duplicate keys is used and unknown fields are ignored.
+func JSONCompatDecoderOptions() DecoderOptions {
+	opt := DefaultDecoderOptions()
+	opt.AcceptJSON5 = true
+	opt.DuplicateKeys = DuplicateKeyLast
+	opt.DisallowUnknownFields = false
+	return opt
+}
+
+// RoundTripDecoderOptions returns options for reading a value that encodes
+// back to the same data: numbers are decoded into an interface{} as
+// json.Number, objects as *OrderedMap keeping the order of their members,
+// and duplicate keys are an error instead of dropping a value.
+func RoundTripDecoderOptions() DecoderOptions {
+	opt := DefaultDecoderOptions()
+	opt.UseNumber = true
+	opt.UseOrderedMap = true
+	opt.DuplicateKeys = DuplicateKeyError
+	return opt
+}
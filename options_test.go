@@ -0,0 +1,116 @@
+package hjson
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestNewEncoderOptions(t *testing.T) {
+	options, err := NewEncoderOptions(WithIndent("\t"), WithQuoteAlways(), WithEol("\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := DefaultOptions()
+	expected.IndentBy = "\t"
+	expected.QuoteAlways = true
+	expected.Eol = "\r\n"
+	if !reflect.DeepEqual(options, expected) {
+		t.Errorf("expected %+v, got %+v", expected, options)
+	}
+
+	skip := func(string, reflect.Value) (interface{}, bool, error) { return nil, true, nil }
+	for _, opts := range [][]EncoderOption{
+		{WithIndent("\t"), WithIndent("  ")},
+		{WithUnknownAsNull(), WithOnUnsupportedType(skip)},
+		{WithOnUnsupportedType(nil)},
+		{WithIndent("x")},
+		{WithEol("\r")},
+	} {
+		if _, err := NewEncoderOptions(opts...); err == nil {
+			t.Errorf("expected an error for %d options", len(opts))
+		}
+	}
+}
+
+func TestNewDecoderOptions(t *testing.T) {
+	options, err := NewDecoderOptions(WithMaxAlloc(1024))
+	if err != nil || options.MaxAlloc != 1024 {
+		t.Errorf("unexpected options %+v, %v", options, err)
+	}
+	if _, err := NewDecoderOptions(WithMaxAlloc(0)); err == nil {
+		t.Error("expected an error for MaxAlloc 0")
+	}
+	if _, err := NewDecoderOptions(WithMaxAlloc(1), WithMaxAlloc(2)); err == nil {
+		t.Error("expected an error for MaxAlloc given twice")
+	}
+
+	lookup := func(string) (string, bool) { return "", false }
+	if _, err := NewDecoderOptions(WithExpandEnv(), WithLookupEnv(lookup)); err != nil {
+		t.Error(err)
+	}
+	if _, err := NewDecoderOptions(WithLookupEnv(lookup)); err == nil || err.Error() != "Conflicting options: LookupEnv has no effect without ExpandEnv" {
+		t.Errorf("expected a conflict for LookupEnv without ExpandEnv, got %v", err)
+	}
+
+	// the defaults are validated together with the options
+	decOpt := DefaultDecoderOptions()
+	defer SetDefaultDecoderOptions(decOpt)
+	for _, change := range []func(o *DecoderOptions){
+		func(o *DecoderOptions) { o.MaxStringLen = -1 },
+		func(o *DecoderOptions) { o.DuplicateKeys = DuplicateKeyMerge + 1 },
+		func(o *DecoderOptions) { o.BytesFormat = "array" },
+		func(o *DecoderOptions) { o.LookupEnv = lookup },
+	} {
+		invalid := decOpt
+		change(&invalid)
+		if err := SetDefaultDecoderOptions(invalid); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := NewDecoderOptions(WithUseNumber()); err == nil {
+			t.Errorf("expected an error for the defaults %+v", invalid)
+		}
+	}
+}
+
+func TestOptionPresets(t *testing.T) {
+	value := map[string]interface{}{"a": []interface{}{"x", 1}}
+	for _, c := range []struct {
+		options  EncoderOptions
+		expected string
+	}{
+		{PrettyOptions(), "{\n  a: [\n    x\n    1\n  ]\n}"},
+		{CompactOptions(), "{\na: [\nx\n1\n]\n}"},
+		{JSONCompatOptions(), "{\n  a: [\n    \"x\"\n    1\n  ]\n}"},
+		{RoundTripOptions(), "{\n  a:\n  [\n    x\n    1\n  ]\n}"},
+	} {
+		buf, err := MarshalWithOptions(value, c.options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(buf) != c.expected {
+			t.Errorf("expected\n%s\ngot\n%s", c.expected, buf)
+		}
+	}
+
+	buf, _ := MarshalWithOptions(math.Copysign(0, -1), RoundTripOptions())
+	var f float64
+	if err := Unmarshal(buf, &f); err != nil || !math.Signbit(f) {
+		t.Errorf("expected -0 to round trip, got %s", buf)
+	}
+
+	data := []byte("{b: 1.50, a: [9007199254740993]}")
+	var v interface{}
+	if err := UnmarshalWithOptions(data, &v, RoundTripDecoderOptions()); err != nil {
+		t.Fatal(err)
+	}
+	if buf, err := MarshalWithOptions(v, RoundTripOptions()); err != nil || string(buf) != "{\n  b: 1.50\n  a:\n  [\n    9007199254740993\n  ]\n}" {
+		t.Errorf("expected the input to round trip, got %s, %v", buf, err)
+	}
+	if err := UnmarshalWithOptions([]byte("{a: 1, a: 2}"), &v, RoundTripDecoderOptions()); err == nil {
+		t.Error("expected an error for a duplicate key")
+	}
+	if err := UnmarshalWithOptions([]byte("{'a': 1, /* JSON5 */ b: +2,}"), &v, JSONCompatDecoderOptions()); err != nil {
+		t.Error(err)
+	}
+}
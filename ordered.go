@@ -0,0 +1,160 @@
+package hjson
+
+import (
+	"reflect"
+)
+
+// OrderedMap is an object that keeps the order of its members. Marshal
+// writes the members in that order instead of sorting them by key, and
+// Unmarshal fills it in the order of the input, decoding nested objects as
+// *OrderedMap too (see DecoderOptions.UseOrderedMap).
+//
+// Keys lists each key of Map once. Use the methods to keep them in sync.
+type OrderedMap struct {
+	Keys []string
+	Map  map[string]interface{}
+}
+
+var orderedMapType = reflect.TypeOf(OrderedMap{})
+
+// NewOrderedMap returns an empty OrderedMap.
+func NewOrderedMap() *OrderedMap {
+	return &OrderedMap{Map: map[string]interface{}{}}
+}
+
+// Len returns the number of members.
+func (o *OrderedMap) Len() int {
+	return len(o.Keys)
+}
+
+// Get returns the value of key and whether there is such a member.
+func (o *OrderedMap) Get(key string) (interface{}, bool) {
+	value, ok := o.Map[key]
+	return value, ok
+}
+
+// Set sets the value of key. A new key is added at the end, an existing key
+// keeps its place. Set reports whether the key existed.
+func (o *OrderedMap) Set(key string, value interface{}) bool {
+	if o.Map == nil {
+		o.Map = map[string]interface{}{}
+	}
+	_, ok := o.Map[key]
+	if !ok {
+		o.Keys = append(o.Keys, key)
+	}
+	o.Map[key] = value
+	return ok
+}
+
+// Delete removes key and reports whether it existed.
+func (o *OrderedMap) Delete(key string) bool {
+	if _, ok := o.Map[key]; !ok {
+		return false
+	}
+	delete(o.Map, key)
+	for i, k := range o.Keys {
+		if k == key {
+			o.Keys = append(o.Keys[:i], o.Keys[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// writeOrderedMap writes the members of om in their order.
+func (e *hjsonEncoder) writeOrderedMap(om OrderedMap, noIndent bool, separator string) error {
+	if len(om.Keys) == 0 {
+		e.WriteString(separator)
+		e.WriteString("{}")
+		return nil
+	}
+
+	indent1 := e.indent
+	if err := e.nest(); err != nil {
+		return err
+	}
+	if e.braceOnNewLine(noIndent) {
+		e.writeIndent(indent1)
+	} else {
+		e.WriteString(separator)
+	}
+	e.WriteString("{")
+
+	members := reflect.ValueOf(om.Map)
+	for _, key := range om.Keys {
+		e.pushKey(key)
+		var elem reflect.Value
+		if members.IsValid() {
+			elem = members.MapIndex(reflect.ValueOf(key))
+		}
+		elem, skip, err := e.replaceUnsupported(elem)
+		if err != nil {
+			return err
+		}
+		if !skip {
+			e.writeItemIndent()
+			e.WriteString(e.quoteName(key))
+			e.WriteString(":")
+			if err := e.str(elem, false, e.keySeparator(), false); err != nil {
+				return err
+			}
+		}
+		e.popPath()
+	}
+
+	e.writeIndent(indent1)
+	e.WriteString("}")
+	e.indent = indent1
+	return nil
+}
+
+// readOrderedMap decodes an object into the OrderedMap dest, merging it
+// into the members of dest with merge, see DuplicateKeyMerge.
+func (p *hjsonParser) readOrderedMap(withoutBraces bool, dest reflect.Value, merge bool) error {
+	useOrderedMap := p.UseOrderedMap
+	p.UseOrderedMap = true
+	defer func() {
+		p.UseOrderedMap = useOrderedMap
+	}()
+	value, err := p.readObject(withoutBraces, reflect.Value{})
+	if err != nil {
+		return err
+	}
+	if merge {
+		existing := dest.Interface().(OrderedMap)
+		value = mergeValues(&existing, value)
+	}
+	dest.Set(reflect.ValueOf(value).Elem())
+	return nil
+}
+
+// mergeValues returns the decoded value b merged into the earlier value a,
+// see DuplicateKeyMerge: the members of objects are merged and any other
+// value replaces a.
+func mergeValues(a, b interface{}) interface{} {
+	switch b := b.(type) {
+	case map[string]interface{}:
+		if a, ok := a.(map[string]interface{}); ok {
+			for key, value := range b {
+				if old, ok := a[key]; ok {
+					value = mergeValues(old, value)
+				}
+				a[key] = value
+			}
+			return a
+		}
+	case *OrderedMap:
+		if a, ok := a.(*OrderedMap); ok {
+			for _, key := range b.Keys {
+				value := b.Map[key]
+				if old, ok := a.Get(key); ok {
+					value = mergeValues(old, value)
+				}
+				a.Set(key, value)
+			}
+			return a
+		}
+	}
+	return b
+}
@@ -0,0 +1,58 @@
+package hjson
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOrderedMap(t *testing.T) {
+	data := []byte("z: 1\na: {\n  y: true\n  b: [\n    {\n      d: 1\n      c: 2\n    }\n  ]\n}\nm: x\n")
+	var om OrderedMap
+	if err := Unmarshal(data, &om); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(om.Keys, []string{"z", "a", "m"}) {
+		t.Errorf("unexpected keys %v", om.Keys)
+	}
+	a, _ := om.Get("a")
+	inner, ok := a.(*OrderedMap)
+	if !ok || !reflect.DeepEqual(inner.Keys, []string{"y", "b"}) {
+		t.Fatalf("expected a nested *OrderedMap, got %#v", a)
+	}
+	b, _ := inner.Get("b")
+	if elem, ok := b.([]interface{})[0].(*OrderedMap); !ok || !reflect.DeepEqual(elem.Keys, []string{"d", "c"}) {
+		t.Errorf("expected an *OrderedMap in the array, got %#v", b)
+	}
+
+	out, err := Marshal(om)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\n  z: 1\n  a:\n  {\n    y: true\n    b:\n    [\n      {\n        d: 1\n        c: 2\n      }\n    ]\n  }\n  m: x\n}"
+	if string(out) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out)
+	}
+
+	if om.Set("z", 2) != true || om.Set("new", nil) != false || !om.Delete("a") || om.Delete("a") {
+		t.Error("unexpected result of Set or Delete")
+	}
+	if out, err = Marshal(&om); err != nil {
+		t.Fatal(err)
+	}
+	if expected = "{\n  z: 2\n  m: x\n  new: null\n}"; string(out) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out)
+	}
+
+	var v interface{}
+	opt := DefaultDecoderOptions()
+	opt.UseOrderedMap = true
+	if err = UnmarshalWithOptions([]byte("{b: 1, a: 2}"), &v, opt); err != nil {
+		t.Fatal(err)
+	}
+	if o, ok := v.(*OrderedMap); !ok || !reflect.DeepEqual(o.Keys, []string{"b", "a"}) {
+		t.Errorf("expected an *OrderedMap, got %#v", v)
+	}
+	if err = Unmarshal([]byte("[1]"), &om); err == nil {
+		t.Error("expected a type error")
+	}
+}
@@ -0,0 +1,72 @@
+package hjson
+
+import (
+	"errors"
+	"strings"
+)
+
+// An Override is a value set at runtime, for example by an environment
+// variable or a command line flag, to be written back into a document with
+// WriteOverrides.
+type Override struct {
+	// The path of the member or element, see Node.Lookup
+	Path string
+	// The value, converted with NewNode
+	Value interface{}
+	// Where the value comes from, like "env APP_PORT" or "flag -port"
+	Source string
+}
+
+// overrideComment starts the comments WriteOverrides adds.
+const overrideComment = "overridden by "
+
+// WriteOverrides returns doc with the overrides applied, for example to
+// save the configuration a program runs with. Each overridden member or
+// element keeps its place and its comments and gets a comment at the end
+// of its line naming its Source, replacing the one of an earlier
+// WriteOverrides; a /* */ comment if another value follows on the same
+// line. Members that do not exist are added, like by Node.SetPath. All
+// other bytes of doc are kept as they are. doc is not changed.
+func WriteOverrides(doc *Node, overrides []Override) ([]byte, error) {
+	root := doc.Clone()
+	for _, o := range overrides {
+		if err := root.override(o); err != nil {
+			return nil, err
+		}
+	}
+	return root.Marshal()
+}
+
+// override applies o to n and adds its comment.
+func (n *Node) override(o Override) error {
+	if c, err := n.Lookup(o.Path); err != nil {
+		return err
+	} else if c == n {
+		return errors.New("Cannot override the root")
+	}
+	if err := n.SetPath(o.Path, o.Value); err != nil {
+		return err
+	}
+	c, _ := n.Lookup(o.Path)
+	comment := "# " + overrideComment + o.Source
+	if !c.parsed {
+		c.Comments.Line = comment
+		return nil
+	}
+
+	line, rest := c.Comments.Line, ""
+	if i := strings.IndexAny(line, "\r\n"); i >= 0 {
+		line, rest = line[:i], line[i:]
+	}
+	if i := strings.Index(line, comment[:2+len(overrideComment)]); i >= 0 {
+		line = strings.TrimRight(line[:i], " \t")
+	}
+	if rest == "" && !startsLine(nodeFollow(c.parent, c.parent.indexOf(c))) {
+		// a # comment would end the line
+		comment = "/* " + overrideComment + strings.Replace(o.Source, "*/", "* /", -1) + " */"
+		c.Comments.Line = " " + comment + line
+		return nil
+	}
+	c.Comments.Line = line + " " + comment + rest
+	return nil
+}
@@ -0,0 +1,47 @@
+package hjson
+
+import "testing"
+
+func TestWriteOverrides(t *testing.T) {
+	src := "# server\nhost: example.com\nport: 80 // the port\nlimits: {max: 1, min: 0}\n\nname: app\n"
+	doc, err := ParseNode([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := WriteOverrides(doc, []Override{
+		{Path: "host", Value: "other.org", Source: "flag -host"},
+		{Path: "port", Value: 8080, Source: "env APP_PORT"},
+		{Path: "limits.max", Value: 5, Source: "env APP_MAX"},
+		{Path: "debug", Value: true, Source: "flag -debug"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "# server\nhost: \"other.org\" # overridden by flag -host\nport: 8080 // the port # overridden by env APP_PORT\nlimits: {max: 5 /* overridden by env APP_MAX */, min: 0}\n\nname: app\ndebug: true # overridden by flag -debug\n"
+	if string(out) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out)
+	}
+	if s, _ := doc.Marshal(); string(s) != src {
+		t.Errorf("expected the document to be unchanged, got\n%s", s)
+	}
+
+	// the comment of an earlier override is replaced
+	doc, err = ParseNode(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err = WriteOverrides(doc, []Override{{Path: "host", Value: "example.com", Source: "env APP_HOST"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = "# server\nhost: \"example.com\" # overridden by env APP_HOST\nport: 8080 // the port # overridden by env APP_PORT\nlimits: {max: 5 /* overridden by env APP_MAX */, min: 0}\n\nname: app\ndebug: true # overridden by flag -debug\n"
+	if string(out) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out)
+	}
+
+	for _, o := range []Override{{Path: "", Value: 1}, {Path: "limits[0]", Value: 1}, {Path: "a..b", Value: 1}} {
+		if _, err := WriteOverrides(doc, []Override{o}); err == nil {
+			t.Errorf("%q: expected an error", o.Path)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"math"
 	"strconv"
+	"strings"
 )
 
 type parseNumber struct {
@@ -106,3 +107,48 @@ func tryParseNumber(text []byte, stopAtNext bool) (float64, error) {
 	}
 	return number, nil
 }
+
+// tryParseJSON5Number parses the JSON5 numbers that are not Hjson numbers:
+// hexadecimal integers, Infinity, NaN, and numbers with a leading + or a
+// leading or trailing decimal point. literal is the number as JSON, or
+// Infinity, -Infinity or NaN.
+func tryParseJSON5Number(text []byte) (number float64, literal string, err error) {
+	s := string(text)
+	sign := ""
+	if len(s) > 0 && (s[0] == '+' || s[0] == '-') {
+		if s[0] == '-' {
+			sign = "-"
+		}
+		s = s[1:]
+	}
+	switch {
+	case s == "Infinity" && sign == "":
+		return math.Inf(1), s, nil
+	case s == "Infinity":
+		return math.Inf(-1), "-" + s, nil
+	case s == "NaN":
+		return math.NaN(), s, nil
+	case len(s) > 2 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X'):
+		n, err := strconv.ParseUint(s[2:], 16, 64)
+		if err != nil {
+			return 0, "", errors.New("Invalid number")
+		}
+		if sign == "" {
+			return float64(n), strconv.FormatUint(n, 10), nil
+		}
+		return -float64(n), "-" + strconv.FormatUint(n, 10), nil
+	}
+	isDigit := func(i int) bool {
+		return i >= 0 && i < len(s) && s[i] >= '0' && s[i] <= '9'
+	}
+	if i := strings.IndexByte(s, '.'); i == 0 && isDigit(1) {
+		s = "0" + s
+	} else if i > 0 && isDigit(i-1) && !isDigit(i+1) {
+		s = s[:i] + s[i+1:]
+	}
+	literal = sign + s
+	if number, err = tryParseNumber([]byte(literal), false); err != nil {
+		return 0, "", err
+	}
+	return number, literal, nil
+}
@@ -0,0 +1,278 @@
+package hjson
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// A PatchOp is an operation of a Patch.
+type PatchOp struct {
+	// "add", "remove", "replace" or "comment"
+	Op string `json:"op"`
+	// The JSON Pointer (RFC 6901) of the value, see Node.Pointer. For add
+	// into an array it is the index the value gets, "-" for the end.
+	Path string `json:"path"`
+	// For add and replace, the Hjson of the value with its comments
+	Value string `json:"value,omitempty"`
+	// For comment, the comments of the value, one per line: before it (or
+	// before the key of a member), on its line and, for an object or
+	// array, after its last member or element
+	Before string `json:"before,omitempty"`
+	Line   string `json:"line,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// A Patch is a list of operations that change a document, like a JSON
+// Patch (RFC 6902) that also changes comments, so that annotated changes
+// of a configuration can be distributed to the systems using it. A Patch
+// can be encoded and decoded with Marshal and Unmarshal, or with
+// encoding/json.
+type Patch []PatchOp
+
+// CreatePatch returns the Patch that turns the document a into b for
+// ApplyPatch: values are compared like Diff does, and comments by their
+// text, without the whitespace and blank lines around them. Added and
+// replaced values are carried with their comments.
+func CreatePatch(a, b *Node) (Patch, error) {
+	var c patchCreator
+	if err := c.create("", a, b); err != nil {
+		return nil, err
+	}
+	return c.patch, nil
+}
+
+type patchCreator struct {
+	patch Patch
+}
+
+// create adds the operations that turn a into b at the pointer ptr.
+func (c *patchCreator) create(ptr string, a, b *Node) error {
+	if a.Kind != b.Kind || a.Kind == ValueNode && !sameValue(a.Value, b.Value) {
+		value, err := patchValue(b, false)
+		if err != nil {
+			return err
+		}
+		c.patch = append(c.patch, PatchOp{Op: "replace", Path: ptr, Value: value})
+	}
+	before, line, after := nodeComments(a)
+	bBefore, bLine, bAfter := nodeComments(b)
+	if bBefore != before || bLine != line || a.Kind == b.Kind && bAfter != after {
+		c.patch = append(c.patch, PatchOp{Op: "comment", Path: ptr, Before: bBefore, Line: bLine, After: bAfter})
+	}
+	if a.Kind != b.Kind {
+		return nil
+	}
+
+	switch a.Kind {
+	case ArrayNode:
+		for i := 0; i < len(a.Children) && i < len(b.Children); i++ {
+			if err := c.create(fmt.Sprintf("%s/%d", ptr, i), a.Children[i], b.Children[i]); err != nil {
+				return err
+			}
+		}
+		// from the end, so that the indexes stay valid
+		for i := len(a.Children) - 1; i >= len(b.Children); i-- {
+			c.patch = append(c.patch, PatchOp{Op: "remove", Path: fmt.Sprintf("%s/%d", ptr, i)})
+		}
+		for i := len(a.Children); i < len(b.Children); i++ {
+			if err := c.add(fmt.Sprintf("%s/%d", ptr, i), b.Children[i]); err != nil {
+				return err
+			}
+		}
+	case ObjectNode:
+		// like Get, only the last of duplicate keys counts
+		aIndex := memberIndex(a)
+		bIndex := memberIndex(b)
+		for i, m := range a.Children {
+			if aIndex[m.Key] != i {
+				continue
+			}
+			memberPtr := ptr + "/" + escapePointerToken(m.Key)
+			if j, ok := bIndex[m.Key]; ok {
+				if err := c.create(memberPtr, m, b.Children[j]); err != nil {
+					return err
+				}
+			} else {
+				c.patch = append(c.patch, PatchOp{Op: "remove", Path: memberPtr})
+			}
+		}
+		for i, m := range b.Children {
+			if _, ok := aIndex[m.Key]; ok || bIndex[m.Key] != i {
+				continue
+			}
+			if err := c.add(ptr+"/"+escapePointerToken(m.Key), m); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// add adds an operation that adds n at ptr.
+func (c *patchCreator) add(ptr string, n *Node) error {
+	value, err := patchValue(n, true)
+	if err == nil {
+		c.patch = append(c.patch, PatchOp{Op: "add", Path: ptr, Value: value})
+	}
+	return err
+}
+
+// nodeComments returns the comments of n for a PatchOp.
+func nodeComments(n *Node) (before, line, after string) {
+	return commentText(n.Comments.Before, n.Comments.Key), commentText(n.Comments.Line), commentText(n.Comments.After)
+}
+
+// patchValue returns the Hjson of n for a PatchOp, with the comments
+// before and on the line of n if withComments is set.
+func patchValue(n *Node, withComments bool) (string, error) {
+	n = n.Clone()
+	n.forgetLayout()
+	if !withComments {
+		n.Comments.Before, n.Comments.Line = "", ""
+	}
+	out, err := n.Marshal()
+	return string(out), err
+}
+
+// ApplyPatch applies the operations of patch to the document n in order.
+// The values that are not changed keep their formatting, and the added and
+// replaced values, as well as those with changed comments, are written
+// like new nodes, see ParseNode.
+//
+// Like JSON Patch, add replaces an existing member, or inserts into an
+// array; remove and replace need an existing value. ApplyPatch stops at
+// the first operation that fails, the operations before it are applied.
+func ApplyPatch(n *Node, patch Patch) error {
+	if n.frozen {
+		return errFrozen
+	}
+	for _, op := range patch {
+		if err := applyPatchOp(n, op); err != nil {
+			return fmt.Errorf("Cannot %s '%s': %v", op.Op, op.Path, err)
+		}
+	}
+	return nil
+}
+
+func applyPatchOp(root *Node, op PatchOp) error {
+	tokens, err := splitPointer(op.Path)
+	if err != nil {
+		return err
+	}
+	var parent *Node
+	n := root
+	for _, tok := range tokens {
+		if n == nil {
+			return errors.New("no such value")
+		}
+		parent, n = n, n.pointerChild(tok)
+	}
+	if n == nil && op.Op != "add" {
+		return errors.New("no such value")
+	}
+
+	switch op.Op {
+	case "add":
+		if parent == nil {
+			return replaceValue(root, op.Value)
+		}
+		v, err := patchNode(op.Value)
+		if err != nil {
+			return err
+		}
+		last := tokens[len(tokens)-1]
+		i := len(parent.Children)
+		switch parent.Kind {
+		case ObjectNode:
+			if n != nil {
+				i = parent.indexOf(n)
+				parent.remove(i)
+			}
+			v.Key = last
+		case ArrayNode:
+			if last != "-" {
+				var ok bool
+				if i, ok = arrayIndex(last); !ok || i > len(parent.Children) {
+					return errors.New("no such index")
+				}
+			}
+		default:
+			return errors.New("not an object or array")
+		}
+		parent.insert(i, v)
+	case "remove":
+		if parent == nil {
+			return errors.New("the root cannot be removed")
+		}
+		for i := len(parent.Children) - 1; i >= 0; i-- {
+			// with all duplicates of a key
+			if c := parent.Children[i]; c == n || parent.Kind == ObjectNode && c.Key == n.Key {
+				parent.remove(i)
+			}
+		}
+	case "replace":
+		return replaceValue(n, op.Value)
+	case "comment":
+		n.setComments(op.Before, op.Line, op.After, parent == nil)
+	default:
+		return errors.New("unknown operation")
+	}
+	return nil
+}
+
+// patchNode parses the value of a PatchOp.
+func patchNode(value string) (*Node, error) {
+	n, err := ParseNode([]byte(value))
+	if err != nil {
+		return nil, err
+	}
+	n.forgetLayout()
+	return n, nil
+}
+
+// replaceValue replaces the value of n with the value of a PatchOp.
+func replaceValue(n *Node, value string) error {
+	v, err := patchNode(value)
+	if err != nil {
+		return err
+	}
+	return n.SetValue(v)
+}
+
+// setComments changes the comments of n that differ from those of a
+// PatchOp. A parsed root keeps its source text, other nodes are written
+// like new nodes then.
+func (n *Node) setComments(before, line, after string, isRoot bool) {
+	eol := "\n"
+	if n.style != nil {
+		eol = n.style.eol()
+	}
+	b, l, a := nodeComments(n)
+	switch {
+	case isRoot && n.parsed:
+		if b != before {
+			if n.Comments.Before = ""; before != "" {
+				n.Comments.Before = strings.Replace(before, "\n", eol, -1) + eol
+			}
+		}
+		if l != line {
+			if n.Comments.Line = eol; line != "" {
+				n.Comments.Line = " " + strings.Replace(line, "\n", eol, -1) + eol
+			}
+		}
+	case b != before || l != line:
+		n.Comments.Before, n.Comments.Key, n.Comments.Line = before, "", line
+		n.parsed, n.keyGap = false, ""
+	}
+	switch {
+	case n.Kind == ValueNode || a == after:
+	case n.braceless && n.parsedInside:
+		// the end of the document
+		if n.Comments.After = eol; after != "" {
+			n.Comments.After = eol + strings.Replace(after, "\n", eol, -1) + eol
+		}
+	default:
+		n.Comments.After, n.parsedInside = after, false
+	}
+}
@@ -0,0 +1,145 @@
+package hjson
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestPatch(t *testing.T) {
+	a := `# config
+server: {
+  port: 80 // http
+  hosts: ["a", "b", "c"]
+  debug: true, name: "web"
+  tls: { cert: "a.pem" }
+}
+`
+	b := `# the config
+server: {
+  port: 8080 // http
+  hosts: ["a"]
+  # the name
+  name: "web"
+  tls: [1, 2]
+  # limits
+  limits: {
+    conns: 10 // per host
+  }
+}
+# end
+`
+	na, err := ParseNode([]byte(a))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nb, err := ParseNode([]byte(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	patch, err := CreatePatch(na, nb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Patch{
+		{Op: "comment", Path: "", Before: "# the config", After: "# end"},
+		{Op: "replace", Path: "/server/port", Value: "8080"},
+		{Op: "remove", Path: "/server/hosts/2"},
+		{Op: "remove", Path: "/server/hosts/1"},
+		{Op: "remove", Path: "/server/debug"},
+		{Op: "comment", Path: "/server/name", Before: "# the name"},
+		{Op: "replace", Path: "/server/tls", Value: "[\n  1\n  2\n]"},
+		{Op: "add", Path: "/server/limits", Value: "# limits\n{\n  conns: 10 // per host\n}"},
+	}
+	if !reflect.DeepEqual(patch, expected) {
+		t.Errorf("expected\n%+v\ngot\n%+v", expected, patch)
+	}
+
+	// the patch is distributed as Hjson or JSON
+	for _, marshal := range []func(interface{}) ([]byte, error){Marshal, json.Marshal} {
+		data, err := marshal(patch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded Patch
+		if err = Unmarshal(data, &decoded); err != nil || !reflect.DeepEqual(decoded, patch) {
+			t.Errorf("unexpected %+v, %v for\n%s", decoded, err, data)
+		}
+	}
+
+	if err = ApplyPatch(na, patch); err != nil {
+		t.Fatal(err)
+	}
+	out, err := na.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := `# the config
+server: {
+  port: 8080 // http
+  hosts: ["a"]
+  # the name
+  name: "web"
+  tls: [
+    1
+    2
+  ]
+  # limits
+  limits: {
+    conns: 10 // per host
+  }
+}
+# end
+`
+	if string(out) != result {
+		t.Errorf("expected\n%q\ngot\n%q", result, out)
+	}
+	if changes := DiffWithOptions(na, nb, DiffOptions{Comments: true}); len(changes) != 0 {
+		t.Errorf("unexpected changes %v", changes)
+	}
+
+	for _, c := range [][2]string{
+		{"a: 1, b: 2 // two\nc: 3", "a: 1 // one\nb: 2\nc: 3"},
+		{"[1, 2, 3]", "[\n  # zero\n  0\n  1\n]"},
+		{"{a: {b: [1]}}", "// root\n{a: {b: [1, {c: 2}]}} // end"},
+		{"x: {\n  y: 1\n  # last\n}", "x: {\n  y: 1\n}"},
+		{"1", "[1]"},
+	} {
+		na, _ := ParseNode([]byte(c[0]))
+		nb, _ := ParseNode([]byte(c[1]))
+		patch, err := CreatePatch(na, nb)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = ApplyPatch(na, patch); err != nil {
+			t.Fatalf("%q: %v", c[0], err)
+		}
+		out, err := na.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if applied, err := ParseNode(out); err != nil {
+			t.Errorf("%q: invalid result %q: %v", c[0], out, err)
+		} else if changes := DiffWithOptions(applied, nb, DiffOptions{Comments: true}); len(changes) != 0 {
+			t.Errorf("%q: unexpected changes %v in %q", c[0], changes, out)
+		}
+	}
+
+	for _, op := range []PatchOp{
+		{Op: "move", Path: "/server"},
+		{Op: "remove", Path: ""},
+		{Op: "remove", Path: "/missing"},
+		{Op: "replace", Path: "/missing/x", Value: "1"},
+		{Op: "add", Path: "/server/hosts/5", Value: "1"},
+		{Op: "add", Path: "/server/port/x", Value: "1"},
+		{Op: "add", Path: "/x", Value: "{"},
+		{Op: "comment", Path: "server"},
+	} {
+		if err = ApplyPatch(na, Patch{op}); err == nil {
+			t.Errorf("%+v: expected an error", op)
+		}
+	}
+	if err = ApplyPatch(na.Freeze(), patch); err != errFrozen {
+		t.Errorf("expected errFrozen, got %v", err)
+	}
+}
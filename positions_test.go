@@ -0,0 +1,90 @@
+package hjson
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPositions(t *testing.T) {
+	input := `# servers
+servers: [
+  {
+    host: a.example.com
+    port: 80
+  }
+  { host: "b.example.com", port: 70000 }
+]
+name: app
+`
+	expected := map[string]Position{
+		"":                {10, 2, 1},
+		"servers":         {19, 2, 10},
+		"servers[0]":      {23, 3, 3},
+		"servers[0].host": {35, 4, 11},
+		"servers[0].port": {59, 5, 11},
+		"servers[1]":      {68, 7, 3},
+		"servers[1].host": {76, 7, 11},
+		"servers[1].port": {99, 7, 34},
+		"name":            {115, 9, 7},
+	}
+
+	type server struct {
+		Host string
+		Port int
+	}
+	var config struct {
+		Servers []server
+		Name    string
+	}
+	var v interface{}
+	for _, dest := range []interface{}{&v, &config} {
+		positions := map[string]Position{}
+		decOpt, err := NewDecoderOptions(WithPositions(positions))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = UnmarshalWithOptions([]byte(input), dest, decOpt); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(positions, expected) {
+			t.Errorf("%T: expected\n%v\ngot\n%v", dest, expected, positions)
+		}
+	}
+
+	// the paths can be looked up in a Node, "" being the root
+	node, err := ParseNode([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path := range expected {
+		if n, err := node.Lookup(path); err != nil || n == nil {
+			t.Errorf("%q: expected a node, got %v, %v", path, n, err)
+		}
+	}
+
+	positions := map[string]Position{}
+	decOpt := DefaultDecoderOptions()
+	decOpt.Positions = positions
+	decOpt.DuplicateKeys = DuplicateKeyFirst
+	if err := UnmarshalWithOptions([]byte("{\n  a: 1\n  a: [2]\n}"), &v, decOpt); err != nil {
+		t.Fatal(err)
+	}
+	// the position of the value that is kept
+	if len(positions) != 2 || positions["a"] != (Position{7, 2, 6}) {
+		t.Errorf("unexpected %v", positions)
+	}
+
+	positions = map[string]Position{}
+	decOpt = DefaultDecoderOptions()
+	decOpt.Positions = positions
+	if err := UnmarshalWithOptions([]byte("\n  'text'"), &v, decOpt); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(positions, map[string]Position{"": {3, 2, 3}}) {
+		t.Errorf("unexpected %v", positions)
+	}
+
+	if _, err := NewDecoderOptions(WithPositions(nil)); err == nil {
+		t.Error("expected an error for nil Positions")
+	}
+}
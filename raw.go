@@ -0,0 +1,115 @@
+package hjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+var rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+
+// writeRawMessage writes the JSON of a json.RawMessage as it is, which is
+// valid Hjson, after checking it.
+func (e *hjsonEncoder) writeRawMessage(raw json.RawMessage, separator string) error {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		raw = json.RawMessage("null")
+	} else if !json.Valid(raw) {
+		return errors.New("Invalid JSON in json.RawMessage")
+	}
+	e.WriteString(separator)
+	e.Write(raw)
+	return nil
+}
+
+// readRawMessage decodes a value with read into the json.RawMessage dest as
+// compact JSON, keeping the order of the members and the text of the
+// numbers.
+func (p *hjsonParser) readRawMessage(dest reflect.Value, read func(reflect.Value) (interface{}, error)) error {
+	useNumber, useOrderedMap := p.UseNumber, p.UseOrderedMap
+	p.UseNumber, p.UseOrderedMap = true, true
+	value, err := read(reflect.Value{})
+	p.UseNumber, p.UseOrderedMap = useNumber, useOrderedMap
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err = writeCompactJSON(&buf, value); err != nil {
+		return p.errAt(err.Error())
+	}
+	dest.SetBytes(buf.Bytes())
+	return p.alloc(buf.Len())
+}
+
+// writeCompactJSON writes a decoded value as compact JSON.
+func writeCompactJSON(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case json.Number:
+		if json.Valid([]byte(v)) {
+			buf.WriteString(string(v))
+		} else if f, err := v.Float64(); err == nil {
+			// an Hjson number that is written differently in JSON
+			buf.WriteString(strconv.FormatFloat(f, 'g', -1, 64))
+		} else {
+			return err
+		}
+	case string:
+		writeJSONString(buf, v)
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, elem := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCompactJSON(buf, elem); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case *OrderedMap:
+		buf.WriteByte('{')
+		for i, key := range v.Keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeJSONString(buf, key)
+			buf.WriteByte(':')
+			if err := writeCompactJSON(buf, v.Map[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return writeCompactJSON(buf, &OrderedMap{keys, v})
+	default:
+		// a value parsed by an extension
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+	}
+	return nil
+}
+
+// writeJSONString writes s as a JSON string without escaping HTML
+// characters.
+func writeJSONString(buf *bytes.Buffer, s string) {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	// Encode ends with a newline
+	buf.Truncate(buf.Len() - 1)
+}
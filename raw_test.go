@@ -0,0 +1,59 @@
+package hjson
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRawMessage(t *testing.T) {
+	var payload struct {
+		Kind string
+		Data json.RawMessage
+		Ptr  *json.RawMessage
+	}
+	data := `{
+  Kind: point
+  Data: {
+    # coordinates
+    y: 1.50
+    x: <&>
+    z: [true, null]
+  }
+  Ptr: null
+}`
+	if err := Unmarshal([]byte(data), &payload); err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"y":1.50,"x":"<&>","z":[true,null]}`
+	if string(payload.Data) != expected || payload.Ptr != nil {
+		t.Errorf("expected %s, got %s %v", expected, payload.Data, payload.Ptr)
+	}
+
+	var root json.RawMessage
+	if err := Unmarshal([]byte("a: 1\nb: text"), &root); err != nil || string(root) != `{"a":1,"b":"text"}` {
+		t.Errorf("unexpected %s, %v", root, err)
+	}
+
+	buf, err := Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = "{\n  Kind: point\n  Data:\n  {\n    x: <&>\n    y: 1.50\n    z:\n    [\n      true\n      null\n    ]\n  }\n  Ptr: null\n}"
+	if string(buf) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf)
+	}
+
+	opt := DefaultOptions()
+	opt.RawMessageVerbatim = true
+	if buf, err = MarshalWithOptions(payload, opt); err != nil {
+		t.Fatal(err)
+	}
+	expected = "{\n  Kind: point\n  Data: {\"y\":1.50,\"x\":\"<&>\",\"z\":[true,null]}\n  Ptr: null\n}"
+	if string(buf) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf)
+	}
+	payload.Data = json.RawMessage("{bad")
+	if _, err = MarshalWithOptions(payload, opt); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+}
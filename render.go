@@ -0,0 +1,171 @@
+package hjson
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// A problem is what the Render functions write: a Diagnostic with notes.
+type problem struct {
+	Diagnostic
+	notes []string
+}
+
+// RenderError writes err, returned for the Hjson document data, for people
+// reading it in a terminal, see RenderDiagnostics. Each SyntaxError in err
+// is written at its position, an UnmarshalTypeError with a note naming the
+// path of the value. Other errors are written without a source excerpt.
+// name is the file of data, "" if it has none.
+func RenderError(w io.Writer, name string, data []byte, err error) error {
+	var problems []problem
+	var errs SyntaxErrors
+	var te *UnmarshalTypeError
+	switch {
+	case errors.As(err, &errs):
+		for _, se := range errs {
+			problems = append(problems, problem{Diagnostic: diagnostic(SeverityError, se)})
+		}
+	case errors.As(err, &te):
+		pr := problem{Diagnostic: diagnostic(SeverityError, te)}
+		if te.Path != "" {
+			pr.notes = append(pr.notes, "at "+te.Path)
+		}
+		problems = append(problems, pr)
+	case err != nil:
+		problems = append(problems, problem{Diagnostic: diagnostic(SeverityError, err)})
+	}
+	return render(w, name, data, problems, colorOutput(w))
+}
+
+// RenderDiagnostics writes diags, found in the Hjson document data by
+// Validate, for people reading them in a terminal: each one with its
+// severity, message and position, the line of data it is on with a caret
+// under its column, and notes, if any:
+//
+//	config.hjson:3:7: error: Found ':' but no key name
+//	   |
+//	 3 |   a: {: 1}
+//	   |       ^
+//
+// The output is colored if w is a terminal, and plain text otherwise or if
+// the environment variable NO_COLOR is set. name is the file of data, ""
+// if it has none.
+func RenderDiagnostics(w io.Writer, name string, data []byte, diags []Diagnostic) error {
+	problems := make([]problem, len(diags))
+	for i, d := range diags {
+		problems[i] = problem{Diagnostic: d}
+	}
+	return render(w, name, data, problems, colorOutput(w))
+}
+
+// RenderSchemaViolations writes violations, found in the Hjson document
+// data by ValidateSchema, as errors for people reading them in a terminal,
+// see RenderDiagnostics, with notes naming the path of the value and the
+// schema keyword.
+func RenderSchemaViolations(w io.Writer, name string, data []byte, violations []SchemaViolation) error {
+	problems := make([]problem, len(violations))
+	for i, v := range violations {
+		problems[i] = problem{
+			Diagnostic: Diagnostic{Severity: SeverityError, Message: v.Message, Line: v.Line, Column: v.Column, Offset: v.Offset},
+			notes:      []string{"at " + v.Path, "schema keyword " + v.Keyword},
+		}
+		if v.Path == "" {
+			problems[i].notes[0] = "at the root"
+		}
+	}
+	return render(w, name, data, problems, colorOutput(w))
+}
+
+// colorOutput reports whether the output to w is colored: if it is a
+// terminal and NO_COLOR is not set (see https://no-color.org).
+func colorOutput(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// ANSI escape sequences of the colored output.
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiRed    = "\x1b[1;31m"
+	ansiYellow = "\x1b[1;33m"
+	ansiBlue   = "\x1b[1;34m"
+	ansiCyan   = "\x1b[1;36m"
+)
+
+// render writes problems, with ANSI colors if color is set.
+func render(w io.Writer, name string, data []byte, problems []problem, color bool) error {
+	paint := func(style, text string) string {
+		if !color {
+			return text
+		}
+		return style + text + ansiReset
+	}
+	var buf bytes.Buffer
+	for i, pr := range problems {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		style := ansiRed
+		if pr.Severity == SeverityWarning {
+			style = ansiYellow
+		}
+		if pr.Line == 0 {
+			// without a position
+			fmt.Fprintf(&buf, "%s %s\n", paint(style, pr.Severity+":"), paint(ansiBold, pr.Message))
+			continue
+		}
+
+		location := fmt.Sprintf("%d:%d", pr.Line, pr.Column)
+		if name != "" {
+			location = name + ":" + location
+		}
+		fmt.Fprintf(&buf, "%s %s %s\n", paint(ansiBold, location+":"), paint(style, pr.Severity+":"), paint(ansiBold, pr.Message))
+		gutter := strings.Repeat(" ", len(fmt.Sprint(pr.Line))+2)
+		if line, ok := sourceLine(data, pr.Offset); ok {
+			// the caret under the column, with the tabs of the line
+			column := pr.Column - 1
+			if column > len(line) {
+				column = len(line)
+			}
+			caret := []rune(line[:column])
+			for j, r := range caret {
+				if r != '\t' {
+					caret[j] = ' '
+				}
+			}
+			fmt.Fprintf(&buf, "%s%s\n", gutter, paint(ansiBlue, "|"))
+			fmt.Fprintf(&buf, "%s %s\n", paint(ansiBlue, fmt.Sprintf(" %d |", pr.Line)), line)
+			fmt.Fprintf(&buf, "%s%s %s%s\n", gutter, paint(ansiBlue, "|"), string(caret), paint(style, "^"))
+		}
+		for _, note := range pr.notes {
+			fmt.Fprintf(&buf, "%s%s %s\n", gutter, paint(ansiBlue, "="), paint(ansiCyan, "note:")+" "+note)
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// sourceLine returns the line of data with the byte at offset, without its
+// end of line. It fails if data does not have that offset.
+func sourceLine(data []byte, offset int) (string, bool) {
+	if offset < 0 || offset > len(data) {
+		return "", false
+	}
+	start := bytes.LastIndexByte(data[:offset], '\n') + 1
+	end := bytes.IndexByte(data[offset:], '\n')
+	if end < 0 {
+		end = len(data)
+	} else {
+		end += offset
+	}
+	return strings.TrimRight(string(data[start:end]), "\r"), true
+}
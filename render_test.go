@@ -0,0 +1,70 @@
+package hjson
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestRenderError(t *testing.T) {
+	data := []byte("{\n\ta: {: 1}\n}\n")
+	var v interface{}
+	err := Unmarshal(data, &v)
+	var buf bytes.Buffer
+	if err := RenderError(&buf, "config.hjson", data, err); err != nil {
+		t.Fatal(err)
+	}
+	expected := "config.hjson:2:6: error: Found ':' but no key name (for an empty key name use quotes)\n   |\n 2 | \ta: {: 1}\n   | \t    ^\n"
+	if buf.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf.String())
+	}
+
+	var config struct{ Port int }
+	data = []byte("# server\nport: eighty\n")
+	buf.Reset()
+	RenderError(&buf, "", data, Unmarshal(data, &config))
+	expected = "2:13: error: Cannot unmarshal string into Go value of type int\n   |\n 2 | port: eighty\n   |             ^\n   = note: at port\n"
+	if buf.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf.String())
+	}
+
+	buf.Reset()
+	RenderError(&buf, "", nil, errors.New("Cannot read the file"))
+	if buf.String() != "error: Cannot read the file\n" {
+		t.Errorf("unexpected %q", buf.String())
+	}
+}
+
+func TestRenderDiagnostics(t *testing.T) {
+	data := []byte("a: 1\na: [2\n")
+	var buf bytes.Buffer
+	if err := RenderDiagnostics(&buf, "x.hjson", data, Validate(data)); err != nil {
+		t.Fatal(err)
+	}
+	expected := "x.hjson:2:3: warning: duplicate key a\n   |\n 2 | a: [2\n   |   ^\n\nx.hjson:2:6: error: End of input while parsing an array (did you forget a closing ']'?)\n   |\n 2 | a: [2\n   |      ^\n"
+	if buf.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf.String())
+	}
+
+	// with colors for terminals
+	buf.Reset()
+	render(&buf, "", data, []problem{{Diagnostic: Diagnostic{SeverityError, "bad", 1, 4, 3}, notes: []string{"a note"}}}, true)
+	expected = "\x1b[1m1:4:\x1b[0m \x1b[1;31merror:\x1b[0m \x1b[1mbad\x1b[0m\n   \x1b[1;34m|\x1b[0m\n\x1b[1;34m 1 |\x1b[0m a: 1\n   \x1b[1;34m|\x1b[0m    \x1b[1;31m^\x1b[0m\n   \x1b[1;34m=\x1b[0m \x1b[1;36mnote:\x1b[0m a note\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestRenderSchemaViolations(t *testing.T) {
+	doc := []byte("port: 70000\n")
+	violations, err := ValidateSchema(doc, []byte(`{properties: {port: {maximum: 65535}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	RenderSchemaViolations(&buf, "", doc, violations)
+	expected := "1:7: error: " + violations[0].Message + "\n   |\n 1 | port: 70000\n   |       ^\n   = note: at port\n   = note: schema keyword maximum\n"
+	if buf.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf.String())
+	}
+}
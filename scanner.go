@@ -0,0 +1,340 @@
+package hjson
+
+import (
+	"bytes"
+)
+
+// TokenKind tells which kind of syntax element a Token is.
+type TokenKind int
+
+const (
+	// TokenBeginObject is the '{' that starts an object
+	TokenBeginObject TokenKind = iota
+	// TokenEndObject is the '}' that ends an object
+	TokenEndObject
+	// TokenBeginArray is the '[' that starts an array
+	TokenBeginArray
+	// TokenEndArray is the ']' that ends an array
+	TokenEndArray
+	// TokenColon is the ':' between the key and the value of a member
+	TokenColon
+	// TokenComma is a ',' after a value
+	TokenComma
+	// TokenKey is the key of an object member, quoted or not
+	TokenKey
+	// TokenString is a quoted, quoteless or multiline string value
+	TokenString
+	// TokenNumber is a number value
+	TokenNumber
+	// TokenBool is true or false
+	TokenBool
+	// TokenNull is null
+	TokenNull
+	// TokenComment is a #, // or /* */ comment
+	TokenComment
+)
+
+// A Token is a syntax element of an Hjson document read by a Scanner.
+type Token struct {
+	Kind TokenKind
+	// The source text of the token, like "'a\\tb'" or "# port", without the
+	// whitespace after quoteless strings and line comments
+	Text string
+	// The key or string for TokenKey and TokenString, a float64 for
+	// TokenNumber, a bool for TokenBool and nil for the other kinds
+	Value  interface{}
+	Offset int // byte offset of the token in the input, starting at 0
+	End    int // byte offset after the token
+	Line   int // line of the start of the token, starting at 1
+	Column int // column in bytes of the start of the token, starting at 1
+}
+
+// What a Scanner reads next, apart from comments.
+const (
+	scanRoot  = iota // the root value or the first member of an object without braces
+	scanValue        // a value after a colon
+	scanColon        // the colon after a key
+	scanNext         // a member or element, or the end of its object or array
+	scanAfter        // like scanNext, or a comma
+)
+
+// A Scanner splits an Hjson document into tokens, with the same rules as
+// Unmarshal. It is meant for tools that work on the source text, such as
+// syntax highlighters and linters:
+//
+//	s := hjson.NewScanner(data)
+//	for s.Scan() {
+//		tok := s.Token()
+//		...
+//	}
+//	if err := s.Err(); err != nil {
+//		...
+//	}
+//
+// The tokens follow the source, so a root object without braces has no
+// TokenBeginObject and TokenEndObject. Whitespace is skipped, comments are
+// returned as tokens.
+type Scanner struct {
+	p     *hjsonParser
+	token Token
+	err   error
+	done  bool
+	state int
+	stack []byte // '{' and '[' of the open objects and arrays, 'r' for a root object without braces
+
+	line      int // the line of lineStart
+	lineStart int // the offset of the start of that line
+	lineAt    int // the offset up to which lines were counted
+}
+
+// NewScanner returns a Scanner that reads the tokens of data.
+func NewScanner(data []byte) *Scanner {
+	return newScanner(data, builtinDecoderOptions())
+}
+
+// newScanner returns a Scanner for the syntax of options, which only
+// AcceptJSON5 changes.
+func newScanner(data []byte, options DecoderOptions) *Scanner {
+	opt := builtinDecoderOptions()
+	opt.AcceptJSON5 = options.AcceptJSON5
+	p := &hjsonParser{DecoderOptions: opt, data: data}
+	p.resetAt()
+	return &Scanner{p: p, line: 1}
+}
+
+// syntaxErrors returns up to max syntax errors of data, going on with the
+// next line after each one, see DecoderOptions.MaxErrors.
+func syntaxErrors(data []byte, options DecoderOptions, max int) []*SyntaxError {
+	s := newScanner(data, options)
+	var errs []*SyntaxError
+	for len(errs) < max {
+		for s.Scan() {
+		}
+		se, ok := s.err.(*SyntaxError)
+		if !ok {
+			break
+		}
+		errs = append(errs, se)
+		if !s.resync(se.Offset) {
+			break
+		}
+	}
+	return errs
+}
+
+// resync continues scanning after a syntax error at offset with the next
+// line, if the error is inside an object or array and not at the end of
+// the input. The braces and brackets on the rest of the line still open
+// and close objects and arrays, so that an error in [1,, 2] does not
+// affect the lines after it.
+func (s *Scanner) resync(offset int) bool {
+	i := bytes.IndexByte(s.p.data[offset:], '\n')
+	if s.done || len(s.stack) == 0 || i < 0 {
+		return false
+	}
+	for _, c := range s.p.data[offset : offset+i] {
+		top := s.stack[len(s.stack)-1]
+		switch {
+		case c == '{' || c == '[':
+			s.stack = append(s.stack, c)
+		case c == '}' && top == '{' || c == ']' && top == '[':
+			s.stack = s.stack[:len(s.stack)-1]
+		}
+	}
+	s.p.at = offset + i + 1
+	s.p.ch = '\n'
+	s.err = nil
+	s.state = scanAfter
+	return true
+}
+
+// Scan reads the next token, which is then available from Token. It
+// returns false at the end of the input or at the first syntax error,
+// which Err returns.
+func (s *Scanner) Scan() bool {
+	if s.err != nil || s.done {
+		return false
+	}
+	p := s.p
+	for p.ch > 0 && p.ch <= ' ' {
+		p.next()
+	}
+	start := s.offset()
+	if p.ch == '#' || p.ch == '/' && (p.peek(0) == '/' || p.peek(0) == '*') {
+		p.skipComment()
+		text := bytes.TrimRight(p.data[start:s.offset()], " \t\r")
+		return s.emit(TokenComment, start, start+len(text), nil)
+	}
+
+	if s.state == scanRoot {
+		s.state = scanValue
+		if p.ch != '{' && p.ch != '[' && s.braceless() {
+			s.stack = append(s.stack, 'r')
+			s.state = scanNext
+		}
+	}
+	if p.ch == 0 {
+		return s.end()
+	}
+
+	switch s.state {
+	case scanColon:
+		if p.ch != ':' {
+			return s.fail(p.errCode("expected-colon", string(p.ch)))
+		}
+		p.next()
+		s.state = scanValue
+		return s.emit(TokenColon, start, start+1, nil)
+	case scanValue:
+		return s.value(start)
+	}
+	if len(s.stack) == 0 {
+		return s.fail(p.errCode("trailing-characters"))
+	}
+	if p.ch == ',' && s.state == scanAfter {
+		p.next()
+		s.state = scanNext
+		return s.emit(TokenComma, start, start+1, nil)
+	}
+	switch top := s.stack[len(s.stack)-1]; {
+	case top == '[' && p.ch == ']':
+		return s.close(TokenEndArray, start)
+	case top == '[':
+		return s.value(start)
+	case top == '{' && p.ch == '}':
+		return s.close(TokenEndObject, start)
+	}
+	key, err := p.readKeyname()
+	if err != nil {
+		return s.fail(err)
+	}
+	end := start + len(key)
+	if p.data[start] == '"' || p.data[start] == '\'' {
+		end = s.offset()
+	}
+	s.state = scanColon
+	return s.emit(TokenKey, start, end, key)
+}
+
+// Token returns the token read by the last call to Scan.
+func (s *Scanner) Token() Token {
+	return s.token
+}
+
+// Err returns the syntax error that stopped Scan, or nil at the end of the
+// input.
+func (s *Scanner) Err() error {
+	return s.err
+}
+
+// value reads the value at start.
+func (s *Scanner) value(start int) bool {
+	p := s.p
+	switch p.ch {
+	case '{', '[':
+		kind := TokenBeginObject
+		if p.ch == '[' {
+			kind = TokenBeginArray
+		}
+		s.stack = append(s.stack, p.ch)
+		p.next()
+		s.state = scanNext
+		return s.emit(kind, start, start+1, nil)
+	case '"', '\'':
+		str, err := p.readString(true)
+		if err != nil {
+			return s.fail(err)
+		}
+		s.state = scanAfter
+		return s.emit(TokenString, start, s.offset(), str)
+	}
+	value, literal, err := p.readTfnns()
+	if err != nil {
+		return s.fail(err)
+	}
+	kind := TokenString
+	switch value.(type) {
+	case nil:
+		kind = TokenNull
+	case bool:
+		kind = TokenBool
+	case float64:
+		kind = TokenNumber
+	}
+	s.state = scanAfter
+	return s.emit(kind, start, start+len(literal), value)
+}
+
+// close reads the '}' or ']' at start.
+func (s *Scanner) close(kind TokenKind, start int) bool {
+	s.stack = s.stack[:len(s.stack)-1]
+	s.p.next()
+	s.state = scanAfter
+	return s.emit(kind, start, start+1, nil)
+}
+
+// end checks that the input may end at this point.
+func (s *Scanner) end() bool {
+	p := s.p
+	s.done = true
+	if len(s.stack) == 0 && s.state == scanAfter ||
+		len(s.stack) == 1 && s.stack[0] == 'r' && s.state != scanColon && s.state != scanValue {
+		return false
+	}
+	switch {
+	case s.state == scanColon:
+		return s.fail(p.errCode("expected-colon", string(p.ch)))
+	case s.state == scanValue || len(s.stack) == 0:
+		return s.fail(p.errCode("value-eof"))
+	case s.stack[len(s.stack)-1] == '[':
+		return s.fail(p.errCode("array-eof"))
+	}
+	return s.fail(p.errCode("object-eof"))
+}
+
+// braceless reports whether the input, which does not start with '{' or
+// '[', is read as an object without braces like rootValue does.
+func (s *Scanner) braceless() bool {
+	g := getter{p: &hjsonParser{DecoderOptions: s.p.DecoderOptions, data: s.p.data}}
+	g.p.resetAt()
+	if g.p.checkTrailingErr(g.object(true, nil, false)) == nil {
+		return true
+	}
+	// if neither works, report the errors of the object like rootValue
+	g.p.resetAt()
+	return g.p.checkTrailingErr(g.value(nil, false)) != nil
+}
+
+// offset returns the offset of the current character.
+func (s *Scanner) offset() int {
+	if s.p.ch == 0 && s.p.at == len(s.p.data) {
+		return len(s.p.data)
+	}
+	return s.p.at - 1
+}
+
+// emit makes the source from start to end the current token.
+func (s *Scanner) emit(kind TokenKind, start, end int, value interface{}) bool {
+	for ; s.lineAt < start; s.lineAt++ {
+		if s.p.data[s.lineAt] == '\n' {
+			s.line++
+			s.lineStart = s.lineAt + 1
+		}
+	}
+	s.token = Token{
+		Kind:   kind,
+		Text:   string(s.p.data[start:end]),
+		Value:  value,
+		Offset: start,
+		End:    end,
+		Line:   s.line,
+		Column: start - s.lineStart + 1,
+	}
+	return true
+}
+
+// fail stops the Scanner at err.
+func (s *Scanner) fail(err error) bool {
+	s.err = err
+	return false
+}
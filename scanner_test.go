@@ -0,0 +1,152 @@
+package hjson
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScanner(t *testing.T) {
+	input := `# config
+name: "a b" // the name
+list: [1, true, null, x y ]
+]
+obj: {
+  'k': '''
+    ml
+    '''
+}
+`
+	expected := []Token{
+		{TokenComment, "# config", nil, 0, 8, 1, 1},
+		{TokenKey, "name", "name", 9, 13, 2, 1},
+		{TokenColon, ":", nil, 13, 14, 2, 5},
+		{TokenString, `"a b"`, "a b", 15, 20, 2, 7},
+		{TokenComment, "// the name", nil, 21, 32, 2, 13},
+		{TokenKey, "list", "list", 33, 37, 3, 1},
+		{TokenColon, ":", nil, 37, 38, 3, 5},
+		{TokenBeginArray, "[", nil, 39, 40, 3, 7},
+		{TokenNumber, "1", 1.0, 40, 41, 3, 8},
+		{TokenComma, ",", nil, 41, 42, 3, 9},
+		{TokenBool, "true", true, 43, 47, 3, 11},
+		{TokenComma, ",", nil, 47, 48, 3, 15},
+		{TokenNull, "null", nil, 49, 53, 3, 17},
+		{TokenComma, ",", nil, 53, 54, 3, 21},
+		// a quoteless string runs to the end of the line
+		{TokenString, "x y ]", "x y ]", 55, 60, 3, 23},
+		{TokenEndArray, "]", nil, 61, 62, 4, 1},
+		{TokenKey, "obj", "obj", 63, 66, 5, 1},
+		{TokenColon, ":", nil, 66, 67, 5, 4},
+		{TokenBeginObject, "{", nil, 68, 69, 5, 6},
+		{TokenKey, "'k'", "k", 72, 75, 6, 3},
+		{TokenColon, ":", nil, 75, 76, 6, 6},
+		{TokenString, "'''\n    ml\n    '''", "ml", 77, 95, 6, 8},
+		{TokenEndObject, "}", nil, 96, 97, 9, 1},
+	}
+
+	s := NewScanner([]byte(input))
+	var tokens []Token
+	for s.Scan() {
+		tokens = append(tokens, s.Token())
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tokens, expected) {
+		t.Errorf("expected\n%v\ngot\n%v", expected, tokens)
+	}
+
+	for input, expected := range map[string]string{
+		"":          "",
+		"5":         "",
+		"a b":       "",
+		"[1, 2]":    "",
+		"[1,, 2]":   "Found a punctuator character ','",
+		"{a: 1":     "End of input while parsing an object",
+		"[1":        "End of input while parsing an array",
+		"a: 1\n}":   "Found '}' where a key name was expected",
+		"a:":        "",
+		"a: 1\nb:":  "Found EOF while looking for a value",
+		"[1] 2":     "Syntax error, found trailing characters",
+		"\"a\" 1":   "Expected ':' instead of '1'",
+		"{\"a\" 1}": "Expected ':' instead of '1'",
+	} {
+		s := NewScanner([]byte(input))
+		for s.Scan() {
+		}
+		if err := s.Err(); expected == "" && err != nil || expected != "" && (err == nil || !strings.Contains(err.Error(), expected)) {
+			t.Errorf("%q: expected an error with %q, got %v", input, expected, err)
+		}
+	}
+}
+
+// scannedValue builds the value of the tokens of s like Unmarshal.
+func scannedValue(s *Scanner) (interface{}, error) {
+	// the open objects and arrays, and the keys of the open members
+	stack := []interface{}{&[]interface{}{}}
+	var keys []string
+	set := func(v interface{}) {
+		switch parent := stack[len(stack)-1].(type) {
+		case map[string]interface{}:
+			parent[keys[len(keys)-1]] = v
+			keys = keys[:len(keys)-1]
+		case *[]interface{}:
+			*parent = append(*parent, v)
+		}
+	}
+	for s.Scan() {
+		switch tok := s.Token(); tok.Kind {
+		case TokenKey:
+			if len(stack) == 1 {
+				// a root object without braces
+				stack = append(stack, map[string]interface{}{})
+			}
+			keys = append(keys, tok.Value.(string))
+		case TokenBeginObject:
+			stack = append(stack, map[string]interface{}{})
+		case TokenBeginArray:
+			stack = append(stack, &[]interface{}{})
+		case TokenEndObject, TokenEndArray:
+			v := stack[len(stack)-1]
+			if a, ok := v.(*[]interface{}); ok {
+				v = *a
+			}
+			stack = stack[:len(stack)-1]
+			set(v)
+		case TokenString, TokenNumber, TokenBool, TokenNull:
+			set(tok.Value)
+		}
+	}
+	if len(stack) == 2 {
+		return stack[1], s.Err()
+	} else if root := *stack[0].(*[]interface{}); len(root) == 1 {
+		return root[0], s.Err()
+	}
+	// an empty input is an empty object
+	return map[string]interface{}{}, s.Err()
+}
+
+func TestScannerAssets(t *testing.T) {
+	files := strings.Split(string(getContent("assets/testlist.txt")), "\n")
+	for _, file := range files {
+		if file == "" || strings.HasPrefix(file, "stringify/quotes") || strings.HasPrefix(file, "extra/") {
+			continue
+		}
+		name := strings.TrimSuffix(file, "_test"+file[strings.LastIndex(file, "."):])
+		input := getTestContent(name)
+		var expected interface{}
+		err := Unmarshal(input, &expected)
+		got, err2 := scannedValue(NewScanner(input))
+		if err != nil {
+			if err2 == nil {
+				t.Errorf("%s: expected an error", name)
+			}
+			continue
+		}
+		if err2 != nil {
+			t.Errorf("%s: %v", name, err2)
+		} else if !reflect.DeepEqual(got, expected) {
+			t.Errorf("%s: expected %#v, got %#v", name, expected, got)
+		}
+	}
+}
@@ -0,0 +1,733 @@
+package hjson
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// A SchemaViolation describes a part of a document that does not match its
+// JSON Schema, see ValidateSchema.
+type SchemaViolation struct {
+	// The path of the value, like "servers[1].port", in the form taken by
+	// Node.Lookup; "" for the root value
+	Path string `json:"path"`
+	// The schema keyword that failed, like "minimum" or "required"
+	Keyword string `json:"keyword"`
+	// Description of the problem
+	Message string `json:"message"`
+	// Line of the value, starting at 1
+	Line int `json:"line"`
+	// Column in bytes of the value, starting at 1
+	Column int `json:"column"`
+	// Byte offset of the value in the document, starting at 0
+	Offset int `json:"offset"`
+}
+
+// Keywords of draft 2020-12 that need the annotations of other keywords or
+// dynamic scopes, which ValidateSchema does not collect.
+var unsupportedSchemaKeywords = []string{
+	"unevaluatedProperties", "unevaluatedItems", "$dynamicRef", "$recursiveRef",
+}
+
+// maxSchemaRefs limits the nested $refs taken without going deeper into the
+// document, to stop on schemas that refer to themselves.
+const maxSchemaRefs = 100
+
+// ValidateSchema parses the Hjson document doc and checks it against the
+// JSON Schema (draft 2020-12) schema, which may be written in JSON or
+// Hjson. It returns the violations found, or nil if doc matches, each at
+// the line and column of the value in doc:
+//
+//	violations, err := hjson.ValidateSchema(doc, schema)
+//	for _, v := range violations {
+//		fmt.Printf("%d:%d: %s: %s\n", v.Line, v.Column, v.Path, v.Message)
+//	}
+//
+// A missing member is reported at the object that lacks it. The format
+// keyword is an annotation, as by default in draft 2020-12, and patterns
+// are Go regular expressions. $ref may refer to the schema itself with a
+// JSON Pointer fragment like "#/$defs/port" or an anchor like "#port", but
+// not to other documents. An error is returned if doc or schema is not
+// valid Hjson, or if the parts of the schema that apply to doc are invalid
+// or use keywords that are not supported, like unevaluatedProperties.
+func ValidateSchema(doc []byte, schema []byte) ([]SchemaViolation, error) {
+	// the numbers of the schema are kept as written, like those of doc
+	decOpt := DefaultDecoderOptions()
+	decOpt.UseNumber = true
+	var s interface{}
+	if err := UnmarshalWithOptions(schema, &s, decOpt); err != nil {
+		return nil, fmt.Errorf("Invalid schema: %v", err)
+	}
+	root, err := ParseNode(doc)
+	if err != nil {
+		return nil, err
+	}
+	v := &schemaValidator{root: s}
+	violations := v.check(root, nil, s)
+	if v.err != nil {
+		return nil, v.err
+	}
+	return violations, nil
+}
+
+type schemaValidator struct {
+	root interface{} // the whole schema, for $ref
+	refs int         // the nested $refs at the current value
+	err  error       // the first problem of the schema
+}
+
+// fail records a problem of the schema.
+func (v *schemaValidator) fail(format string, args ...interface{}) {
+	if v.err == nil {
+		v.err = fmt.Errorf("Invalid schema: "+format, args...)
+	}
+}
+
+// violation returns a SchemaViolation at the value n.
+func violation(n *Node, path []pathElem, keyword, format string, args ...interface{}) SchemaViolation {
+	start := n.valueSpan.Start
+	return SchemaViolation{
+		Path:    formatPath(path),
+		Keyword: keyword,
+		Message: fmt.Sprintf(format, args...),
+		Line:    start.Line,
+		Column:  start.Column,
+		Offset:  start.Offset,
+	}
+}
+
+// check returns the violations of schema by the value n at path.
+func (v *schemaValidator) check(n *Node, path []pathElem, schema interface{}) []SchemaViolation {
+	if v.err != nil {
+		return nil
+	}
+	switch s := schema.(type) {
+	case bool:
+		if !s {
+			return []SchemaViolation{violation(n, path, "false", "no value is allowed here")}
+		}
+		return nil
+	case map[string]interface{}:
+		for _, keyword := range unsupportedSchemaKeywords {
+			if _, ok := s[keyword]; ok {
+				v.err = errors.New("Unsupported schema keyword " + keyword)
+				return nil
+			}
+		}
+		var violations []SchemaViolation
+		if ref, ok := s["$ref"]; ok {
+			violations = append(violations, v.checkRef(n, path, ref)...)
+		}
+		violations = append(violations, v.checkAny(n, path, s)...)
+		violations = append(violations, v.checkApplicators(n, path, s)...)
+		switch n.Kind {
+		case ValueNode:
+			violations = append(violations, v.checkValue(n, path, s)...)
+		case ArrayNode:
+			violations = append(violations, v.checkArray(n, path, s)...)
+		case ObjectNode:
+			violations = append(violations, v.checkObject(n, path, s)...)
+		}
+		if v.err != nil {
+			return nil
+		}
+		return violations
+	}
+	v.fail("a schema must be an object or a bool, not %s", jsonType(schema))
+	return nil
+}
+
+// checkRef checks n against the schema that ref refers to.
+func (v *schemaValidator) checkRef(n *Node, path []pathElem, ref interface{}) []SchemaViolation {
+	str, ok := ref.(string)
+	if !ok {
+		v.fail("$ref must be a string")
+		return nil
+	}
+	target, err := v.resolve(str)
+	if err != nil {
+		v.err = err
+		return nil
+	}
+	if v.refs++; v.refs > maxSchemaRefs {
+		v.fail("$ref '%s' refers to itself", str)
+		return nil
+	}
+	defer func() { v.refs-- }()
+	return v.check(n, path, target)
+}
+
+// resolve returns the part of the schema that ref refers to.
+func (v *schemaValidator) resolve(ref string) (interface{}, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, errors.New("Unsupported $ref '" + ref + "': only references into the schema itself are supported")
+	}
+	fragment := ref[1:]
+	if fragment != "" && fragment[0] != '/' {
+		if s := findAnchor(v.root, fragment); s != nil {
+			return s, nil
+		}
+		return nil, errors.New("Invalid schema: no $anchor '" + fragment + "' for $ref '" + ref + "'")
+	}
+	tokens, err := splitPointer(fragment)
+	if err != nil {
+		return nil, err
+	}
+	s := v.root
+	for _, tok := range tokens {
+		found := false
+		switch t := s.(type) {
+		case map[string]interface{}:
+			s, found = t[tok]
+		case []interface{}:
+			var i int
+			if i, found = arrayIndex(tok); found && i < len(t) {
+				s = t[i]
+			} else {
+				found = false
+			}
+		}
+		if !found {
+			return nil, errors.New("Invalid schema: $ref '" + ref + "' refers to nothing")
+		}
+	}
+	return s, nil
+}
+
+// findAnchor returns the subschema of s with the $anchor name, or nil.
+func findAnchor(s interface{}, name string) interface{} {
+	switch t := s.(type) {
+	case map[string]interface{}:
+		if t["$anchor"] == name {
+			return t
+		}
+		for _, c := range t {
+			if found := findAnchor(c, name); found != nil {
+				return found
+			}
+		}
+	case []interface{}:
+		for _, c := range t {
+			if found := findAnchor(c, name); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
+
+// checkAny checks the keywords for all types: type, enum and const.
+func (v *schemaValidator) checkAny(n *Node, path []pathElem, s map[string]interface{}) []SchemaViolation {
+	var violations []SchemaViolation
+	if t, ok := s["type"]; ok {
+		types, ok := t.([]interface{})
+		if !ok {
+			types = []interface{}{t}
+		}
+		actual := nodeType(n)
+		match := false
+		var names []string
+		for _, t := range types {
+			name, ok := t.(string)
+			if !ok {
+				v.fail("type must be a string or an array of strings")
+				return nil
+			}
+			names = append(names, name)
+			match = match || name == actual || name == "number" && actual == "integer"
+		}
+		if !match {
+			violations = append(violations, violation(n, path, "type", "expected %s, found %s", strings.Join(names, " or "), actual))
+		}
+	}
+	if enum, ok := s["enum"]; ok {
+		values, ok := enum.([]interface{})
+		if !ok {
+			v.fail("enum must be an array")
+			return nil
+		}
+		match := false
+		for _, e := range values {
+			match = match || equalSchemaValue(n, e)
+		}
+		if !match {
+			violations = append(violations, violation(n, path, "enum", "must be one of %s", schemaValues(values)))
+		}
+	}
+	if c, ok := s["const"]; ok && !equalSchemaValue(n, c) {
+		violations = append(violations, violation(n, path, "const", "must be %s", schemaValues([]interface{}{c})))
+	}
+	return violations
+}
+
+// checkApplicators checks the keywords that combine subschemas: allOf,
+// anyOf, oneOf, not and if, then and else.
+func (v *schemaValidator) checkApplicators(n *Node, path []pathElem, s map[string]interface{}) []SchemaViolation {
+	var violations []SchemaViolation
+	if all, ok := s["allOf"]; ok {
+		for _, sub := range v.schemas("allOf", all) {
+			violations = append(violations, v.check(n, path, sub)...)
+		}
+	}
+	if anyOf, ok := s["anyOf"]; ok {
+		subs := v.schemas("anyOf", anyOf)
+		match := false
+		for _, sub := range subs {
+			match = match || v.check(n, path, sub) == nil
+		}
+		if !match && len(subs) > 0 {
+			violations = append(violations, violation(n, path, "anyOf", "must match at least one schema of anyOf"))
+		}
+	}
+	if one, ok := s["oneOf"]; ok {
+		matches := 0
+		for _, sub := range v.schemas("oneOf", one) {
+			if v.check(n, path, sub) == nil {
+				matches++
+			}
+		}
+		if matches != 1 {
+			violations = append(violations, violation(n, path, "oneOf", "must match exactly one schema of oneOf, not %d", matches))
+		}
+	}
+	if not, ok := s["not"]; ok && v.check(n, path, not) == nil {
+		violations = append(violations, violation(n, path, "not", "must not match the schema of not"))
+	}
+	if cond, ok := s["if"]; ok {
+		branch := "else"
+		if v.check(n, path, cond) == nil {
+			branch = "then"
+		}
+		if sub, ok := s[branch]; ok {
+			violations = append(violations, v.check(n, path, sub)...)
+		}
+	}
+	return violations
+}
+
+// schemas returns the subschemas of the keyword, which must be a non-empty
+// array.
+func (v *schemaValidator) schemas(keyword string, value interface{}) []interface{} {
+	subs, ok := value.([]interface{})
+	if !ok || len(subs) == 0 {
+		v.fail("%s must be a non-empty array", keyword)
+		return nil
+	}
+	return subs
+}
+
+// number returns the exact number of the keyword, if s has it.
+func (v *schemaValidator) number(s map[string]interface{}, keyword string) (*big.Rat, bool) {
+	value, ok := s[keyword]
+	if !ok {
+		return nil, false
+	}
+	r, ok := schemaNumber(value)
+	if !ok {
+		v.fail("%s must be a number", keyword)
+	}
+	return r, ok
+}
+
+// count returns the non-negative integer of the keyword, if s has it.
+func (v *schemaValidator) count(s map[string]interface{}, keyword string) (int, bool) {
+	r, ok := v.number(s, keyword)
+	if !ok {
+		return 0, false
+	}
+	if r.Sign() < 0 || !r.IsInt() {
+		v.fail("%s must be a non-negative integer", keyword)
+		return 0, false
+	}
+	if !r.Num().IsInt64() || r.Num().Int64() > math.MaxInt32 {
+		return math.MaxInt32, true
+	}
+	return int(r.Num().Int64()), true
+}
+
+// schemaNumber returns the exact value of a number of the schema.
+func schemaNumber(value interface{}) (*big.Rat, bool) {
+	n, ok := value.(json.Number)
+	if !ok {
+		return nil, false
+	}
+	return new(big.Rat).SetString(string(n))
+}
+
+// nodeNumber returns the exact value of the number n as it is written in
+// the document, so that 0.1 is a tenth and not the float64 closest to it.
+func nodeNumber(n *Node) *big.Rat {
+	if n.unchanged() {
+		if r, ok := new(big.Rat).SetString(n.literal); ok {
+			return r
+		}
+	}
+	return new(big.Rat).SetFloat64(n.Value.(float64))
+}
+
+// checkValue checks the keywords for numbers and strings.
+func (v *schemaValidator) checkValue(n *Node, path []pathElem, s map[string]interface{}) []SchemaViolation {
+	var violations []SchemaViolation
+	switch value := n.Value.(type) {
+	case float64:
+		// the limits are printed as written in the schema
+		number := nodeNumber(n)
+		if limit, ok := v.number(s, "minimum"); ok && number.Cmp(limit) < 0 {
+			violations = append(violations, violation(n, path, "minimum", "must be at least %v", s["minimum"]))
+		}
+		if limit, ok := v.number(s, "exclusiveMinimum"); ok && number.Cmp(limit) <= 0 {
+			violations = append(violations, violation(n, path, "exclusiveMinimum", "must be greater than %v", s["exclusiveMinimum"]))
+		}
+		if limit, ok := v.number(s, "maximum"); ok && number.Cmp(limit) > 0 {
+			violations = append(violations, violation(n, path, "maximum", "must be at most %v", s["maximum"]))
+		}
+		if limit, ok := v.number(s, "exclusiveMaximum"); ok && number.Cmp(limit) >= 0 {
+			violations = append(violations, violation(n, path, "exclusiveMaximum", "must be less than %v", s["exclusiveMaximum"]))
+		}
+		if factor, ok := v.number(s, "multipleOf"); ok {
+			if factor.Sign() <= 0 {
+				v.fail("multipleOf must be greater than 0")
+			} else if !new(big.Rat).Quo(number, factor).IsInt() {
+				violations = append(violations, violation(n, path, "multipleOf", "must be a multiple of %v", s["multipleOf"]))
+			}
+		}
+	case string:
+		length := utf8.RuneCountInString(value)
+		if limit, ok := v.count(s, "minLength"); ok && length < limit {
+			violations = append(violations, violation(n, path, "minLength", "must have at least %d characters", limit))
+		}
+		if limit, ok := v.count(s, "maxLength"); ok && length > limit {
+			violations = append(violations, violation(n, path, "maxLength", "must have at most %d characters", limit))
+		}
+		if pattern, ok := s["pattern"]; ok {
+			re := v.regexp("pattern", pattern)
+			if re != nil && !re.MatchString(value) {
+				violations = append(violations, violation(n, path, "pattern", "must match the pattern %s", re))
+			}
+		}
+	}
+	return violations
+}
+
+// regexp compiles the pattern of the keyword.
+func (v *schemaValidator) regexp(keyword string, pattern interface{}) *regexp.Regexp {
+	str, ok := pattern.(string)
+	if !ok {
+		v.fail("%s must be a string", keyword)
+		return nil
+	}
+	re, err := regexp.Compile(str)
+	if err != nil {
+		v.fail("%s %v", keyword, err)
+		return nil
+	}
+	return re
+}
+
+// checkArray checks the keywords for arrays.
+func (v *schemaValidator) checkArray(n *Node, path []pathElem, s map[string]interface{}) []SchemaViolation {
+	var violations []SchemaViolation
+	length := len(n.Children)
+	if limit, ok := v.count(s, "minItems"); ok && length < limit {
+		violations = append(violations, violation(n, path, "minItems", "must have at least %d elements", limit))
+	}
+	if limit, ok := v.count(s, "maxItems"); ok && length > limit {
+		violations = append(violations, violation(n, path, "maxItems", "must have at most %d elements", limit))
+	}
+	if unique, ok := s["uniqueItems"]; ok && unique == true {
+	unique:
+		for i, c := range n.Children {
+			for j := 0; j < i; j++ {
+				if equalNodes(n.Children[j], c) {
+					violations = append(violations, violation(n, path, "uniqueItems", "elements %d and %d are equal", j, i))
+					break unique
+				}
+			}
+		}
+	}
+
+	first := 0
+	if prefix, ok := s["prefixItems"]; ok {
+		subs := v.schemas("prefixItems", prefix)
+		for i := 0; i < len(subs) && i < length; i++ {
+			violations = append(violations, v.check(n.Children[i], append(path, pathElem{"", i}), subs[i])...)
+		}
+		first = len(subs)
+	}
+	if items, ok := s["items"]; ok {
+		for i := first; i < length; i++ {
+			violations = append(violations, v.check(n.Children[i], append(path, pathElem{"", i}), items)...)
+		}
+	}
+	if contains, ok := s["contains"]; ok {
+		matches := 0
+		for i, c := range n.Children {
+			if v.check(c, append(path, pathElem{"", i}), contains) == nil {
+				matches++
+			}
+		}
+		least := 1
+		if limit, ok := v.count(s, "minContains"); ok {
+			least = limit
+		}
+		if matches < least {
+			violations = append(violations, violation(n, path, "contains", "must contain at least %d matching elements, not %d", least, matches))
+		}
+		if limit, ok := v.count(s, "maxContains"); ok && matches > limit {
+			violations = append(violations, violation(n, path, "maxContains", "must contain at most %d matching elements, not %d", limit, matches))
+		}
+	}
+	return violations
+}
+
+// checkObject checks the keywords for objects.
+func (v *schemaValidator) checkObject(n *Node, path []pathElem, s map[string]interface{}) []SchemaViolation {
+	var violations []SchemaViolation
+	members, keys := objectMembers(n)
+	if limit, ok := v.count(s, "minProperties"); ok && len(keys) < limit {
+		violations = append(violations, violation(n, path, "minProperties", "must have at least %d members", limit))
+	}
+	if limit, ok := v.count(s, "maxProperties"); ok && len(keys) > limit {
+		violations = append(violations, violation(n, path, "maxProperties", "must have at most %d members", limit))
+	}
+	if required, ok := s["required"]; ok {
+		for _, key := range v.strings("required", required) {
+			if _, ok := members[key]; !ok {
+				violations = append(violations, violation(n, path, "required", "missing member %s", key))
+			}
+		}
+	}
+	if deps, ok := s["dependentRequired"].(map[string]interface{}); ok {
+		for _, key := range keys {
+			for _, dep := range v.strings("dependentRequired", deps[key]) {
+				if _, ok := members[dep]; !ok {
+					violations = append(violations, violation(n, path, "dependentRequired", "missing member %s, required by %s", dep, key))
+				}
+			}
+		}
+	}
+	if deps, ok := s["dependentSchemas"].(map[string]interface{}); ok {
+		for _, key := range keys {
+			if sub, ok := deps[key]; ok {
+				violations = append(violations, v.check(n, path, sub)...)
+			}
+		}
+	}
+	if names, ok := s["propertyNames"]; ok {
+		for _, key := range keys {
+			c := members[key]
+			// the name is checked as a string value at the key
+			name := &Node{Kind: ValueNode, Value: key, valueSpan: c.keySpan}
+			for _, nv := range v.check(name, append(path, pathElem{key, -1}), names) {
+				nv.Keyword = "propertyNames"
+				nv.Message = "invalid member name: " + nv.Message
+				violations = append(violations, nv)
+			}
+		}
+	}
+
+	properties, _ := s["properties"].(map[string]interface{})
+	var patterns []*regexp.Regexp
+	var patternSchemas []interface{}
+	if pp, ok := s["patternProperties"].(map[string]interface{}); ok {
+		var sorted []string
+		for pattern := range pp {
+			sorted = append(sorted, pattern)
+		}
+		sort.Strings(sorted)
+		for _, pattern := range sorted {
+			if re := v.regexp("patternProperties", pattern); re != nil {
+				patterns = append(patterns, re)
+				patternSchemas = append(patternSchemas, pp[pattern])
+			}
+		}
+	}
+	additional, hasAdditional := s["additionalProperties"]
+	for _, key := range keys {
+		c, memberPath := members[key], append(path, pathElem{key, -1})
+		matched := false
+		if sub, ok := properties[key]; ok {
+			matched = true
+			violations = append(violations, v.check(c, memberPath, sub)...)
+		}
+		for i, re := range patterns {
+			if re.MatchString(key) {
+				matched = true
+				violations = append(violations, v.check(c, memberPath, patternSchemas[i])...)
+			}
+		}
+		if matched || !hasAdditional {
+			continue
+		}
+		if additional == false {
+			violations = append(violations, violation(c, memberPath, "additionalProperties", "unknown member %s", key))
+		} else {
+			violations = append(violations, v.check(c, memberPath, additional)...)
+		}
+	}
+	return violations
+}
+
+// strings returns the strings of the array value of the keyword.
+func (v *schemaValidator) strings(keyword string, value interface{}) []string {
+	if value == nil {
+		return nil
+	}
+	array, ok := value.([]interface{})
+	var strs []string
+	for _, e := range array {
+		str, isString := e.(string)
+		ok = ok && isString
+		strs = append(strs, str)
+	}
+	if !ok {
+		v.fail("%s must be an array of strings", keyword)
+		return nil
+	}
+	return strs
+}
+
+// nodeType returns the JSON Schema type of the value n, with "integer" for
+// numbers without a fractional part.
+func nodeType(n *Node) string {
+	switch n.Kind {
+	case ObjectNode:
+		return "object"
+	case ArrayNode:
+		return "array"
+	}
+	if _, ok := n.Value.(float64); ok {
+		if nodeNumber(n).IsInt() {
+			return "integer"
+		}
+		return "number"
+	}
+	return jsonType(n.Value)
+}
+
+// objectMembers returns the members of the object n by key, and the keys
+// in the order of the object. Like with Interface, the last of duplicate
+// members is used.
+func objectMembers(n *Node) (map[string]*Node, []string) {
+	members := make(map[string]*Node, len(n.Children))
+	var keys []string
+	for _, c := range n.Children {
+		if _, ok := members[c.Key]; !ok {
+			keys = append(keys, c.Key)
+		}
+		members[c.Key] = c
+	}
+	return members, keys
+}
+
+// equalSchemaValue reports whether the value n equals a value of the
+// schema, comparing numbers exactly, so that 1.0 equals 1 but
+// 9007199254740993 does not equal 9007199254740992.
+func equalSchemaValue(n *Node, value interface{}) bool {
+	switch n.Kind {
+	case ObjectNode:
+		object, ok := value.(map[string]interface{})
+		members, keys := objectMembers(n)
+		if !ok || len(keys) != len(object) {
+			return false
+		}
+		for key, c := range members {
+			if e, ok := object[key]; !ok || !equalSchemaValue(c, e) {
+				return false
+			}
+		}
+		return true
+	case ArrayNode:
+		array, ok := value.([]interface{})
+		if !ok || len(array) != len(n.Children) {
+			return false
+		}
+		for i, c := range n.Children {
+			if !equalSchemaValue(c, array[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	if _, ok := n.Value.(float64); ok {
+		r, ok := schemaNumber(value)
+		return ok && nodeNumber(n).Cmp(r) == 0
+	}
+	return n.Value == value
+}
+
+// equalNodes reports whether the values a and b are equal, like
+// equalSchemaValue.
+func equalNodes(a, b *Node) bool {
+	if a.Kind != b.Kind || len(a.Children) != len(b.Children) {
+		return false
+	}
+	switch a.Kind {
+	case ObjectNode:
+		membersA, keysA := objectMembers(a)
+		membersB, keysB := objectMembers(b)
+		if len(keysA) != len(keysB) {
+			return false
+		}
+		for key, c := range membersA {
+			if d, ok := membersB[key]; !ok || !equalNodes(c, d) {
+				return false
+			}
+		}
+		return true
+	case ArrayNode:
+		for i, c := range a.Children {
+			if !equalNodes(c, b.Children[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	_, numberA := a.Value.(float64)
+	_, numberB := b.Value.(float64)
+	if numberA && numberB {
+		return nodeNumber(a).Cmp(nodeNumber(b)) == 0
+	}
+	return a.Value == b.Value
+}
+
+// jsonType returns the JSON Schema type of a value decoded into an
+// interface{}.
+func jsonType(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if r, ok := schemaNumber(value); ok && r.IsInt() {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	}
+	return "object"
+}
+
+// schemaValues returns values in JSON, separated by commas.
+func schemaValues(values []interface{}) string {
+	var strs []string
+	for _, value := range values {
+		b, err := json.Marshal(value)
+		if err != nil {
+			b = []byte(fmt.Sprint(value))
+		}
+		strs = append(strs, string(b))
+	}
+	return strings.Join(strs, ", ")
+}
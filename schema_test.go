@@ -0,0 +1,144 @@
+package hjson
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateSchema(t *testing.T) {
+	schema := `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "type": "object",
+  "required": ["name", "servers"],
+  "properties": {
+    "name": {"type": "string", "minLength": 1},
+    "servers": {"type": "array", "minItems": 1, "items": {"$ref": "#/$defs/server"}},
+    "level": {"enum": ["debug", "info"]},
+    "tags": {"type": "array", "uniqueItems": true, "contains": {"const": "web"}}
+  },
+  "additionalProperties": false,
+  "$defs": {
+    "server": {
+      "type": "object",
+      "required": ["host"],
+      "properties": {
+        "host": {"type": "string", "pattern": "^[a-z.]+$"},
+        "port": {"$ref": "#port"}
+      }
+    },
+    "port": {"$anchor": "port", "type": "integer", "minimum": 1, "maximum": 65535}
+  }
+}`
+	doc := `# servers
+servers: [
+  { host: "a.example.com", port: 80 }
+  {
+    host: "B.example.com"
+    port: 70000
+  }
+  { port: 1.5 }
+]
+level: trace
+tags: ["db", "db"]
+debug: true
+`
+	violations, err := ValidateSchema([]byte(doc), []byte(schema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual []string
+	for _, v := range violations {
+		actual = append(actual, fmtViolation(v))
+	}
+	expected := []string{
+		// a root object without braces starts at its first member
+		`2:1 "" required: missing member name`,
+		`5:11 "servers[1].host" pattern: must match the pattern ^[a-z.]+$`,
+		`6:11 "servers[1].port" maximum: must be at most 65535`,
+		`8:3 "servers[2]" required: missing member host`,
+		`8:11 "servers[2].port" type: expected integer, found number`,
+		`10:8 "level" enum: must be one of "debug", "info"`,
+		`11:7 "tags" uniqueItems: elements 0 and 1 are equal`,
+		`11:7 "tags" contains: must contain at least 1 matching elements, not 0`,
+		`12:8 "debug" additionalProperties: unknown member debug`,
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(actual, "\n"))
+	}
+
+	if violations, err = ValidateSchema([]byte("name: app\nservers: [{host: \"a\"}]"), []byte(schema)); err != nil || violations != nil {
+		t.Errorf("expected no violations, got %v, %v", violations, err)
+	}
+}
+
+// fmtViolation returns v as "LINE:COLUMN PATH KEYWORD: MESSAGE".
+func fmtViolation(v SchemaViolation) string {
+	return fmt.Sprintf("%d:%d %q %s: %s", v.Line, v.Column, v.Path, v.Keyword, v.Message)
+}
+
+func TestValidateSchemaKeywords(t *testing.T) {
+	for _, test := range []struct {
+		schema, doc string
+		expected    []string
+	}{
+		{`{"anyOf": [{"type": "string"}, {"type": "null"}]}`, `"a"`, nil},
+		{`{"anyOf": [{"type": "string"}, {"type": "null"}]}`, `1`, []string{`1:1 "" anyOf: must match at least one schema of anyOf`}},
+		{`{"oneOf": [{"minimum": 1}, {"maximum": 5}]}`, `3`, []string{`1:1 "" oneOf: must match exactly one schema of oneOf, not 2`}},
+		{`{"not": {"type": "array"}}`, `[]`, []string{`1:1 "" not: must not match the schema of not`}},
+		{`{"if": {"required": ["tls"]}, "then": {"required": ["cert"]}}`, `{tls: true}`, []string{`1:1 "" required: missing member cert`}},
+		{`{"dependentRequired": {"user": ["password"]}}`, `{user: "u"}`, []string{`1:1 "" dependentRequired: missing member password, required by user`}},
+		{`{"propertyNames": {"maxLength": 3}}`, "{\n  name: 1\n}", []string{`2:3 "name" propertyNames: invalid member name: must have at most 3 characters`}},
+		{`{"patternProperties": {"^x-": {"type": "string"}}, "additionalProperties": {"type": "integer"}}`, `{"x-a": 1, b: 2}`, []string{`1:9 "x-a" type: expected string, found integer`}},
+		{`{"prefixItems": [{"type": "string"}], "items": false}`, `["a", 1]`, []string{`1:7 "[1]" false: no value is allowed here`}},
+		{`{"multipleOf": 0.5, "exclusiveMaximum": 2}`, `2`, []string{`1:1 "" exclusiveMaximum: must be less than 2`}},
+		{`{"multipleOf": 0.5}`, `0.7`, []string{`1:1 "" multipleOf: must be a multiple of 0.5`}},
+		// numbers are compared as written, not as float64
+		{`{"multipleOf": 0.01}`, `[19.99, 4.01]`, nil},
+		{`{"items": {"multipleOf": 0.01}}`, `[19.99, 4.01, 4.015]`, []string{`1:15 "[2]" multipleOf: must be a multiple of 0.01`}},
+		{`{"const": 9007199254740993}`, `9007199254740992`, []string{`1:1 "" const: must be 9007199254740993`}},
+		{`{"const": 9007199254740993}`, `9007199254740993`, nil},
+		{`{"enum": [1.0, {"a": [2]}]}`, `[1, {a: [2.00]}]`, []string{`1:1 "" enum: must be one of 1.0, {"a":[2]}`}},
+		{`{"items": {"enum": [1.0, {"a": [2]}]}}`, `[1, {a: [2.00]}]`, nil},
+		{`{"uniqueItems": true}`, `[1, 1.0]`, []string{`1:1 "" uniqueItems: elements 0 and 1 are equal`}},
+		{`{"uniqueItems": true}`, `[9007199254740992, 9007199254740993]`, nil},
+		{`{"type": "integer"}`, `2.0`, nil},
+		{`{"$ref": "#/$defs/a", "$defs": {"a": {"items": {"$ref": "#/$defs/a"}, "maxItems": 1}}}`, `[[], [[1, 2]]]`, []string{
+			`1:1 "" maxItems: must have at most 1 elements`,
+			`1:7 "[1][0]" maxItems: must have at most 1 elements`,
+		}},
+		{`true`, `a: 1`, nil},
+	} {
+		violations, err := ValidateSchema([]byte(test.doc), []byte(test.schema))
+		if err != nil {
+			t.Errorf("%s %s: %v", test.schema, test.doc, err)
+			continue
+		}
+		var actual []string
+		for _, v := range violations {
+			actual = append(actual, fmtViolation(v))
+		}
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%s %s: expected\n%s\ngot\n%s", test.schema, test.doc, strings.Join(test.expected, "\n"), strings.Join(actual, "\n"))
+		}
+	}
+
+	for schema, expected := range map[string]string{
+		`{"unevaluatedProperties": false}`:         "Unsupported schema keyword unevaluatedProperties",
+		`{"$ref": "other.json#/a"}`:                "Unsupported $ref 'other.json#/a'",
+		`{"$ref": "#/$defs/missing"}`:              "Invalid schema: $ref '#/$defs/missing' refers to nothing",
+		`{"$ref": "#"}`:                            "Invalid schema: $ref '#' refers to itself",
+		`{"properties": {"a": {"minLength": -1}}}`: "Invalid schema: minLength must be a non-negative integer",
+		`{"properties": {"a": {"pattern": 1}}}`:    "Invalid schema: pattern must be a string",
+		`[1]`:                                      "Invalid schema: a schema must be an object or a bool, not array",
+		`{`:                                        "Invalid schema: End of input",
+	} {
+		if _, err := ValidateSchema([]byte("{a: \"b\"}"), []byte(schema)); err == nil || !strings.HasPrefix(err.Error(), expected) {
+			t.Errorf("%s: expected %q, got %v", schema, expected, err)
+		}
+	}
+	if _, err := ValidateSchema([]byte("[1,, 2]"), []byte("true")); err == nil {
+		t.Error("expected a syntax error")
+	}
+}
@@ -0,0 +1,54 @@
+package hjson
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+var (
+	valuerType     = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+	scannerType    = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	nullStringType = reflect.TypeOf(sql.NullString{})
+	nullTimeType   = reflect.TypeOf(sql.NullTime{})
+)
+
+// isSQLNull reports whether t is one of the Null types of database/sql
+// (sql.NullString, sql.NullInt64, ...). They are encoded as their value or
+// null and decoded through their Scan method.
+func isSQLNull(t reflect.Type) bool {
+	return t.PkgPath() == "database/sql" && t.Kind() == reflect.Struct &&
+		t.Implements(valuerType) && reflect.PtrTo(t).Implements(scannerType)
+}
+
+// sqlNullValue returns the value held by a database/sql Null type, or nil if
+// it is not valid.
+func sqlNullValue(v reflect.Value) (interface{}, error) {
+	return v.Interface().(driver.Valuer).Value()
+}
+
+// scanSQLNull stores a decoded value in the database/sql Null type dest.
+func (p *hjsonParser) scanSQLNull(dest reflect.Value, value interface{}, literal string) error {
+	src := value
+	switch v := value.(type) {
+	case string:
+		if dest.Type() == nullTimeType {
+			t, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				return p.typeError("string "+strconv.Quote(v), dest.Type())
+			}
+			src = t
+		}
+	case float64, bool:
+		if dest.Type() == nullStringType {
+			// like for plain strings, keep quoteless values as written
+			src = literal
+		}
+	}
+	if err := dest.Addr().Interface().(sql.Scanner).Scan(src); err != nil {
+		return p.typeError(describe(value), dest.Type())
+	}
+	return nil
+}
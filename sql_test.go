@@ -0,0 +1,57 @@
+package hjson
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testSQLStruct struct {
+	Name    sql.NullString
+	Count   sql.NullInt64
+	Flag    sql.NullBool
+	Created sql.NullTime
+	Missing sql.NullString `json:",omitempty"`
+	Ptr     *sql.NullFloat64
+}
+
+func TestSQLNullTypes(t *testing.T) {
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	input := testSQLStruct{
+		Name:    sql.NullString{String: "1.10", Valid: true},
+		Count:   sql.NullInt64{Int64: 42, Valid: true},
+		Created: sql.NullTime{Time: created, Valid: true},
+		Ptr:     &sql.NullFloat64{Float64: 0.5, Valid: true},
+	}
+	buf, err := Marshal(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\n  Name: \"1.10\"\n  Count: 42\n  Flag: null\n  Created: 2020-01-02T03:04:05Z\n  Ptr: 0.5\n}"
+	if string(buf) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf)
+	}
+
+	var output testSQLStruct
+	if err = Unmarshal(buf, &output); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(input, output) {
+		t.Errorf("expected\n%#v\ngot\n%#v", input, output)
+	}
+
+	output.Flag = sql.NullBool{Bool: true, Valid: true}
+	if err = Unmarshal([]byte(`{Name: 1.10, Flag: null}`), &output); err != nil {
+		t.Fatal(err)
+	}
+	if output.Name.String != "1.10" || output.Flag.Valid {
+		t.Errorf("unexpected result %#v", output)
+	}
+
+	for _, data := range []string{`Count: abc`, `Count: {}`, `Created: yesterday`} {
+		if err = Unmarshal([]byte(data), &output); err == nil {
+			t.Errorf("expected an error for %q", data)
+		}
+	}
+}
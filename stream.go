@@ -0,0 +1,452 @@
+package hjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// An Encoder writes Hjson values to an output stream.
+type Encoder struct {
+	w       io.Writer
+	options EncoderOptions
+}
+
+// NewEncoder returns a new encoder that writes to w using the given options.
+func NewEncoder(w io.Writer, options EncoderOptions) *Encoder {
+	return &Encoder{w: w, options: options}
+}
+
+// Encode writes the Hjson encoding of v to the stream, followed by an end of
+// line.
+//
+// See MarshalWithOptions for details about the conversion of Go values to
+// Hjson.
+func (enc *Encoder) Encode(v interface{}) error {
+	b, err := MarshalWithOptions(v, enc.options)
+	if err != nil {
+		return err
+	}
+	b = append(b, enc.options.eol()...)
+	_, err = enc.w.Write(b)
+	return err
+}
+
+// EncodeStream drains the channel ch and writes its elements as the items of
+// an Hjson array, followed by an end of line. Each element is written to the
+// stream as soon as it is received, so producers can stream large result
+// sets without holding them in memory. EncodeStream returns when ch is
+// closed or an error occurs.
+//
+// ch must be a channel that can be received from.
+func (enc *Encoder) EncodeStream(ch interface{}) error {
+	value := reflect.ValueOf(ch)
+	if value.Kind() != reflect.Chan || value.Type().ChanDir()&reflect.RecvDir == 0 {
+		return fmt.Errorf("EncodeStream expects a receive channel, got %T", ch)
+	}
+
+	e, err := newHjsonEncoder(enc.options)
+	if err != nil {
+		return err
+	}
+	flush := func() error {
+		_, err := enc.w.Write(e.Bytes())
+		e.Reset()
+		return err
+	}
+
+	e.WriteString("[")
+	empty := true
+	for {
+		item, ok := value.Recv()
+		if !ok {
+			break
+		}
+		if !empty && e.jsonOutput {
+			e.WriteString(",")
+		}
+		empty = false
+		e.indent = 1
+		e.writeIndent(e.indent)
+		if err := e.str(item, true, "", false); err != nil {
+			return err
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+
+	if !empty {
+		e.writeIndent(0)
+	}
+	e.WriteString("]")
+	e.WriteString(enc.options.eol())
+	return flush()
+}
+
+// A Decoder reads Hjson values from an input stream.
+//
+// Besides decoding whole values with Decode, it can read the input token by
+// token with Token and More, like the Decoder of encoding/json, to decode a
+// large array element by element:
+//
+//	dec := hjson.NewDecoder(r, hjson.DefaultDecoderOptions())
+//	if _, err := dec.Token(); err != nil { // the opening [
+//		return err
+//	}
+//	for dec.More() {
+//		var item Item
+//		if err := dec.Decode(&item); err != nil {
+//			return err
+//		}
+//		...
+//	}
+//	_, err := dec.Token() // the closing ]
+//
+// The input is read as it is needed, and only the parts that have not been
+// decoded yet are kept in memory. The input buffer, the space to unescape
+// strings and the fields of the struct types decoded so far are kept
+// between calls, so decoding many values from one stream costs little more
+// than decoding one.
+type Decoder struct {
+	r       io.Reader
+	options DecoderOptions
+	exts    []*Extension
+	buf     []byte
+	scanp   int   // the start of the unread data in buf
+	err     error // the error of the last read, io.EOF at the end of the input
+
+	// the position of buf[scanp] in the input
+	offset int // byte offset, starting at 0
+	line   int // line, starting at 1
+	column int // bytes before it on its line
+
+	stack []byte // '{' and '[' of the objects and arrays opened by Token, 'r' for a root object without braces
+	state int    // what comes next, see tokenTop
+	comma bool   // a comma may come next
+
+	p hjsonParser // reused for each parse, see parser
+}
+
+// What a Decoder reads next.
+const (
+	tokenTop   = iota // a value at the top level
+	tokenValue        // the value of an object member
+	tokenElem         // an array element or the end of the array
+	tokenKey          // an object key or the end of the object
+)
+
+// NewDecoder returns a new decoder that reads from r using the given
+// options. Values of the top level that start with '{' or '[' are read one
+// after the other; any other value, or an object without braces, extends to
+// the end of the input.
+func NewDecoder(r io.Reader, options DecoderOptions) *Decoder {
+	options.Positions = nil
+	dec := &Decoder{r: r, options: options, line: 1}
+	dec.exts, dec.err = lookupExtensions(options.Extensions)
+	dec.p = hjsonParser{DecoderOptions: options, extensions: dec.exts, fields: map[reflect.Type]*structFields{}}
+	return dec
+}
+
+// Decode reads the next value from the input and stores it in the value
+// pointed to by v: the next value of the top level, the next element of an
+// array or the value of an object member whose key Token has returned. It
+// returns io.EOF at the end of the input.
+//
+// See UnmarshalWithOptions for details about the conversion of Hjson into a
+// Go value.
+func (dec *Decoder) Decode(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("non-pointer %v", reflect.TypeOf(v))
+	}
+	c, err := dec.peek()
+	if err != nil {
+		return err
+	}
+	switch {
+	case c == 0:
+		return dec.endError()
+	case dec.state == tokenKey:
+		return fmt.Errorf("Decode expects a value, not an object key (read it with Token)")
+	case dec.state == tokenTop && c != '{' && c != '[':
+		// the rest of the input is a single value or an object without
+		// braces
+		for dec.err == nil {
+			dec.refill()
+		}
+		if dec.err != io.EOF {
+			return dec.err
+		}
+		data := dec.buf[dec.scanp:]
+		err = dec.fix(UnmarshalWithOptions(data, v, dec.options))
+		dec.consume(len(data))
+		return err
+	}
+
+	n, err := dec.parse(func(p *hjsonParser) error {
+		return (&getter{p: p}).value(nil, false)
+	})
+	if err != nil {
+		return err
+	}
+	// with the character after the value, errors are reported at the same
+	// position as by Unmarshal
+	end := dec.scanp + n
+	if end < len(dec.buf) {
+		end++
+	}
+	_, err = dec.parser(dec.buf[dec.scanp:end]).readValue(rv.Elem())
+	err = dec.fix(err)
+	dec.consume(n)
+	dec.afterValue()
+	return err
+}
+
+// Token returns the next token of the input: a json.Delim for the '{', '}',
+// '[' and ']' of objects and arrays, a string for object keys and the
+// value of strings, numbers, bools and null as Decode stores them in an
+// interface{}. Commas and colons are skipped. A root object without braces
+// starts and ends with the json.Delim '{' and '}' as well. Token returns
+// io.EOF at the end of the input.
+//
+// Token can be mixed with calls to Decode, for example to decode the
+// elements of an array one by one.
+func (dec *Decoder) Token() (json.Token, error) {
+	c, err := dec.peek()
+	if err != nil {
+		return nil, err
+	}
+	if c != 0 && c != '{' && c != '[' && dec.state == tokenTop && dec.isKey() {
+		dec.stack = append(dec.stack, 'r')
+		dec.state, dec.comma = tokenKey, false
+		return json.Delim('{'), nil
+	}
+
+	var top byte
+	if len(dec.stack) > 0 {
+		top = dec.stack[len(dec.stack)-1]
+	}
+	switch {
+	case c == 0 && top == 'r' && dec.state == tokenKey:
+		dec.stack = dec.stack[:len(dec.stack)-1]
+		dec.afterValue()
+		return json.Delim('}'), nil
+	case c == 0:
+		return nil, dec.endError()
+	case c == ']' && top == '[', c == '}' && top == '{' && dec.state == tokenKey:
+		dec.consume(1)
+		dec.stack = dec.stack[:len(dec.stack)-1]
+		dec.afterValue()
+		return json.Delim(c), nil
+	case dec.state == tokenKey:
+		var key string
+		n, err := dec.parse(func(p *hjsonParser) (err error) {
+			p.next()
+			if key, err = p.readKeyname(); err != nil {
+				return err
+			}
+			p.white()
+			if p.ch != ':' {
+				return p.errCode("expected-colon", string(p.ch))
+			}
+			p.next()
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		dec.consume(n)
+		dec.state, dec.comma = tokenValue, false
+		return key, nil
+	case c == '{' || c == '[':
+		dec.consume(1)
+		dec.stack = append(dec.stack, c)
+		dec.state, dec.comma = tokenElem, false
+		if c == '{' {
+			dec.state = tokenKey
+		}
+		return json.Delim(c), nil
+	}
+
+	var value interface{}
+	n, err := dec.parse(func(p *hjsonParser) (err error) {
+		value, err = p.readValue(reflect.Value{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	dec.consume(n)
+	dec.afterValue()
+	return value, nil
+}
+
+// More reports whether there is another element in the current array or
+// object, or another value at the top level.
+func (dec *Decoder) More() bool {
+	c, err := dec.peek()
+	return err == nil && c != 0 && c != ']' && c != '}'
+}
+
+// peek skips whitespace, comments and a comma after a value, and returns
+// the next character or 0 at the end of the input.
+func (dec *Decoder) peek() (byte, error) {
+	comma := false
+	n, err := dec.parse(func(p *hjsonParser) error {
+		p.white()
+		if comma = p.ch == ',' && dec.comma; comma {
+			p.next()
+			p.white()
+		}
+		if p.ch == '/' && p.at == len(p.data) {
+			// this may start a comment
+			return p.errCode("comment-eof")
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if comma {
+		dec.comma = false
+	}
+	dec.consume(n)
+	if dec.scanp == len(dec.buf) {
+		if dec.err != io.EOF {
+			return 0, dec.err
+		}
+		return 0, nil
+	}
+	return dec.buf[dec.scanp], nil
+}
+
+// isKey reports whether the input continues with a key and a colon, which
+// start an object without braces.
+func (dec *Decoder) isKey() bool {
+	_, err := dec.parse(func(p *hjsonParser) error {
+		p.next()
+		if _, err := p.readKeyname(); err != nil {
+			return err
+		}
+		p.white()
+		if p.ch != ':' {
+			return p.errAt("Expected ':'")
+		}
+		return nil
+	})
+	return err == nil
+}
+
+// parse calls f with a parser of the unread input, reading more input
+// until f succeeds without reaching its end, or all input is read. It
+// returns the number of bytes f has read, up to the current character of
+// the parser.
+func (dec *Decoder) parse(f func(p *hjsonParser) error) (int, error) {
+	for {
+		p := dec.parser(dec.buf[dec.scanp:])
+		err := f(p)
+		n := p.at - 1
+		if p.ch == 0 && p.at == len(p.data) {
+			n = len(p.data)
+		}
+		if dec.err == nil && (err != nil || n == len(p.data)) {
+			// the input may continue the value
+			dec.refill()
+			continue
+		}
+		if err != nil {
+			if dec.err != io.EOF {
+				return 0, dec.err
+			}
+			return 0, dec.fix(err)
+		}
+		return n, nil
+	}
+}
+
+// parser returns the parser of the Decoder, reset to parse data. It keeps
+// its scratch buffers and struct fields from earlier parses.
+func (dec *Decoder) parser(data []byte) *hjsonParser {
+	p := &dec.p
+	p.data = data
+	p.includes = nil
+	p.mergeNext = false
+	p.resetAt()
+	return p
+}
+
+// refill reads at least as much input as is left unread, or sets dec.err.
+func (dec *Decoder) refill() {
+	unread := len(dec.buf) - dec.scanp
+	copy(dec.buf, dec.buf[dec.scanp:])
+	dec.buf = dec.buf[:unread]
+	dec.scanp = 0
+	if min := unread + 512; cap(dec.buf)-unread < min {
+		buf := make([]byte, unread, 2*cap(dec.buf)+min)
+		copy(buf, dec.buf)
+		dec.buf = buf
+	}
+	n, err := dec.r.Read(dec.buf[unread:cap(dec.buf)])
+	dec.buf = dec.buf[:unread+n]
+	if err != nil {
+		dec.err = err
+	}
+}
+
+// consume marks n bytes of the unread input as read.
+func (dec *Decoder) consume(n int) {
+	data := dec.buf[dec.scanp : dec.scanp+n]
+	if i := bytes.LastIndexByte(data, '\n'); i >= 0 {
+		dec.line += bytes.Count(data, []byte{'\n'})
+		dec.column = n - i - 1
+	} else {
+		dec.column += n
+	}
+	dec.offset += n
+	dec.scanp += n
+}
+
+// afterValue sets the state after a value has been read.
+func (dec *Decoder) afterValue() {
+	dec.state, dec.comma = tokenTop, false
+	if len(dec.stack) > 0 {
+		dec.state, dec.comma = tokenKey, true
+		if dec.stack[len(dec.stack)-1] == '[' {
+			dec.state = tokenElem
+		}
+	}
+}
+
+// endError returns the error for the end of the input at this point: io.EOF
+// at the top level, otherwise a syntax error.
+func (dec *Decoder) endError() error {
+	p := dec.parser(dec.buf[dec.scanp:])
+	p.next()
+	switch {
+	case dec.state == tokenTop:
+		return io.EOF
+	case dec.state == tokenValue:
+		return dec.fix(p.errCode("value-eof"))
+	case dec.stack[len(dec.stack)-1] == '[':
+		return dec.fix(p.errCode("array-eof"))
+	}
+	return dec.fix(p.errCode("object-eof"))
+}
+
+// fix changes the position of a SyntaxError in the unread input to its
+// position in the whole input.
+func (dec *Decoder) fix(err error) error {
+	var se *SyntaxError
+	if errors.As(err, &se) {
+		if se.Line == 1 {
+			se.Column += dec.column
+		}
+		se.Line += dec.line - 1
+		se.Offset += dec.offset
+	}
+	return err
+}
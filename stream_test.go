@@ -0,0 +1,216 @@
+package hjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestEncoderEncode(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf, DefaultOptions())
+	if err := enc.Encode(map[string]int{"a": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode([]string{"b"}); err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\n  a: 1\n}\n[\n  b\n]\n"
+	if buf.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf.String())
+	}
+}
+
+func TestEncoderEncodeStream(t *testing.T) {
+	ch := make(chan map[string]interface{})
+	go func() {
+		for i := 0; i < 3; i++ {
+			ch <- map[string]interface{}{"id": i}
+		}
+		close(ch)
+	}()
+
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf, DefaultOptions()).EncodeStream(ch); err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := Marshal([]map[string]interface{}{{"id": 0}, {"id": 1}, {"id": 2}})
+	if buf.String() != string(expected)+"\n" {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf.String())
+	}
+
+	empty := make(chan int)
+	close(empty)
+	buf.Reset()
+	if err := NewEncoder(&buf, DefaultOptions()).EncodeStream(empty); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "[]\n" {
+		t.Errorf("expected [] but got %s", buf.String())
+	}
+
+	if err := NewEncoder(&buf, DefaultOptions()).EncodeStream(make(chan<- int)); err == nil {
+		t.Error("expected an error for a send-only channel")
+	}
+}
+
+func TestDecoderToken(t *testing.T) {
+	input := `# users
+users: [
+  {
+    name: Ann Lee
+    "age": 30
+  },
+  { name: "Bob", admin: true, x: null }
+]
+count: 2
+`
+	expected := []interface{}{
+		json.Delim('{'), "users", json.Delim('['),
+		json.Delim('{'), "name", "Ann Lee", "age", 30.0, json.Delim('}'),
+		json.Delim('{'), "name", "Bob", "admin", true, "x", nil, json.Delim('}'),
+		json.Delim(']'), "count", 2.0, json.Delim('}'),
+	}
+	// reading a byte at a time tests values split between reads
+	for _, r := range []io.Reader{strings.NewReader(input), iotest.OneByteReader(strings.NewReader(input))} {
+		dec := NewDecoder(r, DefaultDecoderOptions())
+		var tokens []interface{}
+		for {
+			tok, err := dec.Token()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			tokens = append(tokens, tok)
+		}
+		if !reflect.DeepEqual(tokens, expected) {
+			t.Errorf("expected\n%#v\ngot\n%#v", expected, tokens)
+		}
+	}
+}
+
+func TestDecoderDecode(t *testing.T) {
+	type item struct {
+		ID   int
+		Name string
+	}
+	input := `[
+  # the first item
+  {
+    id: 1
+    name: a
+  }
+  { id: 2, name: "b" }, {"id": 3, "name": "c"}
+  ]
+{ id: 4 } [5]`
+	for _, r := range []io.Reader{strings.NewReader(input), iotest.OneByteReader(strings.NewReader(input))} {
+		dec := NewDecoder(r, DefaultDecoderOptions())
+		if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+			t.Fatalf("expected [, got %v, %v", tok, err)
+		}
+		var items []item
+		for dec.More() {
+			var it item
+			if err := dec.Decode(&it); err != nil {
+				t.Fatal(err)
+			}
+			items = append(items, it)
+		}
+		if tok, err := dec.Token(); err != nil || tok != json.Delim(']') {
+			t.Fatalf("expected ], got %v, %v", tok, err)
+		}
+		if !reflect.DeepEqual(items, []item{{1, "a"}, {2, "b"}, {3, "c"}}) {
+			t.Errorf("unexpected %+v", items)
+		}
+
+		var it item
+		var list []int
+		if err := dec.Decode(&it); err != nil || it.ID != 4 {
+			t.Errorf("unexpected %+v, %v", it, err)
+		}
+		if err := dec.Decode(&list); err != nil || !reflect.DeepEqual(list, []int{5}) {
+			t.Errorf("unexpected %v, %v", list, err)
+		}
+		if dec.More() {
+			t.Error("expected no more values")
+		}
+		if err := dec.Decode(&list); err != io.EOF {
+			t.Errorf("expected io.EOF, got %v", err)
+		}
+	}
+
+	// the rest of the input is a single value or an object without braces
+	var v interface{}
+	dec := NewDecoder(strings.NewReader("a: 1\nb: [2]"), DefaultDecoderOptions())
+	if err := dec.Decode(&v); err != nil || !reflect.DeepEqual(v, map[string]interface{}{"a": 1.0, "b": []interface{}{2.0}}) {
+		t.Errorf("unexpected %#v, %v", v, err)
+	}
+}
+
+func TestDecoderReuse(t *testing.T) {
+	type record struct {
+		Name string
+		Text string
+	}
+	var input strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&input, "{name: \"n\\t%d\", text:\n  '''\n  line %d\n  '''\n}\n", i, i)
+	}
+	dec := NewDecoder(strings.NewReader(input.String()), DefaultDecoderOptions())
+	var records []record
+	for dec.More() {
+		var r record
+		if err := dec.Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, r)
+	}
+	if len(records) != 1000 {
+		t.Fatalf("expected 1000 records, got %d", len(records))
+	}
+	// the strings built in the shared scratch buffer are copies
+	for i, r := range records {
+		if r.Name != fmt.Sprintf("n\t%d", i) || r.Text != fmt.Sprintf("line %d", i) {
+			t.Fatalf("unexpected record %d: %+v", i, r)
+		}
+	}
+	if len(dec.p.fields) != 1 || dec.p.scratch == nil {
+		t.Errorf("expected the parser state to be kept, got %d struct types", len(dec.p.fields))
+	}
+}
+
+func TestDecoderErrors(t *testing.T) {
+	var n int
+	dec := NewDecoder(strings.NewReader("[\n  1\n  x\n]"), DefaultDecoderOptions())
+	dec.Token()
+	if err := dec.Decode(&n); err != nil || n != 1 {
+		t.Fatalf("unexpected %d, %v", n, err)
+	}
+	var se *SyntaxError
+	if err := dec.Decode(&n); !errors.As(err, &se) || se.Line != 3 || se.Column != 4 || se.Offset != 9 {
+		t.Errorf("expected an error at the same position as by Unmarshal, got %v", err)
+	}
+
+	for input, expected := range map[string]string{
+		"[1, 2":         "End of input while parsing an array",
+		"{\"a\": 1":     "End of input while parsing an object",
+		"[1,\n2,\n,3]":  "Found a punctuator character ',' when expecting a quoteless string (check your syntax) at line 3",
+		"{\n\"a\" 1\n}": "Expected ':' instead of '1' at line 2",
+	} {
+		dec := NewDecoder(strings.NewReader(input), DefaultDecoderOptions())
+		var err error
+		for err == nil {
+			_, err = dec.Token()
+		}
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("%q: expected an error with %q, got %v", input, expected, err)
+		}
+	}
+}
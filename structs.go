@@ -0,0 +1,307 @@
+package hjson
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// structField describes how a struct field is mapped to an object member.
+type structField struct {
+	name      string
+	index     []int
+	tagged    bool   // the name comes from the hjson or json tag
+	omitEmpty bool   // the tag has the omitempty option
+	quoted    bool   // the tag has the string option for a number or bool
+	format    string // the format option of the tag, for times and durations
+	comment   string // the comment tag
+}
+
+// structFields holds the fields of a struct type that take part in
+// encoding and decoding, in the order of the struct.
+type structFields struct {
+	list     []structField
+	byName   map[string]int // exact names
+	byFolded map[string]int // lower case names, the first field wins
+}
+
+var structFieldCache sync.Map // map[reflect.Type]*structFields
+
+// cachedStructFields returns the fields of the struct type t, computing them
+// only once per type.
+func cachedStructFields(t reflect.Type) *structFields {
+	if f, ok := structFieldCache.Load(t); ok {
+		return f.(*structFields)
+	}
+	f, _ := structFieldCache.LoadOrStore(t, getStructFields(t))
+	return f.(*structFields)
+}
+
+var legacyStructFieldCache sync.Map // map[reflect.Type]*structFields
+
+// cachedLegacyStructFields returns the fields of the struct type t as they
+// are encoded before FormatVersion3, see legacyStructFields.
+func cachedLegacyStructFields(t reflect.Type) *structFields {
+	if f, ok := legacyStructFieldCache.Load(t); ok {
+		return f.(*structFields)
+	}
+	f, _ := legacyStructFieldCache.LoadOrStore(t, legacyStructFields(t))
+	return f.(*structFields)
+}
+
+// legacyStructFields lists the fields of t as they are encoded with
+// FormatVersion1 and FormatVersion2: all fields but those tagged
+// `json:"-"`, including unexported ones, and embedded structs as a member
+// named after their type. Only the name and the omitempty option of the
+// json tag are used. The list is only meant for encoding.
+func legacyStructFields(t reflect.Type) *structFields {
+	fields := &structFields{}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		splits := strings.Split(tag, ",")
+		field := structField{name: sf.Name, index: []int{i}, comment: sf.Tag.Get("comment")}
+		if splits[0] != "" {
+			field.name = splits[0]
+		}
+		for _, opt := range splits[1:] {
+			field.omitEmpty = field.omitEmpty || opt == "omitempty"
+		}
+		fields.list = append(fields.list, field)
+	}
+	return fields
+}
+
+// getStructFields lists the fields of t like encoding/json does: unexported
+// fields and fields tagged `json:"-"` are left out, and the fields of
+// embedded structs without a name in their json tag are promoted. Among
+// fields with the same name the least nested one wins, then the one with a
+// json tag; if that leaves several fields, they are all left out.
+//
+// An hjson tag replaces the json tag, so Hjson names can differ from JSON
+// names. Its inline option also promotes the fields of a struct field that
+// is not embedded.
+func getStructFields(t reflect.Type) *structFields {
+	type embedded struct {
+		t     reflect.Type
+		index []int
+	}
+	var all []structField
+	visited := map[reflect.Type]bool{}
+	for current := []embedded{{t, nil}}; len(current) > 0; {
+		var next []embedded
+		for _, em := range current {
+			if visited[em.t] {
+				continue
+			}
+			visited[em.t] = true
+			for i := 0; i < em.t.NumField(); i++ {
+				sf := em.t.Field(i)
+				ft := sf.Type
+				if ft.Name() == "" && ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if sf.Anonymous {
+					if sf.PkgPath != "" && ft.Kind() != reflect.Struct {
+						// unexported embedded non-struct
+						continue
+					}
+				} else if sf.PkgPath != "" {
+					// unexported
+					continue
+				}
+				tag := sf.Tag.Get("hjson")
+				if tag == "" {
+					tag = sf.Tag.Get("json")
+				}
+				if tag == "-" {
+					continue
+				}
+				splits := strings.Split(tag, ",")
+				inline := false
+				for _, opt := range splits[1:] {
+					inline = inline || opt == "inline"
+				}
+				index := make([]int, len(em.index)+1)
+				copy(index, em.index)
+				index[len(em.index)] = i
+				if (sf.Anonymous && splits[0] == "" || inline) && ft.Kind() == reflect.Struct {
+					next = append(next, embedded{ft, index})
+					continue
+				}
+				field := structField{
+					name:    sf.Name,
+					index:   index,
+					tagged:  splits[0] != "",
+					comment: sf.Tag.Get("comment"),
+				}
+				if field.tagged {
+					field.name = splits[0]
+				}
+				for _, opt := range splits[1:] {
+					switch {
+					case opt == "omitempty":
+						field.omitEmpty = true
+					case opt == "string":
+						field.quoted = isQuotable(ft)
+					case strings.HasPrefix(opt, "format="):
+						field.format = opt[len("format="):]
+					}
+				}
+				all = append(all, field)
+			}
+		}
+		current = next
+	}
+
+	// keep the dominant field of each name
+	sort.SliceStable(all, func(i, j int) bool {
+		if all[i].name != all[j].name {
+			return all[i].name < all[j].name
+		}
+		if len(all[i].index) != len(all[j].index) {
+			return len(all[i].index) < len(all[j].index)
+		}
+		return all[i].tagged && !all[j].tagged
+	})
+	var list []structField
+	for i := 0; i < len(all); {
+		j := i + 1
+		for j < len(all) && all[j].name == all[i].name {
+			j++
+		}
+		dominant := true
+		if j > i+1 {
+			second := all[i+1]
+			dominant = len(second.index) > len(all[i].index) || all[i].tagged && !second.tagged
+		}
+		if dominant {
+			list = append(list, all[i])
+		}
+		i = j
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i].index, list[j].index
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+
+	fields := &structFields{
+		list:     list,
+		byName:   make(map[string]int),
+		byFolded: make(map[string]int),
+	}
+	for i, f := range list {
+		fields.byName[f.name] = i
+		folded := strings.ToLower(f.name)
+		if _, ok := fields.byFolded[folded]; !ok {
+			fields.byFolded[folded] = i
+		}
+	}
+	return fields
+}
+
+// isQuotable reports whether the string tag option applies to values of
+// type t, numbers and bools, which are then encoded as strings.
+func isQuotable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// lookup finds the field for an object member, preferring an exact match
+// over a case-insensitive one. It returns nil if there is no such field.
+func (fields *structFields) lookup(name string) *structField {
+	i, ok := fields.byName[name]
+	if !ok {
+		i, ok = fields.byFolded[strings.ToLower(name)]
+	}
+	if !ok {
+		return nil
+	}
+	return &fields.list[i]
+}
+
+// suggest returns the name of the field that name is most likely a typo
+// of, or "" if no name is close enough.
+func (fields *structFields) suggest(name string) string {
+	// allow a typo for every three bytes
+	limit := len(name) / 3
+	if limit < 1 {
+		limit = 1
+	}
+	best, bestDist := "", limit+1
+	for _, f := range fields.list {
+		if d := editDistance(strings.ToLower(name), strings.ToLower(f.name)); d < bestDist {
+			best, bestDist = f.name, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the number of single byte insertions, deletions,
+// substitutions and transpositions of adjacent bytes that turn a into b.
+func editDistance(a, b string) int {
+	// the distances between the prefixes of a and the prefixes of b, for
+	// the last three prefixes of a
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d := prev[j-1] + cost
+			if prev[j]+1 < d {
+				d = prev[j] + 1
+			}
+			if cur[j-1]+1 < d {
+				d = cur[j-1] + 1
+			}
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] && prev2[j-2]+1 < d {
+				d = prev2[j-2] + 1
+			}
+			cur[j] = d
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(b)]
+}
+
+// fieldByIndex returns the field of v with the given index. Nil pointers to
+// embedded structs are allocated if alloc is true; otherwise, or if they
+// cannot be set, the field does not exist and the result is invalid.
+func fieldByIndex(v reflect.Value, index []int, alloc bool) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !alloc || !v.CanSet() {
+					return reflect.Value{}
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
@@ -0,0 +1,156 @@
+package hjson
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// timeLayouts maps the names accepted by EncoderOptions.TimeFormat and the
+// format tag option to the layouts of the time package.
+var timeLayouts = map[string]string{
+	"ANSIC":       time.ANSIC,
+	"UnixDate":    time.UnixDate,
+	"RubyDate":    time.RubyDate,
+	"RFC822":      time.RFC822,
+	"RFC822Z":     time.RFC822Z,
+	"RFC850":      time.RFC850,
+	"RFC1123":     time.RFC1123,
+	"RFC1123Z":    time.RFC1123Z,
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"Kitchen":     time.Kitchen,
+	"DateTime":    "2006-01-02 15:04:05",
+	"DateOnly":    "2006-01-02",
+	"TimeOnly":    "15:04:05",
+}
+
+// timeLayout returns the layout for a time format, either the name of a
+// layout of the time package or a layout itself.
+func timeLayout(format string) string {
+	if layout, ok := timeLayouts[format]; ok {
+		return layout
+	}
+	return format
+}
+
+// writeTime writes t in the given format (see EncoderOptions.TimeFormat).
+func (e *hjsonEncoder) writeTime(t time.Time, format, separator string, isRootObject bool) {
+	switch format {
+	case "unix":
+		e.WriteString(separator)
+		e.WriteString(strconv.FormatInt(t.Unix(), 10))
+	case "unixms":
+		e.WriteString(separator)
+		e.WriteString(strconv.FormatInt(t.Unix()*1000+int64(t.Nanosecond()/1e6), 10))
+	default:
+		e.quote(t.Format(timeLayout(format)), separator, isRootObject)
+	}
+}
+
+// formattedValue writes a field with the format tag option: a time.Time or
+// a byte slice in that format, or a time.Duration as a string with
+// format=string. Other values are written as usual.
+func (e *hjsonEncoder) formattedValue(value reflect.Value, format, separator string) error {
+	for value.Kind() == reflect.Interface || value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			break
+		}
+		value = value.Elem()
+	}
+	switch {
+	case value.Type() == timeType:
+		e.writeTime(value.Interface().(time.Time), format, separator, false)
+		return nil
+	case value.Type() == durationType && format == "string":
+		e.quote(time.Duration(value.Int()).String(), separator, false)
+		return nil
+	case isByteSlice(value.Type()):
+		saved := e.BytesFormat
+		e.BytesFormat = format
+		err := e.str(value, false, separator, false)
+		e.BytesFormat = saved
+		return err
+	}
+	return e.str(value, false, separator, false)
+}
+
+// readFormatted decodes a value into dest, a field with the format tag
+// option, which takes the place of DecoderOptions.TimeFormat or
+// BytesFormat for that field. A time.Duration always accepts strings.
+func (p *hjsonParser) readFormatted(dest reflect.Value, format string) error {
+	t := dest.Type()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	var setting *string
+	switch {
+	case t == timeType:
+		setting = &p.TimeFormat
+	case isByteSlice(t):
+		setting = &p.BytesFormat
+	default:
+		_, err := p.readValue(dest)
+		return err
+	}
+	saved := *setting
+	*setting = format
+	_, err := p.readValue(dest)
+	*setting = saved
+	return err
+}
+
+// setTime stores a decoded string or number in the time.Time dest, parsed
+// in the given format (see DecoderOptions.TimeFormat).
+func (p *hjsonParser) setTime(dest reflect.Value, value interface{}, format string) error {
+	var t time.Time
+	switch v := value.(type) {
+	case string:
+		if format == "unix" || format == "unixms" {
+			return p.typeError(describe(value), dest.Type())
+		}
+		var err error
+		if t, err = time.Parse(timeLayout(format), v); err != nil {
+			return p.errAt(err.Error())
+		}
+	case float64, json.Number:
+		var f float64
+		if n, ok := v.(json.Number); ok {
+			f, _ = n.Float64()
+		} else {
+			f = v.(float64)
+		}
+		switch format {
+		case "unix":
+			sec, frac := math.Modf(f)
+			t = time.Unix(int64(sec), int64(math.Floor(frac*1e9+0.5)))
+		case "unixms":
+			ms := int64(f)
+			t = time.Unix(ms/1000, ms%1000*1e6)
+		default:
+			return p.typeError(describe(value), dest.Type())
+		}
+	default:
+		return p.typeError(describe(value), dest.Type())
+	}
+	dest.Set(reflect.ValueOf(t))
+	return nil
+}
+
+// setDuration stores a duration string like "1h30m" in the time.Duration
+// dest.
+func (p *hjsonParser) setDuration(dest reflect.Value, s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return p.typeError("string "+strconv.Quote(s), dest.Type())
+	}
+	dest.SetInt(int64(d))
+	return nil
+}
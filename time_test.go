@@ -0,0 +1,99 @@
+package hjson
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testTimeStruct struct {
+	Default time.Time
+	Day     time.Time      `json:"day,format=DateOnly"`
+	Stamp   time.Time      `json:"stamp,format=unixms"`
+	Ptr     *time.Time     `json:"ptr,format=unix"`
+	Timeout time.Duration  `json:"timeout,format=string"`
+	Retry   *time.Duration `json:"retry,format=string"`
+	Wait    time.Duration
+}
+
+func TestTimeFormatTags(t *testing.T) {
+	at := time.Date(2021, 3, 4, 5, 6, 7, 8e6, time.UTC)
+	day := time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC)
+	retry := 90 * time.Second
+	input := testTimeStruct{
+		Default: at,
+		Day:     day,
+		Stamp:   at,
+		Timeout: time.Hour + 30*time.Minute,
+		Retry:   &retry,
+		Wait:    time.Second,
+	}
+	buf, err := Marshal(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\n  Default: 2021-03-04T05:06:07.008Z\n  day: 2021-03-04\n  stamp: 1614834367008\n  ptr: null\n  timeout: 1h30m0s\n  retry: 1m30s\n  Wait: 1000000000\n}"
+	if string(buf) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf)
+	}
+
+	var output testTimeStruct
+	if err = Unmarshal(buf, &output); err != nil {
+		t.Fatal(err)
+	}
+	output.Stamp = output.Stamp.UTC()
+	if !reflect.DeepEqual(input, output) {
+		t.Errorf("expected\n%#v\ngot\n%#v", input, output)
+	}
+
+	if err = Unmarshal([]byte("ptr: 1614834367\nWait: 2m"), &output); err != nil {
+		t.Fatal(err)
+	}
+	if output.Ptr == nil || !output.Ptr.Equal(at.Truncate(time.Second)) || output.Wait != 2*time.Minute {
+		t.Errorf("unexpected %v %v", output.Ptr, output.Wait)
+	}
+
+	for _, data := range []string{"day: 04.03.2021", "stamp: soon", "Wait: 2 minutes", "day: [1]"} {
+		if err = Unmarshal([]byte(data), &output); err == nil {
+			t.Errorf("expected an error for %q", data)
+		}
+	}
+}
+
+func TestTimeFormatOptions(t *testing.T) {
+	input := map[string]interface{}{
+		"at":   time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC),
+		"wait": 1500 * time.Millisecond,
+	}
+	options, err := NewEncoderOptions(WithTimeFormat("DateTime"), WithDurationAsString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := MarshalWithOptions(input, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\n  at: 2021-03-04 05:06:07\n  wait: 1.5s\n}"
+	if string(buf) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf)
+	}
+
+	decOptions, err := NewDecoderOptions(WithDecodeTimeFormat("DateTime"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var output struct {
+		At   time.Time
+		Wait time.Duration
+	}
+	if err = UnmarshalWithOptions(buf, &output, decOptions); err != nil {
+		t.Fatal(err)
+	}
+	if !output.At.Equal(input["at"].(time.Time)) || output.Wait != input["wait"] {
+		t.Errorf("unexpected %#v", output)
+	}
+
+	if _, err = NewEncoderOptions(WithTimeFormat("")); err == nil {
+		t.Error("expected an error for an empty TimeFormat")
+	}
+}
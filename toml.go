@@ -0,0 +1,855 @@
+package hjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ToTOML converts the Hjson document data, which must be an object, to
+// TOML, keeping its comments.
+//
+// Objects become tables with a [header] and arrays of objects arrays of
+// tables with a [[header]]; the key/value pairs of a table are written
+// before its tables, so members may change their order. Other arrays, and
+// the objects inside them, are written inline. Strings that span several
+// lines are written as multi-line strings, and numbers keep their literal
+// if TOML has the same number for it. Comments are written with #, before
+// the key or header they precede or at the end of the line of the value
+// they follow; comments inside inline arrays are dropped. Like Unmarshal,
+// only the last of duplicate keys is written. TOML has no null, so null
+// values return an error.
+func ToTOML(data []byte) ([]byte, error) {
+	root, err := ParseNode(data)
+	if err != nil {
+		return nil, err
+	}
+	if root.Kind != ObjectNode {
+		return nil, errors.New("ToTOML: the root must be an object")
+	}
+	e := &tomlEncoder{}
+	line, others := splitComments(root.Comments.Before)
+	writeHashComments(&e.Buffer, trimLeadingBlank(append(line, others...)), "")
+	if err = e.table(root, ""); err != nil {
+		return nil, err
+	}
+	var tail []string
+	if !root.braceless {
+		// the comments at the end of a braceless root are in After
+		line, tail = splitComments(root.Comments.Line)
+		tail = append(line, tail...)
+	}
+	writeHashComments(&e.Buffer, trimTrailingBlank(tail), "")
+	return e.Bytes(), nil
+}
+
+// tomlEncoder writes TOML for ToTOML.
+type tomlEncoder struct {
+	bytes.Buffer
+}
+
+// isTOMLTable reports whether n is written as a table.
+func isTOMLTable(n *Node) bool {
+	return n.Kind == ObjectNode && len(n.Children) > 0
+}
+
+// isTOMLTableArray reports whether n is written as an array of tables.
+func isTOMLTableArray(n *Node) bool {
+	if n.Kind != ArrayNode || len(n.Children) == 0 {
+		return false
+	}
+	for _, c := range n.Children {
+		if c.Kind != ObjectNode {
+			return false
+		}
+	}
+	return true
+}
+
+// onlyTables reports whether the members of the object n are all written
+// as tables or arrays of tables, without comments after them.
+func onlyTables(n *Node) bool {
+	for _, c := range n.Children {
+		if !isTOMLTable(c) && !isTOMLTableArray(c) {
+			return false
+		}
+	}
+	return len(insideComments(n)) == 0
+}
+
+// table writes the members of the object n, the table at path whose header
+// is written.
+func (e *tomlEncoder) table(n *Node, path string) error {
+	before, line := childComments(n)
+	last := memberIndex(n)
+	var tables []int
+	first := true
+	for i, c := range n.Children {
+		if last[c.Key] != i {
+			continue
+		}
+		if isTOMLTable(c) || isTOMLTableArray(c) {
+			tables = append(tables, i)
+			continue
+		}
+		items := before[i]
+		if first {
+			items = trimLeadingBlank(items)
+			first = false
+		}
+		writeHashComments(&e.Buffer, items, "")
+		e.WriteString(tomlKey(c.Key) + " = ")
+		if err := e.inline(c, joinPath(path, tomlKey(c.Key))); err != nil {
+			return err
+		}
+		e.WriteString(hashLineComment(line[i]) + "\n")
+	}
+	writeHashComments(&e.Buffer, insideComments(n), "")
+
+	for _, i := range tables {
+		c := n.Children[i]
+		childPath := joinPath(path, tomlKey(c.Key))
+		if c.Kind == ObjectNode && onlyTables(c) && len(before[i]) == 0 && len(line[i]) == 0 {
+			// TOML does not need the header of a table with only tables
+			if err := e.table(c, childPath); err != nil {
+				return err
+			}
+			continue
+		}
+		if e.Len() > 0 {
+			e.WriteString("\n")
+		}
+		writeHashComments(&e.Buffer, trimLeadingBlank(before[i]), "")
+		if c.Kind == ObjectNode {
+			e.WriteString("[" + childPath + "]" + hashLineComment(line[i]) + "\n")
+			if err := e.table(c, childPath); err != nil {
+				return err
+			}
+			continue
+		}
+		elemBefore, elemLine := childComments(c)
+		for j, elem := range c.Children {
+			if j > 0 {
+				e.WriteString("\n")
+			}
+			writeHashComments(&e.Buffer, trimLeadingBlank(elemBefore[j]), "")
+			comments := elemLine[j]
+			if j == 0 {
+				comments = append(line[i], comments...)
+			}
+			e.WriteString("[[" + childPath + "]]" + hashLineComment(comments) + "\n")
+			if err := e.table(elem, childPath); err != nil {
+				return err
+			}
+		}
+		writeHashComments(&e.Buffer, insideComments(c), "")
+	}
+	return nil
+}
+
+// joinPath returns the dotted key of key in the table at path.
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// inline writes n, the value at path, on the current line.
+func (e *tomlEncoder) inline(n *Node, path string) error {
+	switch n.Kind {
+	case ObjectNode:
+		if len(n.Children) == 0 {
+			e.WriteString("{}")
+			return nil
+		}
+		e.WriteString("{ ")
+		last := memberIndex(n)
+		first := true
+		for i, c := range n.Children {
+			if last[c.Key] != i {
+				continue
+			}
+			if !first {
+				e.WriteString(", ")
+			}
+			first = false
+			e.WriteString(tomlKey(c.Key) + " = ")
+			if err := e.inline(c, joinPath(path, tomlKey(c.Key))); err != nil {
+				return err
+			}
+		}
+		e.WriteString(" }")
+	case ArrayNode:
+		e.WriteString("[")
+		for i, c := range n.Children {
+			if i > 0 {
+				e.WriteString(", ")
+			}
+			if err := e.inline(c, path+"["+strconv.Itoa(i)+"]"); err != nil {
+				return err
+			}
+		}
+		e.WriteString("]")
+	default:
+		switch v := n.Value.(type) {
+		case nil:
+			return errors.New("ToTOML: " + path + " is null, which TOML cannot represent")
+		case bool:
+			e.WriteString(strconv.FormatBool(v))
+		case float64:
+			e.WriteString(tomlNumber(n, v))
+		case string:
+			e.WriteString(tomlString(v))
+		default:
+			return fmt.Errorf("ToTOML: %s is a %T, which TOML cannot represent", path, v)
+		}
+	}
+	return nil
+}
+
+// tomlNumber returns the TOML of the number v held by n.
+func tomlNumber(n *Node, v float64) string {
+	if n.unchanged() {
+		if strings.ContainsAny(n.literal, ".eE") {
+			return n.literal
+		}
+		if _, err := strconv.ParseInt(n.literal, 10, 64); err == nil {
+			return n.literal
+		}
+		// an integer that is too large for TOML
+	}
+	if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// tomlString returns s as a basic string, or a multi-line basic string if
+// it spans several lines.
+func tomlString(s string) string {
+	if !strings.Contains(s, "\n") {
+		return tomlQuote(s)
+	}
+	text := `"""` + "\n"
+	for i, line := range strings.Split(s, "\n") {
+		if i > 0 {
+			text += "\n"
+		}
+		quoted := tomlQuote(line)
+		text += quoted[1 : len(quoted)-1]
+	}
+	return text + `"""`
+}
+
+// tomlQuote returns s as a basic string.
+func tomlQuote(s string) string {
+	var buf bytes.Buffer
+	writeJSONString(&buf, s)
+	// JSON allows DEL in strings, TOML does not
+	return strings.Replace(buf.String(), "\x7f", `\u007f`, -1)
+}
+
+// tomlKey returns key as a bare or quoted TOML key.
+func tomlKey(key string) string {
+	if key == "" || strings.Trim(key, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789_-") != "" {
+		return tomlQuote(key)
+	}
+	return key
+}
+
+// FromTOML converts the TOML document data to Hjson, formatted with
+// options, keeping its comments where Hjson has a place for them: comments
+// on lines of their own are written before the key or table that follows
+// them, and a comment at the end of the line of a value after it. Comments
+// inside arrays are dropped.
+//
+// Tables and arrays of tables become objects and arrays of objects, in the
+// order they appear. Dates and times, which Hjson has no type for, become
+// strings, and numbers keep their literal where it is valid Hjson. inf and
+// nan return an error, as Hjson cannot represent them.
+// options.OutputFormat must be OutputHjson.
+func FromTOML(data []byte, options EncoderOptions) ([]byte, error) {
+	if options.OutputFormat != OutputHjson {
+		return nil, errors.New("FromTOML: OutputFormat JSON is not supported")
+	}
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+	p := &tomlParser{
+		data:        strings.Replace(strings.TrimPrefix(string(data), "\ufeff"), "\r\n", "\n", -1),
+		line:        1,
+		defined:     map[*Node]bool{},
+		inline:      map[*Node]bool{},
+		tableArrays: map[*Node]bool{},
+	}
+	root, err := p.document()
+	if err != nil {
+		return nil, err
+	}
+	root.style = &options
+	out, err := root.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return append(out, options.eol()...), nil
+}
+
+// tomlParser reads the TOML for FromTOML.
+type tomlParser struct {
+	data     string
+	pos      int
+	line     int      // of pos, starting at 1
+	comments []string // the comments on lines before the next key or header
+
+	defined     map[*Node]bool // tables with a [header]
+	inline      map[*Node]bool // inline tables and arrays
+	tableArrays map[*Node]bool // arrays of tables
+}
+
+func (p *tomlParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("FromTOML: "+format+" at line %d", append(args, p.line)...)
+}
+
+// peekByte returns the byte at pos, or 0 at the end of the input.
+func (p *tomlParser) peekByte() byte {
+	if p.pos < len(p.data) {
+		return p.data[p.pos]
+	}
+	return 0
+}
+
+// skipSpace skips spaces and tabs.
+func (p *tomlParser) skipSpace() {
+	for p.pos < len(p.data) && (p.data[p.pos] == ' ' || p.data[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// comment reads the comment at pos, if any, without the line break.
+func (p *tomlParser) comment() string {
+	if p.peekByte() != '#' {
+		return ""
+	}
+	end := strings.IndexByte(p.data[p.pos:], '\n')
+	if end < 0 {
+		end = len(p.data) - p.pos
+	}
+	comment := strings.TrimRight(p.data[p.pos:p.pos+end], " \t")
+	p.pos += end
+	return comment
+}
+
+// skipLines skips blank lines and collects the comments on lines of their
+// own.
+func (p *tomlParser) skipLines() {
+	for {
+		p.skipSpace()
+		if comment := p.comment(); comment != "" {
+			p.comments = append(p.comments, comment)
+		}
+		if p.peekByte() != '\n' {
+			return
+		}
+		p.pos++
+		p.line++
+	}
+}
+
+// endLine reads the end of a line after a key/value pair or header and
+// returns the comment on it.
+func (p *tomlParser) endLine() (string, error) {
+	p.skipSpace()
+	comment := p.comment()
+	switch p.peekByte() {
+	case 0:
+	case '\n':
+		p.pos++
+		p.line++
+	default:
+		return "", p.errorf("expected the end of the line instead of %q", p.rest())
+	}
+	return comment, nil
+}
+
+// rest returns the rest of the current line, for errors.
+func (p *tomlParser) rest() string {
+	text := p.data[p.pos:]
+	if end := strings.IndexByte(text, '\n'); end >= 0 {
+		text = text[:end]
+	}
+	return text
+}
+
+// takeComments returns the comments collected before the current line.
+func (p *tomlParser) takeComments() string {
+	comments := strings.Join(p.comments, "\n")
+	p.comments = nil
+	return comments
+}
+
+// document parses the TOML document into the root table.
+func (p *tomlParser) document() (*Node, error) {
+	root := &Node{Kind: ObjectNode}
+	p.skipLines()
+	if p.pos < len(p.data) {
+		root.Comments.Before = p.takeComments()
+	}
+	table := root
+	for p.skipLines(); p.pos < len(p.data); p.skipLines() {
+		comments := p.takeComments()
+		if p.peekByte() != '[' {
+			if err := p.keyValue(table, comments); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		p.pos++
+		isArray := p.peekByte() == '['
+		if isArray {
+			p.pos++
+		}
+		keys, err := p.keys()
+		if err != nil {
+			return nil, err
+		}
+		end := "]"
+		if isArray {
+			end = "]]"
+		}
+		if !strings.HasPrefix(p.data[p.pos:], end) {
+			return nil, p.errorf("expected '%s' instead of %q", end, p.rest())
+		}
+		p.pos += len(end)
+		line, err := p.endLine()
+		if err != nil {
+			return nil, err
+		}
+		comments = joinLines(comments, line)
+
+		name := strings.Join(keys, ".")
+		parent, err := p.navigate(root, keys[:len(keys)-1])
+		if err != nil {
+			return nil, err
+		}
+		key := keys[len(keys)-1]
+		c := parent.Get(key)
+		if !isArray {
+			switch {
+			case c == nil:
+				c = &Node{Kind: ObjectNode, Key: key}
+				parent.Children = append(parent.Children, c)
+			case c.Kind != ObjectNode || p.inline[c]:
+				return nil, p.errorf("%s is not a table", name)
+			case p.defined[c]:
+				return nil, p.errorf("table %s is defined twice", name)
+			}
+			p.defined[c] = true
+			c.Comments.Before = joinLines(c.Comments.Before, comments)
+			table = c
+			continue
+		}
+		if c == nil {
+			c = &Node{Kind: ArrayNode, Key: key}
+			parent.Children = append(parent.Children, c)
+			p.tableArrays[c] = true
+		} else if !p.tableArrays[c] {
+			return nil, p.errorf("%s is not an array of tables", name)
+		}
+		table = &Node{Kind: ObjectNode}
+		table.Comments.Before = comments
+		c.Children = append(c.Children, table)
+	}
+	if len(p.comments) > 0 {
+		root.Comments.After = p.takeComments()
+	}
+	return root, nil
+}
+
+// navigate returns the table at the dotted keys below table, adding the
+// tables that are missing.
+func (p *tomlParser) navigate(table *Node, keys []string) (*Node, error) {
+	for i, key := range keys {
+		c := table.Get(key)
+		switch {
+		case c == nil:
+			c = &Node{Kind: ObjectNode, Key: key}
+			table.Children = append(table.Children, c)
+		case p.tableArrays[c]:
+			c = c.Children[len(c.Children)-1]
+		case c.Kind != ObjectNode || p.inline[c]:
+			return nil, p.errorf("%s is not a table", strings.Join(keys[:i+1], "."))
+		}
+		table = c
+	}
+	return table, nil
+}
+
+// keyValue parses a key/value pair of table, which has the comments
+// before it.
+func (p *tomlParser) keyValue(table *Node, comments string) error {
+	c, err := p.member(table)
+	if err != nil {
+		return err
+	}
+	line, err := p.endLine()
+	if err != nil {
+		return err
+	}
+	c.Comments.Before, c.Comments.Line = comments, line
+	return nil
+}
+
+// member parses a key/value pair and adds it to table.
+func (p *tomlParser) member(table *Node) (*Node, error) {
+	keys, err := p.keys()
+	if err != nil {
+		return nil, err
+	}
+	if p.peekByte() != '=' {
+		return nil, p.errorf("expected '=' instead of %q", p.rest())
+	}
+	p.pos++
+	p.skipSpace()
+	if table, err = p.navigate(table, keys[:len(keys)-1]); err != nil {
+		return nil, err
+	}
+	key := keys[len(keys)-1]
+	if table.Get(key) != nil {
+		return nil, p.errorf("key %s is defined twice", strings.Join(keys, "."))
+	}
+	c, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	c.Key = key
+	table.Children = append(table.Children, c)
+	return c, nil
+}
+
+// keys parses a dotted key.
+func (p *tomlParser) keys() ([]string, error) {
+	var keys []string
+	for {
+		p.skipSpace()
+		var key string
+		var err error
+		switch c := p.peekByte(); {
+		case c == '"' && !strings.HasPrefix(p.data[p.pos:], `"""`):
+			key, err = p.basicString()
+		case c == '\'' && !strings.HasPrefix(p.data[p.pos:], "'''"):
+			key, err = p.literalString()
+		default:
+			start := p.pos
+			for p.pos < len(p.data) && strings.IndexByte("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789_-", p.data[p.pos]) >= 0 {
+				p.pos++
+			}
+			if p.pos == start {
+				return nil, p.errorf("expected a key instead of %q", p.rest())
+			}
+			key = p.data[start:p.pos]
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+		p.skipSpace()
+		if p.peekByte() != '.' {
+			return keys, nil
+		}
+		p.pos++
+	}
+}
+
+// value parses the value at pos.
+func (p *tomlParser) value() (*Node, error) {
+	var s string
+	var err error
+	switch c := p.peekByte(); {
+	case strings.HasPrefix(p.data[p.pos:], `"""`):
+		s, err = p.multilineString(`"""`)
+	case strings.HasPrefix(p.data[p.pos:], "'''"):
+		s, err = p.multilineString("'''")
+	case c == '"':
+		s, err = p.basicString()
+	case c == '\'':
+		s, err = p.literalString()
+	case c == '[':
+		return p.array()
+	case c == '{':
+		return p.inlineTable()
+	default:
+		return p.scalar()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Node{Value: s}, nil
+}
+
+// skipArrayLines skips whitespace, line breaks and comments inside an
+// array.
+func (p *tomlParser) skipArrayLines() {
+	for {
+		p.skipSpace()
+		p.comment()
+		if p.peekByte() != '\n' {
+			return
+		}
+		p.pos++
+		p.line++
+	}
+}
+
+// array parses an inline array.
+func (p *tomlParser) array() (*Node, error) {
+	n := &Node{Kind: ArrayNode}
+	p.inline[n] = true
+	p.pos++
+	for {
+		p.skipArrayLines()
+		switch p.peekByte() {
+		case 0:
+			return nil, p.errorf("end of the input in an array")
+		case ']':
+			p.pos++
+			return n, nil
+		}
+		c, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		n.Children = append(n.Children, c)
+		p.skipArrayLines()
+		switch p.peekByte() {
+		case ',':
+			p.pos++
+		case ']':
+		default:
+			return nil, p.errorf("expected ',' or ']' instead of %q", p.rest())
+		}
+	}
+}
+
+// inlineTable parses an inline table, which has to be on one line.
+func (p *tomlParser) inlineTable() (*Node, error) {
+	n := &Node{Kind: ObjectNode}
+	p.inline[n] = true
+	p.pos++
+	p.skipSpace()
+	if p.peekByte() == '}' {
+		p.pos++
+		return n, nil
+	}
+	for {
+		if _, err := p.member(n); err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		switch p.peekByte() {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return n, nil
+		default:
+			return nil, p.errorf("expected ',' or '}' instead of %q", p.rest())
+		}
+	}
+}
+
+// basicString parses a basic string.
+func (p *tomlParser) basicString() (string, error) {
+	var buf bytes.Buffer
+	for p.pos++; p.pos < len(p.data); p.pos++ {
+		switch c := p.data[p.pos]; {
+		case c == '"':
+			p.pos++
+			return buf.String(), nil
+		case c == '\\':
+			s, err := p.escape()
+			if err != nil {
+				return "", err
+			}
+			buf.WriteString(s)
+		case c == '\n':
+			return "", p.errorf("unterminated string")
+		case c < 0x20 && c != '\t' || c == 0x7f:
+			return "", p.errorf("control character %q in a string", c)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+// escape parses the escape sequence at pos and leaves pos at its last
+// byte.
+func (p *tomlParser) escape() (string, error) {
+	if p.pos+1 == len(p.data) {
+		return "", p.errorf("unterminated string")
+	}
+	p.pos++
+	c := p.data[p.pos]
+	if s, ok := map[byte]string{'b': "\b", 't': "\t", 'n': "\n", 'f': "\f", 'r': "\r", 'e': "\x1b", '"': "\"", '\\': "\\"}[c]; ok {
+		return s, nil
+	}
+	size := map[byte]int{'u': 4, 'U': 8}[c]
+	if size == 0 || p.pos+size >= len(p.data) {
+		return "", p.errorf("invalid escape sequence '\\%c'", c)
+	}
+	r, err := strconv.ParseUint(p.data[p.pos+1:p.pos+1+size], 16, 32)
+	if err != nil || r > 0x10ffff || r >= 0xd800 && r < 0xe000 {
+		return "", p.errorf("invalid escape sequence '\\%s'", p.data[p.pos:p.pos+1+size])
+	}
+	p.pos += size
+	return string(rune(r)), nil
+}
+
+// literalString parses a literal string.
+func (p *tomlParser) literalString() (string, error) {
+	start := p.pos + 1
+	for p.pos = start; p.pos < len(p.data); p.pos++ {
+		switch c := p.data[p.pos]; {
+		case c == '\'':
+			p.pos++
+			return p.data[start : p.pos-1], nil
+		case c == '\n':
+			return "", p.errorf("unterminated string")
+		case c < 0x20 && c != '\t' || c == 0x7f:
+			return "", p.errorf("control character %q in a string", c)
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+// multilineString parses a multi-line basic or literal string starting
+// with quotes.
+func (p *tomlParser) multilineString(quotes string) (string, error) {
+	p.pos += 3
+	if p.peekByte() == '\n' {
+		// a line break right after the quotes is trimmed
+		p.pos++
+		p.line++
+	}
+	var buf bytes.Buffer
+	for ; p.pos < len(p.data); p.pos++ {
+		c := p.data[p.pos]
+		switch {
+		case strings.HasPrefix(p.data[p.pos:], quotes):
+			// up to two quotes before the closing ones are content
+			extra := 0
+			for extra < 2 && strings.HasPrefix(p.data[p.pos+extra+1:], quotes) {
+				extra++
+			}
+			buf.WriteString(quotes[:extra])
+			p.pos += 3 + extra
+			return buf.String(), nil
+		case c == '\\' && quotes == `"""`:
+			rest := strings.TrimLeft(p.data[p.pos+1:], " \t")
+			if strings.HasPrefix(rest, "\n") {
+				// a line ending backslash trims the whitespace after it
+				p.pos = len(p.data) - len(rest)
+				for p.pos < len(p.data) && strings.IndexByte(" \t\n", p.data[p.pos]) >= 0 {
+					if p.data[p.pos] == '\n' {
+						p.line++
+					}
+					p.pos++
+				}
+				p.pos--
+				continue
+			}
+			s, err := p.escape()
+			if err != nil {
+				return "", err
+			}
+			buf.WriteString(s)
+		case c == '\n':
+			p.line++
+			buf.WriteByte(c)
+		case c < 0x20 && c != '\t' || c == 0x7f:
+			return "", p.errorf("control character %q in a string", c)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+// scalar parses a boolean, number, date or time.
+func (p *tomlParser) scalar() (*Node, error) {
+	start := p.pos
+	for p.pos < len(p.data) && strings.IndexByte(" \t\n,]}#", p.data[p.pos]) < 0 {
+		p.pos++
+	}
+	text := p.data[start:p.pos]
+	if isTOMLDate(text) && strings.HasPrefix(p.data[p.pos:], " ") && isTOMLTime(p.data[p.pos+1:]) {
+		// a date and time separated by a space
+		for p.pos++; p.pos < len(p.data) && strings.IndexByte(" \t\n,]}#", p.data[p.pos]) < 0; p.pos++ {
+		}
+		text = p.data[start:p.pos]
+	}
+	switch {
+	case text == "true":
+		return &Node{Value: true}, nil
+	case text == "false":
+		return &Node{Value: false}, nil
+	case text == "":
+		return nil, p.errorf("expected a value instead of %q", p.rest())
+	case strings.TrimLeft(text, "+-") == "inf" || strings.TrimLeft(text, "+-") == "nan":
+		return nil, p.errorf("%s cannot be represented in Hjson", text)
+	case isTOMLDate(text) || isTOMLTime(text):
+		// Hjson has no dates and times
+		return &Node{Value: text}, nil
+	}
+
+	for i := 0; i < len(text); i++ {
+		// an underscore has to be between digits
+		if text[i] == '_' && (i == 0 || i+1 == len(text) || !isHexDigit(text[i-1]) || !isHexDigit(text[i+1])) {
+			return nil, p.errorf("invalid number %s", text)
+		}
+	}
+	digits := strings.Replace(text, "_", "", -1)
+	if len(digits) > 2 && digits[0] == '0' && strings.IndexByte("xob", digits[1]) >= 0 {
+		base := map[byte]int{'x': 16, 'o': 8, 'b': 2}[digits[1]]
+		v, err := strconv.ParseUint(digits[2:], base, 64)
+		if err != nil {
+			return nil, p.errorf("invalid number %s", text)
+		}
+		return &Node{Value: float64(v)}, nil
+	}
+	unsigned := strings.TrimLeft(digits, "+-")
+	v, err := strconv.ParseFloat(digits, 64)
+	if err != nil || strings.Trim(digits, "0123456789.eE+-") != "" ||
+		len(unsigned) > 1 && unsigned[0] == '0' && unsigned[1] >= '0' && unsigned[1] <= '9' {
+		// TOML has no leading zeros
+		return nil, p.errorf("invalid value %s", text)
+	}
+	n := &Node{Value: v}
+	if digits == text && json.Valid([]byte(text)) {
+		// keep the literal, like 1.50
+		n.literal, n.parsedValue = text, v
+	}
+	return n, nil
+}
+
+func isHexDigit(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+// isTOMLDate reports whether text starts with a date like 1979-05-27.
+func isTOMLDate(text string) bool {
+	return len(text) >= 10 && text[4] == '-' && text[7] == '-' &&
+		strings.Trim(text[:4]+text[5:7]+text[8:10], "0123456789") == ""
+}
+
+// isTOMLTime reports whether text starts with a time like 07:32:00.
+func isTOMLTime(text string) bool {
+	return len(text) >= 8 && text[2] == ':' && text[5] == ':' &&
+		strings.Trim(text[:2]+text[3:5]+text[6:8], "0123456789") == ""
+}
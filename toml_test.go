@@ -0,0 +1,274 @@
+package hjson
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestToTOML(t *testing.T) {
+	input := `# config
+{
+  title: "example" // the title
+  owner: {
+    name: Tom
+    dob: 1979-05-27T07:32:00-08:00
+  }
+  /* the
+     ports */
+  ports: [8000, 8001]
+  temp: { cpu: 79.5, "case": 72.0, limits: {} }
+  big: 123456789012345678901
+  motd:
+    '''
+    hello
+    "world"
+    '''
+  servers: [
+    # first
+    {
+      ip: 10.0.0.1
+      ip: 10.0.0.3
+    }
+    {
+      "odd key": []
+    }
+  ]
+}
+# end
+`
+	expected := `# config
+title = "example" # the title
+# the
+# ports
+ports = [8000, 8001]
+big = 1.2345678901234568e+20
+motd = """
+hello
+\"world\""""
+
+[owner]
+name = "Tom"
+dob = "1979-05-27T07:32:00-08:00"
+
+[temp]
+cpu = 79.5
+case = 72.0
+limits = {}
+
+# first
+[[servers]]
+ip = "10.0.0.3"
+
+[[servers]]
+"odd key" = []
+# end
+`
+	out, err := ToTOML([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out)
+	}
+
+	out, err = ToTOML([]byte("a: [{ b: { c: 1 } }, 2]\nd: { e: { f: [true] } }"))
+	expected = "a = [{ b = { c = 1 } }, 2]\n\n[d.e]\nf = [true]\n"
+	if err != nil || string(out) != expected {
+		t.Errorf("expected %q, got %q, %v", expected, out, err)
+	}
+
+	for input, expected := range map[string]string{
+		"[1, 2]":                        "the root must be an object",
+		"a: { b: null }":                "a.b is null",
+		"a: [1, null]":                  "a[1] is null",
+		"a: { \"x.y\": { b: [null] } }": `"x.y".b[0] is null`,
+		"{a: 1":                         "",
+	} {
+		if _, err = ToTOML([]byte(input)); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%q: expected an error with %q, got %v", input, expected, err)
+		}
+	}
+}
+
+func TestFromTOML(t *testing.T) {
+	input := `# header
+
+title = "TOML" # the title
+"quoted key" = 'C:\path'
+site."example.com" = true
+hex = 0xDEAD_BEEF
+num = 1_000
+float = 6.626e-34
+date = 1979-05-27 07:32:00Z
+time = 07:32:00
+multi = """
+Roses are red \
+   violets are blue
+"quoted" \u00e9"""
+
+[owner]
+name = "Tom"
+
+[database]
+ports = [ 8000, # first
+  8001,
+]
+temp = { cpu = 79.5, case = 72.0 }
+
+# alpha
+[servers.alpha]
+ip = "10.0.0.1"
+
+[[products]]
+name = "Hammer"
+
+[[products]] # second
+[products.color]
+red = 1
+# end
+`
+	expected := `# header
+{
+  title: "TOML" # the title
+  "quoted key": C:\path
+  site:
+  {
+    example.com: true
+  }
+  hex: 3735928559
+  num: 1000
+  float: 6.626e-34
+  date: 1979-05-27 07:32:00Z
+  time: 07:32:00
+  multi:
+    '''
+    Roses are red violets are blue
+    "quoted" é
+    '''
+  owner:
+  {
+    name: Tom
+  }
+  database:
+  {
+    ports:
+    [
+      8000
+      8001
+    ]
+    temp:
+    {
+      cpu: 79.5
+      case: 72.0
+    }
+  }
+  servers:
+  {
+    # alpha
+    alpha:
+    {
+      ip: 10.0.0.1
+    }
+  }
+  products:
+  [
+    {
+      name: Hammer
+    }
+    # second
+    {
+      color:
+      {
+        red: 1
+      }
+    }
+  ]
+  # end
+}
+`
+	out, err := FromTOML([]byte(input), DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out)
+	}
+
+	// like Marshal, the root always has braces
+	for input, expected := range map[string]string{
+		"":                      "{}\n",
+		"# only a comment":      "{\n  # only a comment\n}\n",
+		"a = 'x'":               "{\n  a: x\n}\n",
+		"a = '''\nb'''":         "{\n  a: b\n}\n",
+		"a = 0o17\nb = -0.5":    "{\n  a: 15\n  b: -0.5\n}\n",
+		"a.b = 1\n[a.c]\nd = 2": "{\n  a:\n  {\n    b: 1\n    c:\n    {\n      d: 2\n    }\n  }\n}\n",
+	} {
+		if out, err = FromTOML([]byte(input), DefaultOptions()); err != nil || string(out) != expected {
+			t.Errorf("%q: expected %q, got %q, %v", input, expected, out, err)
+		}
+	}
+
+	for input, expected := range map[string]string{
+		"a = 1\na = 2":    "key a is defined twice at line 2",
+		"[a]\n[a]":        "table a is defined twice at line 2",
+		"a = 1\n[a.b]":    "a is not a table at line 2",
+		"a = {}\n[a]":     "a is not a table at line 2",
+		"[a]\n[[a]]":      "a is not an array of tables at line 2",
+		"a = inf":         "inf cannot be represented in Hjson at line 1",
+		"a = nan":         "nan cannot be represented in Hjson",
+		"a = 01":          "invalid value 01",
+		"a = 1__0":        "invalid number 1__0",
+		"a = \"x":         "unterminated string",
+		"a = \"\\q\"":     "invalid escape sequence",
+		"a = \"\\ud800\"": "invalid escape sequence",
+		"a = 1 b = 2":     "expected the end of the line",
+		"a 1":             "expected '='",
+		"= 1":             "expected a key",
+		"a = [1 2]":       "expected ',' or ']'",
+		"a = [1,":         "end of the input in an array",
+		"a = { b = 1\n}":  "expected ',' or '}'",
+		"[a":              "expected ']'",
+		"a = yes":         "invalid value yes",
+		"a = ":            "expected a value",
+		"a = '''x":        "unterminated string",
+	} {
+		if _, err = FromTOML([]byte(input), DefaultOptions()); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%q: expected an error with %q, got %v", input, expected, err)
+		}
+	}
+
+	opt := DefaultOptions()
+	opt.OutputFormat = OutputJSON
+	if _, err = FromTOML([]byte("a = 1"), opt); err == nil {
+		t.Error("expected an error for OutputJSON")
+	}
+}
+
+func TestTOMLAssets(t *testing.T) {
+	files := strings.Split(string(getContent("assets/testlist.txt")), "\n")
+	for _, file := range files {
+		if strings.HasPrefix(file, "fail") || strings.HasPrefix(file, "stringify/quotes") || strings.HasPrefix(file, "extra/") {
+			continue
+		}
+		name := strings.TrimSuffix(file, "_test"+file[strings.LastIndex(file, "."):])
+		input := getTestContent(name)
+		toml, err := ToTOML(input)
+		if err != nil {
+			// not an object, or with null values
+			continue
+		}
+		out, err := FromTOML(toml, DefaultOptions())
+		if err != nil {
+			t.Errorf("%s: %v\n%s", name, err, toml)
+			continue
+		}
+		var expected, actual interface{}
+		if err = Unmarshal(input, &expected); err != nil {
+			t.Fatal(err)
+		}
+		if err = Unmarshal(out, &actual); err != nil || !reflect.DeepEqual(expected, actual) {
+			t.Errorf("%s: %v, converted through\n%s\nto\n%s", name, err, toml, out)
+		}
+	}
+}
@@ -0,0 +1,367 @@
+package hjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+)
+
+// transcodeFlushSize is the size of the output that is collected before it
+// is written.
+const transcodeFlushSize = 32 << 10
+
+// ToJSON converts the Hjson read from r to compact JSON and writes it to w,
+// using the default decoding options.
+//
+// The input is converted as it is parsed, without decoding it into Go
+// values: comments are dropped, strings and keys are quoted and commas are
+// inserted. The memory used is about the size of the input, which is read
+// completely first, and the output is written in chunks while parsing.
+// Numbers keep their literal, and the members of objects keep their order,
+// including duplicate keys. If the input is invalid a *SyntaxError is
+// returned, after part of the output may have been written.
+func ToJSON(r io.Reader, w io.Writer) error {
+	options := DefaultDecoderOptions()
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if options.MaxInputBytes > 0 && len(data) > options.MaxInputBytes {
+		return limitError{fmt.Errorf("Input of %d bytes exceeds the limit of %d bytes", len(data), options.MaxInputBytes)}
+	}
+	exts, err := lookupExtensions(options.Extensions)
+	if err != nil {
+		return err
+	}
+	t := &jsonTranscoder{
+		p: &hjsonParser{DecoderOptions: options, data: data, extensions: exts},
+		w: w,
+	}
+	t.p.resetAt()
+	if err = t.root(); err != nil {
+		return err
+	}
+	return t.flush(0)
+}
+
+// jsonTranscoder writes the JSON of the Hjson read by p.
+type jsonTranscoder struct {
+	p   *hjsonParser
+	buf bytes.Buffer
+	w   io.Writer // nil while only checking the input
+}
+
+// flush writes the output collected in buf once it reaches size bytes.
+func (t *jsonTranscoder) flush(size int) error {
+	if t.buf.Len() < size || t.buf.Len() == 0 {
+		return nil
+	}
+	if t.w != nil {
+		if _, err := t.w.Write(t.buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	t.buf.Reset()
+	return nil
+}
+
+// root transcodes the root value, which may be an object without braces.
+func (t *jsonTranscoder) root() error {
+	p := t.p
+	p.white()
+	if p.ch == '{' || p.ch == '[' {
+		return t.checkTrailing(t.value())
+	}
+
+	// Like Unmarshal, prefer an object without braces to a single value.
+	// Input that is a valid single value is short, as it cannot contain
+	// arrays or objects, so both are tried on it without writing.
+	w := t.w
+	t.w = nil
+	single := p.ch > 0 && t.checkTrailing(t.value()) == nil
+	object := false
+	if single {
+		p.resetAt()
+		object = t.checkTrailing(t.object(true)) == nil
+	}
+	t.w = w
+	t.buf.Reset()
+	p.resetAt()
+	if single && !object {
+		return t.checkTrailing(t.value())
+	}
+	return t.checkTrailing(t.object(true))
+}
+
+func (t *jsonTranscoder) checkTrailing(err error) error {
+	if err != nil {
+		return err
+	}
+	_, err = t.p.checkTrailing(nil, nil)
+	return err
+}
+
+func (t *jsonTranscoder) value() error {
+	p := t.p
+	if err := t.flush(transcodeFlushSize); err != nil {
+		return err
+	}
+	p.white()
+	switch p.ch {
+	case '{':
+		return t.object(false)
+	case '[':
+		return t.array()
+	case '"', '\'':
+		str, err := p.readString(true)
+		if err != nil {
+			return err
+		}
+		writeJSONString(&t.buf, str)
+	default:
+		value, literal, err := p.readTfnns()
+		if err != nil {
+			return err
+		}
+		if _, ok := value.(float64); ok {
+			value = json.Number(literal)
+		}
+		if err = writeCompactJSON(&t.buf, value); err != nil {
+			return p.errAt(err.Error())
+		}
+	}
+	return nil
+}
+
+func (t *jsonTranscoder) array() error {
+	p := t.p
+	err := p.enter()
+	defer p.leave()
+	if err != nil {
+		return err
+	}
+
+	// assuming ch == '['
+	p.next()
+	p.white()
+	t.buf.WriteByte('[')
+	for first := true; p.ch > 0; first = false {
+		if p.ch == ']' {
+			p.next()
+			t.buf.WriteByte(']')
+			return nil
+		}
+		if !first {
+			t.buf.WriteByte(',')
+		}
+		if err = t.value(); err != nil {
+			return err
+		}
+		p.white()
+		if p.ch == ',' {
+			p.next()
+			p.white()
+		}
+	}
+	return p.errCode("array-eof")
+}
+
+func (t *jsonTranscoder) object(withoutBraces bool) error {
+	p := t.p
+	err := p.enter()
+	defer p.leave()
+	if err != nil {
+		return err
+	}
+
+	if !withoutBraces {
+		// assuming ch == '{'
+		p.next()
+	}
+	p.white()
+	t.buf.WriteByte('{')
+	for first := true; p.ch > 0; first = false {
+		if p.ch == '}' && !withoutBraces {
+			p.next()
+			t.buf.WriteByte('}')
+			return nil
+		}
+		key, err := p.readKeyname()
+		if err != nil {
+			return err
+		}
+		p.white()
+		if p.ch != ':' {
+			return p.errCode("expected-colon", string(p.ch))
+		}
+		p.next()
+		if !first {
+			t.buf.WriteByte(',')
+		}
+		writeJSONString(&t.buf, key)
+		t.buf.WriteByte(':')
+		if err = t.value(); err != nil {
+			return err
+		}
+		p.white()
+		if p.ch == ',' {
+			p.next()
+			p.white()
+		}
+	}
+	if withoutBraces {
+		t.buf.WriteByte('}')
+		return nil
+	}
+	return p.errCode("object-eof")
+}
+
+// FromJSON converts the JSON read from r to Hjson and writes it to w,
+// followed by an end of line. The output is the same as that of
+// MarshalWithOptions for the value decoded with UseNumber and
+// UseOrderedMap: strings are quoteless or multiline where possible, commas
+// are left out, the members of objects keep their order and numbers keep
+// their literal.
+//
+// The input is read and converted token by token and the output is written
+// in chunks, so large documents do not have to fit into memory. If the
+// input is invalid an error of encoding/json is returned, after part of the
+// output may have been written. options.OutputFormat must be OutputHjson;
+// json.Indent converts JSON to indented JSON.
+func FromJSON(r io.Reader, w io.Writer, options EncoderOptions) error {
+	e, err := newHjsonEncoder(options)
+	if err != nil {
+		return err
+	}
+	if e.jsonOutput {
+		return errors.New("FromJSON: OutputFormat JSON is not supported")
+	}
+	t := &hjsonTranscoder{e: e, dec: json.NewDecoder(r), w: w}
+	t.dec.UseNumber()
+	tok, err := t.token()
+	if err != nil {
+		return err
+	}
+	if err = t.value(tok, true, "", true); err != nil {
+		return err
+	}
+	if _, err = t.dec.Token(); err != io.EOF {
+		if err == nil {
+			err = errors.New("FromJSON: invalid data after the top-level value")
+		}
+		return err
+	}
+	e.WriteString(e.Eol)
+	return t.flush(0)
+}
+
+// hjsonTranscoder writes the Hjson of the JSON tokens read by dec.
+type hjsonTranscoder struct {
+	e   *hjsonEncoder
+	dec *json.Decoder
+	w   io.Writer
+}
+
+// flush writes the output collected by e once it reaches size bytes.
+func (t *hjsonTranscoder) flush(size int) error {
+	if t.e.Len() < size || t.e.Len() == 0 {
+		return nil
+	}
+	_, err := t.w.Write(t.e.Bytes())
+	t.e.Reset()
+	return err
+}
+
+// token reads the next token, which must exist.
+func (t *hjsonTranscoder) token() (json.Token, error) {
+	tok, err := t.dec.Token()
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return tok, err
+}
+
+// value writes the value starting with tok, like hjsonEncoder.str.
+func (t *hjsonTranscoder) value(tok json.Token, noIndent bool, separator string, isRootObject bool) error {
+	e := t.e
+	if err := t.flush(transcodeFlushSize); err != nil {
+		return err
+	}
+	switch v := tok.(type) {
+	case json.Delim:
+		return t.container(v, noIndent, separator)
+	case string:
+		e.quote(v, separator, isRootObject)
+	case json.Number:
+		e.WriteString(separator)
+		e.WriteString(v.String())
+	case bool:
+		e.WriteString(separator)
+		e.WriteString(strconv.FormatBool(v))
+	default:
+		e.WriteString(separator)
+		e.WriteString("null")
+	}
+	return nil
+}
+
+// container writes the array or object starting with the delimiter open.
+func (t *hjsonTranscoder) container(open json.Delim, noIndent bool, separator string) error {
+	e := t.e
+	end := json.Delim(']')
+	if open == '{' {
+		end = '}'
+	}
+	tok, err := t.token()
+	if err != nil {
+		return err
+	}
+	if tok == end {
+		e.WriteString(separator)
+		e.WriteString(open.String() + end.String())
+		return nil
+	}
+
+	indent1 := e.indent
+	if err = e.nest(); err != nil {
+		return err
+	}
+	if e.braceOnNewLine(noIndent) {
+		e.writeIndent(indent1)
+	} else {
+		e.WriteString(separator)
+	}
+	e.WriteString(open.String())
+
+	for i := 0; tok != end; i++ {
+		e.writeItemIndent()
+		if open == '[' {
+			e.pushIndex(i)
+			err = t.value(tok, true, "", false)
+		} else {
+			key := tok.(string)
+			e.pushKey(key)
+			e.WriteString(e.quoteName(key))
+			e.WriteString(":")
+			if tok, err = t.token(); err == nil {
+				err = t.value(tok, false, e.keySeparator(), false)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		e.popPath()
+		if tok, err = t.token(); err != nil {
+			return err
+		}
+	}
+
+	e.writeIndent(indent1)
+	e.WriteString(end.String())
+	e.indent = indent1
+	return nil
+}
@@ -0,0 +1,155 @@
+package hjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestToJSON(t *testing.T) {
+	files := strings.Split(string(getContent("assets/testlist.txt")), "\n")
+	for _, file := range files {
+		if strings.HasPrefix(file, "stringify/quotes") || strings.HasPrefix(file, "extra/") {
+			continue
+		}
+		name := strings.TrimSuffix(file, "_test"+file[strings.LastIndex(file, "."):])
+		var out bytes.Buffer
+		err := ToJSON(bytes.NewReader(getTestContent(name)), &out)
+		if strings.HasPrefix(file, "fail") {
+			if err == nil {
+				t.Errorf("%s: expected an error", name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		var expected, actual interface{}
+		if err = Unmarshal(getTestContent(name), &expected); err != nil {
+			t.Fatal(err)
+		}
+		if err = json.Unmarshal(out.Bytes(), &actual); err != nil {
+			t.Errorf("%s: invalid JSON %v\n%s", name, err, out.Bytes())
+		} else if !reflect.DeepEqual(expected, actual) {
+			t.Errorf("%s: expected\n%v\ngot\n%v", name, expected, actual)
+		}
+	}
+}
+
+func TestToJSONOutput(t *testing.T) {
+	for input, expected := range map[string]string{
+		"# comment\nb: text, with \"quotes\"\na: [1, 2.50, 1e3 // two\n  true]\nc: '''\n  multi\n  line\n  '''": `{"b":"text, with \"quotes\"","a":[1,2.50,1e3,true],"c":"multi\nline"}`,
+		"{a: 1, a: 2}":     `{"a":1,"a":2}`,
+		"  42  ":           `42`,
+		"a: b":             `{"a":"b"}`,
+		"quoteless string": `"quoteless string"`,
+		"":                 `{}`,
+		"[{}, [], null]":   `[{},[],null]`,
+	} {
+		var out bytes.Buffer
+		if err := ToJSON(strings.NewReader(input), &out); err != nil {
+			t.Errorf("%q: %v", input, err)
+		} else if out.String() != expected {
+			t.Errorf("%q: expected %s, got %s", input, expected, out.String())
+		}
+	}
+
+	var out bytes.Buffer
+	err := ToJSON(strings.NewReader("{a: [1, 2"), &out)
+	var se *SyntaxError
+	if !errors.As(err, &se) || se.Line != 1 {
+		t.Errorf("expected a syntax error, got %v", err)
+	}
+
+	// long input is written in several chunks
+	input := "[" + strings.Repeat("abcdefghij\n", 10000) + "]"
+	w := &countingWriter{}
+	if err = ToJSON(strings.NewReader(input), w); err != nil {
+		t.Fatal(err)
+	}
+	if w.writes < 2 || w.n != 2+10000*13-1 {
+		t.Errorf("unexpected %d writes of %d bytes", w.writes, w.n)
+	}
+}
+
+type countingWriter struct {
+	writes, n int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	w.n += len(p)
+	return len(p), nil
+}
+
+func TestFromJSON(t *testing.T) {
+	files := strings.Split(string(getContent("assets/testlist.txt")), "\n")
+	decOpt := DefaultDecoderOptions()
+	decOpt.UseNumber = true
+	decOpt.UseOrderedMap = true
+	for _, file := range files {
+		if strings.HasPrefix(file, "fail") || strings.HasPrefix(file, "stringify/quotes") || strings.HasPrefix(file, "extra/") {
+			continue
+		}
+		name := strings.TrimSuffix(file, "_test"+file[strings.LastIndex(file, "."):])
+		input, _ := getResultContent(name)
+		var value interface{}
+		if err := UnmarshalWithOptions(input, &value, decOpt); err != nil {
+			t.Fatal(err)
+		}
+		expected, err := Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		if err = FromJSON(bytes.NewReader(input), &out, DefaultOptions()); err != nil {
+			t.Errorf("%s: %v", name, err)
+		} else if out.String() != string(expected)+"\n" {
+			t.Errorf("%s: expected\n%s\ngot\n%s", name, expected, out.String())
+		}
+	}
+}
+
+func TestFromJSONOptions(t *testing.T) {
+	input := `{"b": [], "a": {"x": "multi\nline", "y": [1.50, true, null, {}]}, "c": "true"}`
+	opt := DefaultOptions()
+	opt.BracesSameLine = true
+	opt.IndentBy = "\t"
+	var out bytes.Buffer
+	if err := FromJSON(strings.NewReader(input), &out, opt); err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\n\tb: []\n\ta: {\n\t\tx:\n\t\t\t'''\n\t\t\tmulti\n\t\t\tline\n\t\t\t'''\n\t\ty: [\n\t\t\t1.50\n\t\t\ttrue\n\t\t\tnull\n\t\t\t{}\n\t\t]\n\t}\n\tc: \"true\"\n}\n"
+	if out.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out.String())
+	}
+
+	for _, input := range []string{"", "[1, 2", `{"a" 1}`, "1 2", "[1]]"} {
+		if err := FromJSON(strings.NewReader(input), &out, DefaultOptions()); err == nil {
+			t.Errorf("%q: expected an error", input)
+		}
+	}
+	opt = DefaultOptions()
+	opt.OutputFormat = OutputJSON
+	if err := FromJSON(strings.NewReader("1"), &out, opt); err == nil {
+		t.Error("expected an error for OutputJSON")
+	}
+	opt = DefaultOptions()
+	opt.MaxDepth = 2
+	if err := FromJSON(strings.NewReader("[[[1]]]"), &out, opt); err == nil || !strings.Contains(err.Error(), "[0][0]") {
+		t.Errorf("expected an error at [0][0], got %v", err)
+	}
+
+	// long input is written in several chunks
+	w := &countingWriter{}
+	if err := FromJSON(strings.NewReader("["+strings.Repeat(`"abcdefghij",`, 10000)+"1]"), w, DefaultOptions()); err != nil {
+		t.Fatal(err)
+	}
+	if w.writes < 2 || w.n != 2+10001*3+10000*10+1+2 {
+		t.Errorf("unexpected %d writes of %d bytes", w.writes, w.n)
+	}
+}
@@ -0,0 +1,51 @@
+package hjson
+
+import "errors"
+
+// Transform returns a copy of the tree rooted at n in which fn has rewritten
+// the nodes, for passes like resolving placeholders, removing deprecated
+// keys or converting units; n is not changed. fn is called with each node of
+// the copy, the children before their parent so that it sees them
+// transformed, and returns
+//
+//   - the node it was called with to keep it, possibly after changing it
+//     with methods like Set and SetValue,
+//   - another node to replace the value of the node like SetValue does,
+//     keeping its key and the comments around it,
+//   - or nil to remove the member or element; the root cannot be removed.
+//
+// The nodes that are not changed are written as in the source. Transform
+// stops at the first error of fn and returns it.
+func Transform(n *Node, fn func(n *Node) (*Node, error)) (*Node, error) {
+	root, err := transform(n.Clone(), fn)
+	if err != nil {
+		return nil, err
+	}
+	if root == nil {
+		return nil, errors.New("Cannot remove the root in Transform")
+	}
+	return root, nil
+}
+
+func transform(n *Node, fn func(n *Node) (*Node, error)) (*Node, error) {
+	for i := 0; i < len(n.Children); {
+		c, err := transform(n.Children[i], fn)
+		if err != nil {
+			return nil, err
+		}
+		if c == nil {
+			n.remove(i)
+			continue
+		}
+		i++
+	}
+	r, err := fn(n)
+	if err != nil || r == nil || r == n {
+		return r, err
+	}
+	if r.parent != nil && r.parent.indexOf(r) >= 0 {
+		// still part of a tree
+		r = r.Clone()
+	}
+	return n, n.SetValue(r)
+}
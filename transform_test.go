@@ -0,0 +1,84 @@
+package hjson
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTransform(t *testing.T) {
+	data := `# config
+server: {
+  host: ${HOST}
+  timeout: "30s" // seconds
+  legacy: true, port: 80
+  limits: ["1kB", "2kB"]
+}
+`
+	node, err := ParseNode([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := Transform(node, func(n *Node) (*Node, error) {
+		switch s, _ := n.Value.(string); {
+		case n.Key == "legacy":
+			return nil, nil
+		case s == "${HOST}":
+			return NewNode("localhost")
+		case strings.HasSuffix(s, "kB"):
+			return NewNode(len(s) * 1000)
+		case n.Key == "timeout":
+			// changed in place
+			return n, n.SetValue(30)
+		}
+		return n, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := out.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `# config
+server: {
+  host: localhost
+  timeout: 30 // seconds
+  port: 80
+  limits: [3000, 3000]
+}
+`
+	if string(b) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, b)
+	}
+	if b, _ = node.Marshal(); string(b) != data {
+		t.Errorf("expected the input to be unchanged, got\n%s", b)
+	}
+
+	// a node of the tree is copied
+	out, err = Transform(node, func(n *Node) (*Node, error) {
+		if n.Key == "limits" {
+			return n.parent.Get("port"), nil
+		}
+		return n, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limits, _ := out.Lookup("server.limits"); limits.Value != 80.0 || out.Get("server").Get("port").Value != 80.0 {
+		t.Errorf("unexpected %v", out.Interface())
+	}
+
+	errFailed := errors.New("failed")
+	if _, err = Transform(node, func(n *Node) (*Node, error) { return nil, errFailed }); err != errFailed {
+		t.Errorf("expected the error of fn, got %v", err)
+	}
+	if _, err = Transform(node, func(n *Node) (*Node, error) {
+		if n.parent == nil {
+			return nil, nil
+		}
+		return n, nil
+	}); err == nil {
+		t.Error("expected an error for removing the root")
+	}
+}
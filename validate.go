@@ -0,0 +1,108 @@
+package hjson
+
+import (
+	"errors"
+	"reflect"
+)
+
+// Severities of a Diagnostic.
+const (
+	// The input is not valid Hjson
+	SeverityError = "error"
+	// The input is valid, but likely not what was meant, see Warning
+	SeverityWarning = "warning"
+)
+
+// A Diagnostic describes a problem found by Validate.
+type Diagnostic struct {
+	// SeverityError or SeverityWarning
+	Severity string `json:"severity"`
+	// Description of the problem
+	Message string `json:"message"`
+	// Line of the problem, starting at 1
+	Line int `json:"line"`
+	// Column in bytes of the problem, starting at 1
+	Column int `json:"column"`
+	// Byte offset of the problem in the input, starting at 0
+	Offset int `json:"offset"`
+}
+
+// Validate checks that data is valid Hjson and returns the problems found,
+// or nil if there are none. A syntax error is reported as the last
+// Diagnostic, with SeverityError, as parsing stops there. Duplicate keys
+// and numbers that lose precision as a float64 are reported with
+// SeverityWarning (see Warning); they do not make the input invalid.
+func Validate(data []byte) []Diagnostic {
+	return ValidateWithOptions(data, builtinDecoderOptions())
+}
+
+// ValidateWithOptions is Validate with the given options, which can change
+// the syntax, like AcceptJSON5, or find more problems, like unset
+// environment variables with ExpandEnv. With options.MaxErrors it reports
+// up to that many syntax errors, as the last Diagnostics. options.OnWarning
+// is not called.
+func ValidateWithOptions(data []byte, options DecoderOptions) []Diagnostic {
+	exts, err := lookupExtensions(options.Extensions)
+	if err != nil {
+		return []Diagnostic{diagnostic(SeverityError, err)}
+	}
+	var diags []Diagnostic
+	p := &hjsonParser{DecoderOptions: options, data: data, extensions: exts}
+	p.OnWarning = func(w Warning) {
+		diags = append(diags, diagnostic(SeverityWarning, p.errAt(w.Kind+" "+w.Text)))
+	}
+	p.resetAt()
+	p.white()
+
+	// like rootValue, but dropping the warnings of a failed attempt
+	if p.ch == '{' || p.ch == '[' {
+		_, err = p.checkTrailing(p.readValue(reflect.Value{}))
+	} else if _, err = p.checkTrailing(p.readObject(true, reflect.Value{})); err != nil {
+		// test if we are dealing with a single value instead
+		objectDiags := diags
+		diags = nil
+		p.resetAt()
+		if _, err2 := p.checkTrailing(p.readValue(reflect.Value{})); err2 == nil {
+			err = nil
+		} else {
+			diags = objectDiags
+		}
+	}
+	if errs, ok := moreErrors(data, options, err).(SyntaxErrors); ok {
+		for _, se := range errs {
+			diags = append(diags, diagnostic(SeverityError, se))
+		}
+	} else if err != nil {
+		diags = append(diags, diagnostic(SeverityError, err))
+	}
+	return diags
+}
+
+// Valid reports whether data is valid Hjson, see CheckSyntax.
+func Valid(data []byte) bool {
+	return CheckSyntax(data) == nil
+}
+
+// CheckSyntax returns the first syntax error of the Hjson document data, or
+// nil if it is valid. Unlike Unmarshal and Validate it only checks the
+// syntax, without building the values of the document, which makes it a
+// cheap test for untrusted input before it is stored.
+func CheckSyntax(data []byte) error {
+	g := getter{p: &hjsonParser{DecoderOptions: builtinDecoderOptions(), data: data}}
+	return g.root(nil, false)
+}
+
+// diagnostic returns a Diagnostic for an error of the parser.
+func diagnostic(severity string, err error) Diagnostic {
+	var se *SyntaxError
+	if !errors.As(err, &se) {
+		return Diagnostic{Severity: severity, Message: err.Error()}
+	}
+	return Diagnostic{
+		Severity: severity,
+		Message:  se.Msg,
+		Line:     se.Line,
+		Column:   se.Column,
+		Offset:   se.Offset,
+	}
+}
@@ -0,0 +1,110 @@
+package hjson
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	for data, expected := range map[string][]Diagnostic{
+		"a: 1\nb: [x\n]":        nil,
+		"just a string":         nil,
+		"a: 9007199254740993 }": nil,
+		"a: 1\nb: 2\na: 3\nc: 9007199254740993\n": {
+			{SeverityWarning, "duplicate key a", 3, 3, 12},
+			{SeverityWarning, "precision loss 9007199254740993", 4, 20, 34},
+		},
+		"{\n  a: 1\n  a: [1, 2\n": {
+			{SeverityWarning, "duplicate key a", 3, 5, 13},
+			{SeverityError, "End of input while parsing an array (did you forget a closing ']'?)", 3, 11, 19},
+		},
+		"{a: 1}}": {
+			{SeverityError, "Syntax error, found trailing characters", 1, 7, 6},
+		},
+	} {
+		if diags := Validate([]byte(data)); !reflect.DeepEqual(diags, expected) {
+			t.Errorf("%q: expected\n%+v\ngot\n%+v", data, expected, diags)
+		}
+	}
+}
+
+func TestValid(t *testing.T) {
+	files := strings.Split(string(getContent("assets/testlist.txt")), "\n")
+	for _, file := range files {
+		if file == "" || strings.HasPrefix(file, "stringify/quotes") || strings.HasPrefix(file, "extra/") {
+			continue
+		}
+		name := strings.TrimSuffix(file, "_test"+file[strings.LastIndex(file, "."):])
+		input := getTestContent(name)
+		var v interface{}
+		err := Unmarshal(input, &v)
+		if err2 := CheckSyntax(input); (err == nil) != (err2 == nil) || err != nil && err.Error() != err2.Error() {
+			t.Errorf("%s: expected %v, got %v", name, err, err2)
+		}
+		if Valid(input) != (err == nil) {
+			t.Errorf("%s: expected Valid to be %v", name, err == nil)
+		}
+	}
+
+	data := []byte("# config\nserver: {\n  host: \"localhost\"\n  ports: [80, 443]\n}\nname: app\n")
+	if allocs := testing.AllocsPerRun(10, func() { Valid(data) }); allocs > 0 {
+		t.Errorf("expected no allocations, got %v", allocs)
+	}
+}
+
+func TestMaxErrors(t *testing.T) {
+	input := `server: {
+  host: localhost
+  port 8080
+  tls: {
+    cert: "a.pem
+  }
+}
+list: [1, 2,, 3]
+level: debug
+: x
+}
+`
+	decOpt, err := NewDecoderOptions(WithMaxErrors(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []int
+	for _, d := range ValidateWithOptions([]byte(input), decOpt) {
+		if d.Severity != SeverityError {
+			t.Errorf("unexpected %+v", d)
+		}
+		lines = append(lines, d.Line)
+	}
+	// the error of the string is found at the end of its line
+	if !reflect.DeepEqual(lines, []int{3, 5, 8, 10, 11}) {
+		t.Errorf("expected errors at lines 3, 5, 8, 10 and 11, got %v", lines)
+	}
+
+	var v interface{}
+	err = UnmarshalWithOptions([]byte(input), &v, decOpt)
+	errs, ok := err.(SyntaxErrors)
+	if !ok || len(errs) != 5 {
+		t.Fatalf("expected 5 syntax errors, got %v", err)
+	}
+	var se *SyntaxError
+	if !errors.As(err, &se) || se != errs[0] || !strings.HasPrefix(err.Error(), se.Error()+"\n") {
+		t.Errorf("unexpected %v", err)
+	}
+
+	decOpt.MaxErrors = 2
+	if err = UnmarshalWithOptions([]byte(input), &v, decOpt); len(err.(SyntaxErrors)) != 2 {
+		t.Errorf("expected 2 syntax errors, got %v", err)
+	}
+	// a single error is not wrapped
+	if err = UnmarshalWithOptions([]byte("a: [1,, 2]\nb: 2"), &v, decOpt); !errors.As(err, &se) || se.Line != 1 {
+		t.Errorf("expected a single syntax error, got %#v", err)
+	} else if _, ok := err.(*SyntaxError); !ok {
+		t.Errorf("expected a *SyntaxError, got %#v", err)
+	}
+	if _, err = NewDecoderOptions(WithMaxErrors(0)); err == nil {
+		t.Error("expected an error for MaxErrors 0")
+	}
+}
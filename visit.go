@@ -0,0 +1,106 @@
+package hjson
+
+// A Visitor holds the functions that Visit calls for the parts of a
+// document, in the order they appear. Functions that are nil are skipped.
+type Visitor struct {
+	// Called at the start and end of each object, including a root object
+	// without braces
+	OnObjectStart func() error
+	OnObjectEnd   func() error
+	// Called at the start and end of each array
+	OnArrayStart func() error
+	OnArrayEnd   func() error
+	// Called for the key of each object member, before its value
+	OnKey func(key string) error
+	// Called for each string, number, bool and null, with the value as
+	// Unmarshal decodes it into an interface{}: a string, float64, bool or
+	// nil
+	OnValue func(value interface{}) error
+	// Called for each comment with its source text, like "# port"
+	OnComment func(text string) error
+}
+
+// Visit parses the Hjson document data in a single pass and calls the
+// functions of v for its parts, without building the values of its objects
+// and arrays. This allows to filter, count or re-emit huge documents with
+// little memory:
+//
+//	var depth, count int
+//	err := hjson.Visit(data, hjson.Visitor{
+//		OnObjectStart: func() error { depth++; return nil },
+//		OnObjectEnd:   func() error { depth--; return nil },
+//		OnKey: func(key string) error {
+//			if depth == 2 && key == "id" {
+//				count++
+//			}
+//			return nil
+//		},
+//	})
+//
+// Visit stops at the first syntax error or error returned by a function of
+// v, and returns it. The functions may be called for the parts before a
+// syntax error.
+func Visit(data []byte, v Visitor) error {
+	call := func(f func() error) error {
+		if f == nil {
+			return nil
+		}
+		return f()
+	}
+
+	s := NewScanner(data)
+	braceless, values := false, false
+	for s.Scan() {
+		tok := s.Token()
+		var err error
+		switch tok.Kind {
+		case TokenBeginObject:
+			err = call(v.OnObjectStart)
+		case TokenEndObject:
+			err = call(v.OnObjectEnd)
+		case TokenBeginArray:
+			err = call(v.OnArrayStart)
+		case TokenEndArray:
+			err = call(v.OnArrayEnd)
+		case TokenKey:
+			if !values {
+				// the first member of a root object without braces
+				braceless = true
+				if err = call(v.OnObjectStart); err != nil {
+					return err
+				}
+			}
+			if v.OnKey != nil {
+				err = v.OnKey(tok.Value.(string))
+			}
+		case TokenString, TokenNumber, TokenBool, TokenNull:
+			if v.OnValue != nil {
+				err = v.OnValue(tok.Value)
+			}
+		case TokenComment:
+			if v.OnComment != nil {
+				err = v.OnComment(tok.Text)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if tok.Kind != TokenComment {
+			values = true
+		}
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+	if !values {
+		// an empty document is an empty object
+		if err := call(v.OnObjectStart); err != nil {
+			return err
+		}
+		braceless = true
+	}
+	if braceless {
+		return call(v.OnObjectEnd)
+	}
+	return nil
+}
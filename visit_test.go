@@ -0,0 +1,89 @@
+package hjson
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// recordVisitor returns a Visitor that appends the parts of a document to
+// events.
+func recordVisitor(events *[]string) Visitor {
+	add := func(event string) func() error {
+		return func() error {
+			*events = append(*events, event)
+			return nil
+		}
+	}
+	return Visitor{
+		OnObjectStart: add("{"),
+		OnObjectEnd:   add("}"),
+		OnArrayStart:  add("["),
+		OnArrayEnd:    add("]"),
+		OnKey: func(key string) error {
+			*events = append(*events, "key "+key)
+			return nil
+		},
+		OnValue: func(value interface{}) error {
+			*events = append(*events, fmt.Sprintf("value %#v", value))
+			return nil
+		},
+		OnComment: func(text string) error {
+			*events = append(*events, "comment "+text)
+			return nil
+		},
+	}
+}
+
+func TestVisit(t *testing.T) {
+	for input, expected := range map[string][]string{
+		"# users\nusers: [\n  { name: \"a\", age: 30 }\n  { name: \"b\", admin: true, x: null }\n]\n": {
+			"comment # users", "{", "key users", "[",
+			"{", "key name", `value "a"`, "key age", "value 30", "}",
+			"{", "key name", `value "b"`, "key admin", "value true", "key x", "value <nil>", "}",
+			"]", "}",
+		},
+		"[1, {}, []]":    {"[", "value 1", "{", "}", "[", "]", "]"},
+		"'''\ntext\n'''": {`value "text"`},
+		"// nothing":     {"comment // nothing", "{", "}"},
+	} {
+		var events []string
+		if err := Visit([]byte(input), recordVisitor(&events)); err != nil {
+			t.Errorf("%q: %v", input, err)
+		} else if !reflect.DeepEqual(events, expected) {
+			t.Errorf("%q: expected\n%q\ngot\n%q", input, expected, events)
+		}
+	}
+
+	// the functions that are not set are skipped
+	count := 0
+	err := Visit([]byte("a: [1, 2, 3]\nb: { c: 4 } # five"), Visitor{
+		OnValue: func(value interface{}) error {
+			count++
+			return nil
+		},
+	})
+	if err != nil || count != 4 {
+		t.Errorf("expected 4 values, got %d, %v", count, err)
+	}
+
+	stop := errors.New("stop")
+	var events []string
+	v := recordVisitor(&events)
+	v.OnKey = func(key string) error {
+		if key == "b" {
+			return stop
+		}
+		return nil
+	}
+	if err = Visit([]byte("a: 1\nb: 2\nc: 3"), v); err != stop || !reflect.DeepEqual(events, []string{"{", "value 1"}) {
+		t.Errorf("unexpected %q, %v", events, err)
+	}
+
+	events = nil
+	if err = Visit([]byte("[1, 2"), recordVisitor(&events)); err == nil || !strings.Contains(err.Error(), "End of input while parsing an array") {
+		t.Errorf("expected a syntax error, got %v", err)
+	}
+}
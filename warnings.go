@@ -0,0 +1,70 @@
+package hjson
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Kinds of Warning.
+const (
+	// A number cannot be represented exactly as a float64
+	WarnPrecisionLoss = "precision loss"
+	// A key appears more than once in an object, see
+	// DecoderOptions.DuplicateKeys
+	WarnDuplicateKey = "duplicate key"
+	// A key has no matching field in the struct it is decoded into
+	WarnUnknownField = "unknown field"
+)
+
+// A Warning reports information that was lost while decoding, without
+// failing the decoding. See DecoderOptions.OnWarning.
+//
+// Numbers that do not fit into an integer destination are errors, not
+// warnings, so decoding never truncates integers.
+type Warning struct {
+	// One of WarnPrecisionLoss, WarnDuplicateKey or WarnUnknownField
+	Kind string
+	// The number literal or the key concerned
+	Text string
+	// Description of the warning including its position in the input
+	Message string
+}
+
+func (w Warning) String() string {
+	return w.Message
+}
+
+func (p *hjsonParser) warn(kind, text string) {
+	p.OnWarning(Warning{kind, text, p.errAt(kind + " " + text).Error()})
+}
+
+// checkPrecision warns if literal, which was parsed as n, has more
+// significant digits than n can hold.
+func (p *hjsonParser) checkPrecision(n float64, literal string) {
+	want, wantExp := decimalDigits(literal)
+	got, gotExp := decimalDigits(strconv.FormatFloat(n, 'e', -1, 64))
+	if want != got || (want != "" && wantExp != gotExp) {
+		p.warn(WarnPrecisionLoss, literal)
+	}
+}
+
+// decimalDigits returns the significant digits of a decimal number and the
+// exponent of its first digit, so that numbers with the same value give the
+// same result.
+func decimalDigits(number string) (string, int) {
+	number = strings.TrimLeft(number, "-+")
+	exp := 0
+	if i := strings.IndexAny(number, "eE"); i >= 0 {
+		exp, _ = strconv.Atoi(number[i+1:])
+		number = number[:i]
+	}
+	point := strings.IndexByte(number, '.')
+	if point < 0 {
+		point = len(number)
+	} else {
+		number = number[:point] + number[point+1:]
+	}
+	trimmed := strings.TrimLeft(number, "0")
+	exp += point - (len(number) - len(trimmed))
+	return strings.TrimRight(trimmed, "0"), exp
+}
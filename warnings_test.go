@@ -0,0 +1,37 @@
+package hjson
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOnWarning(t *testing.T) {
+	var warnings []Warning
+	opt := DefaultDecoderOptions()
+	opt.OnWarning = func(w Warning) { warnings = append(warnings, w) }
+
+	var v struct {
+		A float64
+		B []interface{}
+	}
+	data := "a: 0.1\na: 9007199254740993\nb: [1e3, 0.10, 100, -1.5e-7, 12345678901234567890123]\nc: 1"
+	if err := UnmarshalWithOptions([]byte(data), &v, opt); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, w := range warnings {
+		got = append(got, w.Kind+" "+w.Text)
+	}
+	expected := []string{
+		"duplicate key a",
+		"precision loss 9007199254740993",
+		"precision loss 12345678901234567890123",
+		"unknown field c",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+	if warnings[0].Message != "duplicate key a at line 2,3 >>> a: 9007199254740993\n" {
+		t.Errorf("unexpected message %q", warnings[0].Message)
+	}
+}
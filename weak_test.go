@@ -0,0 +1,73 @@
+package hjson
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWeaklyTyped(t *testing.T) {
+	decOpt, err := NewDecoderOptions(WithWeaklyTyped())
+	if err != nil {
+		t.Fatal(err)
+	}
+	type config struct {
+		Port    int
+		Ratio   float32
+		Count   *uint8
+		Debug   bool
+		Verbose bool
+		Enabled bool
+		Retries int
+		Empty   int
+		Name    string
+		Flag    string
+	}
+	input := `{
+  Port: "8080"
+  Ratio: " 2.5 "
+  Count: "7"
+  Debug: 1
+  Verbose: "0"
+  Enabled: "t"
+  Retries: true
+  Empty: ""
+  Name: 42
+  Flag: false
+}`
+	var c config
+	if err = UnmarshalWithOptions([]byte(input), &c, decOpt); err != nil {
+		t.Fatal(err)
+	}
+	if c.Port != 8080 || c.Ratio != 2.5 || c.Count == nil || *c.Count != 7 || !c.Debug || c.Verbose ||
+		!c.Enabled || c.Retries != 1 || c.Empty != 0 || c.Name != "42" || c.Flag != "false" {
+		t.Errorf("unexpected %+v", c)
+	}
+
+	decOpt.DecodeHook = func(from reflect.Kind, to reflect.Type, value interface{}) (interface{}, error) {
+		if s, ok := value.(string); ok && s == "x" {
+			return 1.5, nil
+		}
+		return value, nil
+	}
+	if err = UnmarshalWithOptions([]byte(`{ Name: "x" }`), &c, decOpt); err != nil || c.Name != "1.5" {
+		t.Errorf("expected 1.5, got %q, %v", c.Name, err)
+	}
+
+	for input, expected := range map[string]string{
+		`{ Port: "80x" }`:    `Cannot unmarshal string into Go value of type int`,
+		`{ Port: "1.5" }`:    `Cannot unmarshal number 1.5 into Go value of type int`,
+		`{ Count: "300" }`:   `Cannot unmarshal number 300 into Go value of type uint8`,
+		`{ Debug: "maybe" }`: `Cannot unmarshal string into Go value of type bool`,
+		`{ Port: [1] }`:      `Cannot unmarshal array into Go value of type int`,
+	} {
+		if err = UnmarshalWithOptions([]byte(input), &c, decOpt); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%s: expected an error with %q, got %v", input, expected, err)
+		}
+	}
+
+	// without WeaklyTyped
+	if err = Unmarshal([]byte(`{ Port: "8080" }`), &c); err == nil {
+		t.Error("expected an error for a string in an int field")
+	}
+}
@@ -0,0 +1,311 @@
+package hjson
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+type writerScope struct {
+	isObject bool
+	indent   int  // indent of the opening and closing brace
+	opened   bool // the opening brace has been written
+	hasKey   bool // a key was written and awaits its value
+}
+
+// Writer emits Hjson directly to an io.Writer, one token at a time.
+//
+// It is meant for generators that never hold a complete value in memory.
+// The output is formatted exactly like the output of MarshalWithOptions
+// for an equivalent value.
+//
+// A Writer does not close the underlying io.Writer. After the first error
+// all further calls return that same error.
+type Writer struct {
+	out     io.Writer
+	e       *hjsonEncoder
+	stack   []writerScope
+	started bool // something was written at the root level
+	done    bool // a complete root value was written
+	err     error
+}
+
+// NewWriter returns a Writer that writes Hjson to w using the given options.
+//
+// Invalid options make every method return the validation error.
+func NewWriter(w io.Writer, options EncoderOptions) *Writer {
+	e, err := newHjsonEncoder(options)
+	if err == nil && e.jsonOutput {
+		err = errors.New("Writer: OutputFormat JSON is not supported")
+	}
+	if err != nil {
+		e = &hjsonEncoder{}
+	}
+	return &Writer{out: w, e: e, err: err}
+}
+
+func (w *Writer) flush() error {
+	if w.err == nil && w.e.Len() > 0 {
+		w.started = true
+		_, w.err = w.out.Write(w.e.Bytes())
+	}
+	w.e.Reset()
+	return w.err
+}
+
+func (w *Writer) fail(message string) error {
+	if w.err == nil {
+		w.err = errors.New(message)
+	}
+	return w.err
+}
+
+// open writes the opening brace of the innermost container if this has not
+// happened yet. Braces are written lazily so that empty containers can be
+// written as {} and [] on the line of their key.
+func (w *Writer) open() {
+	if len(w.stack) == 0 {
+		return
+	}
+	s := &w.stack[len(w.stack)-1]
+	if s.opened {
+		return
+	}
+	s.opened = true
+	w.writeStart(s.indent, len(w.stack) == 1)
+	if s.isObject {
+		w.e.WriteString("{")
+	} else {
+		w.e.WriteString("[")
+	}
+}
+
+// writeStart writes what precedes a container's opening brace.
+func (w *Writer) writeStart(indent int, isRoot bool) {
+	parent := w.parent()
+	if isRoot || parent != nil && !parent.isObject {
+		return
+	}
+	if w.e.BracesSameLine {
+		w.e.WriteString(" ")
+	} else {
+		w.e.writeIndent(indent)
+	}
+}
+
+// parent returns the scope that contains the innermost scope, or nil.
+func (w *Writer) parent() *writerScope {
+	if len(w.stack) < 2 {
+		return nil
+	}
+	return &w.stack[len(w.stack)-2]
+}
+
+// beginValue checks that a value may be written at the current position
+// and writes the line break that precedes array elements. It returns the
+// arguments str() expects for a value at this position.
+func (w *Writer) beginValue() (noIndent bool, separator string, isRoot bool, err error) {
+	if w.err != nil {
+		return false, "", false, w.err
+	}
+	if len(w.stack) == 0 {
+		if w.done {
+			return false, "", false, w.fail("Writer: only one root value may be written")
+		}
+		if w.started {
+			w.e.WriteString(w.e.Eol)
+		}
+		return true, "", true, nil
+	}
+	s := &w.stack[len(w.stack)-1]
+	if s.isObject {
+		if !s.hasKey {
+			return false, "", false, w.fail("Writer: expected a key before the value")
+		}
+		s.hasKey = false
+		return false, " ", false, nil
+	}
+	w.open()
+	w.e.writeIndent(w.e.indent)
+	return true, "", false, nil
+}
+
+func (w *Writer) endValue(isRoot bool) error {
+	if isRoot {
+		w.done = true
+	}
+	return w.flush()
+}
+
+func (w *Writer) begin(isObject bool) error {
+	if _, _, _, err := w.beginValue(); err != nil {
+		return err
+	}
+	indent := w.e.indent
+	if err := w.e.nest(); err != nil {
+		return w.fail(err.Error())
+	}
+	w.stack = append(w.stack, writerScope{isObject: isObject, indent: indent})
+	return w.flush()
+}
+
+func (w *Writer) end(isObject bool) error {
+	if w.err != nil {
+		return w.err
+	}
+	if len(w.stack) == 0 || w.stack[len(w.stack)-1].isObject != isObject {
+		if isObject {
+			return w.fail("Writer: EndObject without matching BeginObject")
+		}
+		return w.fail("Writer: EndArray without matching BeginArray")
+	}
+	s := w.stack[len(w.stack)-1]
+	if s.hasKey {
+		return w.fail("Writer: missing value after key")
+	}
+	if !s.opened {
+		// empty container, stays on the line of its key
+		if p := w.parent(); p != nil && p.isObject {
+			w.e.WriteString(" ")
+		}
+		if isObject {
+			w.e.WriteString("{}")
+		} else {
+			w.e.WriteString("[]")
+		}
+	} else {
+		w.e.writeIndent(s.indent)
+		if isObject {
+			w.e.WriteString("}")
+		} else {
+			w.e.WriteString("]")
+		}
+	}
+	w.stack = w.stack[:len(w.stack)-1]
+	w.e.indent = s.indent
+	return w.endValue(len(w.stack) == 0)
+}
+
+// BeginObject starts a new object.
+func (w *Writer) BeginObject() error {
+	return w.begin(true)
+}
+
+// EndObject closes the object started by the last unmatched BeginObject.
+func (w *Writer) EndObject() error {
+	return w.end(true)
+}
+
+// BeginArray starts a new array.
+func (w *Writer) BeginArray() error {
+	return w.begin(false)
+}
+
+// EndArray closes the array started by the last unmatched BeginArray.
+func (w *Writer) EndArray() error {
+	return w.end(false)
+}
+
+// WriteKey writes the name of the next object member. It must be followed
+// by exactly one value.
+func (w *Writer) WriteKey(name string) error {
+	if w.err != nil {
+		return w.err
+	}
+	if len(w.stack) == 0 || !w.stack[len(w.stack)-1].isObject {
+		return w.fail("Writer: WriteKey outside of an object")
+	}
+	s := &w.stack[len(w.stack)-1]
+	if s.hasKey {
+		return w.fail("Writer: missing value after key " + strconv.Quote(name))
+	}
+	w.open()
+	s.hasKey = true
+	w.e.writeIndent(w.e.indent)
+	w.e.WriteString(w.e.quoteName(name))
+	w.e.WriteString(":")
+	return w.flush()
+}
+
+// WriteComment writes a # comment on its own line(s). Comments may appear
+// wherever a key or an array element could start.
+func (w *Writer) WriteComment(text string) error {
+	if w.err != nil {
+		return w.err
+	}
+	if len(w.stack) > 0 {
+		s := &w.stack[len(w.stack)-1]
+		if s.hasKey {
+			return w.fail("Writer: comment between key and value")
+		}
+		w.open()
+	}
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if len(w.stack) > 0 {
+			w.e.writeIndent(w.e.indent)
+		} else if w.started || w.e.Len() > 0 {
+			w.e.WriteString(w.e.Eol)
+		}
+		if len(line) > 0 {
+			line = " " + line
+		}
+		w.e.WriteString("#" + line)
+	}
+	return w.flush()
+}
+
+// WriteValue writes any value that Marshal can encode.
+func (w *Writer) WriteValue(v interface{}) error {
+	noIndent, separator, isRoot, err := w.beginValue()
+	if err != nil {
+		return err
+	}
+	if err = w.e.str(reflect.ValueOf(v), noIndent, separator, isRoot); err != nil {
+		w.err = err
+		return err
+	}
+	return w.endValue(isRoot)
+}
+
+// WriteString writes a string value. It is quoted only if necessary.
+func (w *Writer) WriteString(s string) error {
+	return w.WriteValue(s)
+}
+
+// WriteInt writes an integer value.
+func (w *Writer) WriteInt(i int64) error {
+	return w.WriteValue(i)
+}
+
+// WriteFloat writes a floating point value.
+func (w *Writer) WriteFloat(f float64) error {
+	return w.WriteValue(f)
+}
+
+// WriteBool writes true or false.
+func (w *Writer) WriteBool(b bool) error {
+	return w.WriteValue(b)
+}
+
+// WriteNull writes null.
+func (w *Writer) WriteNull() error {
+	return w.WriteValue(nil)
+}
+
+// Close reports an error if the output is not a complete Hjson document.
+// It does not close the underlying io.Writer.
+func (w *Writer) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	if len(w.stack) > 0 {
+		return w.fail("Writer: unclosed object or array")
+	}
+	if !w.done {
+		return w.fail("Writer: no value written")
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package hjson
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriterMatchesMarshal(t *testing.T) {
+	value := map[string]interface{}{
+		"a": 1,
+		"b": []interface{}{"x", map[string]interface{}{}, []interface{}{}},
+		"c": map[string]interface{}{"d": true, "e": nil},
+		"f": map[string]interface{}{},
+		"g": "multi\nline",
+	}
+	for _, sameLine := range []bool{false, true} {
+		opt := DefaultOptions()
+		opt.BracesSameLine = sameLine
+		expected, err := MarshalWithOptions(value, opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+		w := NewWriter(&buf, opt)
+		w.BeginObject()
+		w.WriteKey("a")
+		w.WriteInt(1)
+		w.WriteKey("b")
+		w.BeginArray()
+		w.WriteString("x")
+		w.BeginObject()
+		w.EndObject()
+		w.BeginArray()
+		w.EndArray()
+		w.EndArray()
+		w.WriteKey("c")
+		w.BeginObject()
+		w.WriteKey("d")
+		w.WriteBool(true)
+		w.WriteKey("e")
+		w.WriteNull()
+		w.EndObject()
+		w.WriteKey("f")
+		w.BeginObject()
+		w.EndObject()
+		w.WriteKey("g")
+		w.WriteValue("multi\nline")
+		w.EndObject()
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != string(expected) {
+			t.Errorf("expected\n%s\ngot\n%s", expected, buf.String())
+		}
+	}
+}
+
+func TestWriterComments(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, DefaultOptions())
+	w.WriteComment("generated")
+	w.BeginObject()
+	w.WriteComment("the rate\nin requests/second")
+	w.WriteKey("rate")
+	w.WriteInt(1000)
+	w.EndObject()
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	expected := "# generated\n{\n  # the rate\n  # in requests/second\n  rate: 1000\n}"
+	if buf.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf.String())
+	}
+	var out map[string]interface{}
+	if err := Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriterErrors(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, DefaultOptions())
+	w.BeginObject()
+	if err := w.WriteInt(1); err == nil {
+		t.Error("expected an error for a value without key")
+	}
+	if err := w.EndObject(); err == nil {
+		t.Error("expected the first error to stick")
+	}
+
+	w = NewWriter(&buf, DefaultOptions())
+	w.BeginArray()
+	if err := w.EndObject(); err == nil {
+		t.Error("expected an error for mismatched end")
+	}
+
+	w = NewWriter(&buf, DefaultOptions())
+	w.BeginArray()
+	if err := w.Close(); err == nil {
+		t.Error("expected an error for an unclosed array")
+	}
+}
@@ -0,0 +1,985 @@
+package hjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ToYAML converts the Hjson document data to YAML, keeping its comments.
+//
+// Objects become block mappings and arrays block sequences, indented by two
+// spaces; empty ones are written as {} and []. Strings are written plain
+// where YAML reads them back as the same string, as literal block scalars
+// if they span several lines and double-quoted otherwise. Numbers keep
+// their literal. Comments are written with #, a block comment on one line
+// for each of its lines, before the value they precede or at the end of
+// the line of the value they follow.
+func ToYAML(data []byte) ([]byte, error) {
+	root, err := ParseNode(data)
+	if err != nil {
+		return nil, err
+	}
+	e := &yamlEncoder{}
+	line, others := splitComments(root.Comments.Before)
+	e.comments(trimLeadingBlank(append(line, others...)), 0)
+	var rootLine, tail []string
+	if !root.braceless {
+		// the comments at the end of a braceless root are in After
+		rootLine, tail = splitComments(root.Comments.Line)
+	}
+	switch s, _ := root.Value.(string); {
+	case root.Kind == ValueNode && strings.Contains(s, "\n"):
+		// not as a block scalar, which is indented
+		var buf bytes.Buffer
+		writeJSONString(&buf, s)
+		e.WriteString(buf.String() + hashLineComment(rootLine) + "\n")
+	case root.Kind == ValueNode:
+		head, _ := yamlScalar(root, 0)
+		e.WriteString(head + hashLineComment(rootLine) + "\n")
+	case len(root.Children) == 0:
+		e.WriteString(yamlEmpty(root) + hashLineComment(rootLine) + "\n")
+		e.comments(insideComments(root), 0)
+	default:
+		e.block(root, 0)
+		tail = append(rootLine, tail...)
+	}
+	e.comments(trimTrailingBlank(tail), 0)
+	return e.Bytes(), nil
+}
+
+// yamlEmpty returns the YAML of n, an empty object or array.
+func yamlEmpty(n *Node) string {
+	if n.Kind == ObjectNode {
+		return "{}"
+	}
+	return "[]"
+}
+
+// yamlEncoder writes YAML for ToYAML.
+type yamlEncoder struct {
+	bytes.Buffer
+}
+
+// block writes the members or elements of n, a non-empty object or array,
+// at indent.
+func (e *yamlEncoder) block(n *Node, indent int) {
+	pad := strings.Repeat(" ", indent)
+	before, line := childComments(n)
+	for i, c := range n.Children {
+		items := before[i]
+		if i == 0 {
+			items = trimLeadingBlank(items)
+		}
+		e.comments(items, indent)
+		if n.Kind == ObjectNode {
+			e.WriteString(pad + yamlKey(c.Key) + ":")
+			e.value(c, indent, line[i], false)
+		} else {
+			e.WriteString(pad + "-")
+			e.value(c, indent, line[i], true)
+		}
+	}
+	e.comments(insideComments(n), indent)
+}
+
+// value writes n after its key or the "-" of a sequence entry at indent,
+// with the comments line at the end of its line.
+func (e *yamlEncoder) value(n *Node, indent int, line []string, entry bool) {
+	switch {
+	case n.Kind == ValueNode:
+		head, body := yamlScalar(n, indent+2)
+		e.WriteString(" " + head + hashLineComment(line) + "\n" + body)
+	case len(n.Children) == 0:
+		e.WriteString(" " + yamlEmpty(n) + hashLineComment(line) + "\n")
+		e.comments(insideComments(n), indent+2)
+	case entry && len(line) == 0:
+		// the first member or element on the line of the "-"
+		nested := &yamlEncoder{}
+		nested.block(n, indent+2)
+		e.WriteString(" ")
+		e.Write(nested.Bytes()[indent+2:])
+	default:
+		e.WriteString(hashLineComment(line) + "\n")
+		e.block(n, indent+2)
+	}
+}
+
+// comments writes the comments in items, each on its own line at indent.
+// An empty item stands for a blank line.
+func (e *yamlEncoder) comments(items []string, indent int) {
+	writeHashComments(&e.Buffer, items, strings.Repeat(" ", indent))
+}
+
+// writeHashComments writes the comments in items to buf, each on its own
+// line after pad, see hashComment. An empty item stands for a blank line.
+func writeHashComments(buf *bytes.Buffer, items []string, pad string) {
+	for _, item := range items {
+		if item == "" {
+			buf.WriteString("\n")
+			continue
+		}
+		for _, line := range hashComment(item) {
+			buf.WriteString(pad + line + "\n")
+		}
+	}
+}
+
+// hashComment returns the lines of the comment starting with #, as YAML
+// and TOML use, for an Hjson comment.
+func hashComment(comment string) []string {
+	switch {
+	case strings.HasPrefix(comment, "#"):
+		return []string{comment}
+	case strings.HasPrefix(comment, "//"):
+		return []string{"#" + comment[2:]}
+	}
+	text := strings.TrimSuffix(strings.TrimPrefix(comment, "/*"), "*/")
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" || len(lines) > 0 {
+			lines = append(lines, strings.TrimRight("# "+line, " "))
+		}
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "#" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// hashLineComment returns the comments written at the end of a line.
+func hashLineComment(comments []string) string {
+	var texts []string
+	for _, comment := range comments {
+		for _, line := range hashComment(comment) {
+			if text := strings.TrimSpace(line[1:]); text != "" {
+				texts = append(texts, text)
+			}
+		}
+	}
+	if len(texts) == 0 {
+		return ""
+	}
+	return " # " + strings.Join(texts, " ")
+}
+
+// yamlScalar returns the YAML of the value of n, for its line and, for a
+// block scalar, for the following lines at indent.
+func yamlScalar(n *Node, indent int) (head, body string) {
+	switch v := n.Value.(type) {
+	case nil:
+		return "null", ""
+	case bool:
+		return strconv.FormatBool(v), ""
+	case float64:
+		if n.unchanged() {
+			return n.literal, ""
+		}
+		return strconv.FormatFloat(v, 'g', -1, 64), ""
+	case string:
+		return yamlString(v, indent)
+	}
+	var buf bytes.Buffer
+	if err := writeCompactJSON(&buf, n.Value); err != nil {
+		return "null", ""
+	}
+	return buf.String(), ""
+}
+
+// yamlString returns s as a plain, literal block or double-quoted scalar.
+func yamlString(s string, indent int) (head, body string) {
+	if yamlPlain(s) {
+		return s, ""
+	}
+	if !strings.Contains(s, "\n") || strings.TrimSpace(s) == "" || !yamlPrintable(s, "\n\t") || strings.Contains(s, " \n") {
+		var buf bytes.Buffer
+		writeJSONString(&buf, s)
+		return buf.String(), ""
+	}
+	text := strings.TrimRight(s, "\n")
+	head = "|"
+	if first := strings.TrimLeft(text, "\n"); first[0] == ' ' || first[0] == '\t' {
+		// the indentation of the content is not that of its first line
+		head += "2"
+	}
+	switch trailing := len(s) - len(text); {
+	case trailing == 0:
+		head += "-"
+	case trailing > 1:
+		head += "+"
+	}
+	pad := strings.Repeat(" ", indent)
+	for _, line := range strings.Split(text, "\n") {
+		if line == "" {
+			body += "\n"
+		} else {
+			body += pad + line + "\n"
+		}
+	}
+	for i := 1; i < len(s)-len(text); i++ {
+		body += "\n"
+	}
+	return head, body
+}
+
+// yamlPrintable reports whether s is valid UTF-8 without control
+// characters other than those in allowed.
+func yamlPrintable(s, allowed string) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+	for _, r := range s {
+		if (r < 0x20 || r >= 0x7f && r < 0xa0 || r == 0xfeff) && !strings.ContainsRune(allowed, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// yamlPlain reports whether s can be written as a plain scalar that YAML
+// reads back as the same string. Strings that YAML 1.1 reads as other
+// types, like yes or 2001-12-14, are quoted as well.
+func yamlPlain(s string) bool {
+	if s == "" || strings.TrimSpace(s) != s || !yamlPrintable(s, "") {
+		return false
+	}
+	if strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`0123456789.+") {
+		return false
+	}
+	if strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+		return false
+	}
+	switch strings.ToLower(s) {
+	case "~", "null", "true", "false", "yes", "no", "on", "off", "y", "n":
+		return false
+	}
+	return true
+}
+
+// yamlKey returns key as a YAML mapping key.
+func yamlKey(key string) string {
+	if yamlPlain(key) && !strings.ContainsAny(key, ",[]{}") && key != "<<" {
+		return key
+	}
+	var buf bytes.Buffer
+	writeJSONString(&buf, key)
+	return buf.String()
+}
+
+// FromYAML converts the YAML document data to Hjson, formatted with
+// options, keeping its comments where Hjson has a place for them: comments
+// on lines of their own are written before the value that follows them,
+// and a comment at the end of the line of a value after it.
+//
+// Block and flow collections and all kinds of scalars are supported, with
+// plain scalars resolved like the YAML 1.2 core schema does; numbers keep
+// their literal where it is valid Hjson. Anchors, aliases, tags, complex
+// keys and files with several documents are not supported and return an
+// error, as do .inf and .nan, which Hjson cannot represent.
+// options.OutputFormat must be OutputHjson.
+func FromYAML(data []byte, options EncoderOptions) ([]byte, error) {
+	if options.OutputFormat != OutputHjson {
+		return nil, errors.New("FromYAML: OutputFormat JSON is not supported")
+	}
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+	text := strings.TrimPrefix(string(data), "\ufeff")
+	text = strings.TrimSuffix(strings.Replace(text, "\r\n", "\n", -1), "\n")
+	p := &yamlParser{lines: strings.Split(text, "\n")}
+
+	root := &Node{}
+	header := ""
+	ok, err := p.peek()
+	if ok {
+		header = p.takeComments()
+		root, err = p.blockNode(-1)
+	}
+	if err == nil {
+		if ok, err = p.peek(); ok {
+			err = p.errorf("unexpected %q", p.line.text)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(p.comments) > 0 {
+		if root.Kind == ValueNode {
+			root.Comments.Line = strings.TrimLeft(root.Comments.Line+" "+hashLineComment(p.comments)[1:], " ")
+		} else {
+			root.Comments.After = strings.Join(p.comments, "\n")
+		}
+	}
+	root.Comments.Before = joinLines(header, root.Comments.Before)
+	root.style = &options
+	out, err := root.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return append(out, options.eol()...), nil
+}
+
+// yamlLine is a line of YAML content, or the part of a line after a "-" or
+// a key.
+type yamlLine struct {
+	num     int // starting at 1
+	indent  int
+	text    string // without indentation and comment
+	comment string
+}
+
+// yamlParser reads the YAML for FromYAML.
+type yamlParser struct {
+	lines    []string
+	next     int      // index of the next line in lines
+	line     yamlLine // the current line, if ok
+	ok       bool
+	started  bool     // the content of the document has started
+	comments []string // the comments on lines before line
+}
+
+func (p *yamlParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("FromYAML: "+format+" at line %d", append(args, p.line.num)...)
+}
+
+// peek makes the next line with content the current line and reports
+// whether there is one, collecting the comments before it.
+func (p *yamlParser) peek() (bool, error) {
+	for !p.ok && p.next < len(p.lines) {
+		raw := p.lines[p.next]
+		p.next++
+		p.line = yamlLine{num: p.next}
+		text := strings.TrimLeft(raw, " ")
+		switch {
+		case strings.HasPrefix(text, "\t"):
+			return false, p.errorf("tabs are not allowed for indentation")
+		case strings.TrimSpace(text) == "":
+			continue
+		case text[0] == '#':
+			p.comments = append(p.comments, strings.TrimRight(text, " \t"))
+			continue
+		case raw == "---" || strings.HasPrefix(raw, "--- ") || strings.HasPrefix(raw, "---\t"):
+			if p.started {
+				return false, p.errorf("several documents are not supported")
+			}
+			p.started = true
+			if text = strings.TrimLeft(raw[3:], " \t"); text == "" || text[0] == '#' {
+				continue
+			}
+			// the root starts on the line of the marker
+			raw = strings.Repeat(" ", len(raw)-len(text)) + text
+		case raw == "..." || strings.HasPrefix(raw, "... "):
+			// the end of the document
+			for _, rest := range p.lines[p.next:] {
+				if text := strings.TrimSpace(rest); text != "" && text[0] != '#' {
+					return false, p.errorf("several documents are not supported")
+				}
+			}
+			p.next = len(p.lines)
+			return false, nil
+		case raw[0] == '%' && !p.started:
+			// a directive
+			continue
+		}
+		p.started = true
+		text, comment := splitYAMLComment(strings.TrimLeft(raw, " "))
+		p.line = yamlLine{p.next, len(raw) - len(strings.TrimLeft(raw, " ")), text, comment}
+		p.ok = true
+	}
+	return p.ok, nil
+}
+
+// takeComments returns the comments collected before the current line.
+func (p *yamlParser) takeComments() string {
+	comments := strings.Join(p.comments, "\n")
+	p.comments = nil
+	return comments
+}
+
+// splitYAMLComment splits a line into its content and the comment at its
+// end, if any.
+func splitYAMLComment(text string) (content, comment string) {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && (i == 0 || strings.IndexByte(" \t[{,:-?", text[i-1]) >= 0):
+			quote = c
+		case c == '#' && (i == 0 || text[i-1] == ' ' || text[i-1] == '\t'):
+			return strings.TrimRight(text[:i], " \t"), strings.TrimRight(text[i:], " \t")
+		}
+	}
+	return strings.TrimRight(text, " \t"), ""
+}
+
+// isSequenceEntry reports whether text starts an entry of a block sequence.
+func isSequenceEntry(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ") || strings.HasPrefix(text, "-\t")
+}
+
+// splitKey splits text, a line of a block mapping, into the key and the
+// text after the colon. ok is false if text is not a mapping entry.
+func (p *yamlParser) splitKey(text string) (key, rest string, ok bool, err error) {
+	end := 0
+	switch {
+	case text[0] == '"' || text[0] == '\'':
+		pos := 0
+		if key, err = p.quoted(text, &pos); err == errYAMLEnd {
+			// a quoted scalar spanning several lines
+			return "", "", false, nil
+		} else if err != nil {
+			return "", "", false, err
+		}
+		end = pos
+		for end < len(text) && text[end] == ' ' {
+			end++
+		}
+		if end == len(text) || text[end] != ':' {
+			return "", "", false, nil
+		}
+	case strings.IndexByte("[{", text[0]) >= 0:
+		return "", "", false, nil
+	case text[0] == '?' && (len(text) == 1 || text[1] == ' '):
+		return "", "", false, p.errorf("complex keys are not supported")
+	default:
+		end = -1
+		for i := 0; i < len(text) && end < 0; i++ {
+			if text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ' || text[i+1] == '\t') {
+				end = i
+			}
+		}
+		if end < 0 {
+			return "", "", false, nil
+		}
+		key = strings.TrimRight(text[:end], " \t")
+		if err = yamlUnsupported(key); err != nil {
+			return "", "", false, p.errorf("%v", err)
+		}
+	}
+	return key, strings.TrimLeft(text[end+1:], " \t"), true, nil
+}
+
+// yamlUnsupported returns an error if text starts with an anchor, alias or
+// tag.
+func yamlUnsupported(text string) error {
+	if text == "" {
+		return nil
+	}
+	switch text[0] {
+	case '&':
+		return errors.New("anchors are not supported")
+	case '*':
+		return errors.New("aliases are not supported")
+	case '!':
+		return errors.New("tags are not supported")
+	}
+	return nil
+}
+
+// blockNode parses the node starting at the current line, inside a node at
+// the indentation parent.
+func (p *yamlParser) blockNode(parent int) (*Node, error) {
+	if isSequenceEntry(p.line.text) {
+		return p.sequence(p.line.indent)
+	}
+	if _, _, ok, err := p.splitKey(p.line.text); ok || err != nil {
+		if err != nil {
+			return nil, err
+		}
+		return p.mapping(p.line.indent)
+	}
+	return p.scalar(parent)
+}
+
+// nested parses the value of an entry at indent whose value starts on the
+// next line, or is empty.
+func (p *yamlParser) nested(indent int, inSequence bool) (*Node, error) {
+	ok, err := p.peek()
+	if err != nil {
+		return nil, err
+	}
+	if !ok || p.line.indent < indent || p.line.indent == indent && (inSequence || !isSequenceEntry(p.line.text)) {
+		return &Node{}, nil
+	}
+	return p.blockNode(indent)
+}
+
+// sequence parses a block sequence at indent.
+func (p *yamlParser) sequence(indent int) (*Node, error) {
+	n := &Node{Kind: ArrayNode}
+	for {
+		ok, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		if !ok || p.line.indent != indent || !isSequenceEntry(p.line.text) {
+			break
+		}
+		before := p.takeComments()
+		var c *Node
+		if rest := strings.TrimLeft(p.line.text[1:], " \t"); rest == "" {
+			comment := p.line.comment
+			p.ok = false
+			if c, err = p.nested(indent, true); err == nil {
+				before = joinLines(before, comment)
+			}
+		} else {
+			p.line.indent += len(p.line.text) - len(rest)
+			p.line.text = rest
+			c, err = p.blockNode(indent)
+		}
+		if err != nil {
+			return nil, err
+		}
+		c.Comments.Before = joinLines(before, c.Comments.Before)
+		n.Children = append(n.Children, c)
+	}
+	return n, p.checkIndent(indent)
+}
+
+// mapping parses a block mapping at indent.
+func (p *yamlParser) mapping(indent int) (*Node, error) {
+	n := &Node{Kind: ObjectNode}
+	for {
+		ok, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		if !ok || p.line.indent != indent || isSequenceEntry(p.line.text) {
+			break
+		}
+		key, rest, ok, err := p.splitKey(p.line.text)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, p.errorf("expected a key instead of %q", p.line.text)
+		}
+		before := p.takeComments()
+		var c *Node
+		if rest == "" {
+			comment := p.line.comment
+			p.ok = false
+			if c, err = p.nested(indent, false); err == nil {
+				before = joinLines(before, comment)
+			}
+		} else {
+			p.line.indent += len(p.line.text) - len(rest)
+			p.line.text = rest
+			c, err = p.scalar(indent)
+		}
+		if err != nil {
+			return nil, err
+		}
+		c.Key = key
+		c.Comments.Before = joinLines(before, c.Comments.Before)
+		n.Children = append(n.Children, c)
+	}
+	return n, p.checkIndent(indent)
+}
+
+// checkIndent fails if the current line is indented more than the
+// collection at indent that ends before it.
+func (p *yamlParser) checkIndent(indent int) error {
+	if p.ok && p.line.indent > indent {
+		return p.errorf("bad indentation of %q", p.line.text)
+	}
+	return nil
+}
+
+// joinLines joins two texts of comments with a line break.
+func joinLines(a, b string) string {
+	if a == "" || b == "" {
+		return a + b
+	}
+	return a + "\n" + b
+}
+
+// scalar parses the scalar or flow collection starting at the current
+// line, inside a node at the indentation parent.
+func (p *yamlParser) scalar(parent int) (*Node, error) {
+	text, comment := p.line.text, p.line.comment
+	p.ok = false
+	var n *Node
+	var err error
+	switch {
+	case text[0] == '|' || text[0] == '>':
+		var s string
+		if s, err = p.blockScalar(text, parent); err == nil {
+			n = &Node{Value: s}
+		}
+	case text[0] == '[' || text[0] == '{' || text[0] == '"' || text[0] == '\'':
+		for {
+			pos := 0
+			if n, err = p.flowNode(text, &pos); err == nil {
+				if pos < len(text) {
+					err = p.errorf("unexpected %q", text[pos:])
+				}
+				break
+			}
+			if err != errYAMLEnd || p.next == len(p.lines) {
+				if err == errYAMLEnd {
+					err = p.errorf("unexpected end of the input")
+				}
+				break
+			}
+			// a flow collection or quoted scalar spanning several lines
+			more, moreComment := splitYAMLComment(strings.TrimSpace(p.lines[p.next]))
+			p.next++
+			p.line.num = p.next
+			text += "\n" + more
+			comment = strings.TrimLeft(comment+" "+moreComment, " ")
+		}
+	default:
+		for comment == "" && p.next < len(p.lines) {
+			// the continuation lines of a plain scalar
+			raw := p.lines[p.next]
+			more := strings.TrimLeft(raw, " ")
+			indent := len(raw) - len(more)
+			if more != "" && (indent <= parent || more[0] == '#' || isSequenceEntry(more)) {
+				break
+			}
+			if more != "" {
+				if _, _, ok, _ := p.splitKey(more); ok {
+					break
+				}
+			}
+			more, moreComment := splitYAMLComment(more)
+			p.next++
+			text += "\n" + more
+			if moreComment != "" {
+				comment = moreComment
+				break
+			}
+		}
+		text = strings.TrimRight(foldLines(text), "\n")
+		if strings.Contains(text, ": ") {
+			return nil, p.errorf("mapping values are not allowed in %q", text)
+		}
+		n, err = p.plain(text)
+	}
+	if err != nil {
+		return nil, err
+	}
+	n.Comments.Line = comment
+	return n, nil
+}
+
+// foldLines joins the lines of a plain or quoted scalar: a single line
+// break becomes a space and each blank line a line break.
+func foldLines(text string) string {
+	if !strings.Contains(text, "\n") {
+		return text
+	}
+	var buf bytes.Buffer
+	breaks := 0
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			line = strings.TrimLeft(line, " \t")
+		}
+		if line == "" && i > 0 {
+			breaks++
+			continue
+		}
+		switch {
+		case i == 0:
+		case breaks == 0:
+			buf.WriteByte(' ')
+		default:
+			buf.WriteString(strings.Repeat("\n", breaks))
+		}
+		buf.WriteString(strings.TrimRight(line, " \t"))
+		breaks = 0
+	}
+	return buf.String()
+}
+
+// blockScalar parses the literal or folded block scalar with the header
+// text, inside a node at the indentation parent.
+func (p *yamlParser) blockScalar(header string, parent int) (string, error) {
+	chomp, indent := byte(0), -1 // detected from the first line with content
+	for _, c := range []byte(header[1:]) {
+		switch {
+		case (c == '-' || c == '+') && chomp == 0:
+			chomp = c
+		case c >= '1' && c <= '9' && indent < 0:
+			indent = parent + int(c-'0')
+		default:
+			return "", p.errorf("invalid block scalar header %q", header)
+		}
+	}
+
+	var lines []string
+	end := p.next // after the last line with content
+	for i := p.next; i < len(p.lines); i++ {
+		raw := p.lines[i]
+		text := strings.TrimLeft(raw, " ")
+		spaces := len(raw) - len(text)
+		if text == "" {
+			lines = append(lines, "")
+			continue
+		}
+		if indent < 0 {
+			if spaces <= parent {
+				break
+			}
+			indent = spaces
+		}
+		if spaces < indent {
+			break
+		}
+		lines = append(lines, raw[indent:])
+		end = i + 1
+	}
+	lines = lines[:end-p.next]
+	trailing := 0 // blank lines after the content
+	for i := end; i < len(p.lines) && strings.TrimSpace(p.lines[i]) == ""; i++ {
+		trailing++
+	}
+	p.next = end
+	p.line.num = end
+
+	var text string
+	if header[0] == '|' {
+		text = strings.Join(lines, "\n")
+	} else {
+		var buf bytes.Buffer
+		breaks, prevNormal := 0, false
+		for i, line := range lines {
+			if line == "" {
+				breaks++
+				continue
+			}
+			normal := line[0] != ' ' && line[0] != '\t'
+			switch {
+			case i == breaks:
+				// leading blank lines
+				buf.WriteString(strings.Repeat("\n", breaks))
+			case prevNormal && normal && breaks == 0:
+				buf.WriteByte(' ')
+			case prevNormal && normal:
+				buf.WriteString(strings.Repeat("\n", breaks))
+			default:
+				buf.WriteString(strings.Repeat("\n", breaks+1))
+			}
+			buf.WriteString(line)
+			breaks, prevNormal = 0, normal
+		}
+		text = buf.String()
+	}
+	switch {
+	case chomp == '-' || len(lines) == 0 && chomp == 0:
+	case chomp == '+':
+		text += strings.Repeat("\n", trailing+1)
+	default:
+		text += "\n"
+	}
+	return text, nil
+}
+
+// errYAMLEnd is returned by flowNode for input that ends inside a flow
+// collection or quoted scalar.
+var errYAMLEnd = errors.New("end of the input")
+
+// flowNode parses the flow collection or scalar at text[*pos].
+func (p *yamlParser) flowNode(text string, pos *int) (*Node, error) {
+	skipSpace(text, pos)
+	if *pos == len(text) {
+		return nil, errYAMLEnd
+	}
+	switch text[*pos] {
+	case '[', '{':
+		open := text[*pos]
+		n := &Node{Kind: ArrayNode}
+		end := byte(']')
+		if open == '{' {
+			n.Kind, end = ObjectNode, '}'
+		}
+		*pos++
+		for {
+			skipSpace(text, pos)
+			if *pos == len(text) {
+				return nil, errYAMLEnd
+			}
+			if text[*pos] == end {
+				*pos++
+				return n, nil
+			}
+			var c *Node
+			var err error
+			if open == '{' {
+				c, err = p.flowMember(text, pos)
+			} else {
+				c, err = p.flowNode(text, pos)
+			}
+			if err != nil {
+				return nil, err
+			}
+			n.Children = append(n.Children, c)
+			skipSpace(text, pos)
+			if *pos == len(text) {
+				return nil, errYAMLEnd
+			}
+			switch text[*pos] {
+			case ',':
+				*pos++
+			case end:
+			default:
+				return nil, p.errorf("expected ',' or '%c' instead of %q", end, text[*pos:])
+			}
+		}
+	case '"', '\'':
+		s, err := p.quoted(text, pos)
+		if err != nil {
+			return nil, err
+		}
+		return &Node{Value: s}, nil
+	case ']', '}', ',':
+		return nil, p.errorf("unexpected '%c'", text[*pos])
+	}
+	start := *pos
+	for *pos < len(text) && strings.IndexByte(",[]{}", text[*pos]) < 0 &&
+		!(text[*pos] == ':' && (*pos+1 == len(text) || strings.IndexByte(" \t\n,[]{}", text[*pos+1]) >= 0)) {
+		*pos++
+	}
+	return p.plain(foldLines(strings.TrimRight(text[start:*pos], " \t\n")))
+}
+
+// flowMember parses a member of a flow mapping at text[*pos].
+func (p *yamlParser) flowMember(text string, pos *int) (*Node, error) {
+	k, err := p.flowNode(text, pos)
+	if err != nil {
+		return nil, err
+	}
+	if k.Kind != ValueNode {
+		return nil, p.errorf("complex keys are not supported")
+	}
+	key, ok := k.Value.(string)
+	if !ok {
+		// a key like 1 or true
+		var buf bytes.Buffer
+		writeCompactJSON(&buf, k.Value)
+		key = buf.String()
+	}
+	skipSpace(text, pos)
+	if *pos < len(text) && text[*pos] == ':' {
+		*pos++
+		skipSpace(text, pos)
+		if *pos < len(text) && text[*pos] != ',' && text[*pos] != '}' {
+			c, err := p.flowNode(text, pos)
+			if err != nil {
+				return nil, err
+			}
+			c.Key = key
+			return c, nil
+		}
+	}
+	return &Node{Key: key}, nil
+}
+
+func skipSpace(text string, pos *int) {
+	for *pos < len(text) && strings.IndexByte(" \t\n", text[*pos]) >= 0 {
+		*pos++
+	}
+}
+
+// quoted parses the single- or double-quoted scalar at text[*pos].
+func (p *yamlParser) quoted(text string, pos *int) (string, error) {
+	quote := text[*pos]
+	var buf bytes.Buffer
+	start := *pos + 1
+	for i := start; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == quote && quote == '\'' && i+1 < len(text) && text[i+1] == '\'':
+			buf.WriteString(foldLines(text[start:i]) + "'")
+			i++
+			start = i + 1
+		case c == quote:
+			buf.WriteString(foldLines(text[start:i]))
+			*pos = i + 1
+			return buf.String(), nil
+		case c == '\\' && quote == '"':
+			buf.WriteString(foldLines(text[start:i]))
+			if i+1 == len(text) {
+				return "", errYAMLEnd
+			}
+			i++
+			s, n, err := yamlEscape(text[i:])
+			if err != nil {
+				return "", p.errorf("%v", err)
+			}
+			buf.WriteString(s)
+			i += n - 1
+			start = i + 1
+		}
+	}
+	return "", errYAMLEnd
+}
+
+// yamlEscape returns the text of the escape sequence at the start of text,
+// after the backslash, and its length.
+func yamlEscape(text string) (string, int, error) {
+	simple := map[byte]string{
+		'0': "\x00", 'a': "\a", 'b': "\b", 't': "\t", '\t': "\t", 'n': "\n",
+		'v': "\v", 'f': "\f", 'r': "\r", 'e': "\x1b", ' ': " ", '"': "\"",
+		'/': "/", '\\': "\\", 'N': "\u0085", '_': " ", 'L': " ",
+		'P': " ", '\n': "",
+	}
+	if s, ok := simple[text[0]]; ok {
+		return s, 1, nil
+	}
+	size := map[byte]int{'x': 2, 'u': 4, 'U': 8}[text[0]]
+	if size == 0 || len(text) < 1+size {
+		return "", 0, fmt.Errorf("invalid escape sequence '\\%c'", text[0])
+	}
+	r, err := strconv.ParseUint(text[1:1+size], 16, 32)
+	if err != nil || !utf8.ValidRune(rune(r)) {
+		return "", 0, fmt.Errorf("invalid escape sequence '\\%s'", text[:1+size])
+	}
+	return string(rune(r)), 1 + size, nil
+}
+
+// plain returns the node for the plain scalar text, resolved like the YAML
+// 1.2 core schema does.
+func (p *yamlParser) plain(text string) (*Node, error) {
+	if err := yamlUnsupported(text); err != nil {
+		return nil, p.errorf("%v", err)
+	}
+	switch text {
+	case "", "~", "null", "Null", "NULL":
+		return &Node{}, nil
+	case "true", "True", "TRUE":
+		return &Node{Value: true}, nil
+	case "false", "False", "FALSE":
+		return &Node{Value: false}, nil
+	case ".inf", ".Inf", ".INF", "+.inf", "+.Inf", "+.INF", "-.inf", "-.Inf", "-.INF", ".nan", ".NaN", ".NAN":
+		return nil, p.errorf("%s cannot be represented in Hjson", text)
+	}
+	if strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "0o") {
+		base := map[byte]int{'x': 16, 'o': 8}[text[1]]
+		if v, err := strconv.ParseUint(text[2:], base, 64); err == nil {
+			return &Node{Value: float64(v)}, nil
+		}
+	}
+	if strings.Trim(text, "0123456789.eE+-") == "" && strings.IndexAny(text, "0123456789") >= 0 {
+		if v, err := strconv.ParseFloat(text, 64); err == nil {
+			n := &Node{Value: v}
+			if json.Valid([]byte(text)) {
+				// keep the literal, like 1.50
+				n.literal, n.parsedValue = text, v
+			}
+			return n, nil
+		}
+	}
+	return &Node{Value: text}, nil
+}
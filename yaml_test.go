@@ -0,0 +1,232 @@
+package hjson
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestToYAML(t *testing.T) {
+	input := `# config
+{
+  port: 80 // http
+  /* hosts
+     to serve */
+  hosts: ["a.example.com", "b.example.com"]
+
+  motd:
+    '''
+    hello
+      world
+    '''
+  items: [{a: 1, b: "yes"}, [1.50, 2], {}, null]
+  "key: x": "true"
+  "": 2001-12-14
+}
+# end
+`
+	expected := `# config
+port: 80 # http
+# hosts
+# to serve
+hosts:
+  - a.example.com
+  - b.example.com
+
+motd: |-
+  hello
+    world
+items:
+  - a: 1
+    b: "yes"
+  - - 1.50
+    - 2
+  - {}
+  - null
+"key: x": "true"
+"": "2001-12-14"
+# end
+`
+	out, err := ToYAML([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out)
+	}
+
+	for input, expected := range map[string]string{
+		"42 # answer":        "42 # answer\n",
+		"'a\\nb'":            "\"a\\nb\"\n",
+		"[]":                 "[]\n",
+		"a: '  x\\ny\\n\\n'": "a: |2+\n    x\n  y\n\n",
+	} {
+		if out, err = ToYAML([]byte(input)); err != nil || string(out) != expected {
+			t.Errorf("%q: expected %q, got %q, %v", input, expected, out, err)
+		}
+	}
+	if _, err = ToYAML([]byte("{a: 1")); err == nil {
+		t.Error("expected an error for invalid Hjson")
+	}
+}
+
+func TestFromYAML(t *testing.T) {
+	input := `%YAML 1.2
+---
+# header
+name: test   # the name
+list:
+- a
+-   b: 1
+    c: [x, "y z", {k: v}]
+- - 2.50
+  - 0x1F
+block: |
+  line 1
+    indented
+
+folded: >-
+  one
+  two
+
+  three
+quoted: "tab\tq\u00e9"
+single: 'it''s'
+multi: plain text
+  continued
+empty:
+flow: {a: ~,
+  b: true}
+url: http://example.com/x#y
+...
+`
+	expected := `# header
+{
+  name: "test" # the name
+  list:
+  [
+    a
+    {
+      b: 1
+      c:
+      [
+        x
+        y z
+        {
+          k: v
+        }
+      ]
+    }
+    [
+      2.50
+      31
+    ]
+  ]
+  block:
+    '''
+    line 1
+      indented
+
+    '''
+  folded:
+    '''
+    one two
+    three
+    '''
+  quoted: '''tab	qé'''
+  single: it's
+  multi: plain text continued
+  empty: null
+  flow:
+  {
+    a: null
+    b: true
+  }
+  url: http://example.com/x#y
+}
+`
+	out, err := FromYAML([]byte(input), DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out)
+	}
+
+	// like Marshal, the root always has braces
+	for input, expected := range map[string]string{
+		"- 1\n- x":  "[\n  1\n  x\n]\n",
+		"just text": "just text\n",
+		"":          "null\n",
+		"a: 1":      "{\n  a: 1\n}\n",
+	} {
+		if out, err = FromYAML([]byte(input), DefaultOptions()); err != nil || string(out) != expected {
+			t.Errorf("%q: expected %q, got %q, %v", input, expected, out, err)
+		}
+	}
+
+	for input, expected := range map[string]string{
+		"a: &x 1":               "anchors are not supported at line 1",
+		"a: *x":                 "aliases are not supported at line 1",
+		"a: !!str 1":            "tags are not supported at line 1",
+		"---\na: 1\n---\nb: 2":  "several documents are not supported at line 3",
+		"a: .inf":               ".inf cannot be represented in Hjson at line 1",
+		"a:\n\t b: 1":           "tabs are not allowed for indentation at line 2",
+		"a: b: c":               "mapping values are not allowed",
+		"? x\n: y":              "complex keys are not supported at line 1",
+		"a: [1,\n  2":           "unexpected end of the input at line 2",
+		"a: 1\n  b: 2":          "bad indentation",
+		"a:\n  - 1\n  x: 2":     "bad indentation",
+		"a: 1\nfoo":             "expected a key instead of \"foo\" at line 2",
+		"a: \"\\q\"":            "invalid escape sequence",
+		"a: 1\n...\nb: 2":       "several documents are not supported",
+		"[1, 2] x":              "unexpected",
+		"a: {b: [1, 2], c: 3}}": "unexpected",
+		"a: [{b: 1}: 2]":        "expected ',' or ']'",
+		"a: |x\n  b":            "invalid block scalar header",
+		"a: 'unterminated\n\nb": "unexpected end of the input",
+		"a:\n  b: 1\n   c: 2":   "bad indentation",
+		"a: x\n- b":             "unexpected",
+		"a: \"\\u12\"":          "invalid escape sequence",
+		"a: 'x'y":               "unexpected",
+		"- a\nb: 1":             "unexpected",
+	} {
+		if _, err = FromYAML([]byte(input), DefaultOptions()); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%q: expected an error with %q, got %v", input, expected, err)
+		}
+	}
+
+	opt := DefaultOptions()
+	opt.OutputFormat = OutputJSON
+	if _, err = FromYAML([]byte("a: 1"), opt); err == nil {
+		t.Error("expected an error for OutputJSON")
+	}
+}
+
+func TestYAMLAssets(t *testing.T) {
+	files := strings.Split(string(getContent("assets/testlist.txt")), "\n")
+	for _, file := range files {
+		if strings.HasPrefix(file, "fail") || strings.HasPrefix(file, "stringify/quotes") || strings.HasPrefix(file, "extra/") {
+			continue
+		}
+		name := strings.TrimSuffix(file, "_test"+file[strings.LastIndex(file, "."):])
+		input := getTestContent(name)
+		yaml, err := ToYAML(input)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		out, err := FromYAML(yaml, DefaultOptions())
+		if err != nil {
+			t.Errorf("%s: %v\n%s", name, err, yaml)
+			continue
+		}
+		var expected, actual interface{}
+		if err = Unmarshal(input, &expected); err != nil {
+			t.Fatal(err)
+		}
+		if err = Unmarshal(out, &actual); err != nil || !reflect.DeepEqual(expected, actual) {
+			t.Errorf("%s: %v, converted through\n%s\nto\n%s", name, err, yaml, out)
+		}
+	}
+}